@@ -0,0 +1,244 @@
+// Package aggregator 将逐笔成交聚合为K线、成交统计等策略使用的数据
+package aggregator
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/types"
+)
+
+const (
+	// ConfirmOpen K线未完结
+	ConfirmOpen = "0"
+	// ConfirmClosed K线已完结
+	ConfirmClosed = "1"
+)
+
+// BarHandler K线回调，Confirm为ConfirmOpen时为未完结K线的快照，ConfirmClosed时为已收盘K线
+type BarHandler func(bar broker.KlineEvent)
+
+// BarAggregator 将逐笔成交按Rule聚合为与broker.KlineEvent兼容的K线，每个交易对独立聚合。
+// 时间K线在下一个周期的第一笔成交到达或调用Advance时收盘，其它类型在达到阈值的那笔成交后立即收盘。
+// 可以在多个goroutine中并发调用
+type BarAggregator struct {
+	mu      sync.Mutex
+	rule    Rule
+	handler BarHandler
+	opts    *barOptions
+	bars    map[string]*barState
+}
+
+// barState 单个交易对正在聚合的K线
+type barState struct {
+	bar  broker.KlineEvent
+	open bool
+	// imbalance 失衡K线的估计状态，跨K线保留
+	imbalance *imbalance
+	// lastClose 上一根K线的收盘价，用于补齐空K线
+	lastClose decimal.Decimal
+}
+
+// NewBarAggregator 创建K线聚合器，handler在调用Update、Advance或Flush的goroutine中持有锁同步执行，不能再调用聚合器的方法
+func NewBarAggregator(rule Rule, handler BarHandler, opts ...BarOption) (*BarAggregator, error) {
+	if err := rule.validate(); err != nil {
+		return nil, err
+	}
+	if handler == nil {
+		return nil, errors.New("handler cannot be nil")
+	}
+	return &BarAggregator{
+		rule:    rule,
+		handler: handler,
+		opts:    applyBarOptions(opts...),
+		bars:    make(map[string]*barState),
+	}, nil
+}
+
+// Rule 返回聚合规则
+func (a *BarAggregator) Rule() Rule {
+	return a.rule
+}
+
+// Update 计入一笔成交。时间K线中早于当前K线开盘时间的迟到成交会被丢弃
+func (a *BarAggregator) Update(trade types.TradeEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s := a.state(trade.Symbol)
+	if a.rule.barType == BarTypeTime {
+		openTime := trade.Timestamp - mod(trade.Timestamp, a.rule.interval)
+		if s.open && openTime < s.bar.OpenTime {
+			return
+		}
+		if s.open && openTime > s.bar.OpenTime {
+			a.closeTimeBars(s, openTime)
+		}
+		if !s.open {
+			s.start(trade, openTime, openTime+a.rule.interval-1)
+		}
+	} else if !s.open {
+		s.start(trade, trade.Timestamp, trade.Timestamp)
+	}
+
+	s.add(trade)
+	if a.rule.barType != BarTypeTime {
+		s.bar.CloseTime = trade.Timestamp
+	}
+
+	if a.shouldClose(s, trade) {
+		a.close(s)
+		return
+	}
+	if a.opts.partial {
+		bar := s.bar
+		bar.Confirm = ConfirmOpen
+		a.handler(bar)
+	}
+}
+
+// Advance 通知聚合器当前时间已到ts（毫秒），收盘所有在ts之前结束的时间K线，
+// 用于在没有新成交时按时收盘，例如由时钟的Ticker驱动。非时间K线忽略该调用
+func (a *BarAggregator) Advance(ts int64) {
+	if a.rule.barType != BarTypeTime {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	openTime := ts - mod(ts, a.rule.interval)
+	for _, s := range a.bars {
+		if s.open && s.bar.OpenTime < openTime {
+			a.closeTimeBars(s, openTime)
+		}
+	}
+}
+
+// Flush 收盘所有未完结的K线，用于数据流结束时
+func (a *BarAggregator) Flush() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, s := range a.bars {
+		if s.open {
+			a.close(s)
+		}
+	}
+}
+
+// Current 返回交易对当前未完结K线的快照
+func (a *BarAggregator) Current(symbol string) (broker.KlineEvent, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.bars[symbol]
+	if !ok || !s.open {
+		return broker.KlineEvent{}, false
+	}
+	bar := s.bar
+	bar.Confirm = ConfirmOpen
+	return bar, true
+}
+
+func (a *BarAggregator) state(symbol string) *barState {
+	s, ok := a.bars[symbol]
+	if !ok {
+		s = &barState{}
+		if a.rule.barType == BarTypeTickImbalance || a.rule.barType == BarTypeVolumeImbalance {
+			s.imbalance = newImbalance(a.rule)
+		}
+		a.bars[symbol] = s
+	}
+	return s
+}
+
+// shouldClose 判断计入trade后是否收盘，调用方需持有锁
+func (a *BarAggregator) shouldClose(s *barState, trade types.TradeEvent) bool {
+	switch a.rule.barType {
+	case BarTypeTick:
+		return s.bar.NumberOfTrades >= a.rule.count
+	case BarTypeVolume:
+		return s.bar.Volume.GreaterThanOrEqual(a.rule.threshold)
+	case BarTypeDollar:
+		return s.bar.QuoteAssetVolume.GreaterThanOrEqual(a.rule.threshold)
+	case BarTypeTickImbalance, BarTypeVolumeImbalance:
+		return s.imbalance.add(trade)
+	}
+	return false
+}
+
+// closeTimeBars 收盘当前时间K线，开启空K线补齐时为nextOpen之前没有成交的周期生成空K线，调用方需持有锁
+func (a *BarAggregator) closeTimeBars(s *barState, nextOpen int64) {
+	a.close(s)
+	if !a.opts.emitEmpty {
+		return
+	}
+	for openTime := s.bar.OpenTime + a.rule.interval; openTime < nextOpen; openTime += a.rule.interval {
+		a.handler(broker.KlineEvent{
+			Symbol:    s.bar.Symbol,
+			OpenTime:  openTime,
+			CloseTime: openTime + a.rule.interval - 1,
+			Open:      s.lastClose,
+			High:      s.lastClose,
+			Low:       s.lastClose,
+			Close:     s.lastClose,
+			Confirm:   ConfirmClosed,
+		})
+	}
+}
+
+// close 收盘当前K线，调用方需持有锁
+func (a *BarAggregator) close(s *barState) {
+	s.open = false
+	s.lastClose = s.bar.Close
+	if s.imbalance != nil {
+		s.imbalance.reset()
+	}
+	bar := s.bar
+	bar.Confirm = ConfirmClosed
+	a.handler(bar)
+}
+
+// start 以trade开启新K线
+func (s *barState) start(trade types.TradeEvent, openTime, closeTime int64) {
+	s.open = true
+	s.bar = broker.KlineEvent{
+		Symbol:    trade.Symbol,
+		OpenTime:  openTime,
+		CloseTime: closeTime,
+		Open:      trade.Price,
+		High:      trade.Price,
+		Low:       trade.Price,
+	}
+}
+
+// add 计入一笔成交
+func (s *barState) add(trade types.TradeEvent) {
+	amount := trade.Price.Mul(trade.Size)
+	if trade.Price.GreaterThan(s.bar.High) {
+		s.bar.High = trade.Price
+	}
+	if trade.Price.LessThan(s.bar.Low) {
+		s.bar.Low = trade.Price
+	}
+	s.bar.Close = trade.Price
+	s.bar.NumberOfTrades++
+	s.bar.Volume = s.bar.Volume.Add(trade.Size)
+	s.bar.QuoteAssetVolume = s.bar.QuoteAssetVolume.Add(amount)
+	if trade.Side == types.SideTypeBuy {
+		s.bar.TakerBuyBaseAssetVolume = s.bar.TakerBuyBaseAssetVolume.Add(trade.Size)
+		s.bar.TakerBuyQuoteAssetVolume = s.bar.TakerBuyQuoteAssetVolume.Add(amount)
+	}
+}
+
+// mod 返回非负余数，使负时间戳也能正确对齐
+func mod(a, b int64) int64 {
+	m := a % b
+	if m < 0 {
+		m += b
+	}
+	return m
+}
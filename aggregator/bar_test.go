@@ -0,0 +1,195 @@
+package aggregator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/types"
+)
+
+func trade(ts int64, price, size string, side types.SideType) types.TradeEvent {
+	return types.TradeEvent{
+		Timestamp: ts,
+		Symbol:    "BTCUSDT",
+		Price:     decimal.RequireFromString(price),
+		Size:      decimal.RequireFromString(size),
+		Side:      side,
+	}
+}
+
+func collect(t *testing.T, rule Rule, opts ...BarOption) (*BarAggregator, *[]broker.KlineEvent) {
+	var bars []broker.KlineEvent
+	a, err := NewBarAggregator(rule, func(bar broker.KlineEvent) { bars = append(bars, bar) }, opts...)
+	require.NoError(t, err)
+	return a, &bars
+}
+
+func assertBar(t *testing.T, bar broker.KlineEvent, open, high, low, close, volume string) {
+	t.Helper()
+	assert.Equal(t, open, bar.Open.String(), "open")
+	assert.Equal(t, high, bar.High.String(), "high")
+	assert.Equal(t, low, bar.Low.String(), "low")
+	assert.Equal(t, close, bar.Close.String(), "close")
+	assert.Equal(t, volume, bar.Volume.String(), "volume")
+}
+
+func TestTimeBars(t *testing.T) {
+	a, bars := collect(t, TimeRule(time.Minute), WithEmptyBars())
+
+	a.Update(trade(60_000, "100", "1", types.SideTypeBuy))
+	a.Update(trade(61_000, "105", "2", types.SideTypeSell))
+	a.Update(trade(119_999, "98", "1", types.SideTypeBuy))
+	// 迟到的成交被丢弃
+	a.Update(trade(59_000, "1", "1", types.SideTypeBuy))
+	require.Empty(t, *bars)
+
+	current, ok := a.Current("BTCUSDT")
+	require.True(t, ok)
+	assert.Equal(t, ConfirmOpen, current.Confirm)
+
+	// 跨过两个周期，中间没有成交的周期生成空K线
+	a.Update(trade(240_500, "101", "3", types.SideTypeBuy))
+	require.Len(t, *bars, 3)
+
+	bar := (*bars)[0]
+	assertBar(t, bar, "100", "105", "98", "98", "4")
+	assert.Equal(t, int64(60_000), bar.OpenTime)
+	assert.Equal(t, int64(119_999), bar.CloseTime)
+	assert.Equal(t, int64(3), bar.NumberOfTrades)
+	assert.Equal(t, "408", bar.QuoteAssetVolume.String())
+	assert.Equal(t, "2", bar.TakerBuyBaseAssetVolume.String())
+	assert.Equal(t, "198", bar.TakerBuyQuoteAssetVolume.String())
+	assert.Equal(t, ConfirmClosed, bar.Confirm)
+
+	for i, openTime := range []int64{120_000, 180_000} {
+		empty := (*bars)[i+1]
+		assert.Equal(t, openTime, empty.OpenTime)
+		assertBar(t, empty, "98", "98", "98", "98", "0")
+	}
+
+	// 没有新成交时由Advance收盘
+	a.Advance(299_999)
+	require.Len(t, *bars, 3)
+	a.Advance(300_000)
+	require.Len(t, *bars, 4)
+	assert.Equal(t, int64(240_000), (*bars)[3].OpenTime)
+	assertBar(t, (*bars)[3], "101", "101", "101", "101", "3")
+
+	_, ok = a.Current("BTCUSDT")
+	assert.False(t, ok)
+}
+
+func TestThresholdBars(t *testing.T) {
+	t.Run("tick", func(t *testing.T) {
+		a, bars := collect(t, TickRule(2), WithPartialBars())
+		a.Update(trade(1, "10", "1", types.SideTypeBuy))
+		a.Update(trade(2, "11", "1", types.SideTypeBuy))
+		a.Update(trade(3, "12", "1", types.SideTypeBuy))
+		a.Flush()
+
+		require.Len(t, *bars, 4)
+		assert.Equal(t, []string{ConfirmOpen, ConfirmClosed, ConfirmOpen, ConfirmClosed},
+			[]string{(*bars)[0].Confirm, (*bars)[1].Confirm, (*bars)[2].Confirm, (*bars)[3].Confirm})
+		assertBar(t, (*bars)[1], "10", "11", "10", "11", "2")
+		assert.Equal(t, int64(1), (*bars)[1].OpenTime)
+		assert.Equal(t, int64(2), (*bars)[1].CloseTime)
+		assertBar(t, (*bars)[3], "12", "12", "12", "12", "1")
+	})
+
+	t.Run("volume", func(t *testing.T) {
+		a, bars := collect(t, VolumeRule(decimal.NewFromInt(3)))
+		a.Update(trade(1, "10", "1", types.SideTypeBuy))
+		a.Update(trade(2, "9", "1.5", types.SideTypeSell))
+		// 达到阈值的成交不拆分
+		a.Update(trade(3, "11", "2", types.SideTypeBuy))
+		a.Update(trade(4, "12", "1", types.SideTypeBuy))
+
+		require.Len(t, *bars, 1)
+		assertBar(t, (*bars)[0], "10", "11", "9", "11", "4.5")
+	})
+
+	t.Run("dollar", func(t *testing.T) {
+		a, bars := collect(t, DollarRule(decimal.NewFromInt(100)))
+		a.Update(trade(1, "10", "5", types.SideTypeBuy))
+		a.Update(trade(2, "10", "5", types.SideTypeBuy))
+		a.Update(trade(3, "10", "1", types.SideTypeBuy))
+
+		require.Len(t, *bars, 1)
+		assert.Equal(t, "100", (*bars)[0].QuoteAssetVolume.String())
+	})
+
+	t.Run("symbols are independent", func(t *testing.T) {
+		a, bars := collect(t, TickRule(2))
+		eth := trade(2, "1", "1", types.SideTypeBuy)
+		eth.Symbol = "ETHUSDT"
+		a.Update(trade(1, "10", "1", types.SideTypeBuy))
+		a.Update(eth)
+		require.Empty(t, *bars)
+		a.Update(trade(3, "10", "1", types.SideTypeBuy))
+		require.Len(t, *bars, 1)
+		assert.Equal(t, "BTCUSDT", (*bars)[0].Symbol)
+	})
+}
+
+func TestImbalanceBars(t *testing.T) {
+	a, bars := collect(t, TickImbalanceRule(4, 1))
+
+	// 第一根K线固定4笔：3买1卖，E[b]=0.5
+	for _, side := range []types.SideType{types.SideTypeBuy, types.SideTypeBuy, types.SideTypeSell, types.SideTypeBuy} {
+		a.Update(trade(1, "10", "1", side))
+	}
+	require.Len(t, *bars, 1)
+	assert.Equal(t, int64(4), (*bars)[0].NumberOfTrades)
+
+	// 阈值为 E[T]×|E[b]| = 4×0.5 = 2，连续两笔买单即收盘
+	a.Update(trade(2, "10", "1", types.SideTypeBuy))
+	a.Update(trade(3, "10", "1", types.SideTypeSell))
+	a.Update(trade(4, "10", "1", types.SideTypeBuy))
+	require.Len(t, *bars, 1)
+	a.Update(trade(5, "10", "1", types.SideTypeBuy))
+	require.Len(t, *bars, 2)
+	assert.Equal(t, int64(4), (*bars)[1].NumberOfTrades)
+}
+
+func TestImbalanceBarsBalanced(t *testing.T) {
+	a, bars := collect(t, TickImbalanceRule(4, 1))
+
+	// 第一根K线买卖均衡，E[b]=0，方向未知的成交不计入笔数
+	for _, side := range []types.SideType{types.SideTypeBuy, types.SideTypeSell, types.SideTypeUnknown, types.SideTypeSell, types.SideTypeBuy} {
+		a.Update(trade(1, "10", "1", side))
+	}
+	require.Len(t, *bars, 1)
+	assert.Equal(t, int64(5), (*bars)[0].NumberOfTrades)
+
+	// 阈值下限为 √E[T]×E[|b|] = 2，不会每笔成交都收盘
+	a.Update(trade(2, "10", "1", types.SideTypeBuy))
+	a.Update(trade(3, "10", "1", types.SideTypeSell))
+	a.Update(trade(4, "10", "1", types.SideTypeBuy))
+	require.Len(t, *bars, 1)
+	a.Update(trade(5, "10", "1", types.SideTypeBuy))
+	require.Len(t, *bars, 2)
+	assert.Equal(t, int64(4), (*bars)[1].NumberOfTrades)
+}
+
+func TestNewBarAggregatorErrors(t *testing.T) {
+	handler := func(broker.KlineEvent) {}
+	for _, rule := range []Rule{
+		{},
+		TimeRule(0),
+		TickRule(0),
+		VolumeRule(decimal.Zero),
+		DollarRule(decimal.NewFromInt(-1)),
+		TickImbalanceRule(0, 0.5),
+		VolumeImbalanceRule(10, 0),
+	} {
+		_, err := NewBarAggregator(rule, handler)
+		assert.Error(t, err, rule.Type().String())
+	}
+	_, err := NewBarAggregator(TickRule(1), nil)
+	assert.Error(t, err)
+}
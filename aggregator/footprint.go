@@ -0,0 +1,127 @@
+package aggregator
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/types"
+)
+
+// FootprintHandler 足迹图回调，profile为bar内成交的分档买卖量、POC和价值区域
+type FootprintHandler func(bar broker.KlineEvent, profile VolumeProfile)
+
+// FootprintAggregator 在K线聚合的同时统计每根K线内的成交量分布（足迹图），
+// K线的切分、收盘时机与回调语义与BarAggregator相同，空K线的分布为空。
+// 档位宽度由 types.Symbol.PricePrecision 决定，所有交易对共用。可以在多个goroutine中并发调用
+type FootprintAggregator struct {
+	mu      sync.Mutex
+	bars    *BarAggregator
+	handler FootprintHandler
+	opts    *profileOptions
+	symbol  types.Symbol
+	states  map[string]*footprintState
+}
+
+// footprintState 单个交易对正在统计的足迹图
+type footprintState struct {
+	bins *profileBins
+	// pending 正在计入的成交。时间K线在下一周期的第一笔成交到达时收盘，
+	// 此时该成交不属于收盘的K线；其他K线在计入成交后收盘，成交属于收盘的K线
+	pending *types.TradeEvent
+}
+
+// NewFootprintAggregator 创建足迹图聚合器，symbol提供价格精度，
+// handler在调用Update、Advance或Flush的goroutine中持有锁同步执行，不能再调用聚合器的方法
+func NewFootprintAggregator(rule Rule, symbol types.Symbol, handler FootprintHandler, opts ...ProfileOption) (*FootprintAggregator, error) {
+	if handler == nil {
+		return nil, errors.New("handler cannot be nil")
+	}
+	o := applyProfileOptions(opts...)
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	f := &FootprintAggregator{
+		handler: handler,
+		opts:    o,
+		symbol:  symbol,
+		states:  make(map[string]*footprintState),
+	}
+	bars, err := NewBarAggregator(rule, f.onBar, o.barOptions...)
+	if err != nil {
+		return nil, err
+	}
+	f.bars = bars
+	return f, nil
+}
+
+// Update 计入一笔成交
+func (f *FootprintAggregator) Update(trade types.TradeEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := f.state(trade.Symbol)
+	s.pending = &trade
+	f.bars.Update(trade)
+	if s.pending != nil {
+		// 迟到被丢弃的成交也会走到这里，只有K线已计入的成交才计入分布
+		if bar, ok := f.bars.Current(trade.Symbol); ok && bar.NumberOfTrades > s.bins.trades {
+			s.bins.add(trade, 1)
+		}
+		s.pending = nil
+	}
+}
+
+// Advance 通知聚合器当前时间已到ts（毫秒），见BarAggregator.Advance
+func (f *FootprintAggregator) Advance(ts int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.bars.Advance(ts)
+}
+
+// Flush 收盘所有未完结的K线，用于数据流结束时
+func (f *FootprintAggregator) Flush() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.bars.Flush()
+}
+
+// Current 返回交易对当前未完结K线及其分布的快照
+func (f *FootprintAggregator) Current(symbol string) (broker.KlineEvent, VolumeProfile, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bar, ok := f.bars.Current(symbol)
+	if !ok {
+		return broker.KlineEvent{}, VolumeProfile{}, false
+	}
+	return bar, f.state(symbol).bins.snapshot(f.opts.valueArea), true
+}
+
+// onBar BarAggregator的回调，在f.mu持有期间执行
+func (f *FootprintAggregator) onBar(bar broker.KlineEvent) {
+	s := f.state(bar.Symbol)
+	if s.pending != nil && bar.NumberOfTrades > s.bins.trades {
+		s.bins.add(*s.pending, 1)
+		s.pending = nil
+	}
+
+	profile := s.bins.snapshot(f.opts.valueArea)
+	if bar.NumberOfTrades == 0 {
+		// 空K线
+		profile = VolumeProfile{Symbol: bar.Symbol, LevelSize: s.bins.size}
+	}
+	f.handler(bar, profile)
+	if bar.Confirm == ConfirmClosed && bar.NumberOfTrades > 0 {
+		s.bins.reset()
+	}
+}
+
+func (f *FootprintAggregator) state(symbol string) *footprintState {
+	s, ok := f.states[symbol]
+	if !ok {
+		s = &footprintState{bins: newProfileBins(f.opts.levelSize(f.symbol))}
+		f.states[symbol] = s
+	}
+	return s
+}
@@ -0,0 +1,34 @@
+package aggregator
+
+// barOptions K线聚合器配置
+type barOptions struct {
+	// partial 每笔成交后回调未完结K线
+	partial bool
+	// emitEmpty 时间K线为没有成交的周期生成空K线
+	emitEmpty bool
+}
+
+func applyBarOptions(opts ...BarOption) *barOptions {
+	o := &barOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// BarOption 是BarAggregator的配置选项
+type BarOption func(o *barOptions)
+
+// WithPartialBars 每笔成交后以ConfirmOpen回调未完结K线的快照，默认只回调已收盘K线
+func WithPartialBars() BarOption {
+	return func(o *barOptions) {
+		o.partial = true
+	}
+}
+
+// WithEmptyBars 时间K线为没有成交的周期生成空K线，开高低收均为上一根K线的收盘价，成交量为0
+func WithEmptyBars() BarOption {
+	return func(o *barOptions) {
+		o.emitEmpty = true
+	}
+}
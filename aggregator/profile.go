@@ -0,0 +1,404 @@
+package aggregator
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/types"
+)
+
+// PriceLevel 成交量分布中的一个价格档位
+type PriceLevel struct {
+	// Price 档位下沿，为档位宽度的整数倍
+	Price decimal.Decimal
+	// Volume 总成交量，包括方向未知的成交
+	Volume decimal.Decimal
+	// BuyVolume 主动买入量
+	BuyVolume decimal.Decimal
+	// SellVolume 主动卖出量
+	SellVolume decimal.Decimal
+	// Trades 成交笔数
+	Trades int64
+}
+
+// Delta 主动买入量减主动卖出量
+func (l PriceLevel) Delta() decimal.Decimal {
+	return l.BuyVolume.Sub(l.SellVolume)
+}
+
+// VolumeProfile 成交量分布快照
+type VolumeProfile struct {
+	// Symbol 交易对
+	Symbol string
+	// StartTime、EndTime 统计范围内第一笔和最后一笔成交的时间
+	StartTime int64
+	EndTime   int64
+	// LevelSize 档位宽度，为最小价格变动单位的整数倍
+	LevelSize decimal.Decimal
+	// Levels 有成交的档位，按价格升序
+	Levels []PriceLevel
+	// Volume、BuyVolume、SellVolume 总成交量、主动买入量、主动卖出量
+	Volume     decimal.Decimal
+	BuyVolume  decimal.Decimal
+	SellVolume decimal.Decimal
+	// POC 成交量最大的档位价格，成交量相同时取较低的价格
+	POC decimal.Decimal
+	// ValueAreaHigh、ValueAreaLow 价值区域最高和最低档位的价格
+	ValueAreaHigh decimal.Decimal
+	ValueAreaLow  decimal.Decimal
+}
+
+// Level 返回价格所在的档位
+func (p *VolumeProfile) Level(price decimal.Decimal) (PriceLevel, bool) {
+	if len(p.Levels) == 0 || !p.LevelSize.IsPositive() {
+		return PriceLevel{}, false
+	}
+	floor := levelPrice(price, p.LevelSize)
+	i := sort.Search(len(p.Levels), func(i int) bool {
+		return p.Levels[i].Price.GreaterThanOrEqual(floor)
+	})
+	if i < len(p.Levels) && p.Levels[i].Price.Equal(floor) {
+		return p.Levels[i], true
+	}
+	return PriceLevel{}, false
+}
+
+// InValueArea 价格是否在价值区域内
+func (p *VolumeProfile) InValueArea(price decimal.Decimal) bool {
+	if len(p.Levels) == 0 {
+		return false
+	}
+	floor := levelPrice(price, p.LevelSize)
+	return floor.GreaterThanOrEqual(p.ValueAreaLow) && floor.LessThanOrEqual(p.ValueAreaHigh)
+}
+
+// ProfileHandler 成交量分布回调
+type ProfileHandler func(profile VolumeProfile)
+
+// profileOptions 成交量分布配置
+type profileOptions struct {
+	ticksPerLevel int64
+	valueArea     decimal.Decimal
+	barOptions    []BarOption
+}
+
+func applyProfileOptions(opts ...ProfileOption) *profileOptions {
+	o := &profileOptions{ticksPerLevel: 1, valueArea: decimal.NewFromFloat(0.7)}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *profileOptions) validate() error {
+	if o.ticksPerLevel <= 0 {
+		return errors.New("ticks per level must be positive")
+	}
+	if !o.valueArea.IsPositive() || o.valueArea.GreaterThan(decimal.NewFromInt(1)) {
+		return errors.New("value area must be in (0, 1]")
+	}
+	return nil
+}
+
+// levelSize 档位宽度，最小价格变动单位为 10^-PricePrecision
+func (o *profileOptions) levelSize(symbol types.Symbol) decimal.Decimal {
+	return decimal.New(o.ticksPerLevel, -symbol.PricePrecision)
+}
+
+// ProfileOption 是成交量分布与足迹图的配置选项
+type ProfileOption func(o *profileOptions)
+
+// WithTicksPerLevel 每个档位包含n个最小价格变动单位，默认为1
+func WithTicksPerLevel(n int64) ProfileOption {
+	return func(o *profileOptions) {
+		o.ticksPerLevel = n
+	}
+}
+
+// WithValueArea 设置价值区域包含的成交量比例，默认为0.7
+func WithValueArea(ratio decimal.Decimal) ProfileOption {
+	return func(o *profileOptions) {
+		o.valueArea = ratio
+	}
+}
+
+// WithFootprintBarOptions 设置足迹图内部K线聚合器的选项
+func WithFootprintBarOptions(opts ...BarOption) ProfileOption {
+	return func(o *profileOptions) {
+		o.barOptions = append(o.barOptions, opts...)
+	}
+}
+
+// VolumeProfiler 按价格档位统计单个交易对的成交量分布，档位宽度由 types.Symbol.PricePrecision 决定。
+// 窗口语义与TradeAggregator相同：滚动窗口（例如按UTC日划分的交易时段）在窗口结束时回调一次，
+// 滑动窗口在每笔成交后回调。滑动窗口保存窗口内的成交用于淘汰。可以在多个goroutine中并发调用
+type VolumeProfiler struct {
+	mu      sync.Mutex
+	window  Window
+	handler ProfileHandler
+	opts    *profileOptions
+	bins    *profileBins
+	// trades 滑动窗口内的成交
+	trades queue[types.TradeEvent]
+	// start 按时间划分的滚动窗口起点
+	start   int64
+	hasLast bool
+	last    int64
+}
+
+// NewVolumeProfiler 创建成交量分布统计，handler在调用Update、Advance或Flush的goroutine中持有锁同步执行，不能再调用统计器的方法
+func NewVolumeProfiler(symbol types.Symbol, window Window, handler ProfileHandler, opts ...ProfileOption) (*VolumeProfiler, error) {
+	if err := window.validate(); err != nil {
+		return nil, err
+	}
+	if handler == nil {
+		return nil, errors.New("handler cannot be nil")
+	}
+	o := applyProfileOptions(opts...)
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	return &VolumeProfiler{
+		window:  window,
+		handler: handler,
+		opts:    o,
+		bins:    newProfileBins(o.levelSize(symbol)),
+	}, nil
+}
+
+// Update 计入一笔成交，成交应按时间顺序到达。按时间划分的窗口中，早于当前窗口起点的迟到成交会被丢弃
+func (v *VolumeProfiler) Update(trade types.TradeEvent) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	w := v.window
+	if w.windowType == WindowTumbling && w.duration > 0 {
+		start := trade.Timestamp - mod(trade.Timestamp, w.duration)
+		if v.hasLast && start < v.start {
+			return
+		}
+		if v.bins.trades > 0 && start > v.start {
+			v.emit()
+			v.bins.reset()
+		}
+		v.start = start
+	}
+	if w.windowType == WindowSliding && w.duration > 0 && v.hasLast && trade.Timestamp <= v.last-w.duration {
+		return
+	}
+	v.hasLast, v.last = true, trade.Timestamp
+
+	v.bins.add(trade, 1)
+
+	switch w.windowType {
+	case WindowSliding:
+		v.trades.push(trade)
+		if w.count > 0 {
+			for v.trades.len() > w.count {
+				v.pop()
+			}
+		} else {
+			v.expire(trade.Timestamp)
+		}
+		v.emit()
+	case WindowTumbling:
+		if w.count > 0 && v.bins.trades >= int64(w.count) {
+			v.emit()
+			v.bins.reset()
+		}
+	}
+}
+
+// Advance 通知统计器当前时间已到ts（毫秒），回调并清空已结束的按时间划分的滚动窗口，
+// 滑动窗口淘汰 (ts-duration) 之前的成交但不回调
+func (v *VolumeProfiler) Advance(ts int64) {
+	w := v.window
+	if w.duration <= 0 {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.bins.trades == 0 {
+		return
+	}
+	switch w.windowType {
+	case WindowTumbling:
+		if ts-mod(ts, w.duration) > v.start {
+			v.emit()
+			v.bins.reset()
+		}
+	case WindowSliding:
+		v.expire(ts)
+	}
+}
+
+// Flush 回调并清空未结束的滚动窗口，用于数据流结束时
+func (v *VolumeProfiler) Flush() {
+	if v.window.windowType != WindowTumbling {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.bins.trades > 0 {
+		v.emit()
+		v.bins.reset()
+	}
+}
+
+// Profile 返回当前窗口的成交量分布
+func (v *VolumeProfiler) Profile() (VolumeProfile, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.bins.trades == 0 {
+		return VolumeProfile{}, false
+	}
+	return v.bins.snapshot(v.opts.valueArea), true
+}
+
+// expire 淘汰时间不晚于 ts-duration 的成交，调用方需持有锁
+func (v *VolumeProfiler) expire(ts int64) {
+	for v.trades.len() > 0 && v.trades.front().Timestamp <= ts-v.window.duration {
+		v.pop()
+	}
+}
+
+// pop 从滑动窗口移除最早的成交，调用方需持有锁
+func (v *VolumeProfiler) pop() {
+	v.bins.add(v.trades.popFront(), -1)
+	if v.trades.len() > 0 {
+		v.bins.start = v.trades.front().Timestamp
+	}
+}
+
+// emit 回调当前分布，调用方需持有锁
+func (v *VolumeProfiler) emit() {
+	v.handler(v.bins.snapshot(v.opts.valueArea))
+}
+
+// profileBins 按档位累计的成交量
+type profileBins struct {
+	size   decimal.Decimal
+	levels map[int64]*PriceLevel
+	trades int64
+	symbol string
+	// start、end 最早和最晚成交时间，滑动窗口淘汰成交后由VolumeProfiler更新start
+	start, end int64
+}
+
+func newProfileBins(size decimal.Decimal) *profileBins {
+	return &profileBins{size: size, levels: make(map[int64]*PriceLevel)}
+}
+
+// levelIndex 价格所在档位的序号
+func (b *profileBins) levelIndex(price decimal.Decimal) int64 {
+	return price.Div(b.size).Floor().IntPart()
+}
+
+// levelPrice 价格所在档位的下沿
+func levelPrice(price, size decimal.Decimal) decimal.Decimal {
+	return price.Div(size).Floor().Mul(size)
+}
+
+// add 计入（sign为1）或移除（sign为-1）一笔成交
+func (b *profileBins) add(trade types.TradeEvent, sign int64) {
+	idx := b.levelIndex(trade.Price)
+	level, ok := b.levels[idx]
+	if !ok {
+		level = &PriceLevel{Price: decimal.NewFromInt(idx).Mul(b.size)}
+		b.levels[idx] = level
+	}
+	size := trade.Size
+	if sign < 0 {
+		size = size.Neg()
+	}
+	level.Volume = level.Volume.Add(size)
+	level.Trades += sign
+	switch trade.Side {
+	case types.SideTypeBuy:
+		level.BuyVolume = level.BuyVolume.Add(size)
+	case types.SideTypeSell:
+		level.SellVolume = level.SellVolume.Add(size)
+	}
+	if level.Trades == 0 {
+		delete(b.levels, idx)
+	}
+
+	b.trades += sign
+	if sign > 0 {
+		b.symbol = trade.Symbol
+		if b.trades == 1 {
+			b.start = trade.Timestamp
+		}
+		b.end = trade.Timestamp
+	}
+}
+
+func (b *profileBins) reset() {
+	b.levels = make(map[int64]*PriceLevel)
+	b.trades = 0
+}
+
+// snapshot 生成按价格升序的分布并计算POC与价值区域
+func (b *profileBins) snapshot(valueArea decimal.Decimal) VolumeProfile {
+	p := VolumeProfile{
+		Symbol:    b.symbol,
+		StartTime: b.start,
+		EndTime:   b.end,
+		LevelSize: b.size,
+		Levels:    make([]PriceLevel, 0, len(b.levels)),
+	}
+	for _, level := range b.levels {
+		p.Levels = append(p.Levels, *level)
+	}
+	sort.Slice(p.Levels, func(i, j int) bool {
+		return p.Levels[i].Price.LessThan(p.Levels[j].Price)
+	})
+	if len(p.Levels) == 0 {
+		return p
+	}
+
+	poc := 0
+	for i, level := range p.Levels {
+		p.Volume = p.Volume.Add(level.Volume)
+		p.BuyVolume = p.BuyVolume.Add(level.BuyVolume)
+		p.SellVolume = p.SellVolume.Add(level.SellVolume)
+		if level.Volume.GreaterThan(p.Levels[poc].Volume) {
+			poc = i
+		}
+	}
+	p.POC = p.Levels[poc].Price
+
+	// 从POC开始每次向成交量较大的一侧扩展一个档位，两侧相同时同时扩展，直到达到目标比例
+	target := p.Volume.Mul(valueArea)
+	lo, hi := poc, poc
+	acc := p.Levels[poc].Volume
+	for acc.LessThan(target) && (lo > 0 || hi < len(p.Levels)-1) {
+		switch {
+		case lo == 0:
+			hi++
+			acc = acc.Add(p.Levels[hi].Volume)
+		case hi == len(p.Levels)-1:
+			lo--
+			acc = acc.Add(p.Levels[lo].Volume)
+		default:
+			up, down := p.Levels[hi+1].Volume, p.Levels[lo-1].Volume
+			if up.GreaterThanOrEqual(down) {
+				hi++
+				acc = acc.Add(up)
+			}
+			if down.GreaterThanOrEqual(up) {
+				lo--
+				acc = acc.Add(down)
+			}
+		}
+	}
+	p.ValueAreaLow = p.Levels[lo].Price
+	p.ValueAreaHigh = p.Levels[hi].Price
+	return p
+}
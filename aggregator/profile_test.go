@@ -0,0 +1,188 @@
+package aggregator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/types"
+)
+
+// 价格精度为1位小数，最小价格变动单位为0.1
+var profileSymbol = types.Symbol{UnifiedSymbol: "BTC-USDT", PricePrecision: 1}
+
+func TestVolumeProfilerSession(t *testing.T) {
+	var profiles []VolumeProfile
+	p, err := NewVolumeProfiler(profileSymbol, TumblingTime(time.Minute), func(profile VolumeProfile) {
+		profiles = append(profiles, profile)
+	}, WithTicksPerLevel(5))
+	require.NoError(t, err)
+
+	for _, tr := range []types.TradeEvent{
+		trade(1_000, "100.0", "1", types.SideTypeBuy),
+		trade(2_000, "100.2", "2", types.SideTypeSell),
+		trade(3_000, "100.6", "4", types.SideTypeBuy),
+		trade(4_000, "101.1", "2", types.SideTypeBuy),
+		trade(5_000, "99.7", "1", types.SideTypeSell),
+		trade(6_000, "101.6", "1", types.SideTypeSell),
+		trade(7_000, "99.4", "1", types.SideTypeBuy),
+	} {
+		p.Update(tr)
+	}
+	require.Empty(t, profiles)
+
+	current, ok := p.Profile()
+	require.True(t, ok)
+	assert.Equal(t, "12", current.Volume.String())
+
+	// 下一时段的第一笔成交结束当前时段
+	p.Update(trade(60_000, "102", "1", types.SideTypeBuy))
+	require.Len(t, profiles, 1)
+	profile := profiles[0]
+
+	assert.Equal(t, "BTCUSDT", profile.Symbol)
+	assert.Equal(t, int64(1_000), profile.StartTime)
+	assert.Equal(t, int64(7_000), profile.EndTime)
+	assert.Equal(t, "0.5", profile.LevelSize.String())
+	require.Len(t, profile.Levels, 6)
+	var prices []string
+	for _, level := range profile.Levels {
+		prices = append(prices, level.Price.String())
+	}
+	assert.Equal(t, []string{"99", "99.5", "100", "100.5", "101", "101.5"}, prices)
+	assert.Equal(t, "8", profile.BuyVolume.String())
+	assert.Equal(t, "4", profile.SellVolume.String())
+
+	// POC为100.5（4），向下扩展100（3）后为7，再向上扩展101（2）达到 12×0.7=8.4
+	assert.Equal(t, "100.5", profile.POC.String())
+	assert.Equal(t, "100", profile.ValueAreaLow.String())
+	assert.Equal(t, "101", profile.ValueAreaHigh.String())
+	assert.True(t, profile.InValueArea(decimal.RequireFromString("101.4")))
+	assert.False(t, profile.InValueArea(decimal.RequireFromString("99.9")))
+
+	level, ok := profile.Level(decimal.RequireFromString("100.3"))
+	require.True(t, ok)
+	assert.Equal(t, "1", level.BuyVolume.String())
+	assert.Equal(t, "2", level.SellVolume.String())
+	assert.Equal(t, "-1", level.Delta().String())
+	assert.Equal(t, int64(2), level.Trades)
+	_, ok = profile.Level(decimal.RequireFromString("98"))
+	assert.False(t, ok)
+
+	p.Flush()
+	require.Len(t, profiles, 2)
+	assert.Equal(t, "102", profiles[1].POC.String())
+}
+
+func TestVolumeProfilerSliding(t *testing.T) {
+	var profiles []VolumeProfile
+	p, err := NewVolumeProfiler(profileSymbol, SlidingTime(10*time.Second), func(profile VolumeProfile) {
+		profiles = append(profiles, profile)
+	})
+	require.NoError(t, err)
+
+	p.Update(trade(1_000, "100.01", "5", types.SideTypeBuy))
+	p.Update(trade(5_000, "100.1", "1", types.SideTypeSell))
+	p.Update(trade(11_000, "100.2", "2", types.SideTypeBuy))
+	require.Len(t, profiles, 3)
+
+	// 1000的成交移出窗口，价格按0.1取档
+	profile := profiles[2]
+	assert.Equal(t, int64(5_000), profile.StartTime)
+	assert.Equal(t, "3", profile.Volume.String())
+	assert.Equal(t, "100.2", profile.POC.String())
+	_, ok := profile.Level(decimal.RequireFromString("100"))
+	assert.False(t, ok)
+
+	p.Advance(30_000)
+	_, ok = p.Profile()
+	assert.False(t, ok)
+	require.Len(t, profiles, 3)
+}
+
+func TestNewVolumeProfilerErrors(t *testing.T) {
+	handler := func(VolumeProfile) {}
+	_, err := NewVolumeProfiler(profileSymbol, Window{}, handler)
+	assert.Error(t, err)
+	_, err = NewVolumeProfiler(profileSymbol, SlidingCount(10), nil)
+	assert.Error(t, err)
+	_, err = NewVolumeProfiler(profileSymbol, SlidingCount(10), handler, WithTicksPerLevel(0))
+	assert.Error(t, err)
+	_, err = NewVolumeProfiler(profileSymbol, SlidingCount(10), handler, WithValueArea(decimal.NewFromInt(2)))
+	assert.Error(t, err)
+}
+
+type footprint struct {
+	bar     broker.KlineEvent
+	profile VolumeProfile
+}
+
+func TestFootprintTimeBars(t *testing.T) {
+	var prints []footprint
+	f, err := NewFootprintAggregator(TimeRule(time.Minute), profileSymbol, func(bar broker.KlineEvent, profile VolumeProfile) {
+		prints = append(prints, footprint{bar, profile})
+	}, WithFootprintBarOptions(WithEmptyBars()))
+	require.NoError(t, err)
+
+	f.Update(trade(60_000, "100.1", "1", types.SideTypeBuy))
+	f.Update(trade(61_000, "100.1", "2", types.SideTypeSell))
+	f.Update(trade(62_000, "100.3", "1", types.SideTypeBuy))
+	// 迟到的成交不计入
+	f.Update(trade(59_000, "100.3", "9", types.SideTypeBuy))
+
+	bar, current, ok := f.Current("BTCUSDT")
+	require.True(t, ok)
+	assert.Equal(t, int64(3), bar.NumberOfTrades)
+	assert.Equal(t, "4", current.Volume.String())
+
+	// 下一周期的成交收盘上一根K线，不计入上一根K线的分布
+	f.Update(trade(180_000, "101", "5", types.SideTypeBuy))
+	require.Len(t, prints, 2)
+
+	first := prints[0]
+	assert.Equal(t, int64(60_000), first.bar.OpenTime)
+	assert.True(t, first.bar.Volume.Equal(first.profile.Volume))
+	require.Len(t, first.profile.Levels, 2)
+	assert.Equal(t, "100.1", first.profile.POC.String())
+	assert.Equal(t, "-1", first.profile.Levels[0].Delta().String())
+	assert.Equal(t, "1", first.profile.Levels[1].BuyVolume.String())
+
+	// 空K线
+	assert.Equal(t, int64(120_000), prints[1].bar.OpenTime)
+	assert.Empty(t, prints[1].profile.Levels)
+
+	f.Flush()
+	require.Len(t, prints, 3)
+	assert.Equal(t, "101", prints[2].profile.POC.String())
+	assert.Equal(t, "5", prints[2].profile.Volume.String())
+}
+
+func TestFootprintTickBars(t *testing.T) {
+	var prints []footprint
+	f, err := NewFootprintAggregator(TickRule(2), profileSymbol, func(bar broker.KlineEvent, profile VolumeProfile) {
+		prints = append(prints, footprint{bar, profile})
+	}, WithFootprintBarOptions(WithPartialBars()))
+	require.NoError(t, err)
+
+	f.Update(trade(1, "10", "1", types.SideTypeBuy))
+	f.Update(trade(2, "11", "3", types.SideTypeSell))
+	f.Update(trade(3, "12", "2", types.SideTypeBuy))
+	require.Len(t, prints, 3)
+
+	// 未完结K线的快照包含已计入的成交
+	assert.Equal(t, ConfirmOpen, prints[0].bar.Confirm)
+	assert.Equal(t, "1", prints[0].profile.Volume.String())
+
+	// 达到笔数后收盘，触发收盘的成交属于该K线
+	closed := prints[1]
+	assert.Equal(t, ConfirmClosed, closed.bar.Confirm)
+	assert.Equal(t, "4", closed.profile.Volume.String())
+	assert.Equal(t, "11", closed.profile.POC.String())
+
+	assert.Equal(t, "2", prints[2].profile.Volume.String())
+	assert.Equal(t, "12", prints[2].profile.POC.String())
+}
@@ -0,0 +1,203 @@
+package aggregator
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/types"
+)
+
+// BarType K线类型
+type BarType int
+
+const (
+	// BarTypeTime 时间K线，按UTC对齐的固定时间间隔切分
+	BarTypeTime BarType = iota + 1
+	// BarTypeTick 成交笔数K线，每N笔成交一根
+	BarTypeTick
+	// BarTypeVolume 成交量K线，成交量累计达到阈值时收盘
+	BarTypeVolume
+	// BarTypeDollar 成交额K线，成交额累计达到阈值时收盘
+	BarTypeDollar
+	// BarTypeTickImbalance 笔数失衡K线，主动买卖笔数之差超过期望阈值时收盘
+	BarTypeTickImbalance
+	// BarTypeVolumeImbalance 成交量失衡K线，主动买卖量之差超过期望阈值时收盘
+	BarTypeVolumeImbalance
+)
+
+// String 返回字符串表示
+func (b BarType) String() string {
+	switch b {
+	case BarTypeTime:
+		return "TIME"
+	case BarTypeTick:
+		return "TICK"
+	case BarTypeVolume:
+		return "VOLUME"
+	case BarTypeDollar:
+		return "DOLLAR"
+	case BarTypeTickImbalance:
+		return "TICK_IMBALANCE"
+	case BarTypeVolumeImbalance:
+		return "VOLUME_IMBALANCE"
+	}
+	return "UNKNOWN"
+}
+
+// Rule K线切分规则，通过TimeRule、TickRule等函数创建
+type Rule struct {
+	barType   BarType
+	interval  int64
+	count     int64
+	threshold decimal.Decimal
+	// 失衡K线参数
+	expected float64
+	alpha    float64
+}
+
+// Type 返回K线类型
+func (r Rule) Type() BarType {
+	return r.barType
+}
+
+// TimeRule 时间K线，interval按Unix纪元对齐，例如1小时K线的开盘时间为UTC整点
+func TimeRule(interval time.Duration) Rule {
+	return Rule{barType: BarTypeTime, interval: interval.Milliseconds()}
+}
+
+// TickRule 每n笔成交一根K线
+func TickRule(n int64) Rule {
+	return Rule{barType: BarTypeTick, count: n}
+}
+
+// VolumeRule 成交量累计达到volume时收盘，达到阈值的那笔成交计入当前K线，不拆分
+func VolumeRule(volume decimal.Decimal) Rule {
+	return Rule{barType: BarTypeVolume, threshold: volume}
+}
+
+// DollarRule 成交额（价格×数量）累计达到amount时收盘，达到阈值的那笔成交计入当前K线，不拆分
+func DollarRule(amount decimal.Decimal) Rule {
+	return Rule{barType: BarTypeDollar, threshold: amount}
+}
+
+// TickImbalanceRule 笔数失衡K线（López de Prado），主动买记+1、主动卖记-1，方向未知的成交不计入失衡，
+// 累计值的绝对值达到 E[T]×|2P(买)-1| 时收盘。第一根K线固定为expectedTicks笔，
+// 之后E[T]与|2P(买)-1|按alpha对已收盘K线做指数加权更新，alpha取值(0,1]。
+// 买卖均衡时|2P(买)-1|接近0，阈值下限为随机游走的标准差√E[T]，E[T]下限为1
+func TickImbalanceRule(expectedTicks int64, alpha float64) Rule {
+	return Rule{barType: BarTypeTickImbalance, expected: float64(expectedTicks), alpha: alpha}
+}
+
+// VolumeImbalanceRule 成交量失衡K线，与TickImbalanceRule相同，但每笔成交按成交量加权，
+// 阈值下限为√E[T]×E[成交量]
+func VolumeImbalanceRule(expectedTicks int64, alpha float64) Rule {
+	return Rule{barType: BarTypeVolumeImbalance, expected: float64(expectedTicks), alpha: alpha}
+}
+
+// validate 检查参数
+func (r Rule) validate() error {
+	switch r.barType {
+	case BarTypeTime:
+		if r.interval <= 0 {
+			return errors.New("time bar interval must be at least 1ms")
+		}
+	case BarTypeTick:
+		if r.count <= 0 {
+			return errors.New("tick bar count must be positive")
+		}
+	case BarTypeVolume, BarTypeDollar:
+		if !r.threshold.IsPositive() {
+			return errors.New("bar threshold must be positive")
+		}
+	case BarTypeTickImbalance, BarTypeVolumeImbalance:
+		if r.expected <= 0 {
+			return errors.New("imbalance bar expected ticks must be positive")
+		}
+		if r.alpha <= 0 || r.alpha > 1 {
+			return errors.New("imbalance bar alpha must be in (0, 1]")
+		}
+	default:
+		return errors.New("unknown bar type")
+	}
+	return nil
+}
+
+// imbalance 失衡K线的期望值估计，每个交易对一份
+type imbalance struct {
+	volume bool
+	alpha  float64
+	// expectedTicks 每根K线的期望笔数E[T]
+	expectedTicks float64
+	// expectedSign 每笔成交带符号权重的期望值，即E[b×v]
+	expectedSign float64
+	// expectedAbs 每笔成交权重绝对值的期望值E[|b×v|]，用于阈值下限
+	expectedAbs float64
+	// estimated 是否已有完整K线用于估计expectedSign
+	estimated bool
+	// theta 当前K线的累计失衡
+	theta float64
+	// ticks 当前K线的成交笔数
+	ticks float64
+	// abs 当前K线的累计权重绝对值
+	abs float64
+}
+
+func newImbalance(r Rule) *imbalance {
+	return &imbalance{
+		volume:        r.barType == BarTypeVolumeImbalance,
+		alpha:         r.alpha,
+		expectedTicks: r.expected,
+	}
+}
+
+// add 计入一笔成交，返回是否应当收盘。方向未知的成交不计入
+func (m *imbalance) add(trade types.TradeEvent) bool {
+	var sign float64
+	switch trade.Side {
+	case types.SideTypeBuy:
+		sign = 1
+	case types.SideTypeSell:
+		sign = -1
+	default:
+		return false
+	}
+	if m.volume {
+		sign *= trade.Size.InexactFloat64()
+	}
+	m.theta += sign
+	m.abs += math.Abs(sign)
+	m.ticks++
+
+	if !m.estimated {
+		// 第一根K线没有历史可用于估计，按期望笔数收盘
+		return m.ticks >= m.expectedTicks
+	}
+	return math.Abs(m.theta) >= m.threshold()
+}
+
+// threshold 收盘阈值 E[T]×|E[b]|，买卖均衡时不低于 √E[T]×E[|b|]
+func (m *imbalance) threshold() float64 {
+	ticks := math.Max(m.expectedTicks, 1)
+	sign := math.Max(math.Abs(m.expectedSign), m.expectedAbs/math.Sqrt(ticks))
+	return ticks * sign
+}
+
+// reset 收盘后更新期望值并清空累计
+func (m *imbalance) reset() {
+	if m.ticks > 0 {
+		mean, abs := m.theta/m.ticks, m.abs/m.ticks
+		if !m.estimated {
+			m.estimated = true
+			m.expectedSign = mean
+			m.expectedAbs = abs
+		} else {
+			m.expectedSign += m.alpha * (mean - m.expectedSign)
+			m.expectedAbs += m.alpha * (abs - m.expectedAbs)
+		}
+		m.expectedTicks += m.alpha * (m.ticks - m.expectedTicks)
+	}
+	m.theta, m.ticks, m.abs = 0, 0, 0
+}
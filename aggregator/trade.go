@@ -0,0 +1,367 @@
+package aggregator
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/types"
+)
+
+// WindowType 窗口类型
+type WindowType int
+
+const (
+	// WindowTumbling 滚动窗口，窗口之间不重叠，窗口结束时回调一次
+	WindowTumbling WindowType = iota + 1
+	// WindowSliding 滑动窗口，每笔成交后回调最近一段时间或最近N笔成交的统计
+	WindowSliding
+)
+
+// Window 成交统计窗口，通过TumblingTime、SlidingCount等函数创建
+type Window struct {
+	windowType WindowType
+	// duration 按时间划分的窗口长度（毫秒），为0时按笔数划分
+	duration int64
+	count    int
+}
+
+// TumblingTime 按UTC对齐的固定时间段统计
+func TumblingTime(d time.Duration) Window {
+	return Window{windowType: WindowTumbling, duration: d.Milliseconds()}
+}
+
+// TumblingCount 每n笔成交统计一次
+func TumblingCount(n int) Window {
+	return Window{windowType: WindowTumbling, count: n}
+}
+
+// SlidingTime 统计最近d时间内的成交，即时间在 (最新成交时间-d, 最新成交时间] 内的成交
+func SlidingTime(d time.Duration) Window {
+	return Window{windowType: WindowSliding, duration: d.Milliseconds()}
+}
+
+// SlidingCount 统计最近n笔成交
+func SlidingCount(n int) Window {
+	return Window{windowType: WindowSliding, count: n}
+}
+
+func (w Window) validate() error {
+	if w.windowType != WindowTumbling && w.windowType != WindowSliding {
+		return errors.New("unknown window type")
+	}
+	if w.duration <= 0 && w.count <= 0 {
+		return errors.New("window duration or count must be positive")
+	}
+	return nil
+}
+
+// AggregateHandler 成交统计回调
+type AggregateHandler func(symbol string, aggregate types.TradeAggregate)
+
+// TradeAggregator 从逐笔成交中按窗口维护 types.TradeAggregate，每个交易对独立统计。
+// PricePoint.ID 为该交易对的成交序号，从0开始递增；Direction为该笔成交相对上一笔成交的价格方向，
+// 价格不变时沿用上一笔的方向。Timestamp为窗口内最新一笔成交的时间。
+// 滚动窗口只保存累计值；滑动窗口使用单调队列维护最高、最低价，淘汰旧成交的均摊复杂度为O(1)
+type TradeAggregator struct {
+	mu      sync.Mutex
+	window  Window
+	handler AggregateHandler
+	states  map[string]*tradeWindow
+}
+
+// NewTradeAggregator 创建成交统计，handler在调用Update、Advance或Flush的goroutine中持有锁同步执行，不能再调用统计器的方法
+func NewTradeAggregator(window Window, handler AggregateHandler) (*TradeAggregator, error) {
+	if err := window.validate(); err != nil {
+		return nil, err
+	}
+	if handler == nil {
+		return nil, errors.New("handler cannot be nil")
+	}
+	return &TradeAggregator{
+		window:  window,
+		handler: handler,
+		states:  make(map[string]*tradeWindow),
+	}, nil
+}
+
+// Update 计入一笔成交，成交应按时间顺序到达。按时间划分的窗口中，早于当前窗口起点的迟到成交会被丢弃
+func (a *TradeAggregator) Update(trade types.TradeEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.states[trade.Symbol]
+	if !ok {
+		s = &tradeWindow{sliding: a.window.windowType == WindowSliding}
+		a.states[trade.Symbol] = s
+	}
+
+	w := a.window
+	if w.windowType == WindowTumbling && w.duration > 0 {
+		start := trade.Timestamp - mod(trade.Timestamp, w.duration)
+		if s.hasLast && start < s.start {
+			return
+		}
+		if s.size() > 0 && start > s.start {
+			a.handler(trade.Symbol, s.aggregate)
+			s.reset()
+		}
+		s.start = start
+	}
+	if w.windowType == WindowSliding && w.duration > 0 && s.hasLast && trade.Timestamp <= s.last.Timestamp-w.duration {
+		return
+	}
+
+	s.push(trade)
+
+	switch w.windowType {
+	case WindowSliding:
+		if w.count > 0 {
+			for s.size() > w.count {
+				s.pop()
+			}
+		} else {
+			for s.front().Timestamp <= trade.Timestamp-w.duration {
+				s.pop()
+			}
+		}
+		a.handler(trade.Symbol, s.aggregate)
+	case WindowTumbling:
+		if w.count > 0 && s.size() >= w.count {
+			a.handler(trade.Symbol, s.aggregate)
+			s.reset()
+		}
+	}
+}
+
+// Advance 通知统计器当前时间已到ts（毫秒），回调并清空所有已结束的按时间划分的滚动窗口，
+// 滑动窗口淘汰 (ts-duration) 之前的成交但不回调
+func (a *TradeAggregator) Advance(ts int64) {
+	w := a.window
+	if w.duration <= 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for symbol, s := range a.states {
+		if s.size() == 0 {
+			continue
+		}
+		switch w.windowType {
+		case WindowTumbling:
+			if ts-mod(ts, w.duration) > s.start {
+				a.handler(symbol, s.aggregate)
+				s.reset()
+			}
+		case WindowSliding:
+			for s.size() > 0 && s.front().Timestamp <= ts-w.duration {
+				s.pop()
+			}
+		}
+	}
+}
+
+// Flush 回调并清空所有未结束的滚动窗口，用于数据流结束时
+func (a *TradeAggregator) Flush() {
+	if a.window.windowType != WindowTumbling {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for symbol, s := range a.states {
+		if s.size() > 0 {
+			a.handler(symbol, s.aggregate)
+			s.reset()
+		}
+	}
+}
+
+// Aggregate 返回交易对当前窗口的统计
+func (a *TradeAggregator) Aggregate(symbol string) (types.TradeAggregate, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.states[symbol]
+	if !ok || s.size() == 0 {
+		return types.TradeAggregate{}, false
+	}
+	return s.aggregate, true
+}
+
+// tradeWindow 单个交易对的窗口状态
+type tradeWindow struct {
+	aggregate types.TradeAggregate
+	// start 按时间划分的滚动窗口起点，清空窗口后保留用于丢弃迟到成交
+	start int64
+	// sliding 是否为滑动窗口，滚动窗口不保存成交明细
+	sliding bool
+	// n 窗口内的成交笔数
+	n int
+	// trades 滑动窗口内的成交
+	trades queue[tradePoint]
+	// maxq、minq 滑动窗口的单调队列，队首分别为窗口内的最高价、最低价
+	maxq queue[types.PricePoint]
+	minq queue[types.PricePoint]
+	// nextID 下一笔成交的序号
+	nextID uint64
+	// last 上一笔成交的价格点，跨窗口保留用于判断方向
+	last    types.PricePoint
+	hasLast bool
+}
+
+// tradePoint 窗口内的一笔成交
+type tradePoint struct {
+	types.PricePoint
+	side   types.SideType
+	size   decimal.Decimal
+	amount decimal.Decimal
+}
+
+func (w *tradeWindow) size() int {
+	return w.n
+}
+
+func (w *tradeWindow) front() tradePoint {
+	return w.trades.front()
+}
+
+// push 计入一笔成交
+func (w *tradeWindow) push(trade types.TradeEvent) {
+	point := types.PricePoint{
+		Price:     trade.Price,
+		ID:        w.nextID,
+		Timestamp: trade.Timestamp,
+		Direction: types.PriceDirectionUnknown,
+	}
+	w.nextID++
+	if w.hasLast {
+		switch trade.Price.Cmp(w.last.Price) {
+		case 1:
+			point.Direction = types.PriceDirectionUp
+		case -1:
+			point.Direction = types.PriceDirectionDown
+		default:
+			point.Direction = w.last.Direction
+		}
+	}
+	w.last, w.hasLast = point, true
+
+	tp := tradePoint{PricePoint: point, side: trade.Side, size: trade.Size, amount: trade.Price.Mul(trade.Size)}
+	agg := &w.aggregate
+	w.n++
+	// 价格相同时保留较早的点，使最高、最低价点为窗口内第一次出现的位置
+	if w.sliding {
+		w.trades.push(tp)
+		for w.maxq.len() > 0 && w.maxq.back().Price.LessThan(point.Price) {
+			w.maxq.popBack()
+		}
+		w.maxq.push(point)
+		for w.minq.len() > 0 && w.minq.back().Price.GreaterThan(point.Price) {
+			w.minq.popBack()
+		}
+		w.minq.push(point)
+		agg.PeakPrice = w.maxq.front()
+		agg.ValleyPrice = w.minq.front()
+	} else {
+		if w.n == 1 || point.Price.GreaterThan(agg.PeakPrice.Price) {
+			agg.PeakPrice = point
+		}
+		if w.n == 1 || point.Price.LessThan(agg.ValleyPrice.Price) {
+			agg.ValleyPrice = point
+		}
+	}
+
+	if tp.side == types.SideTypeSell {
+		agg.SellCount++
+		agg.SellVolume = agg.SellVolume.Add(tp.size)
+		agg.SellAmount = agg.SellAmount.Add(tp.amount)
+	} else {
+		agg.BuyCount++
+		agg.BuyVolume = agg.BuyVolume.Add(tp.size)
+		agg.BuyAmount = agg.BuyAmount.Add(tp.amount)
+	}
+	agg.Timestamp = point.Timestamp
+	agg.CurrentPrice = point
+}
+
+// pop 淘汰滑动窗口中最早的一笔成交
+func (w *tradeWindow) pop() {
+	tp := w.trades.popFront()
+	w.n--
+	if w.maxq.front().ID == tp.ID {
+		w.maxq.popFront()
+	}
+	if w.minq.front().ID == tp.ID {
+		w.minq.popFront()
+	}
+
+	agg := &w.aggregate
+	if tp.side == types.SideTypeSell {
+		agg.SellCount--
+		agg.SellVolume = agg.SellVolume.Sub(tp.size)
+		agg.SellAmount = agg.SellAmount.Sub(tp.amount)
+	} else {
+		agg.BuyCount--
+		agg.BuyVolume = agg.BuyVolume.Sub(tp.size)
+		agg.BuyAmount = agg.BuyAmount.Sub(tp.amount)
+	}
+	if w.n == 0 {
+		w.reset()
+		return
+	}
+	agg.PeakPrice = w.maxq.front()
+	agg.ValleyPrice = w.minq.front()
+}
+
+// reset 清空窗口，保留成交序号和上一笔成交用于判断方向
+func (w *tradeWindow) reset() {
+	w.aggregate = types.TradeAggregate{}
+	w.n = 0
+	w.trades.clear()
+	w.maxq.clear()
+	w.minq.clear()
+}
+
+// queue 基于切片的双端队列，队首出队后空间在队列过半为空时回收
+type queue[T any] struct {
+	items []T
+	head  int
+}
+
+func (q *queue[T]) len() int { return len(q.items) - q.head }
+
+func (q *queue[T]) push(v T) { q.items = append(q.items, v) }
+
+func (q *queue[T]) front() T { return q.items[q.head] }
+
+func (q *queue[T]) back() T { return q.items[len(q.items)-1] }
+
+func (q *queue[T]) popFront() T {
+	v := q.items[q.head]
+	var zero T
+	q.items[q.head] = zero
+	q.head++
+	if q.head > 32 && q.head*2 > len(q.items) {
+		n := copy(q.items, q.items[q.head:])
+		clear(q.items[n:])
+		q.items = q.items[:n]
+		q.head = 0
+	}
+	return v
+}
+
+func (q *queue[T]) popBack() {
+	var zero T
+	q.items[len(q.items)-1] = zero
+	q.items = q.items[:len(q.items)-1]
+}
+
+func (q *queue[T]) clear() {
+	clear(q.items)
+	q.items = q.items[:0]
+	q.head = 0
+}
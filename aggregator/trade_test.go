@@ -0,0 +1,148 @@
+package aggregator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/types"
+)
+
+func collectAggregates(t *testing.T, window Window) (*TradeAggregator, *[]types.TradeAggregate) {
+	var aggs []types.TradeAggregate
+	a, err := NewTradeAggregator(window, func(symbol string, agg types.TradeAggregate) {
+		assert.Equal(t, "BTCUSDT", symbol)
+		aggs = append(aggs, agg)
+	})
+	require.NoError(t, err)
+	return a, &aggs
+}
+
+func TestTradeAggregatorSlidingCount(t *testing.T) {
+	a, aggs := collectAggregates(t, SlidingCount(3))
+
+	a.Update(trade(1, "10", "1", types.SideTypeBuy))
+	a.Update(trade(2, "12", "2", types.SideTypeSell))
+	a.Update(trade(3, "12", "1", types.SideTypeBuy))
+	a.Update(trade(4, "9", "1", types.SideTypeSell))
+	require.Len(t, *aggs, 4)
+
+	agg := (*aggs)[2]
+	assert.Equal(t, uint64(2), agg.BuyCount)
+	assert.Equal(t, uint64(1), agg.SellCount)
+	assert.Equal(t, "22", agg.BuyAmount.String())
+	// 价格相同时最高价点为第一次出现的位置，方向沿用上一笔
+	assert.Equal(t, uint64(1), agg.PeakPrice.ID)
+	assert.Equal(t, uint64(0), agg.ValleyPrice.ID)
+	assert.Equal(t, types.PriceDirectionUnknown, agg.ValleyPrice.Direction)
+	assert.Equal(t, types.PriceDirectionUp, agg.CurrentPrice.Direction)
+
+	// 第一笔成交被淘汰
+	agg = (*aggs)[3]
+	assert.Equal(t, uint64(1), agg.BuyCount)
+	assert.Equal(t, uint64(2), agg.SellCount)
+	assert.Equal(t, "1", agg.BuyVolume.String())
+	assert.Equal(t, "33", agg.SellAmount.String())
+	assert.Equal(t, uint64(1), agg.PeakPrice.ID)
+	assert.Equal(t, "9", agg.ValleyPrice.Price.String())
+	assert.Equal(t, uint64(3), agg.ValleyPrice.ID)
+	assert.Equal(t, types.PriceDirectionDown, agg.CurrentPrice.Direction)
+	assert.Equal(t, int64(4), agg.Timestamp)
+	assert.False(t, agg.IsTrending())
+}
+
+func TestTradeAggregatorSlidingTime(t *testing.T) {
+	a, aggs := collectAggregates(t, SlidingTime(time.Second))
+
+	a.Update(trade(1_000, "10", "1", types.SideTypeBuy))
+	a.Update(trade(1_500, "11", "1", types.SideTypeBuy))
+	a.Update(trade(2_000, "9", "1", types.SideTypeSell))
+	require.Len(t, *aggs, 3)
+
+	// 1000被淘汰，窗口为(1000, 2000]
+	agg := (*aggs)[2]
+	assert.Equal(t, uint64(1), agg.BuyCount)
+	assert.Equal(t, uint64(1), agg.SellCount)
+	assert.Equal(t, "11", agg.PeakPrice.Price.String())
+
+	// 迟到超过窗口长度的成交被丢弃
+	a.Update(trade(500, "1", "1", types.SideTypeBuy))
+	require.Len(t, *aggs, 3)
+
+	a.Advance(2_600)
+	current, ok := a.Aggregate("BTCUSDT")
+	require.True(t, ok)
+	assert.Equal(t, uint64(0), current.BuyCount)
+	assert.Equal(t, uint64(1), current.SellCount)
+	assert.Equal(t, "9", current.PeakPrice.Price.String())
+
+	a.Advance(3_000)
+	_, ok = a.Aggregate("BTCUSDT")
+	assert.False(t, ok)
+}
+
+func TestTradeAggregatorTumbling(t *testing.T) {
+	t.Run("time", func(t *testing.T) {
+		a, aggs := collectAggregates(t, TumblingTime(time.Minute))
+		a.Update(trade(60_000, "10", "1", types.SideTypeBuy))
+		a.Update(trade(90_000, "12", "1", types.SideTypeSell))
+		require.Empty(t, *aggs)
+
+		a.Update(trade(120_000, "11", "2", types.SideTypeBuy))
+		require.Len(t, *aggs, 1)
+		agg := (*aggs)[0]
+		assert.Equal(t, uint64(1), agg.BuyCount)
+		assert.Equal(t, uint64(1), agg.SellCount)
+		assert.Equal(t, int64(90_000), agg.Timestamp)
+		assert.True(t, agg.IsTrending())
+
+		// 迟到的成交被丢弃
+		a.Update(trade(100_000, "1", "1", types.SideTypeBuy))
+		a.Advance(180_000)
+		require.Len(t, *aggs, 2)
+		agg = (*aggs)[1]
+		assert.Equal(t, uint64(1), agg.BuyCount)
+		// 序号跨窗口递增
+		assert.Equal(t, uint64(2), agg.CurrentPrice.ID)
+		assert.Equal(t, types.PriceDirectionDown, agg.CurrentPrice.Direction)
+	})
+
+	t.Run("count", func(t *testing.T) {
+		a, aggs := collectAggregates(t, TumblingCount(2))
+		a.Update(trade(1, "10", "1", types.SideTypeBuy))
+		a.Update(trade(2, "10", "1", types.SideTypeBuy))
+		a.Update(trade(3, "10", "1", types.SideTypeSell))
+		require.Len(t, *aggs, 1)
+		assert.Equal(t, uint64(2), (*aggs)[0].BuyCount)
+		assert.True(t, (*aggs)[0].IsSideways())
+
+		a.Flush()
+		require.Len(t, *aggs, 2)
+		assert.Equal(t, uint64(1), (*aggs)[1].SellCount)
+	})
+}
+
+func TestTradeAggregatorLongWindow(t *testing.T) {
+	// 淘汰大量成交后队列空间被回收，统计保持正确
+	a, aggs := collectAggregates(t, SlidingCount(10))
+	for i := 0; i < 10_000; i++ {
+		a.Update(trade(int64(i), "100", "1", types.SideTypeBuy))
+	}
+	last := (*aggs)[len(*aggs)-1]
+	assert.Equal(t, uint64(10), last.BuyCount)
+	assert.Equal(t, "10", last.BuyVolume.String())
+	assert.Equal(t, uint64(9_990), last.PeakPrice.ID)
+	assert.Less(t, cap(a.states["BTCUSDT"].trades.items), 1_000)
+}
+
+func TestNewTradeAggregatorErrors(t *testing.T) {
+	handler := func(string, types.TradeAggregate) {}
+	for _, w := range []Window{{}, TumblingCount(0), SlidingTime(0)} {
+		_, err := NewTradeAggregator(w, handler)
+		assert.Error(t, err)
+	}
+	_, err := NewTradeAggregator(SlidingCount(1), nil)
+	assert.Error(t, err)
+}
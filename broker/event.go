@@ -13,6 +13,8 @@ type StrategySignalEvent struct {
 	TransactionID string
 	// AccountID 账户ID
 	AccountID string
+	// StrategyID 策略ID
+	StrategyID string
 	// Timestamp 当前时间戳
 	Timestamp int64
 	// ClientOrderID 自定义客户端订单号
@@ -57,6 +59,8 @@ type OrderResultEvent struct {
 	Symbol string
 	// OrderID 交易所订单号
 	OrderID string
+	// TradeID 本次成交的交易所成交ID，非成交更新为空
+	TradeID string
 	// FeeAsset 手续费资产
 	FeeAsset string
 	// TransactionTime 交易时间
@@ -180,3 +184,49 @@ type KlineEvent struct {
 	// Confirm 0 代表 K 线未完结，1 代表 K 线已完结。
 	Confirm string
 }
+
+// BookTickerEvent 最优盘口事件
+type BookTickerEvent struct {
+	// Timestamp 撮合时间
+	Timestamp int64
+	// UpdateID 盘口更新ID
+	UpdateID int64
+	// Symbol 交易对
+	Symbol string
+	// Exchange 交易所
+	Exchange string
+	// BidPrice 买一价
+	BidPrice decimal.Decimal
+	// BidSize 买一量
+	BidSize decimal.Decimal
+	// AskPrice 卖一价
+	AskPrice decimal.Decimal
+	// AskSize 卖一量
+	AskSize decimal.Decimal
+	// MarketType 市场类型
+	MarketType types.MarketType
+}
+
+// DepthEvent 深度快照事件
+type DepthEvent struct {
+	// Timestamp 快照时间
+	Timestamp int64
+	// Symbol 交易对
+	Symbol string
+	// Exchange 交易所
+	Exchange string
+	// Bids 买盘，价格从高到低
+	Bids []DepthLevel
+	// Asks 卖盘，价格从低到高
+	Asks []DepthLevel
+	// MarketType 市场类型
+	MarketType types.MarketType
+}
+
+// DepthLevel 深度档位
+type DepthLevel struct {
+	// Price 价格
+	Price decimal.Decimal
+	// Size 数量
+	Size decimal.Decimal
+}
@@ -0,0 +1,56 @@
+// Package clock 提供可替换的时间源，实盘使用系统时间，回测和单元测试使用模拟时间或由事件驱动的时间，
+// 使依赖当前时间的限流、签名、心跳等逻辑可以确定性地复现。
+package clock
+
+import "time"
+
+// Clock 时间源
+type Clock interface {
+	// Now 返回当前时间
+	Now() time.Time
+	// Since 返回自t以来经过的时间
+	Since(t time.Time) time.Duration
+	// After 在d之后向返回的通道发送当时的时间
+	After(d time.Duration) <-chan time.Time
+	// NewTimer 创建在d之后触发一次的定时器
+	NewTimer(d time.Duration) Timer
+	// NewTicker 创建每隔d触发一次的定时器，d必须大于0
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer 单次定时器
+type Timer interface {
+	// C 返回触发通道
+	C() <-chan time.Time
+	// Stop 停止定时器，定时器已触发或已停止时返回false
+	Stop() bool
+}
+
+// Ticker 周期定时器
+type Ticker interface {
+	// C 返回触发通道
+	C() <-chan time.Time
+	// Stop 停止定时器
+	Stop()
+}
+
+// Observer 由事件时间推进的时钟，回放数据源在回调每个事件前调用Observe
+type Observer interface {
+	// Observe 将时钟推进到事件时间ts（毫秒），早于当前时间的事件被忽略
+	Observe(ts int64)
+}
+
+// OrReal 返回c，c为nil时返回系统时钟
+func OrReal(c Clock) Clock {
+	if c == nil {
+		return Real()
+	}
+	return c
+}
+
+// Observe 在o不为nil时将其推进到事件时间ts
+func Observe(o Observer, ts int64) {
+	if o != nil {
+		o.Observe(ts)
+	}
+}
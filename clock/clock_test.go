@@ -0,0 +1,125 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulatedTimers(t *testing.T) {
+	start := time.UnixMilli(1_000_000)
+	c := NewSimulated(start)
+
+	timer := c.NewTimer(3 * time.Second)
+	ticker := c.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	c.Advance(999 * time.Millisecond)
+	select {
+	case <-ticker.C():
+		t.Fatal("ticker fired early")
+	default:
+	}
+
+	c.Advance(time.Millisecond)
+	require.Equal(t, start.Add(time.Second), <-ticker.C())
+
+	// 一次推进跨越多个周期时通道只保留第一次触发
+	c.Advance(2500 * time.Millisecond)
+	require.Equal(t, start.Add(2*time.Second), <-ticker.C())
+	require.Equal(t, start.Add(3*time.Second), <-timer.C())
+	assert.False(t, timer.Stop())
+	assert.Equal(t, start.Add(3500*time.Millisecond), c.Now())
+
+	c.Advance(500 * time.Millisecond)
+	require.Equal(t, start.Add(4*time.Second), <-ticker.C())
+
+	stopped := c.NewTimer(time.Second)
+	assert.True(t, stopped.Stop())
+	c.Advance(time.Hour)
+	select {
+	case <-stopped.C():
+		t.Fatal("stopped timer fired")
+	default:
+	}
+
+	select {
+	case <-c.After(0):
+	default:
+		t.Fatal("After(0) should fire immediately")
+	}
+}
+
+func TestSimulatedBlockUntil(t *testing.T) {
+	c := NewSimulated(time.Unix(0, 0))
+	fired := make(chan time.Time)
+	go func() {
+		fired <- <-c.After(time.Minute)
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(time.Minute)
+	assert.Equal(t, time.Unix(60, 0), <-fired)
+}
+
+func TestEventClock(t *testing.T) {
+	c := NewEvent()
+	assert.True(t, c.Now().IsZero())
+
+	c.Observe(1_000)
+	timer := c.NewTimer(5 * time.Second)
+
+	c.Observe(5_000)
+	// 乱序的较早事件不会让时间倒退
+	c.Observe(2_000)
+	assert.Equal(t, time.UnixMilli(5_000), c.Now())
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired early")
+	default:
+	}
+
+	c.Observe(6_000)
+	require.Equal(t, time.UnixMilli(6_000), <-timer.C())
+	assert.Equal(t, 2*time.Second, c.Since(time.UnixMilli(4_000)))
+}
+
+func TestObserveNil(t *testing.T) {
+	Observe(nil, 1)
+	assert.Equal(t, Real(), OrReal(nil))
+}
+
+func TestEventClockTickerCatchUp(t *testing.T) {
+	c := NewEvent()
+	ticker := c.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	// 从零时间跳到当前时间时周期定时器只触发一次，之后仍按原来的相位到期
+	now := time.Now().Truncate(time.Second).UnixMilli()
+	done := make(chan struct{})
+	go func() {
+		c.Observe(now)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Observe did not return")
+	}
+	<-ticker.C()
+
+	c.Observe(now + 999)
+	select {
+	case <-ticker.C():
+		t.Fatal("ticker fired early")
+	default:
+	}
+	c.Observe(now + 1_000)
+	select {
+	case <-ticker.C():
+	default:
+		t.Fatal("ticker did not fire")
+	}
+}
@@ -0,0 +1,40 @@
+package clock
+
+import "time"
+
+var (
+	_ Clock    = (*Event)(nil)
+	_ Observer = (*Event)(nil)
+)
+
+// Event 完全由事件时间驱动的时钟，时间等于已观察到的最大事件时间，在第一个事件之前为零值。
+// 与Simulated不同，Event不能手动设置时间，适合将回放数据源作为唯一的时间来源
+type Event struct {
+	sim *Simulated
+}
+
+// NewEvent 创建由事件驱动的时钟
+func NewEvent() *Event {
+	return &Event{sim: NewSimulated(time.Time{})}
+}
+
+// Now 返回已观察到的最大事件时间
+func (e *Event) Now() time.Time { return e.sim.Now() }
+
+// Since 返回自t以来经过的事件时间
+func (e *Event) Since(t time.Time) time.Duration { return e.sim.Since(t) }
+
+// After 在事件时间经过d之后向返回的通道发送当时的时间
+func (e *Event) After(d time.Duration) <-chan time.Time { return e.sim.After(d) }
+
+// NewTimer 创建在事件时间经过d之后触发一次的定时器
+func (e *Event) NewTimer(d time.Duration) Timer { return e.sim.NewTimer(d) }
+
+// NewTicker 创建事件时间每经过d触发一次的定时器
+func (e *Event) NewTicker(d time.Duration) Ticker { return e.sim.NewTicker(d) }
+
+// Observe 将时钟推进到事件时间ts（毫秒），乱序到达的较早事件被忽略
+func (e *Event) Observe(ts int64) { e.sim.Observe(ts) }
+
+// BlockUntil 阻塞直到有n个未停止的定时器
+func (e *Event) BlockUntil(n int) { e.sim.BlockUntil(n) }
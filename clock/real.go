@@ -0,0 +1,34 @@
+package clock
+
+import "time"
+
+var realClock Clock = systemClock{}
+
+// Real 返回使用系统时间的时钟
+func Real() Clock {
+	return realClock
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) Since(t time.Time) time.Duration { return time.Since(t) }
+
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (systemClock) NewTimer(d time.Duration) Timer { return &systemTimer{time.NewTimer(d)} }
+
+func (systemClock) NewTicker(d time.Duration) Ticker { return &systemTicker{time.NewTicker(d)} }
+
+type systemTimer struct{ t *time.Timer }
+
+func (s *systemTimer) C() <-chan time.Time { return s.t.C }
+
+func (s *systemTimer) Stop() bool { return s.t.Stop() }
+
+type systemTicker struct{ t *time.Ticker }
+
+func (s *systemTicker) C() <-chan time.Time { return s.t.C }
+
+func (s *systemTicker) Stop() { s.t.Stop() }
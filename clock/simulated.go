@@ -0,0 +1,197 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	_ Clock    = (*Simulated)(nil)
+	_ Observer = (*Simulated)(nil)
+)
+
+// Simulated 手动控制的模拟时钟，时间只在调用Set、Advance或Observe时变化，
+// 推进时按到期时间顺序触发定时器，周期定时器在一次推进中最多触发一次，跨越的其余周期被丢弃，
+// 与time.Ticker一样，通道容量为1，未及时读取的触发会被丢弃
+type Simulated struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*waiter
+}
+
+// waiter 等待到期的定时器
+type waiter struct {
+	deadline time.Time
+	// period 周期定时器的间隔，单次定时器为0
+	period time.Duration
+	ch     chan time.Time
+}
+
+// NewSimulated 创建从start开始的模拟时钟
+func NewSimulated(start time.Time) *Simulated {
+	s := &Simulated{now: start}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// Now 返回模拟时间
+func (s *Simulated) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.now
+}
+
+// Since 返回自t以来经过的模拟时间
+func (s *Simulated) Since(t time.Time) time.Duration {
+	return s.Now().Sub(t)
+}
+
+// After 在模拟时间经过d之后向返回的通道发送当时的时间
+func (s *Simulated) After(d time.Duration) <-chan time.Time {
+	return s.NewTimer(d).C()
+}
+
+// NewTimer 创建在模拟时间经过d之后触发一次的定时器，d不大于0时立即触发
+func (s *Simulated) NewTimer(d time.Duration) Timer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := &waiter{deadline: s.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.ch <- s.now
+	} else {
+		s.add(w)
+	}
+	return &simTimer{clock: s, w: w}
+}
+
+// NewTicker 创建模拟时间每经过d触发一次的定时器
+func (s *Simulated) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := &waiter{deadline: s.now.Add(d), period: d, ch: make(chan time.Time, 1)}
+	s.add(w)
+	return &simTicker{clock: s, w: w}
+}
+
+// Set 将模拟时间设置为t并触发到期的定时器，t早于当前时间时只修改时间
+func (s *Simulated) Set(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(t)
+}
+
+// Advance 将模拟时间推进d
+func (s *Simulated) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(s.now.Add(d))
+}
+
+// Observe 将模拟时间推进到事件时间ts（毫秒），早于当前时间的事件被忽略
+func (s *Simulated) Observe(ts int64) {
+	t := time.UnixMilli(ts)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.After(s.now) {
+		s.advance(t)
+	}
+}
+
+// BlockUntil 阻塞直到有n个未停止的定时器，用于等待其他goroutine创建定时器后再推进时间
+func (s *Simulated) BlockUntil(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.waiters) < n {
+		s.cond.Wait()
+	}
+}
+
+// advance 按到期时间顺序触发不晚于t的定时器，调用方需持有锁
+func (s *Simulated) advance(t time.Time) {
+	for {
+		i := s.earliest()
+		if i < 0 || s.waiters[i].deadline.After(t) {
+			break
+		}
+		w := s.waiters[i]
+		s.now = w.deadline
+		select {
+		case w.ch <- s.now:
+		default:
+		}
+		if w.period > 0 {
+			w.deadline = nextTick(w.deadline, w.period, t)
+		} else {
+			s.remove(w)
+		}
+	}
+	s.now = t
+}
+
+// nextTick 返回周期定时器晚于t的第一个到期时间，直接跳过中间的周期。
+// 从零时间开始的事件时钟第一次观察到事件时相差的时间超出Duration的范围，Sub会饱和，因此分多步跳过
+func nextTick(deadline time.Time, period time.Duration, t time.Time) time.Time {
+	for !deadline.After(t) {
+		deadline = deadline.Add(t.Sub(deadline) / period * period).Add(period)
+	}
+	return deadline
+}
+
+// earliest 返回最早到期的定时器下标，没有定时器时返回-1
+func (s *Simulated) earliest() int {
+	idx := -1
+	for i, w := range s.waiters {
+		if idx < 0 || w.deadline.Before(s.waiters[idx].deadline) {
+			idx = i
+		}
+	}
+	return idx
+}
+
+func (s *Simulated) add(w *waiter) {
+	s.waiters = append(s.waiters, w)
+	s.cond.Broadcast()
+}
+
+// remove 移除定时器，定时器不存在时返回false
+func (s *Simulated) remove(w *waiter) bool {
+	for i, x := range s.waiters {
+		if x == w {
+			s.waiters = append(s.waiters[:i], s.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type simTimer struct {
+	clock *Simulated
+	w     *waiter
+}
+
+func (t *simTimer) C() <-chan time.Time { return t.w.ch }
+
+func (t *simTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.remove(t.w)
+}
+
+type simTicker struct {
+	clock *Simulated
+	w     *waiter
+}
+
+func (t *simTicker) C() <-chan time.Time { return t.w.ch }
+
+func (t *simTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.clock.remove(t.w)
+}
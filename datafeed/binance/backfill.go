@@ -0,0 +1,178 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-gotop/gotop/requests"
+	"github.com/go-gotop/gotop/types"
+)
+
+const (
+	// backfillLimit 单次回补请求的数量上限
+	backfillLimit = 1000
+	// maxBackfillPages 单个流最多回补的页数，避免长时间断线后无限回补
+	maxBackfillPages = 10
+)
+
+// sequenceOf 从组合流消息中提取流名称和成交ID，现货trade使用"t"，合约aggTrade使用"a"
+func sequenceOf(data []byte) (string, int64, bool) {
+	// 成交时间字段"T"与"t"仅大小写不同，json默认大小写不敏感，需按原始键名读取
+	var m struct {
+		Stream string                     `json:"stream"`
+		Data   map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &m); err != nil || m.Stream == "" {
+		return "", 0, false
+	}
+	for _, key := range []string{"t", "a"} {
+		raw, ok := m.Data[key]
+		if !ok {
+			continue
+		}
+		var seq int64
+		if err := json.Unmarshal(raw, &seq); err != nil {
+			return "", 0, false
+		}
+		return m.Stream, seq, true
+	}
+	return "", 0, false
+}
+
+// bnHistoricalTrade 现货历史成交 /api/v3/historicalTrades
+type bnHistoricalTrade struct {
+	ID           int64  `json:"id"`
+	Price        string `json:"price"`
+	Qty          string `json:"qty"`
+	Time         int64  `json:"time"`
+	IsBuyerMaker bool   `json:"isBuyerMaker"`
+	IsBestMatch  bool   `json:"isBestMatch"`
+}
+
+// bnAggTrade 合约归集成交 /fapi/v1/aggTrades
+type bnAggTrade struct {
+	ID           int64  `json:"a"`
+	Price        string `json:"p"`
+	Qty          string `json:"q"`
+	FirstID      int64  `json:"f"`
+	LastID       int64  `json:"l"`
+	Time         int64  `json:"T"`
+	IsBuyerMaker bool   `json:"m"`
+}
+
+// backfiller 通过REST接口回补断线期间缺失的成交
+type backfiller struct {
+	client  requests.RequestClient
+	market  types.MarketType
+	httpURL string
+}
+
+// backfill 为每个已收到过数据的流回补last之后的成交，消息格式与组合流推送一致
+func (f *backfiller) backfill(ctx context.Context, last map[string]int64) ([][]byte, error) {
+	var (
+		msgs     [][]byte
+		firstErr error
+	)
+	for streamName, seq := range last {
+		trades, err := f.fetch(ctx, streamName, seq+1)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("backfill %s: %w", streamName, err)
+		}
+		msgs = append(msgs, trades...)
+	}
+	return msgs, firstErr
+}
+
+// fetch 从fromID开始分页拉取单个流的成交
+func (f *backfiller) fetch(ctx context.Context, streamName string, fromID int64) ([][]byte, error) {
+	symbol := strings.ToUpper(strings.SplitN(streamName, "@", 2)[0])
+
+	var msgs [][]byte
+	for page := 0; page < maxBackfillPages; page++ {
+		if err := ctx.Err(); err != nil {
+			return msgs, err
+		}
+		body, err := f.get(symbol, fromID)
+		if err != nil {
+			return msgs, err
+		}
+
+		var (
+			batch  []any
+			nextID int64
+		)
+		switch f.market {
+		case types.MarketTypeSpot:
+			var trades []bnHistoricalTrade
+			if err := json.Unmarshal(body, &trades); err != nil {
+				return msgs, err
+			}
+			for _, t := range trades {
+				batch = append(batch, map[string]any{
+					"e": "trade", "E": t.Time, "s": symbol, "t": t.ID, "p": t.Price,
+					"q": t.Qty, "T": t.Time, "m": t.IsBuyerMaker, "M": t.IsBestMatch,
+				})
+				nextID = t.ID + 1
+			}
+		default:
+			var trades []bnAggTrade
+			if err := json.Unmarshal(body, &trades); err != nil {
+				return msgs, err
+			}
+			for _, t := range trades {
+				batch = append(batch, map[string]any{
+					"e": "aggTrade", "E": t.Time, "s": symbol, "a": t.ID, "p": t.Price,
+					"q": t.Qty, "f": t.FirstID, "l": t.LastID, "T": t.Time, "m": t.IsBuyerMaker,
+				})
+				nextID = t.ID + 1
+			}
+		}
+
+		for _, data := range batch {
+			msg, err := json.Marshal(map[string]any{"stream": streamName, "data": data})
+			if err != nil {
+				return msgs, err
+			}
+			msgs = append(msgs, msg)
+		}
+		if len(batch) < backfillLimit {
+			return msgs, nil
+		}
+		fromID = nextID
+	}
+	return msgs, nil
+}
+
+// get 请求一页历史成交
+func (f *backfiller) get(symbol string, fromID int64) ([]byte, error) {
+	apiURL := f.httpURL + "/fapi/v1/aggTrades"
+	if f.market == types.MarketTypeSpot {
+		apiURL = f.httpURL + "/api/v3/historicalTrades"
+	}
+	resp, err := f.client.DoRequest(&requests.Request{
+		Method: http.MethodGet,
+		URL:    apiURL,
+		Params: map[string]any{
+			"symbol": symbol,
+			"fromId": fmt.Sprintf("%d", fromID),
+			"limit":  fmt.Sprintf("%d", backfillLimit),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+	return body, nil
+}
@@ -1,31 +1,39 @@
 package binance
 
 import (
-	"strings"
 	"context"
-	"time"
-	"sync"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/go-gotop/gotop/types"
+	"github.com/go-gotop/gotop/requests"
+	bnexreq "github.com/go-gotop/gotop/requests/binance"
 	"github.com/go-gotop/gotop/stream"
-	binanceStream"github.com/go-gotop/gotop/stream/binance"
+	binanceStream "github.com/go-gotop/gotop/stream/binance"
+	"github.com/go-gotop/gotop/types"
 )
 
 const (
 	spotHTTPURL = "https://api.binance.com"
-	spotWSURL   = "wss://stream.binance.com:9443/ws"
+	spotWSURL   = "wss://stream.binance.com:9443/stream"
 
 	futuresHTTPURL = "https://fapi.binance.com"
-	futuresWSURL   = "wss://fstream.binance.com/ws"
+	futuresWSURL   = "wss://fstream.binance.com/stream"
 )
 
 // NewBinanceDataFeed 创建一个新的BinanceDataFeed
 func NewBinanceDataFeed(opts ...Option) *BinanceDataFeed {
 	o := applyOptions(opts...)
+	client := requests.NewClient()
+	client.SetAdapter(bnexreq.NewBinanceAdapter())
 	return &BinanceDataFeed{
-		opts: o,
+		opts:    o,
+		client:  client,
 		streams: make(map[string]stream.Stream[binanceStream.BinanceRequest]),
+		conns:   make(map[types.MarketType][]*combinedConn),
+		subs:    make(map[string]*subscription),
 	}
 }
 
@@ -47,28 +55,106 @@ type BinanceOrderRequest struct {
 
 // listenKey 监听键
 type listenKey struct {
-    Key        string
-    ExpireTime time.Time
+	Key        string
+	ExpireTime time.Time
 }
 
 // accountInfo 账户信息
 type accountInfo struct {
-	AccountID   string
-    APIKey      string
-    SecretKey   string
-    // 不同类型账户的listenKey
-    ListenKeys  map[types.MarketType]*listenKey
+	AccountID string
+	APIKey    string
+	SecretKey string
+	// 不同类型账户的listenKey
+	ListenKeys map[types.MarketType]*listenKey
+}
+
+// subscription 单个订阅，多个订阅可以共享同一个组合流连接
+type subscription struct {
+	id           string
+	market       types.MarketType
+	streamName   string
+	handler      func(data []byte)
+	errorHandler func(err error)
+	conn         *combinedConn
+	stop         func() bool
+}
+
+// combinedConn 组合流连接，一个连接承载多个流，按流名称分发消息
+type combinedConn struct {
+	mu     sync.RWMutex
+	stream *binanceStream.BinanceStream
+	// routes 流名称 -> 订阅ID -> 订阅
+	routes map[string]map[string]*subscription
+	// size 当前连接上的流数量
+	size int
+	// ready 连接建立完成后关闭，err为建立连接的错误
+	ready chan struct{}
+	err   error
+}
+
+// combinedMessage 组合流消息格式
+type combinedMessage struct {
+	Stream string          `json:"stream"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// dispatch 按流名称将消息分发给对应的订阅
+func (c *combinedConn) dispatch(msg []byte) {
+	var m combinedMessage
+	if err := json.Unmarshal(msg, &m); err != nil || m.Stream == "" {
+		return
+	}
+	c.mu.RLock()
+	subs := make([]*subscription, 0, len(c.routes[m.Stream]))
+	for _, sub := range c.routes[m.Stream] {
+		subs = append(subs, sub)
+	}
+	c.mu.RUnlock()
+
+	for _, sub := range subs {
+		if sub.handler != nil {
+			sub.handler(m.Data)
+		}
+	}
+}
+
+// broadcastErr 将连接级别的错误通知给连接上的所有订阅
+func (c *combinedConn) broadcastErr(err error) {
+	c.mu.RLock()
+	subs := make([]*subscription, 0, c.size)
+	for _, route := range c.routes {
+		for _, sub := range route {
+			subs = append(subs, sub)
+		}
+	}
+	c.mu.RUnlock()
+
+	for _, sub := range subs {
+		if sub.errorHandler != nil {
+			sub.errorHandler(err)
+		}
+	}
 }
 
 // BinanceDataFeed 是Binance的数据订阅器
+// 交易数据通过组合流(/stream?streams=)订阅，多个订阅按maxStreamsPerConn打包到有限数量的连接中。
+// 连接定时轮换时采用无缝重连，意外断线重连后通过REST接口回补缺失的成交。
 type BinanceDataFeed struct {
 	mu sync.Mutex
 	// opts 配置选项
 	opts *options
+	// client 回补数据使用的HTTP客户端
+	client requests.RequestClient
 	// listenKeys 监听键
 	listenKeys map[string]listenKey
-	// streams 数据流
+	// streams 订阅ID -> 承载该订阅的数据流
 	streams map[string]stream.Stream[binanceStream.BinanceRequest]
+	// conns 各市场的组合流连接
+	conns map[types.MarketType][]*combinedConn
+	// subs 订阅ID -> 订阅
+	subs map[string]*subscription
+	// connSeq 连接序号，用于生成连接ID
+	connSeq int
 }
 
 // Name 返回DataFeed的名称, BINANCE
@@ -79,34 +165,164 @@ func (b *BinanceDataFeed) Name() string {
 // TradeStream 订阅交易数据
 // id: 调用方在订阅前就给定的ID，用来唯一标识该订阅。
 // request: 交易数据的订阅请求，类型为BinanceTradeRequest。
+// ctx 取消时自动关闭该订阅，但不会影响共享同一连接的其他订阅。
 func (b *BinanceDataFeed) TradeStream(ctx context.Context, id string, request BinanceTradeRequest) error {
-	var url string
+	var streamName string
 	switch request.Market {
 	case types.MarketTypeSpot:
-		url = fmt.Sprintf("%s/%s@trade", spotWSURL, strings.ToLower(request.Symbol))
+		streamName = fmt.Sprintf("%s@trade", strings.ToLower(request.Symbol))
 	case types.MarketTypeFuturesUSDMargined:
-		url = fmt.Sprintf("%s/%s@aggTrade", futuresWSURL, strings.ToLower(request.Symbol))
+		streamName = fmt.Sprintf("%s@aggTrade", strings.ToLower(request.Symbol))
 	default:
 		return fmt.Errorf("invalid market type: %v", request.Market)
 	}
 
-	s := binanceStream.NewBinanceStream(id, types.StreamTypeTrade)
+	b.mu.Lock()
+	if _, ok := b.subs[id]; ok {
+		b.mu.Unlock()
+		return fmt.Errorf("stream %s already exists", id)
+	}
+	sub := &subscription{
+		id:           id,
+		market:       request.Market,
+		streamName:   streamName,
+		handler:      request.Handler,
+		errorHandler: request.ErrorHandler,
+	}
+	c, subscribe, create := b.attach(sub)
+	b.subs[id] = sub
+	b.mu.Unlock()
 
-	if err := s.Connect(ctx, binanceStream.BinanceRequest{
-		URL: url,
-		Handler: request.Handler,
-		ErrorHandler: request.ErrorHandler,
-	}); err != nil {
+	// 建立连接和发送订阅请求不持有锁，并发的订阅请求由BinanceStream合并发送
+	var err error
+	if create {
+		err = b.connect(ctx, c, sub.market)
+	} else {
+		<-c.ready
+		err = c.err
+		if err == nil && subscribe {
+			err = c.stream.Subscribe(sub.streamName)
+		}
+	}
+	if err != nil {
+		b.detach(sub, create)
 		return err
 	}
 
 	b.mu.Lock()
-	b.streams[id] = s
-	b.mu.Unlock()
-
+	defer b.mu.Unlock()
+	if b.subs[id] != sub {
+		// 建立订阅期间已被关闭
+		return nil
+	}
+	b.streams[id] = c.stream
+	sub.stop = context.AfterFunc(ctx, func() {
+		_ = b.CloseStream(id)
+	})
 	return nil
 }
 
+// attach 为订阅分配一个有空余容量的连接，没有则新建连接，调用方需持有锁。
+// subscribe 表示需要在连接上订阅该流，create 表示连接是新建的，需要调用方建立连接
+func (b *BinanceDataFeed) attach(sub *subscription) (c *combinedConn, subscribe, create bool) {
+	for _, c := range b.conns[sub.market] {
+		c.mu.Lock()
+		if route, ok := c.routes[sub.streamName]; ok {
+			// 同一个流已被订阅，直接复用
+			route[sub.id] = sub
+			c.mu.Unlock()
+			sub.conn = c
+			return c, false, false
+		}
+		if c.size < b.opts.maxStreamsPerConn {
+			c.routes[sub.streamName] = map[string]*subscription{sub.id: sub}
+			c.size++
+			c.mu.Unlock()
+			sub.conn = c
+			return c, true, false
+		}
+		c.mu.Unlock()
+	}
+
+	b.connSeq++
+	c = &combinedConn{
+		stream: binanceStream.NewBinanceStream(
+			fmt.Sprintf("%s-%s-%d", types.BinanceExchange, sub.market, b.connSeq),
+			types.StreamTypeTrade,
+			b.opts.streamOpts...,
+		),
+		routes: map[string]map[string]*subscription{
+			sub.streamName: {sub.id: sub},
+		},
+		size:  1,
+		ready: make(chan struct{}),
+	}
+	sub.conn = c
+	b.conns[sub.market] = append(b.conns[sub.market], c)
+	return c, false, true
+}
+
+// connect 建立新的组合流连接，初始订阅为创建连接时的流，
+// 连接建立期间分配到该连接的其他流在连接建立后由各自的订阅方发送SUBSCRIBE
+func (b *BinanceDataFeed) connect(ctx context.Context, c *combinedConn, market types.MarketType) error {
+	c.mu.RLock()
+	streams := make([]string, 0, len(c.routes))
+	for name := range c.routes {
+		streams = append(streams, name)
+	}
+	c.mu.RUnlock()
+
+	filler := &backfiller{
+		client:  b.client,
+		market:  market,
+		httpURL: b.opts.httpURLs[market],
+	}
+	// 连接由多个订阅共享，生命周期不跟随单个订阅的ctx
+	c.err = c.stream.Connect(context.WithoutCancel(ctx), binanceStream.BinanceRequest{
+		URL:          b.opts.wsURLs[market],
+		Streams:      streams,
+		Logger:       b.opts.logger,
+		Handler:      c.dispatch,
+		ErrorHandler: c.broadcastErr,
+		SequenceFunc: sequenceOf,
+		BackfillFunc: filler.backfill,
+	})
+	close(c.ready)
+	return c.err
+}
+
+// detach 撤销建立失败的订阅，removeConn 表示连接建立失败需要移除
+func (b *BinanceDataFeed) detach(sub *subscription, removeConn bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs[sub.id] == sub {
+		delete(b.subs, sub.id)
+		b.release(sub)
+	}
+	if removeConn {
+		b.removeConn(sub.market, sub.conn)
+	}
+}
+
+// release 从连接的路由中移除订阅，调用方需持有锁。
+// 返回流是否已没有订阅、连接是否已没有任何流
+func (b *BinanceDataFeed) release(sub *subscription) (unsubscribe, empty bool) {
+	c := sub.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	route, ok := c.routes[sub.streamName]
+	if !ok {
+		return false, c.size == 0
+	}
+	delete(route, sub.id)
+	if len(route) == 0 {
+		delete(c.routes, sub.streamName)
+		c.size--
+		unsubscribe = true
+	}
+	return unsubscribe, c.size == 0
+}
+
 // OrderStream 订阅订单数据
 // id: 调用方在订阅前就给定的ID，用来唯一标识该订阅。
 // request: 订单数据的订阅请求，类型为BinanceOrderRequest。
@@ -115,6 +331,7 @@ func (b *BinanceDataFeed) OrderStream(ctx context.Context, id string, request Bi
 }
 
 // Streams 返回当前所有订阅的id列表
+// 打包到同一连接的订阅共享同一个Stream
 func (b *BinanceDataFeed) Streams() map[string]stream.Stream[binanceStream.BinanceRequest] {
 	b.mu.Lock()
 	defer b.mu.Unlock()
@@ -122,26 +339,78 @@ func (b *BinanceDataFeed) Streams() map[string]stream.Stream[binanceStream.Binan
 }
 
 // CloseStream 关闭单个订阅
+// 流没有其他订阅时取消该流的订阅，连接上没有任何流时断开连接
 func (b *BinanceDataFeed) CloseStream(id string) error {
 	b.mu.Lock()
-	defer b.mu.Unlock()
-	if stream, ok := b.streams[id]; ok {
-		if err := stream.Disconnect(); err != nil {
-			return err
+	sub, ok := b.subs[id]
+	if !ok {
+		b.mu.Unlock()
+		return nil
+	}
+	delete(b.subs, id)
+	delete(b.streams, id)
+	if sub.stop != nil {
+		sub.stop()
+	}
+	unsubscribe, empty := b.release(sub)
+	if empty {
+		b.removeConn(sub.market, sub.conn)
+	}
+	b.mu.Unlock()
+
+	c := sub.conn
+	if empty || unsubscribe {
+		// 连接可能仍在建立中
+		<-c.ready
+		if c.err != nil {
+			return nil
 		}
-		delete(b.streams, id)
+	}
+	if empty {
+		return c.stream.Disconnect()
+	}
+	if unsubscribe {
+		return c.stream.Unsubscribe(sub.streamName)
 	}
 	return nil
 }
 
+// removeConn 移除连接，调用方需持有锁
+func (b *BinanceDataFeed) removeConn(market types.MarketType, c *combinedConn) {
+	conns := b.conns[market]
+	for i, v := range conns {
+		if v == c {
+			b.conns[market] = append(conns[:i], conns[i+1:]...)
+			return
+		}
+	}
+}
+
 // Close 关闭所有订阅
 func (b *BinanceDataFeed) Close() error {
 	b.mu.Lock()
-	defer b.mu.Unlock()
-	for _, stream := range b.streams {
-		if err := stream.Disconnect(); err != nil {
-			return err
+	for _, sub := range b.subs {
+		if sub.stop != nil {
+			sub.stop()
 		}
 	}
-	return nil
+	conns := b.conns
+	b.conns = make(map[types.MarketType][]*combinedConn)
+	b.subs = make(map[string]*subscription)
+	b.streams = make(map[string]stream.Stream[binanceStream.BinanceRequest])
+	b.mu.Unlock()
+
+	var firstErr error
+	for _, cs := range conns {
+		for _, c := range cs {
+			<-c.ready
+			if c.err != nil {
+				continue
+			}
+			if err := c.stream.Disconnect(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
 }
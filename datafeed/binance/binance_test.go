@@ -0,0 +1,240 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/types"
+)
+
+// combinedServer 模拟Binance组合流服务端，收到SUBSCRIBE或建立连接后为每个流推送一条消息
+type combinedServer struct {
+	mu          sync.Mutex
+	connections int
+	server      *httptest.Server
+}
+
+func newCombinedServer(t *testing.T) *combinedServer {
+	s := &combinedServer{}
+	upgrader := websocket.Upgrader{}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.connections++
+		s.mu.Unlock()
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		push := func(streams []string) {
+			for _, name := range streams {
+				msg := fmt.Sprintf(`{"stream":%q,"data":{"s":%q}}`, name, name)
+				conn.WriteMessage(websocket.TextMessage, []byte(msg))
+			}
+		}
+		push(strings.Split(r.URL.Query().Get("streams"), "/"))
+
+		for {
+			var req struct {
+				Method string   `json:"method"`
+				Params []string `json:"params"`
+				ID     int64    `json:"id"`
+			}
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"result":null,"id":%d}`, req.ID)))
+			if req.Method == "SUBSCRIBE" {
+				push(req.Params)
+			}
+		}
+	}))
+	return s
+}
+
+func (s *combinedServer) url() string {
+	return "ws" + strings.TrimPrefix(s.server.URL, "http") + "/stream"
+}
+
+func (s *combinedServer) connectionCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections
+}
+
+func TestTradeStreamPacksConnections(t *testing.T) {
+	srv := newCombinedServer(t)
+	defer srv.server.Close()
+
+	feed := NewBinanceDataFeed(
+		WithWSURL(types.MarketTypeSpot, srv.url()),
+		WithMaxStreamsPerConn(2),
+	)
+	defer feed.Close()
+
+	var mu sync.Mutex
+	received := make(map[string][]string)
+	symbols := []string{"BTCUSDT", "ETHUSDT", "BNBUSDT"}
+
+	for _, symbol := range symbols {
+		id := "trade-" + symbol
+		err := feed.TradeStream(context.Background(), id, BinanceTradeRequest{
+			Symbol: symbol,
+			Market: types.MarketTypeSpot,
+			Handler: func(data []byte) {
+				var payload struct {
+					S string `json:"s"`
+				}
+				require.NoError(t, json.Unmarshal(data, &payload))
+				mu.Lock()
+				received[id] = append(received[id], payload.S)
+				mu.Unlock()
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	// 3个流，每个连接最多2个流，应该只建立2个连接
+	require.Equal(t, 2, srv.connectionCount())
+	require.Len(t, feed.Streams(), 3)
+	require.Same(t, feed.Streams()["trade-BTCUSDT"], feed.Streams()["trade-ETHUSDT"])
+	require.NotSame(t, feed.Streams()["trade-BTCUSDT"], feed.Streams()["trade-BNBUSDT"])
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 3
+	}, time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	// 每个订阅只会收到自己流的消息
+	for _, symbol := range symbols {
+		for _, s := range received["trade-"+symbol] {
+			require.Equal(t, strings.ToLower(symbol)+"@trade", s)
+		}
+	}
+	mu.Unlock()
+
+	require.NoError(t, feed.CloseStream("trade-BNBUSDT"))
+	require.Len(t, feed.Streams(), 2)
+	require.Len(t, feed.conns[types.MarketTypeSpot], 1)
+}
+
+func TestTradeStreamContextCancel(t *testing.T) {
+	srv := newCombinedServer(t)
+	defer srv.server.Close()
+
+	feed := NewBinanceDataFeed(WithWSURL(types.MarketTypeSpot, srv.url()))
+	defer feed.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, feed.TradeStream(ctx, "a", BinanceTradeRequest{Symbol: "BTCUSDT", Market: types.MarketTypeSpot}))
+	require.NoError(t, feed.TradeStream(context.Background(), "b", BinanceTradeRequest{Symbol: "ETHUSDT", Market: types.MarketTypeSpot}))
+
+	// 取消单个订阅的ctx只关闭该订阅，共享连接继续服务其他订阅
+	cancel()
+	require.Eventually(t, func() bool {
+		return len(feed.Streams()) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, 1, srv.connectionCount())
+	require.Equal(t, []string{"ethusdt@trade"}, feed.conns[types.MarketTypeSpot][0].stream.Subscriptions())
+}
+
+func TestTradeStreamConcurrent(t *testing.T) {
+	srv := newCombinedServer(t)
+	defer srv.server.Close()
+
+	feed := NewBinanceDataFeed(WithWSURL(types.MarketTypeSpot, srv.url()))
+	defer feed.Close()
+
+	// 并发订阅共享同一个连接，连接建立期间分配到该连接的流在连接建立后订阅
+	var mu sync.Mutex
+	received := make(map[string]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			symbol := fmt.Sprintf("S%dUSDT", i)
+			err := feed.TradeStream(context.Background(), symbol, BinanceTradeRequest{
+				Symbol: symbol,
+				Market: types.MarketTypeSpot,
+				Handler: func(data []byte) {
+					mu.Lock()
+					received[symbol] = true
+					mu.Unlock()
+				},
+			})
+			require.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	require.Equal(t, 1, srv.connectionCount())
+	require.Len(t, feed.Streams(), 10)
+	require.Len(t, feed.conns[types.MarketTypeSpot][0].stream.Subscriptions(), 10)
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 10
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestBackfillAggTrades(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/fapi/v1/aggTrades", r.URL.Path)
+		queries = append(queries, r.URL.RawQuery)
+		fmt.Fprint(w, `[{"a":11,"p":"100.1","q":"0.5","f":20,"l":21,"T":1700000000000,"m":true},`+
+			`{"a":12,"p":"100.2","q":"1","f":22,"l":22,"T":1700000000001,"m":false}]`)
+	}))
+	defer server.Close()
+
+	feed := NewBinanceDataFeed(WithHTTPURL(types.MarketTypeFuturesUSDMargined, server.URL))
+	filler := &backfiller{
+		client:  feed.client,
+		market:  types.MarketTypeFuturesUSDMargined,
+		httpURL: server.URL,
+	}
+
+	msgs, err := filler.backfill(context.Background(), map[string]int64{"btcusdt@aggTrade": 10})
+	require.NoError(t, err)
+	require.Equal(t, []string{"fromId=11&limit=1000&symbol=BTCUSDT"}, queries)
+	require.Len(t, msgs, 2)
+
+	// 回补消息与组合流推送格式一致，可以直接参与去重
+	stream, seq, ok := sequenceOf(msgs[1])
+	require.True(t, ok)
+	require.Equal(t, "btcusdt@aggTrade", stream)
+	require.EqualValues(t, 12, seq)
+
+	var m combinedMessage
+	require.NoError(t, json.Unmarshal(msgs[0], &m))
+	require.JSONEq(t, `{"e":"aggTrade","E":1700000000000,"s":"BTCUSDT","a":11,"p":"100.1","q":"0.5","f":20,"l":21,"T":1700000000000,"m":true}`, string(m.Data))
+}
+
+func TestTradeParser(t *testing.T) {
+	trades, err := TradeParser(types.MarketTypeSpot)([]byte(`{"e":"trade","E":1672515782136,"s":"BNBBTC","t":12345,"p":"0.001","q":"100","T":1672515782136,"m":true,"M":true}`))
+	require.NoError(t, err)
+	require.Len(t, trades, 1)
+	require.Equal(t, uint64(12345), trades[0].TradeID)
+	require.Equal(t, int64(1672515782136), trades[0].Timestamp)
+	require.Equal(t, "BNBBTC", trades[0].Symbol)
+	require.Equal(t, types.SideTypeSell, trades[0].Side)
+	require.Equal(t, "0.001", trades[0].Price.String())
+
+	trades, err = TradeParser(types.MarketTypeFuturesUSDMargined)([]byte(`{"e":"aggTrade","E":123456789,"s":"BTCUSDT","a":5933014,"p":"0.001","q":"100","f":100,"l":105,"T":123456785,"m":false}`))
+	require.NoError(t, err)
+	require.Equal(t, uint64(5933014), trades[0].TradeID)
+	require.Equal(t, types.SideTypeBuy, trades[0].Side)
+}
@@ -2,16 +2,48 @@ package binance
 
 import (
 	"log/slog"
+	"time"
+
+	binanceStream "github.com/go-gotop/gotop/stream/binance"
+	"github.com/go-gotop/gotop/types"
+)
+
+const (
+	// defaultMaxStreamsPerConn 单个组合流连接默认承载的流数量，Binance 上限为1024
+	defaultMaxStreamsPerConn = 200
+	// defaultSeamlessOverlap 定时轮换连接时新旧连接默认并行的时长
+	defaultSeamlessOverlap = 10 * time.Second
 )
 
 type options struct {
 	// logger 日志记录器
 	logger *slog.Logger
+	// maxStreamsPerConn 单个连接承载的最大流数量
+	maxStreamsPerConn int
+	// streamOpts 创建底层BinanceStream时使用的配置
+	streamOpts []binanceStream.Option
+	// wsURLs 各市场的组合流地址
+	wsURLs map[types.MarketType]string
+	// httpURLs 各市场回补数据使用的REST地址
+	httpURLs map[types.MarketType]string
 }
 
 func applyOptions(opts ...Option) *options {
 	o := &options{
-		logger: slog.Default(),
+		logger:            slog.Default(),
+		maxStreamsPerConn: defaultMaxStreamsPerConn,
+		// 默认开启无缝重连，用户配置的streamOpts在其后应用，可以覆盖
+		streamOpts: []binanceStream.Option{
+			binanceStream.WithSeamlessReconnect(defaultSeamlessOverlap),
+		},
+		wsURLs: map[types.MarketType]string{
+			types.MarketTypeSpot:               spotWSURL,
+			types.MarketTypeFuturesUSDMargined: futuresWSURL,
+		},
+		httpURLs: map[types.MarketType]string{
+			types.MarketTypeSpot:               spotHTTPURL,
+			types.MarketTypeFuturesUSDMargined: futuresHTTPURL,
+		},
 	}
 	for _, opt := range opts {
 		opt(o)
@@ -28,3 +60,33 @@ func WithLogger(logger *slog.Logger) Option {
 		o.logger = logger
 	}
 }
+
+// WithMaxStreamsPerConn 设置单个组合流连接承载的最大流数量
+func WithMaxStreamsPerConn(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxStreamsPerConn = n
+		}
+	}
+}
+
+// WithStreamOptions 设置创建底层BinanceStream时使用的配置
+func WithStreamOptions(opts ...binanceStream.Option) Option {
+	return func(o *options) {
+		o.streamOpts = append(o.streamOpts, opts...)
+	}
+}
+
+// WithWSURL 设置指定市场的组合流地址，例如测试网地址
+func WithWSURL(market types.MarketType, url string) Option {
+	return func(o *options) {
+		o.wsURLs[market] = url
+	}
+}
+
+// WithHTTPURL 设置指定市场回补数据使用的REST地址
+func WithHTTPURL(market types.MarketType, url string) Option {
+	return func(o *options) {
+		o.httpURLs[market] = url
+	}
+}
@@ -0,0 +1,72 @@
+package binance
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/types"
+)
+
+// bnTradeEvent 成交推送，trade与aggTrade共用
+// 字段名存在仅大小写不同的情况（t/T、m/M、e/E），需全部声明以保证精确匹配
+type bnTradeEvent struct {
+	EventType    string `json:"e"`
+	EventTime    int64  `json:"E"`
+	Symbol       string `json:"s"`
+	TradeID      *int64 `json:"t"`
+	AggTradeID   *int64 `json:"a"`
+	Price        string `json:"p"`
+	Qty          string `json:"q"`
+	TradeTime    int64  `json:"T"`
+	IsBuyerMaker bool   `json:"m"`
+	IsBestMatch  bool   `json:"M"`
+}
+
+// TradeParser 返回指定市场的成交解析函数，用于解析TradeStream推送给Handler的数据
+// 现货trade使用成交ID，合约aggTrade使用归集成交ID
+func TradeParser(market types.MarketType) func(data []byte) ([]types.TradeEvent, error) {
+	return func(data []byte) ([]types.TradeEvent, error) {
+		var e bnTradeEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil, err
+		}
+
+		var tradeID int64
+		switch {
+		case e.TradeID != nil:
+			tradeID = *e.TradeID
+		case e.AggTradeID != nil:
+			tradeID = *e.AggTradeID
+		default:
+			return nil, fmt.Errorf("missing trade id: %s", data)
+		}
+
+		price, err := decimal.NewFromString(e.Price)
+		if err != nil {
+			return nil, fmt.Errorf("parse price error: %w", err)
+		}
+		size, err := decimal.NewFromString(e.Qty)
+		if err != nil {
+			return nil, fmt.Errorf("parse size error: %w", err)
+		}
+
+		// 买方是挂单方时，主动成交方向为卖
+		side := types.SideTypeBuy
+		if e.IsBuyerMaker {
+			side = types.SideTypeSell
+		}
+
+		return []types.TradeEvent{{
+			Timestamp: e.TradeTime,
+			Symbol:    e.Symbol,
+			Exchange:  types.BinanceExchange,
+			TradeID:   uint64(tradeID),
+			Size:      size,
+			Price:     price,
+			Side:      side,
+			Type:      market,
+		}}, nil
+	}
+}
@@ -0,0 +1,109 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/go-gotop/gotop/datafeed"
+	"github.com/go-gotop/gotop/stream"
+	csvStream "github.com/go-gotop/gotop/stream/csv"
+	"github.com/go-gotop/gotop/types"
+)
+
+var _ datafeed.DataFeed[*csvStream.CSVFileRequest, FileOrderRequest, *csvStream.CSVFileRequest] = (*FileDataFeed)(nil)
+
+// FileOrderRequest 文件数据源没有订单数据，仅用于满足DataFeed接口
+type FileOrderRequest struct{}
+
+// FileDataFeed 由本地CSV/Parquet文件回放的DataFeed，用于离线运行策略
+type FileDataFeed struct {
+	mu      sync.Mutex
+	name    string
+	streams map[string]stream.Stream[*csvStream.CSVFileRequest]
+}
+
+// NewFileDataFeed 创建文件DataFeed，name为Name()的返回值，例如回放的交易所名称
+func NewFileDataFeed(name string) *FileDataFeed {
+	return &FileDataFeed{
+		name:    name,
+		streams: make(map[string]stream.Stream[*csvStream.CSVFileRequest]),
+	}
+}
+
+// Name 返回DataFeed的名称
+func (f *FileDataFeed) Name() string {
+	return f.name
+}
+
+// TradeStream 回放逐笔成交文件
+func (f *FileDataFeed) TradeStream(ctx context.Context, id string, request *csvStream.CSVFileRequest) error {
+	return f.connect(ctx, id, types.StreamTypeTrade, request)
+}
+
+// KlineStream 回放K线文件
+func (f *FileDataFeed) KlineStream(ctx context.Context, id string, request *csvStream.CSVFileRequest) error {
+	return f.connect(ctx, id, types.StreamTypeKline, request)
+}
+
+// DepthStream 回放深度快照文件
+func (f *FileDataFeed) DepthStream(ctx context.Context, id string, request *csvStream.CSVFileRequest) error {
+	return f.connect(ctx, id, types.StreamTypeDepth, request)
+}
+
+// OrderStream 文件数据源不支持订单数据
+func (f *FileDataFeed) OrderStream(ctx context.Context, id string, request FileOrderRequest) error {
+	return errors.New("order stream is not supported by file data feed")
+}
+
+func (f *FileDataFeed) connect(ctx context.Context, id string, st types.StreamType, request *csvStream.CSVFileRequest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.streams[id]; ok {
+		return fmt.Errorf("stream %s already exists", id)
+	}
+	s := csvStream.NewCSVFile(id, st)
+	if err := s.Connect(ctx, request); err != nil {
+		return err
+	}
+	f.streams[id] = s
+	return nil
+}
+
+// Streams 返回当前所有订阅
+func (f *FileDataFeed) Streams() map[string]stream.Stream[*csvStream.CSVFileRequest] {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.streams
+}
+
+// CloseStream 关闭单个订阅
+func (f *FileDataFeed) CloseStream(id string) error {
+	f.mu.Lock()
+	s, ok := f.streams[id]
+	delete(f.streams, id)
+	f.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return s.Disconnect()
+}
+
+// Close 关闭所有订阅
+func (f *FileDataFeed) Close() error {
+	f.mu.Lock()
+	streams := f.streams
+	f.streams = make(map[string]stream.Stream[*csvStream.CSVFileRequest])
+	f.mu.Unlock()
+
+	var firstErr error
+	for _, s := range streams {
+		if err := s.Disconnect(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,50 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	csvStream "github.com/go-gotop/gotop/stream/csv"
+	"github.com/go-gotop/gotop/types"
+)
+
+func TestFileDataFeed(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "1000.csv"), []byte(
+		"trade_id,size,price,side,symbol,quote,traded_at\n1,1,100,BUY,BTCUSDT,USDT,1000\n"), 0o644))
+
+	feed := NewFileDataFeed(types.BinanceExchange)
+	assert.Equal(t, types.BinanceExchange, feed.Name())
+
+	trades := make(chan types.TradeEvent, 1)
+	request := &csvStream.CSVFileRequest{
+		Dir:      dir,
+		Exchange: types.BinanceExchange,
+		Handler:  func(trade types.TradeEvent) { trades <- trade },
+	}
+	require.NoError(t, feed.TradeStream(context.Background(), "btc", request))
+	require.Error(t, feed.TradeStream(context.Background(), "btc", request))
+	require.Error(t, feed.OrderStream(context.Background(), "order", FileOrderRequest{}))
+
+	select {
+	case trade := <-trades:
+		assert.Equal(t, "BTCUSDT", trade.Symbol)
+		assert.Equal(t, types.BinanceExchange, trade.Exchange)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for trade")
+	}
+
+	streams := feed.Streams()
+	require.Contains(t, streams, "btc")
+	assert.Equal(t, types.StreamTypeTrade, streams["btc"].Type())
+
+	require.NoError(t, feed.CloseStream("btc"))
+	assert.Empty(t, feed.Streams())
+	require.NoError(t, feed.Close())
+}
@@ -0,0 +1,184 @@
+package okx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-gotop/gotop/requests"
+	okxStream "github.com/go-gotop/gotop/stream/okx"
+)
+
+const (
+	// backfillLimit 单次回补请求的数量上限
+	backfillLimit = 100
+	// maxBackfillPages 单个频道最多回补的页数，避免长时间断线后无限回补
+	maxBackfillPages = 50
+)
+
+// tradesMessage 成交频道推送消息
+type tradesMessage struct {
+	Arg  pushArg    `json:"arg"`
+	Data []okxTrade `json:"data"`
+}
+
+// pushArg 推送消息中的频道参数
+type pushArg struct {
+	Channel string `json:"channel"`
+	InstID  string `json:"instId"`
+}
+
+// okxTrade 成交数据，推送与REST接口格式一致
+type okxTrade struct {
+	InstID  string `json:"instId"`
+	TradeID string `json:"tradeId"`
+	Px      string `json:"px"`
+	Sz      string `json:"sz"`
+	Side    string `json:"side"`
+	Ts      string `json:"ts"`
+}
+
+// sequenceOf 从成交推送中提取频道标识和最大成交ID
+func sequenceOf(data []byte) (string, int64, bool) {
+	var m struct {
+		Arg  okxStream.Arg `json:"arg"`
+		Data []okxTrade    `json:"data"`
+	}
+	if err := json.Unmarshal(data, &m); err != nil || m.Arg.Channel != "trades" {
+		return "", 0, false
+	}
+	var (
+		seq int64
+		ok  bool
+	)
+	for _, t := range m.Data {
+		id, err := strconv.ParseInt(t.TradeID, 10, 64)
+		if err != nil {
+			continue
+		}
+		if !ok || id > seq {
+			seq, ok = id, true
+		}
+	}
+	return m.Arg.Key(), seq, ok
+}
+
+// backfiller 通过REST接口回补断线期间缺失的成交
+type backfiller struct {
+	client  requests.RequestClient
+	httpURL string
+}
+
+// backfill 为每个已收到过数据的频道回补last之后的成交，每笔成交生成一条与推送格式一致的消息
+func (f *backfiller) backfill(ctx context.Context, last map[string]int64) ([][]byte, error) {
+	var (
+		msgs     [][]byte
+		firstErr error
+	)
+	for key, seq := range last {
+		// 频道标识格式为 channel:instType:instFamily:instId
+		parts := strings.Split(key, ":")
+		if parts[0] != "trades" {
+			continue
+		}
+		instID := parts[len(parts)-1]
+		trades, err := f.fetch(ctx, instID, seq)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("backfill %s: %w", instID, err)
+		}
+		for _, t := range trades {
+			msg, err := json.Marshal(tradesMessage{
+				Arg:  pushArg{Channel: "trades", InstID: instID},
+				Data: []okxTrade{t},
+			})
+			if err != nil {
+				return msgs, err
+			}
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs, firstErr
+}
+
+// fetch 从最新成交开始向前分页，直到成交ID不大于lastID，返回按成交ID升序排列的成交
+func (f *backfiller) fetch(ctx context.Context, instID string, lastID int64) ([]okxTrade, error) {
+	var (
+		trades []okxTrade
+		after  string
+	)
+	for page := 0; page < maxBackfillPages; page++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		batch, err := f.get(instID, after)
+		if err != nil {
+			return nil, err
+		}
+
+		done := len(batch) < backfillLimit
+		for _, t := range batch {
+			id, err := strconv.ParseInt(t.TradeID, 10, 64)
+			if err != nil {
+				continue
+			}
+			if id <= lastID {
+				done = true
+				break
+			}
+			trades = append(trades, t)
+			after = t.TradeID
+		}
+		if done {
+			break
+		}
+	}
+
+	// 接口按时间倒序返回，翻转为升序
+	for i, j := 0, len(trades)-1; i < j; i, j = i+1, j-1 {
+		trades[i], trades[j] = trades[j], trades[i]
+	}
+	return trades, nil
+}
+
+// get 请求一页历史成交，after为空时返回最新成交
+func (f *backfiller) get(instID, after string) ([]okxTrade, error) {
+	params := map[string]any{
+		"instId": instID,
+		"type":   "1",
+		"limit":  fmt.Sprintf("%d", backfillLimit),
+	}
+	if after != "" {
+		params["after"] = after
+	}
+	resp, err := f.client.DoRequest(&requests.Request{
+		Method: http.MethodGet,
+		URL:    f.httpURL + "/api/v5/market/history-trades",
+		Params: params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Code string     `json:"code"`
+		Msg  string     `json:"msg"`
+		Data []okxTrade `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	if result.Code != "0" {
+		return nil, fmt.Errorf("history trades error: %s %s", result.Code, result.Msg)
+	}
+	return result.Data, nil
+}
@@ -0,0 +1,386 @@
+package okx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/go-gotop/gotop/requests"
+	okxreq "github.com/go-gotop/gotop/requests/okx"
+	"github.com/go-gotop/gotop/stream"
+	okxStream "github.com/go-gotop/gotop/stream/okx"
+	"github.com/go-gotop/gotop/types"
+)
+
+const (
+	httpURL     = "https://www.okx.com"
+	publicWSURL = "wss://ws.okx.com:8443/ws/v5/public"
+)
+
+// NewOkxDataFeed 创建一个新的OkxDataFeed
+func NewOkxDataFeed(opts ...Option) *OkxDataFeed {
+	o := applyOptions(opts...)
+	client := requests.NewClient()
+	client.SetAdapter(okxreq.NewOKXAdapter())
+	return &OkxDataFeed{
+		opts:    o,
+		client:  client,
+		streams: make(map[string]stream.Stream[okxStream.OkxRequest]),
+		subs:    make(map[string]*subscription),
+	}
+}
+
+// OkxTradeRequest 是OKX的交易数据订阅请求
+type OkxTradeRequest struct {
+	// Symbol 产品ID，例如"BTC-USDT"、"BTC-USDT-SWAP"
+	Symbol string
+	// Market 可选，市场类型，设置时需与Symbol推断出的市场类型一致，例如"BTC-USDT-SWAP"为U本位永续
+	Market types.MarketType
+	// Handler 数据处理函数，参数为完整的推送消息 {"arg":{...},"data":[...]}
+	Handler func(data []byte)
+	// ErrorHandler 错误处理函数
+	ErrorHandler func(err error)
+}
+
+// OkxOrderRequest 是OKX的订单数据订阅请求
+type OkxOrderRequest struct {
+}
+
+// subscription 单个订阅，多个订阅可以共享同一个连接
+type subscription struct {
+	id           string
+	arg          okxStream.Arg
+	handler      func(data []byte)
+	errorHandler func(err error)
+	conn         *sharedConn
+	stop         func() bool
+}
+
+// sharedConn 共享连接，一个连接通过多参数订阅承载多个频道，按arg分发消息
+type sharedConn struct {
+	mu     sync.RWMutex
+	stream *okxStream.OkxStream
+	// routes 频道标识 -> 订阅ID -> 订阅
+	routes map[string]map[string]*subscription
+	// size 当前连接上的频道数量
+	size int
+	// ready 连接建立完成后关闭，err为建立连接的错误
+	ready chan struct{}
+	err   error
+}
+
+// pushMessage OKX推送消息格式
+type pushMessage struct {
+	Arg okxStream.Arg `json:"arg"`
+}
+
+// dispatch 按频道将消息分发给对应的订阅
+func (c *sharedConn) dispatch(msg []byte) {
+	var m pushMessage
+	if err := json.Unmarshal(msg, &m); err != nil || m.Arg.Channel == "" {
+		return
+	}
+	key := m.Arg.Key()
+	c.mu.RLock()
+	subs := make([]*subscription, 0, len(c.routes[key]))
+	for _, sub := range c.routes[key] {
+		subs = append(subs, sub)
+	}
+	c.mu.RUnlock()
+
+	for _, sub := range subs {
+		if sub.handler != nil {
+			sub.handler(msg)
+		}
+	}
+}
+
+// broadcastErr 将连接级别的错误通知给连接上的所有订阅
+func (c *sharedConn) broadcastErr(err error) {
+	c.mu.RLock()
+	subs := make([]*subscription, 0, c.size)
+	for _, route := range c.routes {
+		for _, sub := range route {
+			subs = append(subs, sub)
+		}
+	}
+	c.mu.RUnlock()
+
+	for _, sub := range subs {
+		if sub.errorHandler != nil {
+			sub.errorHandler(err)
+		}
+	}
+}
+
+// OkxDataFeed 是OKX的数据订阅器
+// 交易数据通过多参数订阅，多个订阅按maxArgsPerConn打包到有限数量的连接中。
+// 连接定时轮换时采用无缝重连，意外断线重连后通过REST接口回补缺失的成交。
+type OkxDataFeed struct {
+	mu sync.Mutex
+	// opts 配置选项
+	opts *options
+	// client 回补数据使用的HTTP客户端
+	client requests.RequestClient
+	// streams 订阅ID -> 承载该订阅的数据流
+	streams map[string]stream.Stream[okxStream.OkxRequest]
+	// conns 公共频道连接
+	conns []*sharedConn
+	// subs 订阅ID -> 订阅
+	subs map[string]*subscription
+	// connSeq 连接序号，用于生成连接ID
+	connSeq int
+}
+
+// Name 返回DataFeed的名称, OKX
+func (o *OkxDataFeed) Name() string {
+	return types.OkxExchange
+}
+
+// TradeStream 订阅交易数据
+// id: 调用方在订阅前就给定的ID，用来唯一标识该订阅。
+// request: 交易数据的订阅请求，类型为OkxTradeRequest。
+// ctx 取消时自动关闭该订阅，但不会影响共享同一连接的其他订阅。
+func (o *OkxDataFeed) TradeStream(ctx context.Context, id string, request OkxTradeRequest) error {
+	if request.Symbol == "" {
+		return fmt.Errorf("symbol is required")
+	}
+	if request.Market != types.MarketTypeUnknown && request.Market != marketOf(request.Symbol) {
+		return fmt.Errorf("symbol %s does not belong to market %s", request.Symbol, request.Market)
+	}
+
+	o.mu.Lock()
+	if _, ok := o.subs[id]; ok {
+		o.mu.Unlock()
+		return fmt.Errorf("stream %s already exists", id)
+	}
+	sub := &subscription{
+		id: id,
+		arg: okxStream.Arg{
+			Channel: "trades",
+			InstID:  request.Symbol,
+		},
+		handler:      request.Handler,
+		errorHandler: request.ErrorHandler,
+	}
+	c, subscribe, create := o.attach(sub)
+	o.subs[id] = sub
+	o.mu.Unlock()
+
+	// 建立连接和发送订阅请求不持有锁，Handler中可以关闭或新建订阅
+	var err error
+	if create {
+		err = o.connect(ctx, c)
+	} else {
+		<-c.ready
+		err = c.err
+		if err == nil && subscribe {
+			err = c.stream.Subscribe(sub.arg)
+		}
+	}
+	if err != nil {
+		o.detach(sub, create)
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.subs[id] != sub {
+		// 建立订阅期间已被关闭
+		return nil
+	}
+	o.streams[id] = c.stream
+	sub.stop = context.AfterFunc(ctx, func() {
+		_ = o.CloseStream(id)
+	})
+	return nil
+}
+
+// attach 为订阅分配一个有空余容量的连接，没有则新建连接，调用方需持有锁。
+// subscribe 表示需要在连接上订阅该频道，create 表示连接是新建的，需要调用方建立连接
+func (o *OkxDataFeed) attach(sub *subscription) (c *sharedConn, subscribe, create bool) {
+	key := sub.arg.Key()
+	for _, c := range o.conns {
+		c.mu.Lock()
+		if route, ok := c.routes[key]; ok {
+			// 同一个频道已被订阅，直接复用
+			route[sub.id] = sub
+			c.mu.Unlock()
+			sub.conn = c
+			return c, false, false
+		}
+		if c.size < o.opts.maxArgsPerConn {
+			c.routes[key] = map[string]*subscription{sub.id: sub}
+			c.size++
+			c.mu.Unlock()
+			sub.conn = c
+			return c, true, false
+		}
+		c.mu.Unlock()
+	}
+
+	o.connSeq++
+	c = &sharedConn{
+		stream: okxStream.NewOkxStream(
+			fmt.Sprintf("%s-PUBLIC-%d", types.OkxExchange, o.connSeq),
+			types.StreamTypeTrade,
+			o.opts.streamOpts...,
+		),
+		routes: map[string]map[string]*subscription{
+			key: {sub.id: sub},
+		},
+		size:  1,
+		ready: make(chan struct{}),
+	}
+	sub.conn = c
+	o.conns = append(o.conns, c)
+	return c, false, true
+}
+
+// connect 建立新的连接，初始订阅为创建连接时的频道，
+// 连接建立期间分配到该连接的其他频道在连接建立后由各自的订阅方发送subscribe
+func (o *OkxDataFeed) connect(ctx context.Context, c *sharedConn) error {
+	c.mu.RLock()
+	args := make([]okxStream.Arg, 0, len(c.routes))
+	for _, route := range c.routes {
+		for _, sub := range route {
+			args = append(args, sub.arg)
+			break
+		}
+	}
+	c.mu.RUnlock()
+
+	// 连接由多个订阅共享，生命周期不跟随单个订阅的ctx
+	c.err = c.stream.Connect(context.WithoutCancel(ctx), okxStream.OkxRequest{
+		URL:          o.opts.wsURL,
+		Args:         args,
+		Logger:       o.opts.logger,
+		Handler:      c.dispatch,
+		ErrorHandler: c.broadcastErr,
+		SequenceFunc: sequenceOf,
+		BackfillFunc: (&backfiller{client: o.client, httpURL: o.opts.httpURL}).backfill,
+	})
+	close(c.ready)
+	return c.err
+}
+
+// detach 撤销建立失败的订阅，removeConn 表示连接建立失败需要移除
+func (o *OkxDataFeed) detach(sub *subscription, removeConn bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.subs[sub.id] == sub {
+		delete(o.subs, sub.id)
+		o.release(sub)
+	}
+	if removeConn {
+		o.removeConn(sub.conn)
+	}
+}
+
+// release 从连接的路由中移除订阅，调用方需持有锁。
+// 返回频道是否已没有订阅、连接是否已没有任何频道
+func (o *OkxDataFeed) release(sub *subscription) (unsubscribe, empty bool) {
+	c := sub.conn
+	key := sub.arg.Key()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	route, ok := c.routes[key]
+	if !ok {
+		return false, c.size == 0
+	}
+	delete(route, sub.id)
+	if len(route) == 0 {
+		delete(c.routes, key)
+		c.size--
+		unsubscribe = true
+	}
+	return unsubscribe, c.size == 0
+}
+
+// OrderStream 订阅订单数据
+func (o *OkxDataFeed) OrderStream(ctx context.Context, id string, request OkxOrderRequest) error {
+	return nil
+}
+
+// Streams 返回当前所有订阅的id列表
+// 打包到同一连接的订阅共享同一个Stream
+func (o *OkxDataFeed) Streams() map[string]stream.Stream[okxStream.OkxRequest] {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.streams
+}
+
+// CloseStream 关闭单个订阅
+// 频道没有其他订阅时取消该频道的订阅，连接上没有任何频道时断开连接
+func (o *OkxDataFeed) CloseStream(id string) error {
+	o.mu.Lock()
+	sub, ok := o.subs[id]
+	if !ok {
+		o.mu.Unlock()
+		return nil
+	}
+	delete(o.subs, id)
+	delete(o.streams, id)
+	if sub.stop != nil {
+		sub.stop()
+	}
+	unsubscribe, empty := o.release(sub)
+	if empty {
+		o.removeConn(sub.conn)
+	}
+	o.mu.Unlock()
+
+	c := sub.conn
+	if empty || unsubscribe {
+		// 连接可能仍在建立中
+		<-c.ready
+		if c.err != nil {
+			return nil
+		}
+	}
+	if empty {
+		return c.stream.Disconnect()
+	}
+	if unsubscribe {
+		return c.stream.Unsubscribe(sub.arg)
+	}
+	return nil
+}
+
+// removeConn 移除连接，调用方需持有锁
+func (o *OkxDataFeed) removeConn(c *sharedConn) {
+	for i, v := range o.conns {
+		if v == c {
+			o.conns = append(o.conns[:i], o.conns[i+1:]...)
+			return
+		}
+	}
+}
+
+// Close 关闭所有订阅
+func (o *OkxDataFeed) Close() error {
+	o.mu.Lock()
+	for _, sub := range o.subs {
+		if sub.stop != nil {
+			sub.stop()
+		}
+	}
+	conns := o.conns
+	o.conns = nil
+	o.subs = make(map[string]*subscription)
+	o.streams = make(map[string]stream.Stream[okxStream.OkxRequest])
+	o.mu.Unlock()
+
+	var firstErr error
+	for _, c := range conns {
+		<-c.ready
+		if c.err != nil {
+			continue
+		}
+		if err := c.stream.Disconnect(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
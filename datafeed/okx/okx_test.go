@@ -0,0 +1,253 @@
+package okx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+
+	okxStream "github.com/go-gotop/gotop/stream/okx"
+	"github.com/go-gotop/gotop/types"
+)
+
+// publicServer 模拟OKX公共频道服务端，收到subscribe后为每个频道推送一笔成交
+type publicServer struct {
+	mu          sync.Mutex
+	connections int
+	// ops 按顺序记录收到的订阅类操作，例如"subscribe trades:::BTC-USDT"
+	ops     []string
+	tradeID int
+	server  *httptest.Server
+}
+
+func newPublicServer(t *testing.T) *publicServer {
+	s := &publicServer{}
+	upgrader := websocket.Upgrader{}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.connections++
+		s.mu.Unlock()
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if string(msg) == "ping" {
+				conn.WriteMessage(websocket.TextMessage, []byte("pong"))
+				continue
+			}
+			var req struct {
+				Op   string          `json:"op"`
+				Args []okxStream.Arg `json:"args"`
+			}
+			require.NoError(t, json.Unmarshal(msg, &req))
+			for _, arg := range req.Args {
+				s.mu.Lock()
+				s.ops = append(s.ops, req.Op+" "+arg.Key())
+				s.tradeID++
+				id := s.tradeID
+				s.mu.Unlock()
+
+				event, _ := json.Marshal(map[string]any{"event": req.Op, "arg": arg})
+				conn.WriteMessage(websocket.TextMessage, event)
+				if req.Op == "subscribe" {
+					conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(
+						`{"arg":{"channel":"trades","instId":%q},"data":[{"instId":%q,"tradeId":"%d","px":"100","sz":"1","side":"buy","ts":"1700000000000"}]}`,
+						arg.InstID, arg.InstID, id)))
+				}
+			}
+		}
+	}))
+	return s
+}
+
+func (s *publicServer) url() string {
+	return "ws" + strings.TrimPrefix(s.server.URL, "http")
+}
+
+func (s *publicServer) connectionCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections
+}
+
+func (s *publicServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.ops...)
+}
+
+func TestTradeStreamPacksConnections(t *testing.T) {
+	srv := newPublicServer(t)
+	defer srv.server.Close()
+
+	feed := NewOkxDataFeed(WithWSURL(srv.url()), WithMaxArgsPerConn(2))
+	defer feed.Close()
+
+	var mu sync.Mutex
+	received := make(map[string][]string)
+	subscribe := func(id, symbol string) {
+		err := feed.TradeStream(context.Background(), id, OkxTradeRequest{
+			Symbol: symbol,
+			Handler: func(data []byte) {
+				trades, err := ParseTrades(data)
+				require.NoError(t, err)
+				mu.Lock()
+				for _, trade := range trades {
+					received[id] = append(received[id], trade.Symbol)
+				}
+				mu.Unlock()
+			},
+		})
+		require.NoError(t, err)
+	}
+	subscribe("btc", "BTC-USDT")
+	subscribe("eth", "ETH-USDT")
+	subscribe("swap", "BTC-USDT-SWAP")
+
+	// 3个频道，每个连接最多2个频道，应该只建立2个连接
+	require.Equal(t, 2, srv.connectionCount())
+	require.Len(t, feed.Streams(), 3)
+	require.Same(t, feed.Streams()["btc"], feed.Streams()["eth"])
+	require.NotSame(t, feed.Streams()["btc"], feed.Streams()["swap"])
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 3
+	}, time.Second, 10*time.Millisecond)
+
+	// 同一频道的订阅共享连接上的频道，不会重复订阅
+	subscribe("btc-2", "BTC-USDT")
+	require.Same(t, feed.Streams()["btc"], feed.Streams()["btc-2"])
+	require.Len(t, srv.received(), 3)
+
+	mu.Lock()
+	// 每个订阅只会收到自己频道的消息
+	require.Equal(t, []string{"BTC-USDT"}, received["btc"])
+	require.Equal(t, []string{"ETH-USDT"}, received["eth"])
+	require.Equal(t, []string{"BTC-USDT-SWAP"}, received["swap"])
+	mu.Unlock()
+
+	// 频道还有其他订阅时不会取消订阅
+	require.NoError(t, feed.CloseStream("btc"))
+	require.NoError(t, feed.CloseStream("eth"))
+	require.Eventually(t, func() bool {
+		ops := srv.received()
+		return len(ops) > 0 && ops[len(ops)-1] == "unsubscribe trades:::ETH-USDT"
+	}, time.Second, 10*time.Millisecond)
+	require.NotContains(t, srv.received(), "unsubscribe trades:::BTC-USDT")
+	require.Len(t, feed.Streams(), 2)
+	require.Len(t, feed.conns, 2)
+
+	// 连接上没有频道时断开连接
+	require.NoError(t, feed.CloseStream("swap"))
+	require.Len(t, feed.conns, 1)
+}
+
+func TestTradeStreamContextCancel(t *testing.T) {
+	srv := newPublicServer(t)
+	defer srv.server.Close()
+
+	feed := NewOkxDataFeed(WithWSURL(srv.url()))
+	defer feed.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, feed.TradeStream(ctx, "a", OkxTradeRequest{Symbol: "BTC-USDT"}))
+	require.NoError(t, feed.TradeStream(context.Background(), "b", OkxTradeRequest{Symbol: "ETH-USDT"}))
+	require.Error(t, feed.TradeStream(context.Background(), "b", OkxTradeRequest{Symbol: "ETH-USDT"}))
+
+	// 取消单个订阅的ctx只关闭该订阅，共享连接继续服务其他订阅
+	cancel()
+	require.Eventually(t, func() bool {
+		return len(feed.Streams()) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, 1, srv.connectionCount())
+	require.Equal(t, []okxStream.Arg{{Channel: "trades", InstID: "ETH-USDT"}}, feed.conns[0].stream.Subscriptions())
+}
+
+func TestTradeStreamMarket(t *testing.T) {
+	srv := newPublicServer(t)
+	defer srv.server.Close()
+
+	feed := NewOkxDataFeed(WithWSURL(srv.url()))
+	defer feed.Close()
+
+	require.Error(t, feed.TradeStream(context.Background(), "a", OkxTradeRequest{
+		Symbol: "BTC-USDT",
+		Market: types.MarketTypePerpetualUSDMargined,
+	}))
+	require.Equal(t, 0, srv.connectionCount())
+
+	require.NoError(t, feed.TradeStream(context.Background(), "a", OkxTradeRequest{
+		Symbol: "BTC-USDT-SWAP",
+		Market: types.MarketTypePerpetualUSDMargined,
+	}))
+	require.NoError(t, feed.TradeStream(context.Background(), "b", OkxTradeRequest{
+		Symbol: "BTC-USD-SWAP",
+		Market: types.MarketTypePerpetualCoinMargined,
+	}))
+}
+
+func TestTradeStreamCloseInHandler(t *testing.T) {
+	srv := newPublicServer(t)
+	defer srv.server.Close()
+
+	feed := NewOkxDataFeed(WithWSURL(srv.url()))
+
+	closed := make(chan error, 1)
+	require.NoError(t, feed.TradeStream(context.Background(), "eth", OkxTradeRequest{Symbol: "ETH-USDT"}))
+	require.NoError(t, feed.TradeStream(context.Background(), "btc", OkxTradeRequest{
+		Symbol: "BTC-USDT",
+		Handler: func(data []byte) {
+			// 在Handler中关闭自己的订阅不会死锁
+			closed <- feed.CloseStream("btc")
+		},
+	}))
+
+	select {
+	case err := <-closed:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for handler to close stream")
+	}
+	require.Eventually(t, func() bool {
+		ops := srv.received()
+		return len(ops) > 0 && ops[len(ops)-1] == "unsubscribe trades:::BTC-USDT"
+	}, time.Second, 10*time.Millisecond)
+	require.Len(t, feed.Streams(), 1)
+
+	// Close断开连接时等待正在执行的Handler，Handler中关闭订阅不会死锁
+	entered := make(chan struct{})
+	require.NoError(t, feed.TradeStream(context.Background(), "sol", OkxTradeRequest{
+		Symbol: "SOL-USDT",
+		Handler: func(data []byte) {
+			close(entered)
+			time.Sleep(100 * time.Millisecond)
+			closed <- feed.CloseStream("sol")
+		},
+	}))
+	<-entered
+	done := make(chan error, 1)
+	go func() { done <- feed.Close() }()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for feed to close")
+	}
+	require.NoError(t, <-closed)
+}
@@ -0,0 +1,85 @@
+package okx
+
+import (
+	"log/slog"
+	"time"
+
+	okxStream "github.com/go-gotop/gotop/stream/okx"
+)
+
+const (
+	// defaultMaxArgsPerConn 单个连接默认承载的订阅频道数量
+	defaultMaxArgsPerConn = 200
+	// defaultSeamlessOverlap 定时轮换连接时新旧连接默认并行的时长
+	defaultSeamlessOverlap = 10 * time.Second
+)
+
+type options struct {
+	// logger 日志记录器
+	logger *slog.Logger
+	// maxArgsPerConn 单个连接承载的最大频道数量
+	maxArgsPerConn int
+	// streamOpts 创建底层OkxStream时使用的配置
+	streamOpts []okxStream.Option
+	// wsURL 公共频道地址
+	wsURL string
+	// httpURL 回补数据使用的REST地址
+	httpURL string
+}
+
+func applyOptions(opts ...Option) *options {
+	o := &options{
+		logger:         slog.Default(),
+		maxArgsPerConn: defaultMaxArgsPerConn,
+		wsURL:          publicWSURL,
+		httpURL:        httpURL,
+		// 默认开启无缝重连，用户配置的streamOpts在其后应用，可以覆盖
+		streamOpts: []okxStream.Option{
+			okxStream.WithSeamlessReconnect(defaultSeamlessOverlap),
+		},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Option 是DataFeed的配置选项
+type Option func(o *options)
+
+// WithLogger 设置日志记录器
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithMaxArgsPerConn 设置单个连接承载的最大频道数量
+func WithMaxArgsPerConn(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxArgsPerConn = n
+		}
+	}
+}
+
+// WithStreamOptions 设置创建底层OkxStream时使用的配置
+func WithStreamOptions(opts ...okxStream.Option) Option {
+	return func(o *options) {
+		o.streamOpts = append(o.streamOpts, opts...)
+	}
+}
+
+// WithWSURL 设置公共频道地址，例如模拟盘地址
+func WithWSURL(url string) Option {
+	return func(o *options) {
+		o.wsURL = url
+	}
+}
+
+// WithHTTPURL 设置回补数据使用的REST地址
+func WithHTTPURL(url string) Option {
+	return func(o *options) {
+		o.httpURL = url
+	}
+}
@@ -0,0 +1,81 @@
+package okx
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/types"
+)
+
+// ParseTrades 解析成交频道的推送消息，一条消息可能包含多笔成交
+func ParseTrades(data []byte) ([]types.TradeEvent, error) {
+	var m tradesMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	if m.Arg.Channel != "trades" {
+		return nil, fmt.Errorf("unexpected channel: %s", m.Arg.Channel)
+	}
+
+	trades := make([]types.TradeEvent, 0, len(m.Data))
+	for _, t := range m.Data {
+		tradeID, err := strconv.ParseUint(t.TradeID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse trade id error: %w", err)
+		}
+		ts, err := strconv.ParseInt(t.Ts, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse ts error: %w", err)
+		}
+		price, err := decimal.NewFromString(t.Px)
+		if err != nil {
+			return nil, fmt.Errorf("parse price error: %w", err)
+		}
+		size, err := decimal.NewFromString(t.Sz)
+		if err != nil {
+			return nil, fmt.Errorf("parse size error: %w", err)
+		}
+
+		side := types.SideTypeBuy
+		if t.Side == "sell" {
+			side = types.SideTypeSell
+		}
+
+		trades = append(trades, types.TradeEvent{
+			Timestamp: ts,
+			Symbol:    t.InstID,
+			Exchange:  types.OkxExchange,
+			TradeID:   tradeID,
+			Size:      size,
+			Price:     price,
+			Side:      side,
+			Type:      marketOf(t.InstID),
+		})
+	}
+	return trades, nil
+}
+
+// marketOf 根据产品ID推断市场类型，例如"BTC-USDT-SWAP"为U本位永续，"BTC-USD-SWAP"为币本位永续
+func marketOf(instID string) types.MarketType {
+	parts := strings.Split(instID, "-")
+	switch {
+	case len(parts) == 2:
+		return types.MarketTypeSpot
+	case len(parts) == 3 && parts[2] == "SWAP":
+		if parts[1] == "USD" {
+			return types.MarketTypePerpetualCoinMargined
+		}
+		return types.MarketTypePerpetualUSDMargined
+	case len(parts) == 3:
+		if parts[1] == "USD" {
+			return types.MarketTypeFuturesCoinMargined
+		}
+		return types.MarketTypeFuturesUSDMargined
+	default:
+		return types.MarketTypeUnknown
+	}
+}
@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/go-gotop/gotop/exchange"
 	"github.com/go-gotop/gotop/requests"
 	bnexreq "github.com/go-gotop/gotop/requests/binance"
+	"github.com/go-gotop/gotop/types"
 	"github.com/shopspring/decimal"
 )
 
@@ -159,3 +161,115 @@ func (b *BnAccountManager) GetBalance(ctx context.Context, authInfo exchange.Aut
 
 	return nil, fmt.Errorf("asset %s not found", asset)
 }
+
+// GetFuturesAccount 获取U本位合约账户快照，包括余额、持仓、账户权益和已用保证金。
+// 返回的账户ID为空，QuoteAsset为USDT
+func (b *BnAccountManager) GetFuturesAccount(ctx context.Context, authInfo exchange.AuthInfo) (*types.Account, error) {
+	apiUrl := BNEX_API_FUTURES_USD_URL + "/fapi/v2/account"
+
+	resp, err := b.client.DoRequest(&requests.Request{
+		Method: http.MethodGet,
+		URL:    apiUrl,
+		Auth: &requests.AuthInfo{
+			APIKey:    authInfo.APIKey,
+			SecretKey: authInfo.SecretKey,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get futures account failed, status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var accountResp bnFuturesAccountResponse
+	err = json.Unmarshal(body, &accountResp)
+	if err != nil {
+		return nil, err
+	}
+
+	account := &types.Account{
+		Exchange:      exchange.ExchangeBinance,
+		Type:          types.AccountTypeClassic,
+		QuoteAsset:    "USDT",
+		Equity:        parseDecimal(accountResp.TotalMarginBalance),
+		HasEquity:     true,
+		MarginUsed:    parseDecimal(accountResp.TotalInitialMargin),
+		HasMarginUsed: true,
+		UpdateTime:    accountResp.UpdateTime,
+	}
+	if account.Equity.IsPositive() {
+		account.MarginRatio = account.MarginUsed.Div(account.Equity)
+	}
+
+	for _, asset := range accountResp.Assets {
+		wallet := parseDecimal(asset.WalletBalance)
+		if wallet.IsZero() {
+			continue
+		}
+		// 可用余额受未实现盈亏影响，可能大于余额
+		free := decimal.Min(parseDecimal(asset.AvailableBalance), wallet)
+		account.Balances = append(account.Balances, types.Asset{
+			AssetName:  asset.Asset,
+			Exchange:   exchange.ExchangeBinance,
+			MarketType: types.MarketTypePerpetualUSDMargined,
+			Free:       free,
+			Locked:     wallet.Sub(free),
+		})
+	}
+
+	for _, position := range accountResp.Positions {
+		size := parseDecimal(position.PositionAmt)
+		if size.IsZero() {
+			continue
+		}
+		side := types.PositionSideUnknown
+		switch position.PositionSide {
+		case "LONG":
+			side = types.PositionSideLong
+		case "SHORT":
+			side = types.PositionSideShort
+		}
+		markPrice := decimal.Zero
+		if notional := parseDecimal(position.Notional); !notional.IsZero() {
+			markPrice = notional.Div(size).Abs()
+		}
+		account.Positions = append(account.Positions, types.AccountPosition{
+			Symbol:        position.Symbol,
+			MarketType:    types.MarketTypePerpetualUSDMargined,
+			PositionSide:  side,
+			Size:          size,
+			EntryPrice:    parseDecimal(position.EntryPrice),
+			MarkPrice:     markPrice,
+			UnrealizedPnL: parseDecimal(position.UnrealizedProfit),
+			Margin:        parseDecimal(position.PositionInitialMargin),
+			SettleAsset:   settleAssetOf(position.Symbol),
+		})
+	}
+
+	return account, nil
+}
+
+// settleAssetOf 返回U本位合约的结算资产，USDC合约（如BTCUSDC）为USDC，其他为USDT
+func settleAssetOf(symbol string) string {
+	if strings.HasSuffix(symbol, "USDC") {
+		return "USDC"
+	}
+	return "USDT"
+}
+
+// parseDecimal 解析数值字符串，空字符串或格式错误时返回0
+func parseDecimal(s string) decimal.Decimal {
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return decimal.Zero
+	}
+	return d
+}
@@ -0,0 +1,67 @@
+package bnexc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/exchange"
+	"github.com/go-gotop/gotop/types"
+)
+
+func TestBnAccountManager_GetFuturesAccount(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		fmt.Fprint(w, `{
+			"totalInitialMargin":"600","totalMaintMargin":"60","totalWalletBalance":"4000",
+			"totalUnrealizedProfit":"-400","totalMarginBalance":"3600","availableBalance":"3000",
+			"assets":[
+				{"asset":"USDT","walletBalance":"4000","unrealizedProfit":"-400","marginBalance":"3600","availableBalance":"3000"},
+				{"asset":"BNB","walletBalance":"0","unrealizedProfit":"0","marginBalance":"0","availableBalance":"0"}
+			],
+			"positions":[
+				{"symbol":"BTCUSDT","positionInitialMargin":"600","unrealizedProfit":"-500","entryPrice":"65000","positionSide":"BOTH","positionAmt":"0.1","notional":"6000"},
+				{"symbol":"ETHUSDT","positionInitialMargin":"0","unrealizedProfit":"100","entryPrice":"3050","positionSide":"SHORT","positionAmt":"-2","notional":"-6000"},
+				{"symbol":"BNBUSDT","positionInitialMargin":"0","unrealizedProfit":"0","entryPrice":"0","positionSide":"BOTH","positionAmt":"0","notional":"0"}
+			],
+			"updateTime":1700000000000}`)
+	}))
+	defer server.Close()
+	target, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	m := NewBnAccountManager()
+	m.client.SetHTTPClient(&http.Client{Transport: rewriteTransport{target: target}})
+
+	account, err := m.GetFuturesAccount(context.Background(), exchange.AuthInfo{APIKey: "key", SecretKey: "secret"})
+	require.NoError(t, err)
+	assert.Equal(t, "/fapi/v2/account", path)
+	assert.Equal(t, exchange.ExchangeBinance, account.Exchange)
+	assert.Equal(t, "USDT", account.QuoteAsset)
+	assert.True(t, account.HasEquity)
+	assert.Equal(t, "3600", account.Equity.String())
+	assert.True(t, account.HasMarginUsed)
+	assert.Equal(t, "600", account.MarginUsed.String())
+	assert.Equal(t, int64(1700000000000), account.UpdateTime)
+
+	require.Len(t, account.Balances, 1)
+	assert.Equal(t, "3000", account.Balances[0].Free.String())
+	assert.Equal(t, "1000", account.Balances[0].Locked.String())
+
+	require.Len(t, account.Positions, 2)
+	assert.Equal(t, "BTCUSDT", account.Positions[0].Symbol)
+	assert.Equal(t, types.PositionSideUnknown, account.Positions[0].PositionSide)
+	assert.Equal(t, "60000", account.Positions[0].MarkPrice.String())
+	assert.Equal(t, "600", account.Positions[0].Margin.String())
+	assert.Equal(t, "USDT", account.Positions[0].SettleAsset)
+	assert.Equal(t, types.PositionSideShort, account.Positions[1].PositionSide)
+	assert.Equal(t, "-2", account.Positions[1].Size.String())
+	assert.Equal(t, "3000", account.Positions[1].MarkPrice.String())
+	assert.Equal(t, "100", account.Positions[1].UnrealizedPnL.String())
+}
@@ -19,6 +19,18 @@ type bnOrderACKResponse struct {
 	Symbol string `json:"symbol"`
 }
 
+// bnCancelOrderResponse 币安撤单响应
+type bnCancelOrderResponse struct {
+	// 交易对
+	Symbol string `json:"symbol"`
+	// 系统订单号
+	OrderID int64 `json:"orderId"`
+	// 用户自定义的订单号，现货为本次撤单请求的ID
+	ClientOrderID string `json:"clientOrderId"`
+	// 被撤销订单的用户自定义订单号，仅现货和杠杆返回
+	OrigClientOrderID string `json:"origClientOrderId"`
+}
+
 // bnCapitalRecoveryResponse 币安资金归集响应
 type bnCapitalRecoveryResponse struct {
 	// 资产名
@@ -89,3 +101,51 @@ type bnCapitalRecoveryResponse struct {
 		Denomination int `json:"denomination"`
 	} `json:"networkList"`
 }
+
+// bnFuturesAccountResponse U本位合约账户信息响应，单资产模式下金额以USDT计价，联合保证金模式下以USD计价
+type bnFuturesAccountResponse struct {
+	// 当前所需起始保证金总额，包括挂单
+	TotalInitialMargin string `json:"totalInitialMargin"`
+	// 维持保证金总额
+	TotalMaintMargin string `json:"totalMaintMargin"`
+	// 账户总余额
+	TotalWalletBalance string `json:"totalWalletBalance"`
+	// 持仓未实现盈亏总额
+	TotalUnrealizedProfit string `json:"totalUnrealizedProfit"`
+	// 保证金总余额，即账户权益
+	TotalMarginBalance string `json:"totalMarginBalance"`
+	// 可用余额
+	AvailableBalance string `json:"availableBalance"`
+	// 各资产
+	Assets []struct {
+		// 资产
+		Asset string `json:"asset"`
+		// 余额
+		WalletBalance string `json:"walletBalance"`
+		// 未实现盈亏
+		UnrealizedProfit string `json:"unrealizedProfit"`
+		// 保证金余额
+		MarginBalance string `json:"marginBalance"`
+		// 可用余额
+		AvailableBalance string `json:"availableBalance"`
+	} `json:"assets"`
+	// 各交易对持仓，包括没有持仓的交易对
+	Positions []struct {
+		// 交易对
+		Symbol string `json:"symbol"`
+		// 持仓所需起始保证金
+		PositionInitialMargin string `json:"positionInitialMargin"`
+		// 持仓未实现盈亏
+		UnrealizedProfit string `json:"unrealizedProfit"`
+		// 持仓成本价
+		EntryPrice string `json:"entryPrice"`
+		// 持仓方向，BOTH、LONG、SHORT
+		PositionSide string `json:"positionSide"`
+		// 持仓数量，单向持仓模式下空头为负数
+		PositionAmt string `json:"positionAmt"`
+		// 持仓名义价值，空头为负数
+		Notional string `json:"notional"`
+	} `json:"positions"`
+	// 更新时间
+	UpdateTime int64 `json:"updateTime"`
+}
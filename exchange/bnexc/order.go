@@ -35,13 +35,13 @@ func (b *BnOrderManager) CreateOrder(ctx context.Context, req *exchange.CreateOr
 	switch req.SizeUnit {
 	case types.SizeUnitContract:
 		if req.MarketType != types.MarketTypeFuturesCoinMargined && req.MarketType != types.MarketTypePerpetualCoinMargined {
-			return nil, errors.New("create order error: unsupported contract size unit for coin margined futures")
+			return nil, fmt.Errorf("create order error: %w: unsupported contract size unit for coin margined futures", exchange.ErrOrderRejected)
 		}
 	case types.SizeUnitQuote:
-		return nil, errors.New("create order error: unsupported quote size unit")
+		return nil, fmt.Errorf("create order error: %w: unsupported quote size unit", exchange.ErrOrderRejected)
 	case types.SizeUnitCoin:
 		if req.MarketType == types.MarketTypeFuturesCoinMargined || req.MarketType == types.MarketTypePerpetualCoinMargined {
-			return nil, errors.New("create order error: unsupported coin size unit for coin margined futures")
+			return nil, fmt.Errorf("create order error: %w: unsupported coin size unit for coin margined futures", exchange.ErrOrderRejected)
 		}
 	}
 
@@ -57,7 +57,7 @@ func (b *BnOrderManager) CreateOrder(ctx context.Context, req *exchange.CreateOr
 	case types.MarketTypeMargin:
 		apiUrl = BNEX_API_SPOT_URL + "/sapi/v1/margin/order"
 	default:
-		return nil, errors.New("invalid market type")
+		return nil, fmt.Errorf("%w: invalid market type", exchange.ErrOrderRejected)
 	}
 
 	params := map[string]any{
@@ -106,7 +106,11 @@ func (b *BnOrderManager) CreateOrder(ctx context.Context, req *exchange.CreateOr
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("create order failed, status code: %d, body: %s", resp.StatusCode, string(body))
+		err := fmt.Errorf("create order failed, status code: %d, body: %s", resp.StatusCode, string(body))
+		if rejected(resp.StatusCode, body) {
+			err = fmt.Errorf("%w: %w", exchange.ErrOrderRejected, err)
+		}
+		return nil, err
 	}
 
 	var orderACK bnOrderACKResponse
@@ -122,8 +126,105 @@ func (b *BnOrderManager) CreateOrder(ctx context.Context, req *exchange.CreateOr
 	}, nil
 }
 
+// rejected 判断下单失败的响应是否为交易所明确拒绝（参数错误、余额不足、价格限制等）。
+// 5XX、408以及-1006/-1007等超时类错误码时订单可能已被接受，状态未知
+func rejected(status int, body []byte) bool {
+	if status < http.StatusBadRequest || status >= http.StatusInternalServerError || status == http.StatusRequestTimeout {
+		return false
+	}
+	var e struct {
+		Code int `json:"code"`
+	}
+	if err := json.Unmarshal(body, &e); err != nil {
+		return false
+	}
+	switch {
+	case e.Code <= -1100 && e.Code > -1200:
+		// 请求参数错误
+		return true
+	case e.Code <= -4000 && e.Code > -5000:
+		// 合约下单参数、价格限制等错误
+		return true
+	}
+	switch e.Code {
+	case -1013, // 过滤器校验失败，例如价格、数量精度
+		-1021, // 时间戳超出recvWindow
+		-1022, // 签名错误
+		-2010, // 下单被拒绝，例如余额不足
+		-2018, // 余额不足
+		-2019: // 保证金不足
+		return true
+	}
+	return false
+}
+
+// CancelOrder 撤销订单，按OrderID撤单，没有OrderID时按ClientOrderID撤单
 func (b *BnOrderManager) CancelOrder(ctx context.Context, req *exchange.CancelOrderRequest) (*exchange.CancelOrderResponse, error) {
-	return nil, errors.New("not implemented")
+	apiUrl := ""
+
+	switch req.MarketType {
+	case types.MarketTypeFuturesUSDMargined, types.MarketTypePerpetualUSDMargined:
+		apiUrl = BNEX_API_FUTURES_USD_URL + "/fapi/v1/order"
+	case types.MarketTypeFuturesCoinMargined, types.MarketTypePerpetualCoinMargined:
+		apiUrl = BNEX_API_FUTURES_COIN_URL + "/dapi/v1/order"
+	case types.MarketTypeSpot:
+		apiUrl = BNEX_API_SPOT_URL + "/api/v3/order"
+	case types.MarketTypeMargin:
+		apiUrl = BNEX_API_SPOT_URL + "/sapi/v1/margin/order"
+	default:
+		return nil, errors.New("invalid market type")
+	}
+
+	params := map[string]any{
+		"symbol": req.Symbol.OriginalSymbol,
+	}
+	switch {
+	case req.OrderID != "":
+		params["orderId"] = req.OrderID
+	case req.ClientOrderID != "":
+		params["origClientOrderId"] = req.ClientOrderID
+	default:
+		return nil, errors.New("cancel order error: order id or client order id is required")
+	}
+
+	resp, err := b.client.DoRequest(&requests.Request{
+		Method: http.MethodDelete,
+		URL:    apiUrl,
+		Params: params,
+		Auth: &requests.AuthInfo{
+			APIKey:    req.APIKey,
+			SecretKey: req.SecretKey,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cancel order failed, status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var cancelResp bnCancelOrderResponse
+	err = json.Unmarshal(body, &cancelResp)
+	if err != nil {
+		return nil, err
+	}
+
+	clientOrderID := cancelResp.OrigClientOrderID
+	if clientOrderID == "" {
+		clientOrderID = cancelResp.ClientOrderID
+	}
+	return &exchange.CancelOrderResponse{
+		Symbol:        cancelResp.Symbol,
+		OrderID:       fmt.Sprintf("%d", cancelResp.OrderID),
+		ClientOrderID: clientOrderID,
+	}, nil
 }
 
 func (b *BnOrderManager) GetOrder(ctx context.Context, req *exchange.GetOrderRequest) (*exchange.GetOrderResponse, error) {
@@ -0,0 +1,123 @@
+package bnexc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/exchange"
+	"github.com/go-gotop/gotop/types"
+)
+
+// rewriteTransport 将请求转发到本地测试服务
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t rewriteTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r.URL.Scheme = t.target.Scheme
+	r.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func newTestOrderManager(t *testing.T, handler http.HandlerFunc) *BnOrderManager {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	target, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	m := NewBnOrderManager()
+	m.client.SetHTTPClient(&http.Client{Transport: rewriteTransport{target: target}})
+	return m
+}
+
+func TestBnOrderManager_CancelOrder(t *testing.T) {
+	var got *http.Request
+	m := newTestOrderManager(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		if r.URL.Query().Get("origClientOrderId") == "missing" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":-2011,"msg":"Unknown order sent."}`)
+			return
+		}
+		fmt.Fprint(w, `{"symbol":"BTCUSDT","orderId":28,"origClientOrderId":"gt1","clientOrderId":"cancel1","status":"CANCELED"}`)
+	})
+
+	resp, err := m.CancelOrder(context.Background(), &exchange.CancelOrderRequest{
+		APIKey:        "key",
+		SecretKey:     "secret",
+		Symbol:        types.Symbol{OriginalSymbol: "BTCUSDT"},
+		MarketType:    types.MarketTypeSpot,
+		ClientOrderID: "gt1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodDelete, got.Method)
+	assert.Equal(t, "/api/v3/order", got.URL.Path)
+	assert.Equal(t, "BTCUSDT", got.URL.Query().Get("symbol"))
+	assert.Equal(t, "gt1", got.URL.Query().Get("origClientOrderId"))
+	assert.NotEmpty(t, got.URL.Query().Get("signature"))
+	assert.Equal(t, "key", got.Header.Get("X-MBX-APIKEY"))
+	assert.Equal(t, &exchange.CancelOrderResponse{Symbol: "BTCUSDT", OrderID: "28", ClientOrderID: "gt1"}, resp)
+
+	// 有OrderID时按OrderID撤单
+	_, err = m.CancelOrder(context.Background(), &exchange.CancelOrderRequest{
+		Symbol:     types.Symbol{OriginalSymbol: "BTCUSDT"},
+		MarketType: types.MarketTypePerpetualUSDMargined,
+		OrderID:    "28",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "/fapi/v1/order", got.URL.Path)
+	assert.Equal(t, "28", got.URL.Query().Get("orderId"))
+
+	_, err = m.CancelOrder(context.Background(), &exchange.CancelOrderRequest{
+		Symbol:        types.Symbol{OriginalSymbol: "BTCUSDT"},
+		MarketType:    types.MarketTypeSpot,
+		ClientOrderID: "missing",
+	})
+	assert.ErrorContains(t, err, "Unknown order sent.")
+
+	_, err = m.CancelOrder(context.Background(), &exchange.CancelOrderRequest{MarketType: types.MarketTypeSpot})
+	assert.Error(t, err)
+}
+
+func TestBnOrderManager_CreateOrderRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		rejected bool
+	}{
+		{"insufficient balance", http.StatusBadRequest, `{"code":-2010,"msg":"Account has insufficient balance for requested action."}`, true},
+		{"invalid parameter", http.StatusBadRequest, `{"code":-1111,"msg":"Precision is over the maximum defined for this asset."}`, true},
+		{"price limit", http.StatusBadRequest, `{"code":-4016,"msg":"Limit price can't be higher than 1000."}`, true},
+		{"request timeout", http.StatusRequestTimeout, `{"code":-1007,"msg":"Timeout waiting for response from backend server."}`, false},
+		{"backend timeout", http.StatusBadRequest, `{"code":-1007,"msg":"Timeout waiting for response from backend server."}`, false},
+		{"unexpected response", http.StatusBadRequest, `{"code":-1006,"msg":"An unexpected response was received from the message bus."}`, false},
+		{"rate limited", http.StatusTooManyRequests, `{"code":-1003,"msg":"Too many requests."}`, false},
+		{"server error", http.StatusBadGateway, `bad gateway`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestOrderManager(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+			_, err := m.CreateOrder(context.Background(), &exchange.CreateOrderRequest{
+				Symbol:        types.Symbol{OriginalSymbol: "BTCUSDT"},
+				MarketType:    types.MarketTypeSpot,
+				Side:          types.SideTypeBuy,
+				OrderType:     types.OrderTypeMarket,
+				SizeUnit:      types.SizeUnitCoin,
+				ClientOrderID: "gt1",
+			})
+			require.Error(t, err)
+			assert.Equal(t, tt.rejected, errors.Is(err, exchange.ErrOrderRejected))
+		})
+	}
+}
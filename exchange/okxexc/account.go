@@ -7,10 +7,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/go-gotop/gotop/exchange"
 	"github.com/go-gotop/gotop/requests"
 	okxreq "github.com/go-gotop/gotop/requests/okx"
+	"github.com/go-gotop/gotop/types"
 	"github.com/shopspring/decimal"
 )
 
@@ -186,3 +189,161 @@ func (m *OkxAccountManager) GetBalance(ctx context.Context, authInfo exchange.Au
 
 	return nil, fmt.Errorf("asset %s not found", asset)
 }
+
+// GetAccount 获取统一账户快照，包括余额、持仓、账户权益和已用保证金。
+// 返回的账户ID为空，Equity、MarginUsed以USD计价，现货和合约模式下交易所不提供账户层面的已用保证金，由持仓估算
+func (m *OkxAccountManager) GetAccount(ctx context.Context, authInfo exchange.AuthInfo) (*types.Account, error) {
+	var balanceResp okxBalanceResponse
+	if err := m.get(OKX_API_BASE_URL+"/api/v5/account/balance", authInfo, &balanceResp); err != nil {
+		return nil, fmt.Errorf("get balances failed: %w", err)
+	}
+	if balanceResp.Code != "0" {
+		return nil, fmt.Errorf("operation failed, code: %s, message: %s", balanceResp.Code, balanceResp.Msg)
+	}
+	if len(balanceResp.Data) == 0 {
+		return nil, errors.New("empty account balance")
+	}
+
+	var positionsResp okxPositionsResponse
+	if err := m.get(OKX_API_BASE_URL+"/api/v5/account/positions", authInfo, &positionsResp); err != nil {
+		return nil, fmt.Errorf("get positions failed: %w", err)
+	}
+	if positionsResp.Code != "0" {
+		return nil, fmt.Errorf("operation failed, code: %s, message: %s", positionsResp.Code, positionsResp.Msg)
+	}
+
+	data := balanceResp.Data[0]
+	account := &types.Account{
+		Exchange:   exchange.ExchangeOKX,
+		Type:       types.AccountTypeUnified,
+		QuoteAsset: "USD",
+		Equity:     parseDecimal(data.TotalEq),
+		HasEquity:  true,
+	}
+	account.UpdateTime, _ = strconv.ParseInt(data.Utime, 10, 64)
+	if data.Imr != "" {
+		account.MarginUsed = parseDecimal(data.Imr)
+		account.HasMarginUsed = true
+		if account.Equity.IsPositive() {
+			account.MarginRatio = account.MarginUsed.Div(account.Equity)
+		}
+	}
+
+	for _, detail := range data.Details {
+		availBal := parseDecimal(detail.AvailBal)
+		frozenBal := parseDecimal(detail.FrozenBal)
+		if availBal.IsZero() && frozenBal.IsZero() {
+			continue
+		}
+		account.Balances = append(account.Balances, types.Asset{
+			AssetName: detail.Ccy,
+			Exchange:  exchange.ExchangeOKX,
+			Free:      availBal,
+			Locked:    frozenBal,
+		})
+	}
+
+	for _, position := range positionsResp.Data {
+		size := parseDecimal(position.Pos)
+		if size.IsZero() {
+			continue
+		}
+		side := types.PositionSideUnknown
+		switch position.PosSide {
+		case "long":
+			side = types.PositionSideLong
+		case "short":
+			side = types.PositionSideShort
+			size = size.Abs().Neg()
+		}
+		margin := parseDecimal(position.Imr)
+		if position.MgnMode == "isolated" {
+			margin = parseDecimal(position.Margin)
+		}
+		account.Positions = append(account.Positions, types.AccountPosition{
+			Symbol:        position.InstID,
+			MarketType:    marketTypeOf(position.InstType, position.InstID),
+			PositionSide:  side,
+			Size:          size,
+			EntryPrice:    parseDecimal(position.AvgPx),
+			MarkPrice:     parseDecimal(position.MarkPx),
+			UnrealizedPnL: parseDecimal(position.Upl),
+			Margin:        margin,
+			SettleAsset:   settleAssetOf(position.InstID, position.Ccy),
+		})
+	}
+
+	return account, nil
+}
+
+// get 发送带签名的GET请求并解析响应
+func (m *OkxAccountManager) get(apiUrl string, authInfo exchange.AuthInfo, v any) error {
+	resp, err := m.client.DoRequest(&requests.Request{
+		Method: http.MethodGet,
+		URL:    apiUrl,
+		Auth: &requests.AuthInfo{
+			APIKey:     authInfo.APIKey,
+			SecretKey:  authInfo.SecretKey,
+			Passphrase: authInfo.Passphrase,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+	return json.Unmarshal(body, v)
+}
+
+// marketTypeOf 根据产品类型和产品ID推断市场类型，例如"BTC-USD-SWAP"为币本位永续
+func marketTypeOf(instType, instID string) types.MarketType {
+	coinMargined := strings.Contains(instID, "-USD-")
+	switch instType {
+	case "SWAP":
+		if coinMargined {
+			return types.MarketTypePerpetualCoinMargined
+		}
+		return types.MarketTypePerpetualUSDMargined
+	case "FUTURES":
+		if coinMargined {
+			return types.MarketTypeFuturesCoinMargined
+		}
+		return types.MarketTypeFuturesUSDMargined
+	case "MARGIN":
+		return types.MarketTypeMargin
+	}
+	return types.MarketTypeUnknown
+}
+
+// settleAssetOf 返回持仓的结算资产，优先使用保证金币种ccy，
+// 否则由产品ID推断：币本位合约（如BTC-USD-SWAP）为BTC，其他（如BTC-USDT-SWAP）为USDT
+func settleAssetOf(instID, ccy string) string {
+	if ccy != "" {
+		return ccy
+	}
+	parts := strings.Split(instID, "-")
+	if len(parts) < 2 {
+		return ""
+	}
+	if parts[1] == "USD" {
+		return parts[0]
+	}
+	return parts[1]
+}
+
+// parseDecimal 解析数值字符串，空字符串或格式错误时返回0
+func parseDecimal(s string) decimal.Decimal {
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return decimal.Zero
+	}
+	return d
+}
@@ -0,0 +1,78 @@
+package okxexc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/exchange"
+	"github.com/go-gotop/gotop/types"
+)
+
+func TestOkxAccountManager_GetAccount(t *testing.T) {
+	imr := `"5000"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v5/account/balance":
+			fmt.Fprintf(w, `{"code":"0","msg":"","data":[{"totalEq":"20000","imr":%s,"uTime":"1700000000000","details":[
+				{"ccy":"USDT","availBal":"15000","frozenBal":"1000"},
+				{"ccy":"BTC","availBal":"0","frozenBal":"0"}
+			]}]}`, imr)
+		case "/api/v5/account/positions":
+			fmt.Fprint(w, `{"code":"0","msg":"","data":[
+				{"instType":"SWAP","instId":"BTC-USDT-SWAP","mgnMode":"cross","posSide":"net","pos":"-3","avgPx":"60000","markPx":"61000","upl":"-30","imr":"1830","margin":""},
+				{"instType":"SWAP","instId":"BTC-USD-SWAP","mgnMode":"isolated","posSide":"short","pos":"5","avgPx":"60000","markPx":"61000","upl":"-0.0001","imr":"","margin":"0.01","ccy":"BTC"},
+				{"instType":"FUTURES","instId":"ETH-USDT-250328","mgnMode":"cross","posSide":"long","pos":"0","avgPx":"","markPx":"3000","upl":"0","imr":"0","margin":""}
+			]}`)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	target, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	m := NewOkxAccountManager()
+	m.client.SetHTTPClient(&http.Client{Transport: rewriteTransport{target: target}})
+
+	account, err := m.GetAccount(context.Background(), exchange.AuthInfo{APIKey: "key", SecretKey: "secret", Passphrase: "pass"})
+	require.NoError(t, err)
+	assert.Equal(t, exchange.ExchangeOKX, account.Exchange)
+	assert.Equal(t, types.AccountTypeUnified, account.Type)
+	assert.Equal(t, "USD", account.QuoteAsset)
+	assert.True(t, account.HasEquity)
+	assert.Equal(t, "20000", account.Equity.String())
+	assert.True(t, account.HasMarginUsed)
+	assert.Equal(t, "5000", account.MarginUsed.String())
+	assert.Equal(t, "0.25", account.MarginRatio.String())
+	assert.Equal(t, int64(1700000000000), account.UpdateTime)
+
+	require.Len(t, account.Balances, 1)
+	assert.Equal(t, "USDT", account.Balances[0].AssetName)
+	assert.Equal(t, "16000", account.Balances[0].Total().String())
+
+	require.Len(t, account.Positions, 2)
+	assert.Equal(t, types.MarketTypePerpetualUSDMargined, account.Positions[0].MarketType)
+	assert.Equal(t, types.PositionSideUnknown, account.Positions[0].PositionSide)
+	assert.Equal(t, "-3", account.Positions[0].Size.String())
+	assert.Equal(t, "1830", account.Positions[0].Margin.String())
+	assert.Equal(t, "USDT", account.Positions[0].SettleAsset)
+	assert.Equal(t, types.MarketTypePerpetualCoinMargined, account.Positions[1].MarketType)
+	assert.Equal(t, types.PositionSideShort, account.Positions[1].PositionSide)
+	assert.Equal(t, "-5", account.Positions[1].Size.String())
+	assert.Equal(t, "0.01", account.Positions[1].Margin.String())
+	// 币本位合约以币结算
+	assert.Equal(t, "BTC", account.Positions[1].SettleAsset)
+
+	// 现货和合约模式下交易所不提供账户层面的已用保证金
+	imr = `""`
+	account, err = m.GetAccount(context.Background(), exchange.AuthInfo{})
+	require.NoError(t, err)
+	assert.False(t, account.HasMarginUsed)
+	assert.True(t, account.MarginUsed.IsZero())
+}
@@ -175,3 +175,35 @@ type okxBalanceResponse struct {
 	} `json:"data"`
 	Msg string `json:"msg"`
 }
+
+// okxPositionsResponse OKX持仓信息响应
+type okxPositionsResponse struct {
+	Code string `json:"code"`
+	Data []struct {
+		// 产品类型，MARGIN、SWAP、FUTURES、OPTION
+		InstType string `json:"instType"`
+		// 产品ID
+		InstID string `json:"instId"`
+		// 保证金模式，cross：全仓，isolated：逐仓
+		MgnMode string `json:"mgnMode"`
+		// 持仓方向，long、short、net
+		PosSide string `json:"posSide"`
+		// 持仓数量，单位为张，net模式下空头为负数
+		Pos string `json:"pos"`
+		// 开仓均价
+		AvgPx string `json:"avgPx"`
+		// 标记价格
+		MarkPx string `json:"markPx"`
+		// 未实现收益，以结算币种计
+		Upl string `json:"upl"`
+		// 初始保证金，仅适用于全仓
+		Imr string `json:"imr"`
+		// 保证金余额，仅适用于逐仓
+		Margin string `json:"margin"`
+		// 占用保证金的币种
+		Ccy string `json:"ccy"`
+		// 最近一次持仓更新时间，Unix时间戳的毫秒数格式
+		UTime string `json:"uTime"`
+	} `json:"data"`
+	Msg string `json:"msg"`
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/go-gotop/gotop/exchange"
 	"github.com/go-gotop/gotop/requests"
@@ -41,7 +42,7 @@ func (o *OkxOrderManager) CreateOrder(ctx context.Context, req *exchange.CreateO
 			isPass = true
 		}
 		if !isPass {
-			return nil, fmt.Errorf("create order error: unsupported contract size unit for %v", req.MarketType.String())
+			return nil, fmt.Errorf("create order error: %w: unsupported contract size unit for %v", exchange.ErrOrderRejected, req.MarketType.String())
 		}
 	case types.SizeUnitCoin:
 		isPass := false
@@ -57,7 +58,7 @@ func (o *OkxOrderManager) CreateOrder(ctx context.Context, req *exchange.CreateO
 		}
 
 		if !isPass {
-			return nil, fmt.Errorf("create order error: unsupported coin size unit for %v %v %v", req.MarketType.String(), req.Side.String(), req.OrderType.String())
+			return nil, fmt.Errorf("create order error: %w: unsupported coin size unit for %v %v %v", exchange.ErrOrderRejected, req.MarketType.String(), req.Side.String(), req.OrderType.String())
 		}
 	case types.SizeUnitQuote:
 		isPass := false
@@ -65,10 +66,10 @@ func (o *OkxOrderManager) CreateOrder(ctx context.Context, req *exchange.CreateO
 			isPass = true
 		}
 		if !isPass {
-			return nil, fmt.Errorf("create order error: unsupported quote size unit for %v %v %v", req.MarketType.String(), req.Side.String(), req.OrderType.String())
+			return nil, fmt.Errorf("create order error: %w: unsupported quote size unit for %v %v %v", exchange.ErrOrderRejected, req.MarketType.String(), req.Side.String(), req.OrderType.String())
 		}
 	default:
-		return nil, fmt.Errorf("create order error: unsupported market type %v", req.MarketType.String())
+		return nil, fmt.Errorf("create order error: %w: unsupported market type %v", exchange.ErrOrderRejected, req.MarketType.String())
 	}
 
 	params, err := o.toOrderParams(req)
@@ -97,7 +98,12 @@ func (o *OkxOrderManager) CreateOrder(ctx context.Context, req *exchange.CreateO
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("status code: %d, body: %s", resp.StatusCode, string(body))
+		err := fmt.Errorf("status code: %d, body: %s", resp.StatusCode, string(body))
+		var respData okxOrderResponse
+		if resp.StatusCode < http.StatusInternalServerError && json.Unmarshal(body, &respData) == nil && rejected(respData.Code) {
+			err = fmt.Errorf("%w: %w", exchange.ErrOrderRejected, err)
+		}
+		return nil, err
 	}
 
 	var respData okxOrderResponse
@@ -113,7 +119,11 @@ func (o *OkxOrderManager) CreateOrder(ctx context.Context, req *exchange.CreateO
 			msg = respData.Data[0].SMsg
 			code = respData.Data[0].SCode
 		}
-		return nil, fmt.Errorf("operation failed, code: %s, message: %s", code, msg)
+		err := fmt.Errorf("operation failed, code: %s, message: %s", code, msg)
+		if rejected(code) {
+			err = fmt.Errorf("%w: %w", exchange.ErrOrderRejected, err)
+		}
+		return nil, err
 	}
 
 	return &exchange.CreateOrderResponse{
@@ -123,9 +133,78 @@ func (o *OkxOrderManager) CreateOrder(ctx context.Context, req *exchange.CreateO
 	}, nil
 }
 
-// CancelOrder 取消订单
+// rejected 判断错误码是否为交易所明确拒绝（参数错误、余额不足、价格限制等）。
+// 50001服务不可用、50004接口超时、50013系统繁忙等错误时订单可能已被接受，状态未知
+func rejected(code string) bool {
+	// 51xxx为下单参数、余额、价格限制等交易类错误
+	if len(code) == 5 && strings.HasPrefix(code, "51") {
+		return true
+	}
+	// 50014必填参数不能为空
+	return code == "50014"
+}
+
+// CancelOrder 取消订单，按OrderID撤单，没有OrderID时按ClientOrderID撤单
 func (o *OkxOrderManager) CancelOrder(ctx context.Context, req *exchange.CancelOrderRequest) (*exchange.CancelOrderResponse, error) {
-	return nil, errors.New("not implemented")
+	apiUrl := OKX_API_BASE_URL + "/api/v5/trade/cancel-order"
+
+	params := map[string]any{
+		"instId": req.Symbol.OriginalSymbol,
+	}
+	switch {
+	case req.OrderID != "":
+		params["ordId"] = req.OrderID
+	case req.ClientOrderID != "":
+		params["clOrdId"] = req.ClientOrderID
+	default:
+		return nil, errors.New("cancel order error: order id or client order id is required")
+	}
+
+	resp, err := o.client.DoRequest(&requests.Request{
+		Method: http.MethodPost,
+		URL:    apiUrl,
+		Params: params,
+		Auth: &requests.AuthInfo{
+			APIKey:     req.APIKey,
+			SecretKey:  req.SecretKey,
+			Passphrase: req.Passphrase,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var respData okxOrderResponse
+	err = json.Unmarshal(body, &respData)
+	if err != nil {
+		return nil, err
+	}
+
+	if respData.Code != "0" || len(respData.Data) == 0 || respData.Data[0].SCode != "0" {
+		msg := respData.Msg
+		code := respData.Code
+		if len(respData.Data) > 0 {
+			msg = respData.Data[0].SMsg
+			code = respData.Data[0].SCode
+		}
+		return nil, fmt.Errorf("operation failed, code: %s, message: %s", code, msg)
+	}
+
+	return &exchange.CancelOrderResponse{
+		Symbol:        req.Symbol.OriginalSymbol,
+		OrderID:       respData.Data[0].OrdId,
+		ClientOrderID: respData.Data[0].ClOrdId,
+	}, nil
 }
 
 // GetOrder 获取订单
@@ -0,0 +1,122 @@
+package okxexc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/exchange"
+	"github.com/go-gotop/gotop/types"
+)
+
+// rewriteTransport 将请求转发到本地测试服务
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t rewriteTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r.URL.Scheme = t.target.Scheme
+	r.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestOkxOrderManager_CancelOrder(t *testing.T) {
+	var method, path string
+	var body map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		body = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body["clOrdId"] == "missing" {
+			fmt.Fprint(w, `{"code":"1","msg":"","data":[{"clOrdId":"missing","ordId":"","sCode":"51400","sMsg":"Order does not exist"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"code":"0","msg":"","data":[{"clOrdId":"gt1","ordId":"312269865356374016","sCode":"0","sMsg":""}]}`)
+	}))
+	defer server.Close()
+	target, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	m := NewOkxOrderManager()
+	m.client.SetHTTPClient(&http.Client{Transport: rewriteTransport{target: target}})
+
+	resp, err := m.CancelOrder(context.Background(), &exchange.CancelOrderRequest{
+		APIKey:        "key",
+		SecretKey:     "secret",
+		Passphrase:    "pass",
+		Symbol:        types.Symbol{OriginalSymbol: "BTC-USDT-SWAP"},
+		MarketType:    types.MarketTypePerpetualUSDMargined,
+		ClientOrderID: "gt1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodPost, method)
+	assert.Equal(t, "/api/v5/trade/cancel-order", path)
+	assert.Equal(t, map[string]string{"instId": "BTC-USDT-SWAP", "clOrdId": "gt1"}, body)
+	assert.Equal(t, &exchange.CancelOrderResponse{Symbol: "BTC-USDT-SWAP", OrderID: "312269865356374016", ClientOrderID: "gt1"}, resp)
+
+	// 有OrderID时按OrderID撤单
+	_, err = m.CancelOrder(context.Background(), &exchange.CancelOrderRequest{
+		Symbol:        types.Symbol{OriginalSymbol: "BTC-USDT-SWAP"},
+		OrderID:       "312269865356374016",
+		ClientOrderID: "gt1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"instId": "BTC-USDT-SWAP", "ordId": "312269865356374016"}, body)
+
+	_, err = m.CancelOrder(context.Background(), &exchange.CancelOrderRequest{
+		Symbol:        types.Symbol{OriginalSymbol: "BTC-USDT-SWAP"},
+		ClientOrderID: "missing",
+	})
+	assert.ErrorContains(t, err, "51400")
+
+	_, err = m.CancelOrder(context.Background(), &exchange.CancelOrderRequest{})
+	assert.Error(t, err)
+}
+
+func TestOkxOrderManager_CreateOrderRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		rejected bool
+	}{
+		{"insufficient balance", http.StatusOK, `{"code":"1","msg":"","data":[{"clOrdId":"gt1","ordId":"","sCode":"51008","sMsg":"Order failed. Insufficient balance"}]}`, true},
+		{"price limit", http.StatusOK, `{"code":"1","msg":"","data":[{"clOrdId":"gt1","ordId":"","sCode":"51006","sMsg":"Order price is not within the price limit"}]}`, true},
+		{"invalid parameter", http.StatusBadRequest, `{"code":"51000","msg":"Parameter sz error","data":[]}`, true},
+		{"timeout", http.StatusOK, `{"code":"50004","msg":"Endpoint request timeout","data":[]}`, false},
+		{"unavailable", http.StatusServiceUnavailable, `{"code":"50001","msg":"Service temporarily unavailable","data":[]}`, false},
+		{"busy", http.StatusOK, `{"code":"1","msg":"","data":[{"clOrdId":"gt1","ordId":"","sCode":"50013","sMsg":"Systems are busy"}]}`, false},
+		{"rate limited", http.StatusTooManyRequests, `{"code":"50011","msg":"Too Many Requests","data":[]}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+			target, err := url.Parse(server.URL)
+			require.NoError(t, err)
+			m := NewOkxOrderManager()
+			m.client.SetHTTPClient(&http.Client{Transport: rewriteTransport{target: target}})
+
+			_, err = m.CreateOrder(context.Background(), &exchange.CreateOrderRequest{
+				Symbol:        types.Symbol{OriginalSymbol: "BTC-USDT"},
+				MarketType:    types.MarketTypeSpot,
+				Side:          types.SideTypeBuy,
+				OrderType:     types.OrderTypeMarket,
+				SizeUnit:      types.SizeUnitCoin,
+				ClientOrderID: "gt1",
+			})
+			require.Error(t, err)
+			assert.Equal(t, tt.rejected, errors.Is(err, exchange.ErrOrderRejected))
+		})
+	}
+}
@@ -2,11 +2,16 @@ package exchange
 
 import (
 	"context"
+	"errors"
 
 	"github.com/go-gotop/gotop/types"
 	"github.com/shopspring/decimal"
 )
 
+// ErrOrderRejected 交易所明确拒绝了下单请求（例如参数错误、余额不足），订单不会存在。
+// CreateOrder返回的其他错误（例如超时、网络错误、交易所内部错误）无法确定订单是否已被接受
+var ErrOrderRejected = errors.New("order rejected")
+
 // OrderManager 提供订单管理相关接口方法，如创建、撤销、查询订单等
 type OrderManager interface {
 	// CreateOrder 下订单
@@ -78,9 +83,29 @@ type CreateOrderResponse struct {
 }
 
 type CancelOrderRequest struct {
+	// APIKey 用户APIKey
+	APIKey string
+	// SecretKey 用户SecretKey
+	SecretKey string
+	// Passphrase 用户Passphrase
+	Passphrase string
+	// Symbol 交易对
+	Symbol types.Symbol
+	// MarketType 市场类型
+	MarketType types.MarketType
+	// OrderID 订单ID，与ClientOrderID至少提供一个
+	OrderID string
+	// ClientOrderID 客户订单ID
+	ClientOrderID string
 }
 
 type CancelOrderResponse struct {
+	// Symbol 交易对
+	Symbol string
+	// OrderID 订单ID
+	OrderID string
+	// ClientOrderID 客户订单ID
+	ClientOrderID string
 }
 
 type GetOrderRequest struct {
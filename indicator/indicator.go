@@ -0,0 +1,141 @@
+// Package indicator 提供增量计算的技术指标、摆动点与支撑阻力识别以及仓位计算。
+// 指标每次更新的复杂度为O(1)，数值使用decimal.Decimal，除法按decimal.DivisionPrecision保留精度
+package indicator
+
+import (
+	"math"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/broker"
+)
+
+// Indicator 增量计算的指标，T为输入类型：基于单一价格序列的指标为decimal.Decimal，
+// 需要最高价、最低价或成交量的指标为broker.KlineEvent。非并发安全
+type Indicator[T any] interface {
+	// Update 输入一个新值，K线指标应只输入已完结的K线
+	Update(input T)
+	// Value 返回当前指标值，Ready为false时的值没有意义
+	Value() decimal.Decimal
+	// Ready 是否已输入足够的数据
+	Ready() bool
+}
+
+// PriceSource 从K线中取指标使用的价格
+type PriceSource func(bar broker.KlineEvent) decimal.Decimal
+
+var (
+	// ClosePrice 收盘价
+	ClosePrice PriceSource = func(bar broker.KlineEvent) decimal.Decimal { return bar.Close }
+	// MedianPrice (最高价+最低价)/2
+	MedianPrice PriceSource = func(bar broker.KlineEvent) decimal.Decimal {
+		return bar.High.Add(bar.Low).Div(decimal.NewFromInt(2))
+	}
+	// TypicalPrice (最高价+最低价+收盘价)/3
+	TypicalPrice PriceSource = func(bar broker.KlineEvent) decimal.Decimal {
+		return bar.High.Add(bar.Low).Add(bar.Close).Div(decimal.NewFromInt(3))
+	}
+)
+
+// Warmup 按顺序输入历史数据预热指标
+func Warmup[T any](ind Indicator[T], inputs []T) {
+	for _, input := range inputs {
+		ind.Update(input)
+	}
+}
+
+// WarmupBars 用历史K线预热基于价格序列的指标，source为nil时使用收盘价
+func WarmupBars(ind Indicator[decimal.Decimal], bars []broker.KlineEvent, source PriceSource) {
+	if source == nil {
+		source = ClosePrice
+	}
+	for _, bar := range bars {
+		ind.Update(source(bar))
+	}
+}
+
+var (
+	two     = decimal.NewFromInt(2)
+	hundred = decimal.NewFromInt(100)
+)
+
+// sqrt 牛顿法求平方根，精度与decimal.DivisionPrecision一致，x不大于0时返回0
+func sqrt(x decimal.Decimal) decimal.Decimal {
+	if !x.IsPositive() {
+		return decimal.Zero
+	}
+	f, _ := x.Float64()
+	z := decimal.NewFromFloat(math.Sqrt(f))
+	if !z.IsPositive() {
+		z = x
+	}
+	// 以float64结果为初值，几次迭代即可收敛到十进制精度
+	for i := 0; i < 4; i++ {
+		next := z.Add(x.Div(z)).Div(two)
+		if next.Equal(z) {
+			break
+		}
+		z = next
+	}
+	return z
+}
+
+// ring 定长环形缓冲区，用于滑动窗口
+type ring struct {
+	items []decimal.Decimal
+	next  int
+	full  bool
+}
+
+func newRing(n int) *ring {
+	return &ring{items: make([]decimal.Decimal, n)}
+}
+
+// push 写入新值，缓冲区已满时返回被覆盖的最早值
+func (r *ring) push(v decimal.Decimal) (decimal.Decimal, bool) {
+	old, evicted := r.items[r.next], r.full
+	r.items[r.next] = v
+	r.next++
+	if r.next == len(r.items) {
+		r.next = 0
+		r.full = true
+	}
+	return old, evicted
+}
+
+// monotonic 单调队列，维护滑动窗口内的最大值（max为true）或最小值
+type monotonic struct {
+	max    bool
+	period int
+	values []decimal.Decimal
+	index  []int
+	count  int
+}
+
+func newMonotonic(period int, max bool) *monotonic {
+	return &monotonic{period: period, max: max}
+}
+
+// push 写入新值并淘汰窗口外的值
+func (m *monotonic) push(v decimal.Decimal) {
+	for len(m.values) > 0 {
+		last := m.values[len(m.values)-1]
+		if (m.max && last.GreaterThan(v)) || (!m.max && last.LessThan(v)) {
+			break
+		}
+		m.values = m.values[:len(m.values)-1]
+		m.index = m.index[:len(m.index)-1]
+	}
+	m.values = append(m.values, v)
+	m.index = append(m.index, m.count)
+	m.count++
+	if m.index[0] <= m.count-1-m.period {
+		m.values = m.values[1:]
+		m.index = m.index[1:]
+	}
+}
+
+// value 返回窗口内的最大值或最小值
+func (m *monotonic) value() decimal.Decimal {
+	return m.values[0]
+}
@@ -0,0 +1,305 @@
+package indicator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/types"
+)
+
+// 黄金测试的参考值由独立的float64实现按StockCharts公式逐步计算得到（未对中间结果取整），
+// 因此与StockCharts表格中按两位小数取整后的数值略有差异
+
+// ohlcv 40根按小时排列的K线：开盘、最高、最低、收盘、成交量
+var ohlcv = [][5]string{
+	{"100.00", "100.23", "98.32", "99.30", "16.5"},
+	{"99.30", "99.99", "99.21", "99.44", "55.7"},
+	{"99.44", "100.09", "97.49", "97.59", "18.2"},
+	{"97.59", "98.83", "97.10", "97.29", "30.1"},
+	{"97.29", "99.22", "96.42", "97.80", "45.7"},
+	{"97.80", "99.78", "96.51", "99.71", "36.1"},
+	{"99.71", "99.89", "97.83", "98.29", "83.5"},
+	{"98.29", "99.16", "96.05", "97.01", "43.5"},
+	{"97.01", "97.29", "96.92", "97.20", "28.5"},
+	{"97.20", "98.56", "96.73", "97.92", "62.7"},
+	{"97.92", "98.37", "96.54", "97.73", "72.9"},
+	{"97.73", "98.59", "95.92", "96.71", "88.8"},
+	{"96.71", "98.06", "95.24", "97.63", "20.6"},
+	{"97.63", "98.77", "97.07", "97.30", "54.0"},
+	{"97.30", "98.30", "94.31", "95.46", "61.6"},
+	{"95.46", "97.43", "94.42", "96.96", "63.5"},
+	{"96.96", "97.96", "95.70", "97.28", "95.0"},
+	{"97.28", "98.28", "97.09", "97.18", "73.1"},
+	{"97.18", "99.26", "95.95", "97.77", "35.6"},
+	{"97.77", "98.77", "97.28", "97.31", "51.6"},
+	{"97.31", "97.49", "95.89", "95.98", "79.1"},
+	{"95.98", "96.35", "93.91", "94.50", "88.4"},
+	{"94.50", "95.17", "92.00", "92.82", "89.5"},
+	{"92.82", "95.40", "92.40", "94.10", "47.4"},
+	{"94.10", "95.43", "92.10", "93.54", "23.6"},
+	{"93.54", "93.89", "91.89", "92.24", "53.6"},
+	{"92.24", "92.99", "92.23", "92.60", "47.7"},
+	{"92.60", "93.45", "90.65", "92.08", "72.1"},
+	{"92.08", "93.07", "91.07", "92.14", "14.9"},
+	{"92.14", "94.91", "90.83", "93.74", "81.8"},
+	{"93.74", "94.34", "93.15", "93.31", "67.1"},
+	{"93.31", "93.41", "91.25", "91.56", "24.6"},
+	{"91.56", "91.64", "90.92", "90.92", "23.6"},
+	{"90.92", "91.47", "89.29", "89.33", "88.7"},
+	{"89.33", "90.01", "88.95", "89.79", "41.3"},
+	{"89.79", "89.97", "87.98", "89.25", "99.4"},
+	{"89.25", "89.98", "88.98", "89.11", "19.2"},
+	{"89.11", "89.51", "87.24", "88.48", "24.5"},
+	{"88.48", "89.91", "85.78", "86.57", "23.2"},
+	{"86.57", "86.78", "85.78", "86.74", "98.1"},
+}
+
+func fixtureBars() []broker.KlineEvent {
+	bars := make([]broker.KlineEvent, 0, len(ohlcv))
+	for i, row := range ohlcv {
+		b := bar(int64(i)*time.Hour.Milliseconds(), row[0], row[1], row[2], row[3])
+		b.Volume = d(row[4])
+		bars = append(bars, b)
+	}
+	return bars
+}
+
+func fixtureCloses() []decimal.Decimal {
+	closes := make([]decimal.Decimal, 0, len(ohlcv))
+	for _, b := range fixtureBars() {
+		closes = append(closes, b.Close)
+	}
+	return closes
+}
+
+func assertNear(t *testing.T, want string, got decimal.Decimal) {
+	t.Helper()
+	diff := got.Sub(d(want)).Abs()
+	assert.True(t, diff.LessThan(d("0.000001")), "want %s, got %s", want, got)
+}
+
+// 更新后依次记录Ready时的指标值
+func collect[T any](ind Indicator[T], inputs []T) []decimal.Decimal {
+	var values []decimal.Decimal
+	for _, input := range inputs {
+		ind.Update(input)
+		if ind.Ready() {
+			values = append(values, ind.Value())
+		}
+	}
+	return values
+}
+
+func TestRSIStockCharts(t *testing.T) {
+	closes := []decimal.Decimal{}
+	for _, c := range []string{
+		"44.34", "44.09", "44.15", "43.61", "44.33", "44.83", "45.10", "45.42", "45.84", "46.08",
+		"45.89", "46.03", "45.61", "46.28", "46.28", "46.00", "46.03", "46.41", "46.22", "45.64",
+		"46.21", "46.25", "45.71", "46.45", "45.78", "45.35", "44.03", "44.18", "44.22", "44.57",
+		"43.42", "42.66", "43.13",
+	} {
+		closes = append(closes, d(c))
+	}
+
+	rsi, err := NewRSI(14)
+	require.NoError(t, err)
+	values := collect[decimal.Decimal](rsi, closes)
+	require.Len(t, values, len(closes)-14)
+	for i, want := range []string{"70.46413502", "66.24961855", "66.48094183", "69.34685316", "66.29471266"} {
+		assertNear(t, want, values[i])
+	}
+	assertNear(t, "37.78877198", values[len(values)-1])
+}
+
+func TestEMAStockCharts(t *testing.T) {
+	closes := []decimal.Decimal{}
+	for _, c := range []string{
+		"22.27", "22.19", "22.08", "22.17", "22.18", "22.13", "22.23", "22.43", "22.24", "22.29",
+		"22.15", "22.39", "22.38", "22.61", "23.36", "24.05", "23.75", "23.83", "23.95", "23.63",
+		"23.82", "23.87", "23.65", "23.19", "23.10", "23.33", "22.68", "23.10", "22.40", "22.17",
+	} {
+		closes = append(closes, d(c))
+	}
+
+	ema, err := NewEMA(10)
+	require.NoError(t, err)
+	values := collect[decimal.Decimal](ema, closes)
+	want := []string{
+		"22.22", "22.21", "22.24", "22.27", "22.33", "22.52", "22.8", "22.97", "23.13", "23.28",
+		"23.34", "23.43", "23.51", "23.53", "23.47", "23.4", "23.39", "23.26", "23.23", "23.08", "22.92",
+	}
+	require.Len(t, values, len(want))
+	for i, v := range values {
+		assert.Equal(t, want[i], v.Round(2).String(), "ema[%d]", i)
+	}
+}
+
+func TestMovingAverages(t *testing.T) {
+	closes := fixtureCloses()
+
+	sma, err := NewSMA(10)
+	require.NoError(t, err)
+	ema, err := NewEMA(10)
+	require.NoError(t, err)
+	wma, err := NewWMA(10)
+	require.NoError(t, err)
+
+	smaValues := collect[decimal.Decimal](sma, closes)
+	emaValues := collect[decimal.Decimal](ema, closes)
+	wmaValues := collect[decimal.Decimal](wma, closes)
+	require.Len(t, smaValues, 31)
+	require.Len(t, emaValues, 31)
+	require.Len(t, wmaValues, 31)
+
+	for i, want := range []string{"91.472", "91.123", "90.763", "90.206", "89.506"} {
+		assertNear(t, want, smaValues[26+i])
+	}
+	for i, want := range []string{"91.27201365", "90.87892026", "90.44275294", "89.73861604", "89.19341312"} {
+		assertNear(t, want, emaValues[26+i])
+	}
+	for i, want := range []string{"90.83163636", "90.40218182", "89.92163636", "89.15927273", "88.52909091"} {
+		assertNear(t, want, wmaValues[26+i])
+	}
+}
+
+func TestMACD(t *testing.T) {
+	_, err := NewMACD(10, 5, 4)
+	require.Error(t, err)
+
+	m, err := NewMACD(5, 10, 4)
+	require.NoError(t, err)
+	closes := fixtureCloses()
+	for i, c := range closes {
+		m.Update(c)
+		// 慢线在第10个价格就绪，信号线还需要3个MACD值
+		assert.Equal(t, i >= 12, m.Ready(), "index %d", i)
+	}
+	assertNear(t, "-1.3145993", m.Value())
+	assertNear(t, "-1.20279523", m.Signal())
+	assertNear(t, "-0.11180407", m.Histogram())
+}
+
+func TestBollinger(t *testing.T) {
+	b, err := NewBollinger(20, d("2"))
+	require.NoError(t, err)
+	WarmupBars(b, fixtureBars()[:39], nil)
+	require.True(t, b.Ready())
+	assertNear(t, "91.9685", b.Value())
+	assertNear(t, "97.14674015", b.Upper())
+	assertNear(t, "86.79025985", b.Lower())
+
+	b.Update(d("86.74"))
+	assertNear(t, "91.44", b.Value())
+	assertNear(t, "96.4855961", b.Upper())
+	assertNear(t, "86.3944039", b.Lower())
+	assertNear(t, "0.5", b.PercentB(d("91.44")))
+}
+
+func TestATR(t *testing.T) {
+	atr, err := NewATR(14)
+	require.NoError(t, err)
+	values := collect[broker.KlineEvent](atr, fixtureBars())
+	require.Len(t, values, 27)
+	assertNear(t, "2.10571429", values[0])
+	assertNear(t, "2.04052485", values[24])
+	assertNear(t, "2.18977308", values[25])
+	assertNear(t, "2.10478929", values[26])
+}
+
+func TestStochastic(t *testing.T) {
+	s, err := NewStochastic(14, 3, 3)
+	require.NoError(t, err)
+	var k, dv []decimal.Decimal
+	for _, b := range fixtureBars() {
+		s.Update(b)
+		if s.Ready() {
+			k = append(k, s.Value())
+			dv = append(dv, s.D())
+		}
+	}
+	// 第14根K线得到第一个原始%K，%K与%D各需再平滑2根
+	require.Len(t, k, 40-13-2-2)
+	for i, want := range []string{"15.78506008", "12.98699779", "11.43599818"} {
+		assertNear(t, want, k[len(k)-3+i])
+	}
+	for i, want := range []string{"13.31193698", "14.43136556", "13.40268535"} {
+		assertNear(t, want, dv[len(dv)-3+i])
+	}
+}
+
+func TestStochasticFlatRange(t *testing.T) {
+	s, err := NewStochastic(3, 1, 1)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		s.Update(bar(int64(i), "10", "10", "10", "10"))
+	}
+	require.True(t, s.Ready())
+	assert.True(t, s.Value().Equal(d("50")))
+}
+
+func TestADX(t *testing.T) {
+	adx, err := NewADX(14)
+	require.NoError(t, err)
+	values := collect[broker.KlineEvent](adx, fixtureBars())
+	// 第2*14根K线后就绪
+	require.Len(t, values, 40-27)
+	for i, want := range []string{"58.79350919", "59.83355559", "60.79931297"} {
+		assertNear(t, want, values[len(values)-3+i])
+	}
+	assertNear(t, "5.18628746", adx.PlusDI())
+	assertNear(t, "33.74126932", adx.MinusDI())
+}
+
+func TestVWAP(t *testing.T) {
+	bars := fixtureBars()
+
+	daily, err := NewVWAP(24 * time.Hour)
+	require.NoError(t, err)
+	Warmup[broker.KlineEvent](daily, bars[:24])
+	// 第25根K线进入新的UTC日，VWAP重置
+	daily.Update(bars[24])
+	assert.True(t, daily.Value().Equal(TypicalPrice(bars[24])))
+	assert.Equal(t, bars[24].OpenTime, daily.SessionStart())
+
+	anchored := NewAnchoredVWAP(0)
+	Warmup[broker.KlineEvent](anchored, bars)
+	assertNear(t, "94.59012525", anchored.Value())
+
+	anchored = NewAnchoredVWAP(bars[20].OpenTime)
+	Warmup[broker.KlineEvent](anchored, bars)
+	assertNear(t, "91.78364807", anchored.Value())
+
+	trades, err := NewVWAP(time.Minute)
+	require.NoError(t, err)
+	assert.False(t, trades.Ready())
+	trades.UpdateTrade(types.TradeEvent{Timestamp: 1000, Price: d("100"), Size: d("1")})
+	trades.UpdateTrade(types.TradeEvent{Timestamp: 2000, Price: d("103"), Size: d("2")})
+	assert.True(t, trades.Value().Equal(d("102")))
+	trades.UpdateTrade(types.TradeEvent{Timestamp: 60000, Price: d("110"), Size: d("1")})
+	assert.True(t, trades.Value().Equal(d("110")))
+	// 上一时段的迟到成交被忽略
+	trades.UpdateTrade(types.TradeEvent{Timestamp: 59000, Price: d("90"), Size: d("1")})
+	assert.True(t, trades.Value().Equal(d("110")))
+}
+
+func TestOBV(t *testing.T) {
+	obv := NewOBV()
+	Warmup[broker.KlineEvent](obv, fixtureBars())
+	assert.True(t, obv.Value().Equal(d("-579.3")))
+}
+
+func TestInvalidPeriods(t *testing.T) {
+	_, err := NewSMA(0)
+	assert.Error(t, err)
+	_, err = NewRSI(-1)
+	assert.Error(t, err)
+	_, err = NewBollinger(20, decimal.Zero)
+	assert.Error(t, err)
+	_, err = NewVWAP(0)
+	assert.Error(t, err)
+}
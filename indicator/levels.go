@@ -0,0 +1,444 @@
+package indicator
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/types"
+)
+
+// Zone 支撑阻力区间
+type Zone struct {
+	// ID 区间编号，从0开始递增
+	ID uint64
+	// Level 支撑或阻力，被突破后互换
+	Level types.PositioningLevel
+	// Low、High 区间上下沿
+	Low  decimal.Decimal
+	High decimal.Decimal
+	// Price 区间中心，为构成区间的摆动点和成交量节点价格的平均
+	Price decimal.Decimal
+	// Touches 触及次数，包括构成区间的摆动点、成交量节点以及价格进入区间
+	Touches int
+	// Volume 区间内的累计成交量
+	Volume decimal.Decimal
+	// Strength 强度评分，触及次数与成交量占比的加权和，越大越强
+	Strength float64
+	// FirstTouch、LastTouch 第一次和最近一次触及的时间
+	FirstTouch int64
+	LastTouch  int64
+	// Broken 被突破后尚未回踩
+	Broken bool
+}
+
+// Contains 价格是否在区间内
+func (z *Zone) Contains(price decimal.Decimal) bool {
+	return price.GreaterThanOrEqual(z.Low) && price.LessThanOrEqual(z.High)
+}
+
+// LevelEventType 区间事件类型
+type LevelEventType int
+
+const (
+	// LevelEventBreak 价格穿过区间，支撑变为阻力或阻力变为支撑
+	LevelEventBreak LevelEventType = iota + 1
+	// LevelEventRetest 突破后价格第一次从突破方向回到区间
+	LevelEventRetest
+)
+
+// String 返回字符串表示
+func (t LevelEventType) String() string {
+	switch t {
+	case LevelEventBreak:
+		return "BREAK"
+	case LevelEventRetest:
+		return "RETEST"
+	}
+	return "UNKNOWN"
+}
+
+// LevelEvent 区间突破或回踩事件
+type LevelEvent struct {
+	Type LevelEventType
+	// Zone 事件发生后的区间快照
+	Zone Zone
+	// Direction 突破方向，向上突破阻力为Up，向下跌破支撑为Down；回踩时为突破时的方向
+	Direction types.PriceDirection
+	// Price 触发事件的价格
+	Price decimal.Decimal
+	// Timestamp 触发事件的时间
+	Timestamp int64
+}
+
+// zoneSide 价格相对区间的位置
+type zoneSide int
+
+const (
+	sideUnknown zoneSide = iota
+	sideBelow
+	sideInside
+	sideAbove
+)
+
+// levelZone 区间及其跟踪状态
+type levelZone struct {
+	Zone
+	// min、max 构成区间的触及价格范围，区间上下沿在此基础上按容差扩展
+	min, max decimal.Decimal
+	// priceSum、memberCount 构成区间的价格之和与个数，用于计算中心
+	priceSum    decimal.Decimal
+	memberCount int
+	// side 价格最近一次在区间外时位于哪一侧
+	side zoneSide
+	// inside 价格当前是否在区间内
+	inside bool
+	// breakDir 最近一次突破的方向
+	breakDir types.PriceDirection
+}
+
+// LevelDetector 将摆动点和成交量节点按价格聚类为支撑阻力区间，并根据成交价格触发突破与回踩事件。
+// 摆动高点形成阻力、摆动低点形成支撑，成交量节点按当时价格所在一侧确定。
+// 非并发安全
+type LevelDetector struct {
+	opts        *levelOptions
+	zones       []*levelZone
+	nextID      uint64
+	totalVolume decimal.Decimal
+	lastPrice   decimal.Decimal
+	hasPrice    bool
+}
+
+// NewLevelDetector 创建支撑阻力识别
+func NewLevelDetector(opts ...LevelOption) (*LevelDetector, error) {
+	o := applyLevelOptions(opts...)
+	if !o.tolerance.IsPositive() {
+		return nil, errors.New("tolerance must be positive")
+	}
+	if o.maxZones <= 0 {
+		return nil, errors.New("max zones must be positive")
+	}
+	return &LevelDetector{opts: o}, nil
+}
+
+// AddSwing 加入一个确认的摆动点，例如 SwingDetector 的输出。高点（Direction为Up）形成阻力，低点形成支撑
+func (l *LevelDetector) AddSwing(point types.PricePoint) {
+	level := types.PositioningLevelSupport
+	if point.Direction == types.PriceDirectionUp {
+		level = types.PositioningLevelResistance
+	}
+	l.touch(point.Price, decimal.Zero, point.Timestamp, level)
+}
+
+// AddVolumeNode 加入一个成交量集中的价格，例如成交量分布的POC，volume计入区间成交量
+func (l *LevelDetector) AddVolumeNode(price, volume decimal.Decimal, ts int64) {
+	level := types.PositioningLevelUnknown
+	if l.hasPrice {
+		level = types.PositioningLevelSupport
+		if price.GreaterThan(l.lastPrice) {
+			level = types.PositioningLevelResistance
+		}
+	}
+	l.totalVolume = l.totalVolume.Add(volume)
+	l.touch(price, volume, ts, level)
+}
+
+// UpdateTrade 输入一笔成交，累计区间成交量并返回本次触发的突破与回踩事件
+func (l *LevelDetector) UpdateTrade(trade types.TradeEvent) []LevelEvent {
+	return l.update(trade.Price, trade.Size, trade.Timestamp)
+}
+
+// UpdateBar 输入一根K线，以收盘价和成交量更新
+func (l *LevelDetector) UpdateBar(bar broker.KlineEvent) []LevelEvent {
+	return l.update(bar.Close, bar.Volume, bar.CloseTime)
+}
+
+// Zones 返回所有区间，按价格从低到高排列
+func (l *LevelDetector) Zones() []Zone {
+	zones := make([]Zone, 0, len(l.zones))
+	for _, z := range l.zones {
+		zones = append(zones, l.snapshot(z))
+	}
+	sort.Slice(zones, func(i, j int) bool { return zones[i].Price.LessThan(zones[j].Price) })
+	return zones
+}
+
+// Support 返回价格下方（含所在区间）最近的支撑
+func (l *LevelDetector) Support(price decimal.Decimal) (Zone, bool) {
+	var best *levelZone
+	for _, z := range l.zones {
+		if z.Level != types.PositioningLevelSupport || z.Low.GreaterThan(price) {
+			continue
+		}
+		if best == nil || z.High.GreaterThan(best.High) {
+			best = z
+		}
+	}
+	if best == nil {
+		return Zone{}, false
+	}
+	return l.snapshot(best), true
+}
+
+// Resistance 返回价格上方（含所在区间）最近的阻力
+func (l *LevelDetector) Resistance(price decimal.Decimal) (Zone, bool) {
+	var best *levelZone
+	for _, z := range l.zones {
+		if z.Level != types.PositioningLevelResistance || z.High.LessThan(price) {
+			continue
+		}
+		if best == nil || z.Low.LessThan(best.Low) {
+			best = z
+		}
+	}
+	if best == nil {
+		return Zone{}, false
+	}
+	return l.snapshot(best), true
+}
+
+func (l *LevelDetector) update(price, volume decimal.Decimal, ts int64) []LevelEvent {
+	l.lastPrice, l.hasPrice = price, true
+	l.totalVolume = l.totalVolume.Add(volume)
+
+	var events []LevelEvent
+	for _, z := range l.zones {
+		side := sideBelow
+		if price.GreaterThan(z.High) {
+			side = sideAbove
+		} else if z.Contains(price) {
+			side = sideInside
+		}
+
+		if z.Level == types.PositioningLevelUnknown && side != sideInside {
+			z.Level = types.PositioningLevelSupport
+			if side == sideBelow {
+				z.Level = types.PositioningLevelResistance
+			}
+		}
+
+		if side == sideInside {
+			z.Volume = z.Volume.Add(volume)
+			if !z.inside {
+				z.inside = true
+				l.addTouch(z, price, ts, false)
+				// 突破后从突破方向回到区间视为回踩
+				if z.Broken && z.side == l.brokenSide(z) {
+					z.Broken = false
+					events = append(events, l.event(LevelEventRetest, z, z.breakDir, price, ts))
+				}
+			}
+			continue
+		}
+		z.inside = false
+
+		if z.side != sideUnknown && z.side != side {
+			dir := types.PriceDirectionUp
+			z.Level = types.PositioningLevelSupport
+			if side == sideBelow {
+				dir = types.PriceDirectionDown
+				z.Level = types.PositioningLevelResistance
+			}
+			z.Broken = true
+			z.breakDir = dir
+			events = append(events, l.event(LevelEventBreak, z, dir, price, ts))
+		}
+		z.side = side
+	}
+	return events
+}
+
+// brokenSide 突破后价格所在的一侧
+func (l *LevelDetector) brokenSide(z *levelZone) zoneSide {
+	if z.breakDir == types.PriceDirectionUp {
+		return sideAbove
+	}
+	return sideBelow
+}
+
+// touch 将价格归入已有区间或新建区间
+func (l *LevelDetector) touch(price, volume decimal.Decimal, ts int64, level types.PositioningLevel) {
+	for _, z := range l.zones {
+		if z.Contains(price) {
+			z.Volume = z.Volume.Add(volume)
+			if z.Level == types.PositioningLevelUnknown {
+				z.Level = level
+			}
+			l.addTouch(z, price, ts, true)
+			l.mergeOverlaps(z)
+			return
+		}
+	}
+
+	z := &levelZone{
+		Zone: Zone{
+			ID:         l.nextID,
+			Level:      level,
+			Volume:     volume,
+			FirstTouch: ts,
+		},
+		min: price,
+		max: price,
+	}
+	l.nextID++
+	l.addTouch(z, price, ts, true)
+	if l.hasPrice {
+		switch {
+		case l.lastPrice.GreaterThan(z.High):
+			z.side = sideAbove
+		case l.lastPrice.LessThan(z.Low):
+			z.side = sideBelow
+		default:
+			z.inside = true
+		}
+	}
+	l.zones = append(l.zones, z)
+	l.mergeOverlaps(z)
+	l.evict()
+}
+
+// addTouch 记录一次触及，member为true时价格参与区间范围和中心的计算
+func (l *LevelDetector) addTouch(z *levelZone, price decimal.Decimal, ts int64, member bool) {
+	z.Touches++
+	if ts > z.LastTouch {
+		z.LastTouch = ts
+	}
+	if ts < z.FirstTouch {
+		z.FirstTouch = ts
+	}
+	if !member {
+		return
+	}
+	z.priceSum = z.priceSum.Add(price)
+	z.memberCount++
+	z.min = decimal.Min(z.min, price)
+	z.max = decimal.Max(z.max, price)
+	l.resize(z)
+}
+
+// resize 按构成价格和容差计算区间上下沿与中心
+func (l *LevelDetector) resize(z *levelZone) {
+	one := decimal.NewFromInt(1)
+	z.Low = z.min.Mul(one.Sub(l.opts.tolerance))
+	z.High = z.max.Mul(one.Add(l.opts.tolerance))
+	if z.memberCount > 0 {
+		z.Price = z.priceSum.Div(decimal.NewFromInt(int64(z.memberCount)))
+	}
+}
+
+// mergeOverlaps 合并与z重叠的区间，保留较早的编号
+func (l *LevelDetector) mergeOverlaps(z *levelZone) {
+	for i := 0; i < len(l.zones); i++ {
+		o := l.zones[i]
+		if o == z || o.Low.GreaterThan(z.High) || o.High.LessThan(z.Low) {
+			continue
+		}
+		if o.ID < z.ID {
+			o, z = z, o
+		}
+		z.Touches += o.Touches
+		z.Volume = z.Volume.Add(o.Volume)
+		z.priceSum = z.priceSum.Add(o.priceSum)
+		z.memberCount += o.memberCount
+		z.min = decimal.Min(z.min, o.min)
+		z.max = decimal.Max(z.max, o.max)
+		if o.FirstTouch < z.FirstTouch {
+			z.FirstTouch = o.FirstTouch
+		}
+		if o.LastTouch > z.LastTouch {
+			z.LastTouch = o.LastTouch
+		}
+		l.resize(z)
+		l.remove(o)
+		i = -1
+	}
+}
+
+// evict 区间数量超过上限时移除最弱的区间
+func (l *LevelDetector) evict() {
+	for len(l.zones) > l.opts.maxZones {
+		weakest := l.zones[0]
+		for _, z := range l.zones[1:] {
+			if l.strength(z) < l.strength(weakest) {
+				weakest = z
+			}
+		}
+		l.remove(weakest)
+	}
+}
+
+func (l *LevelDetector) remove(z *levelZone) {
+	for i, o := range l.zones {
+		if o == z {
+			l.zones = append(l.zones[:i], l.zones[i+1:]...)
+			return
+		}
+	}
+}
+
+// strength 触及次数×触及权重 + 成交量占比×成交量权重
+func (l *LevelDetector) strength(z *levelZone) float64 {
+	s := float64(z.Touches) * l.opts.touchWeight
+	if l.totalVolume.IsPositive() {
+		s += z.Volume.Div(l.totalVolume).InexactFloat64() * l.opts.volumeWeight
+	}
+	return s
+}
+
+func (l *LevelDetector) snapshot(z *levelZone) Zone {
+	zone := z.Zone
+	zone.Strength = l.strength(z)
+	return zone
+}
+
+func (l *LevelDetector) event(t LevelEventType, z *levelZone, dir types.PriceDirection, price decimal.Decimal, ts int64) LevelEvent {
+	return LevelEvent{Type: t, Zone: l.snapshot(z), Direction: dir, Price: price, Timestamp: ts}
+}
+
+// levelOptions 支撑阻力识别配置
+type levelOptions struct {
+	tolerance    decimal.Decimal
+	maxZones     int
+	touchWeight  float64
+	volumeWeight float64
+}
+
+func applyLevelOptions(opts ...LevelOption) *levelOptions {
+	o := &levelOptions{
+		tolerance:    decimal.RequireFromString("0.002"),
+		maxZones:     20,
+		touchWeight:  1,
+		volumeWeight: 5,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// LevelOption 是LevelDetector的配置选项
+type LevelOption func(o *levelOptions)
+
+// WithTolerance 设置区间宽度容差，区间上下沿为构成价格的最低、最高价向外扩展该比例，默认0.002即0.2%
+func WithTolerance(tolerance decimal.Decimal) LevelOption {
+	return func(o *levelOptions) {
+		o.tolerance = tolerance
+	}
+}
+
+// WithMaxZones 设置最多保留的区间数，超过时移除强度最低的区间，默认20
+func WithMaxZones(n int) LevelOption {
+	return func(o *levelOptions) {
+		o.maxZones = n
+	}
+}
+
+// WithStrengthWeights 设置强度评分中触及次数与成交量占比的权重，默认分别为1和5
+func WithStrengthWeights(touch, volume float64) LevelOption {
+	return func(o *levelOptions) {
+		o.touchWeight = touch
+		o.volumeWeight = volume
+	}
+}
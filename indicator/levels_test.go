@@ -0,0 +1,114 @@
+package indicator
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/types"
+)
+
+func tick(ts int64, price, size string) types.TradeEvent {
+	return types.TradeEvent{Timestamp: ts, Price: d(price), Size: d(size)}
+}
+
+func TestLevelDetectorClustering(t *testing.T) {
+	l, err := NewLevelDetector(WithTolerance(d("0.01")))
+	require.NoError(t, err)
+
+	l.AddSwing(types.PricePoint{Price: d("100"), Timestamp: 1, Direction: types.PriceDirectionDown})
+	l.AddSwing(types.PricePoint{Price: d("120"), Timestamp: 2, Direction: types.PriceDirectionUp})
+	// 100.5落在[99, 101]内，归入同一支撑区间
+	l.AddSwing(types.PricePoint{Price: d("100.5"), Timestamp: 3, Direction: types.PriceDirectionDown})
+	l.AddVolumeNode(d("121"), d("50"), 4)
+
+	zones := l.Zones()
+	require.Len(t, zones, 2)
+
+	support := zones[0]
+	assert.Equal(t, types.PositioningLevelSupport, support.Level)
+	assert.Equal(t, 2, support.Touches)
+	assert.Equal(t, "100.25", support.Price.String())
+	assert.Equal(t, "99", support.Low.String())
+	assert.Equal(t, "101.505", support.High.String())
+	assert.Equal(t, int64(1), support.FirstTouch)
+	assert.Equal(t, int64(3), support.LastTouch)
+
+	resistance := zones[1]
+	assert.Equal(t, types.PositioningLevelResistance, resistance.Level)
+	assert.Equal(t, uint64(1), resistance.ID)
+	assert.Equal(t, 2, resistance.Touches)
+	assert.Equal(t, "50", resistance.Volume.String())
+	// 触及2次 + 成交量占比100%×5
+	assert.Equal(t, 7.0, resistance.Strength)
+
+	s, ok := l.Support(d("110"))
+	require.True(t, ok)
+	assert.Equal(t, support.ID, s.ID)
+	r, ok := l.Resistance(d("110"))
+	require.True(t, ok)
+	assert.Equal(t, resistance.ID, r.ID)
+	_, ok = l.Support(d("90"))
+	assert.False(t, ok)
+}
+
+func TestLevelDetectorBreakRetest(t *testing.T) {
+	l, err := NewLevelDetector(WithTolerance(d("0.01")))
+	require.NoError(t, err)
+	l.AddSwing(types.PricePoint{Price: d("100"), Timestamp: 1, Direction: types.PriceDirectionUp})
+
+	var events []LevelEvent
+	for i, price := range []string{"95", "99.5", "97", "102", "105", "100.5", "104"} {
+		events = append(events, l.UpdateTrade(tick(int64(10+i), price, "1"))...)
+	}
+
+	require.Len(t, events, 2)
+	// 99.5进入区间后回落到97不算突破，102向上突破阻力
+	assert.Equal(t, LevelEventBreak, events[0].Type)
+	assert.Equal(t, types.PriceDirectionUp, events[0].Direction)
+	assert.Equal(t, types.PositioningLevelSupport, events[0].Zone.Level)
+	assert.True(t, events[0].Zone.Broken)
+	assert.Equal(t, int64(13), events[0].Timestamp)
+	// 从上方回到区间为回踩
+	assert.Equal(t, LevelEventRetest, events[1].Type)
+	assert.Equal(t, "100.5", events[1].Price.String())
+	assert.False(t, events[1].Zone.Broken)
+
+	zone := l.Zones()[0]
+	assert.Equal(t, 3, zone.Touches)
+	assert.Equal(t, "2", zone.Volume.String())
+	assert.Equal(t, int64(15), zone.LastTouch)
+
+	// 再次跌破支撑
+	events = l.UpdateTrade(tick(20, "98", "1"))
+	require.Len(t, events, 1)
+	assert.Equal(t, types.PriceDirectionDown, events[0].Direction)
+	assert.Equal(t, types.PositioningLevelResistance, events[0].Zone.Level)
+}
+
+func TestLevelDetectorMaxZones(t *testing.T) {
+	l, err := NewLevelDetector(WithTolerance(d("0.001")), WithMaxZones(2))
+	require.NoError(t, err)
+
+	l.AddSwing(types.PricePoint{Price: d("100"), Timestamp: 1})
+	l.AddSwing(types.PricePoint{Price: d("100"), Timestamp: 2})
+	l.AddSwing(types.PricePoint{Price: d("110"), Timestamp: 3})
+	l.AddVolumeNode(d("120"), d("10"), 4)
+
+	// 只触及一次且没有成交量的110被移除
+	zones := l.Zones()
+	require.Len(t, zones, 2)
+	assert.Equal(t, "100", zones[0].Price.String())
+	assert.Equal(t, "120", zones[1].Price.String())
+	assert.Equal(t, types.PositioningLevelUnknown, zones[1].Level)
+
+	l.UpdateTrade(tick(5, "115", "1"))
+	assert.Equal(t, types.PositioningLevelResistance, l.Zones()[1].Level)
+
+	_, err = NewLevelDetector(WithTolerance(decimal.Zero))
+	assert.Error(t, err)
+	_, err = NewLevelDetector(WithMaxZones(0))
+	assert.Error(t, err)
+}
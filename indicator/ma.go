@@ -0,0 +1,138 @@
+package indicator
+
+import (
+	"errors"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	_ Indicator[decimal.Decimal] = (*SMA)(nil)
+	_ Indicator[decimal.Decimal] = (*EMA)(nil)
+	_ Indicator[decimal.Decimal] = (*WMA)(nil)
+)
+
+// SMA 简单移动平均
+type SMA struct {
+	period int
+	window *ring
+	sum    decimal.Decimal
+	value  decimal.Decimal
+}
+
+// NewSMA 创建简单移动平均
+func NewSMA(period int) (*SMA, error) {
+	if period <= 0 {
+		return nil, errors.New("period must be positive")
+	}
+	return &SMA{period: period, window: newRing(period)}, nil
+}
+
+// Update 输入新值
+func (s *SMA) Update(v decimal.Decimal) {
+	s.sum = s.sum.Add(v)
+	if old, ok := s.window.push(v); ok {
+		s.sum = s.sum.Sub(old)
+	}
+	if s.window.full {
+		s.value = s.sum.Div(decimal.NewFromInt(int64(s.period)))
+	}
+}
+
+// Value 返回最近period个值的平均
+func (s *SMA) Value() decimal.Decimal { return s.value }
+
+// Ready 输入period个值后就绪
+func (s *SMA) Ready() bool { return s.window.full }
+
+// EMA 指数移动平均，平滑系数为2/(period+1)，以前period个值的简单平均作为初值
+type EMA struct {
+	period int
+	// 平滑系数为 num/den，先乘后除使精度不随更新次数增长
+	num, den decimal.Decimal
+	count    int
+	sum      decimal.Decimal
+	value    decimal.Decimal
+}
+
+// NewEMA 创建指数移动平均
+func NewEMA(period int) (*EMA, error) {
+	if period <= 0 {
+		return nil, errors.New("period must be positive")
+	}
+	return &EMA{period: period, num: two, den: decimal.NewFromInt(int64(period + 1))}, nil
+}
+
+// newWilder 创建Wilder平滑的移动平均，平滑系数为1/period
+func newWilder(period int) *EMA {
+	return &EMA{period: period, num: decimal.NewFromInt(1), den: decimal.NewFromInt(int64(period))}
+}
+
+// Update 输入新值
+func (e *EMA) Update(v decimal.Decimal) {
+	e.count++
+	switch {
+	case e.count < e.period:
+		e.sum = e.sum.Add(v)
+	case e.count == e.period:
+		e.value = e.sum.Add(v).Div(decimal.NewFromInt(int64(e.period)))
+		e.sum = decimal.Zero
+	default:
+		e.value = e.value.Add(v.Sub(e.value).Mul(e.num).Div(e.den))
+	}
+}
+
+// Value 返回当前平均值
+func (e *EMA) Value() decimal.Decimal { return e.value }
+
+// Ready 输入period个值后就绪
+func (e *EMA) Ready() bool { return e.count >= e.period }
+
+// WMA 线性加权移动平均，最新值权重为period，最早值权重为1
+type WMA struct {
+	period int
+	window *ring
+	// sum 窗口内数值之和，weighted 加权和
+	sum      decimal.Decimal
+	weighted decimal.Decimal
+	count    int
+	divisor  decimal.Decimal
+	value    decimal.Decimal
+}
+
+// NewWMA 创建线性加权移动平均
+func NewWMA(period int) (*WMA, error) {
+	if period <= 0 {
+		return nil, errors.New("period must be positive")
+	}
+	return &WMA{
+		period:  period,
+		window:  newRing(period),
+		divisor: decimal.NewFromInt(int64(period * (period + 1) / 2)),
+	}, nil
+}
+
+// Update 输入新值
+func (w *WMA) Update(v decimal.Decimal) {
+	if w.count < w.period {
+		// 窗口未满时新值权重为当前个数
+		w.count++
+		w.weighted = w.weighted.Add(v.Mul(decimal.NewFromInt(int64(w.count))))
+		w.sum = w.sum.Add(v)
+		w.window.push(v)
+	} else {
+		// 窗口内每个旧值的权重减1，最早值移出窗口
+		w.weighted = w.weighted.Sub(w.sum).Add(v.Mul(decimal.NewFromInt(int64(w.period))))
+		old, _ := w.window.push(v)
+		w.sum = w.sum.Sub(old).Add(v)
+	}
+	if w.count == w.period {
+		w.value = w.weighted.Div(w.divisor)
+	}
+}
+
+// Value 返回当前加权平均值
+func (w *WMA) Value() decimal.Decimal { return w.value }
+
+// Ready 输入period个值后就绪
+func (w *WMA) Ready() bool { return w.count >= w.period }
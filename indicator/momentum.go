@@ -0,0 +1,166 @@
+package indicator
+
+import (
+	"errors"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/broker"
+)
+
+var (
+	_ Indicator[decimal.Decimal]   = (*RSI)(nil)
+	_ Indicator[decimal.Decimal]   = (*MACD)(nil)
+	_ Indicator[broker.KlineEvent] = (*Stochastic)(nil)
+)
+
+// RSI 相对强弱指标，平均涨幅与平均跌幅使用Wilder平滑，取值[0, 100]
+type RSI struct {
+	gain, loss *EMA
+	prev       decimal.Decimal
+	started    bool
+	value      decimal.Decimal
+}
+
+// NewRSI 创建相对强弱指标，常用周期为14
+func NewRSI(period int) (*RSI, error) {
+	if period <= 0 {
+		return nil, errors.New("period must be positive")
+	}
+	return &RSI{gain: newWilder(period), loss: newWilder(period)}, nil
+}
+
+// Update 输入新价格
+func (r *RSI) Update(v decimal.Decimal) {
+	if !r.started {
+		r.started = true
+		r.prev = v
+		return
+	}
+	change := v.Sub(r.prev)
+	r.prev = v
+	if change.IsPositive() {
+		r.gain.Update(change)
+		r.loss.Update(decimal.Zero)
+	} else {
+		r.gain.Update(decimal.Zero)
+		r.loss.Update(change.Neg())
+	}
+	if !r.gain.Ready() {
+		return
+	}
+
+	gain, loss := r.gain.Value(), r.loss.Value()
+	switch {
+	case loss.IsZero() && gain.IsZero():
+		r.value = decimal.NewFromInt(50)
+	case loss.IsZero():
+		r.value = hundred
+	default:
+		r.value = hundred.Sub(hundred.Div(gain.Div(loss).Add(decimal.NewFromInt(1))))
+	}
+}
+
+// Value 返回RSI，价格没有变动时为50
+func (r *RSI) Value() decimal.Decimal { return r.value }
+
+// Ready 输入period+1个价格后就绪
+func (r *RSI) Ready() bool { return r.gain.Ready() }
+
+// MACD 指数平滑异同移动平均，Value为快慢EMA之差，Signal为其EMA，Histogram为两者之差
+type MACD struct {
+	fast, slow, signal *EMA
+	value              decimal.Decimal
+}
+
+// NewMACD 创建MACD，常用参数为12、26、9
+func NewMACD(fast, slow, signal int) (*MACD, error) {
+	if fast <= 0 || slow <= 0 || signal <= 0 {
+		return nil, errors.New("periods must be positive")
+	}
+	if fast >= slow {
+		return nil, errors.New("fast period must be less than slow period")
+	}
+	f, _ := NewEMA(fast)
+	s, _ := NewEMA(slow)
+	sig, _ := NewEMA(signal)
+	return &MACD{fast: f, slow: s, signal: sig}, nil
+}
+
+// Update 输入新价格
+func (m *MACD) Update(v decimal.Decimal) {
+	m.fast.Update(v)
+	m.slow.Update(v)
+	if !m.slow.Ready() {
+		return
+	}
+	m.value = m.fast.Value().Sub(m.slow.Value())
+	m.signal.Update(m.value)
+}
+
+// Value 返回MACD线，慢线就绪后即有值
+func (m *MACD) Value() decimal.Decimal { return m.value }
+
+// Signal 返回信号线
+func (m *MACD) Signal() decimal.Decimal { return m.signal.Value() }
+
+// Histogram 返回MACD线与信号线之差
+func (m *MACD) Histogram() decimal.Decimal { return m.value.Sub(m.signal.Value()) }
+
+// Ready 信号线就绪后就绪，即输入slow+signal-1个价格后
+func (m *MACD) Ready() bool { return m.signal.Ready() }
+
+// Stochastic 随机指标，Value为%K，D为%K的简单平均
+type Stochastic struct {
+	high, low *monotonic
+	period    int
+	count     int
+	// k 对原始%K做slowing周期的平滑，slowing为1时即快速随机指标
+	k *SMA
+	d *SMA
+}
+
+// NewStochastic 创建随机指标，常用参数为14、3、3（慢速）或14、1、3（快速）
+func NewStochastic(period, slowing, dPeriod int) (*Stochastic, error) {
+	if period <= 0 || slowing <= 0 || dPeriod <= 0 {
+		return nil, errors.New("periods must be positive")
+	}
+	k, _ := NewSMA(slowing)
+	d, _ := NewSMA(dPeriod)
+	return &Stochastic{
+		high:   newMonotonic(period, true),
+		low:    newMonotonic(period, false),
+		period: period,
+		k:      k,
+		d:      d,
+	}, nil
+}
+
+// Update 输入已完结的K线
+func (s *Stochastic) Update(bar broker.KlineEvent) {
+	s.high.push(bar.High)
+	s.low.push(bar.Low)
+	s.count++
+	if s.count < s.period {
+		return
+	}
+
+	highest, lowest := s.high.value(), s.low.value()
+	raw := decimal.NewFromInt(50)
+	if rng := highest.Sub(lowest); rng.IsPositive() {
+		raw = hundred.Mul(bar.Close.Sub(lowest)).Div(rng)
+	}
+	s.k.Update(raw)
+	if s.k.Ready() {
+		s.d.Update(s.k.Value())
+	}
+}
+
+// Value 返回%K，区间内最高价等于最低价时原始%K为50
+func (s *Stochastic) Value() decimal.Decimal { return s.k.Value() }
+
+// D 返回%D
+func (s *Stochastic) D() decimal.Decimal { return s.d.Value() }
+
+// Ready %D就绪后就绪
+func (s *Stochastic) Ready() bool { return s.d.Ready() }
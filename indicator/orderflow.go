@@ -0,0 +1,254 @@
+package indicator
+
+import (
+	"errors"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/types"
+)
+
+var (
+	_ Indicator[types.TradeEvent] = (*CVD)(nil)
+	_ Indicator[types.TradeEvent] = (*TradeImbalance)(nil)
+	_ Indicator[types.TradeEvent] = (*AggressorRatio)(nil)
+	_ Indicator[types.TradeEvent] = (*WhaleDetector)(nil)
+)
+
+// 订单流指标按成交的Side区分主动方：Buy为主动买入，Sell为主动卖出，其他成交被忽略。
+// 与types.TradeAggregate的VolumeDelta、ImbalanceRatio、AggressorRatio使用相同的定义
+
+// signedSize 主动买入为正，主动卖出为负，方向未知时返回false
+func signedSize(trade types.TradeEvent) (decimal.Decimal, bool) {
+	switch trade.Side {
+	case types.SideTypeBuy:
+		return trade.Size, true
+	case types.SideTypeSell:
+		return trade.Size.Neg(), true
+	}
+	return decimal.Zero, false
+}
+
+// CVD 累计成交量差，主动买入量减主动卖出量的累计值
+type CVD struct {
+	value   decimal.Decimal
+	started bool
+}
+
+// NewCVD 创建累计成交量差
+func NewCVD() *CVD {
+	return &CVD{}
+}
+
+// Update 输入一笔成交
+func (c *CVD) Update(trade types.TradeEvent) {
+	if delta, ok := signedSize(trade); ok {
+		c.value = c.value.Add(delta)
+		c.started = true
+	}
+}
+
+// Reset 清零，例如在新的交易时段开始时
+func (c *CVD) Reset() {
+	c.value = decimal.Zero
+}
+
+// Value 返回累计成交量差
+func (c *CVD) Value() decimal.Decimal { return c.value }
+
+// Ready 输入第一笔有方向的成交后就绪
+func (c *CVD) Ready() bool { return c.started }
+
+// TradeImbalance 最近period笔成交的笔数失衡，(买入笔数-卖出笔数)/总笔数，取值[-1, 1]
+type TradeImbalance struct {
+	period int
+	window *ring
+	sum    decimal.Decimal
+}
+
+// NewTradeImbalance 创建笔数失衡指标
+func NewTradeImbalance(period int) (*TradeImbalance, error) {
+	if period <= 0 {
+		return nil, errors.New("period must be positive")
+	}
+	return &TradeImbalance{period: period, window: newRing(period)}, nil
+}
+
+// Update 输入一笔成交
+func (t *TradeImbalance) Update(trade types.TradeEvent) {
+	delta, ok := signedSize(trade)
+	if !ok {
+		return
+	}
+	sign := decimal.NewFromInt(int64(delta.Sign()))
+	t.sum = t.sum.Add(sign)
+	if old, ok := t.window.push(sign); ok {
+		t.sum = t.sum.Sub(old)
+	}
+}
+
+// Value 返回笔数失衡
+func (t *TradeImbalance) Value() decimal.Decimal {
+	return t.sum.Div(decimal.NewFromInt(int64(t.period)))
+}
+
+// Ready 输入period笔有方向的成交后就绪
+func (t *TradeImbalance) Ready() bool { return t.window.full }
+
+// AggressorRatio 最近period笔成交中主动买入量占总成交量的比例，取值[0, 1]，0.5为均衡
+type AggressorRatio struct {
+	window *ring
+	// buys 主动买入量，窗口中以正数记录买入、负数记录卖出
+	buys  decimal.Decimal
+	total decimal.Decimal
+}
+
+// NewAggressorRatio 创建主动买入占比指标
+func NewAggressorRatio(period int) (*AggressorRatio, error) {
+	if period <= 0 {
+		return nil, errors.New("period must be positive")
+	}
+	return &AggressorRatio{window: newRing(period)}, nil
+}
+
+// Update 输入一笔成交
+func (a *AggressorRatio) Update(trade types.TradeEvent) {
+	delta, ok := signedSize(trade)
+	if !ok {
+		return
+	}
+	a.add(delta, 1)
+	if old, ok := a.window.push(delta); ok {
+		a.add(old, -1)
+	}
+}
+
+func (a *AggressorRatio) add(delta decimal.Decimal, sign int64) {
+	s := decimal.NewFromInt(sign)
+	a.total = a.total.Add(delta.Abs().Mul(s))
+	if delta.IsPositive() {
+		a.buys = a.buys.Add(delta.Mul(s))
+	}
+}
+
+// Value 返回主动买入占比，窗口内成交量为0时返回0.5
+func (a *AggressorRatio) Value() decimal.Decimal {
+	if !a.total.IsPositive() {
+		return decimal.NewFromFloat(0.5)
+	}
+	return a.buys.Div(a.total)
+}
+
+// Ready 输入period笔有方向的成交后就绪
+func (a *AggressorRatio) Ready() bool { return a.window.full }
+
+// WhaleOption 大单识别选项
+type WhaleOption func(*WhaleDetector)
+
+// WithWhaleNotional 按成交额（价格×数量）而不是成交量识别大单
+func WithWhaleNotional() WhaleOption {
+	return func(w *WhaleDetector) {
+		w.notional = true
+	}
+}
+
+// WithWhaleMinimum 设置阈值下限，自适应阈值低于该值时使用下限
+func WithWhaleMinimum(minimum decimal.Decimal) WhaleOption {
+	return func(w *WhaleDetector) {
+		w.minimum = minimum
+	}
+}
+
+// WhaleDetector 自适应阈值的大单识别，阈值为成交规模指数加权均值加k倍指数加权标准差，
+// 随市场活跃度变化。Value返回当前阈值
+type WhaleDetector struct {
+	period int
+	k      decimal.Decimal
+	// 平滑系数为 num/den
+	num, den decimal.Decimal
+	count    int
+	mean     decimal.Decimal
+	variance decimal.Decimal
+	notional bool
+	minimum  decimal.Decimal
+
+	largeBuys, largeSells           uint64
+	largeBuyVolume, largeSellVolume decimal.Decimal
+}
+
+// NewWhaleDetector 创建大单识别，period为指数加权的周期，同时也是预热的成交笔数，
+// k为标准差倍数，常用参数为100、3
+func NewWhaleDetector(period int, k decimal.Decimal, opts ...WhaleOption) (*WhaleDetector, error) {
+	if period <= 0 {
+		return nil, errors.New("period must be positive")
+	}
+	if !k.IsPositive() {
+		return nil, errors.New("k must be positive")
+	}
+	w := &WhaleDetector{
+		period: period,
+		k:      k,
+		num:    two,
+		den:    decimal.NewFromInt(int64(period + 1)),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w, nil
+}
+
+// Update 输入一笔成交
+func (w *WhaleDetector) Update(trade types.TradeEvent) {
+	w.Detect(trade)
+}
+
+// Detect 输入一笔成交并返回其是否为大单。判断使用输入前的阈值，预热完成前总是返回false
+func (w *WhaleDetector) Detect(trade types.TradeEvent) bool {
+	x := trade.Size
+	if w.notional {
+		x = trade.Size.Mul(trade.Price)
+	}
+
+	large := w.Ready() && x.GreaterThan(w.Value())
+	if large {
+		switch trade.Side {
+		case types.SideTypeBuy:
+			w.largeBuys++
+			w.largeBuyVolume = w.largeBuyVolume.Add(trade.Size)
+		case types.SideTypeSell:
+			w.largeSells++
+			w.largeSellVolume = w.largeSellVolume.Add(trade.Size)
+		}
+	}
+
+	// 指数加权均值与方差的增量更新
+	w.count++
+	if w.count == 1 {
+		w.mean = x
+		return large
+	}
+	diff := x.Sub(w.mean)
+	incr := diff.Mul(w.num).Div(w.den)
+	w.mean = w.mean.Add(incr)
+	w.variance = w.variance.Add(diff.Mul(incr)).Mul(w.den.Sub(w.num)).Div(w.den)
+	return large
+}
+
+// Value 返回当前大单阈值
+func (w *WhaleDetector) Value() decimal.Decimal {
+	threshold := w.mean.Add(sqrt(w.variance).Mul(w.k))
+	return decimal.Max(threshold, w.minimum)
+}
+
+// Ready 输入period笔成交后就绪
+func (w *WhaleDetector) Ready() bool { return w.count >= w.period }
+
+// LargeBuys 返回大单主动买入的笔数和成交量
+func (w *WhaleDetector) LargeBuys() (uint64, decimal.Decimal) {
+	return w.largeBuys, w.largeBuyVolume
+}
+
+// LargeSells 返回大单主动卖出的笔数和成交量
+func (w *WhaleDetector) LargeSells() (uint64, decimal.Decimal) {
+	return w.largeSells, w.largeSellVolume
+}
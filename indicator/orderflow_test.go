@@ -0,0 +1,121 @@
+package indicator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/types"
+)
+
+func flow(side types.SideType, size string) types.TradeEvent {
+	return types.TradeEvent{Side: side, Price: d("100"), Size: d(size)}
+}
+
+func TestCVD(t *testing.T) {
+	cvd := NewCVD()
+	assert.False(t, cvd.Ready())
+	Warmup[types.TradeEvent](cvd, []types.TradeEvent{
+		flow(types.SideTypeBuy, "2"),
+		flow(types.SideTypeSell, "0.5"),
+		flow(types.SideTypeBuy, "1"),
+		{Size: d("10")},
+	})
+	require.True(t, cvd.Ready())
+	assert.Equal(t, "2.5", cvd.Value().String())
+
+	cvd.Reset()
+	assert.True(t, cvd.Value().IsZero())
+}
+
+func TestTradeImbalanceAndAggressorRatio(t *testing.T) {
+	imbalance, err := NewTradeImbalance(4)
+	require.NoError(t, err)
+	aggressor, err := NewAggressorRatio(4)
+	require.NoError(t, err)
+
+	trades := []types.TradeEvent{
+		flow(types.SideTypeBuy, "1"),
+		flow(types.SideTypeBuy, "1"),
+		flow(types.SideTypeSell, "4"),
+		flow(types.SideTypeBuy, "2"),
+	}
+	for _, trade := range trades {
+		imbalance.Update(trade)
+		aggressor.Update(trade)
+	}
+	require.True(t, imbalance.Ready())
+	require.True(t, aggressor.Ready())
+	// 3买1卖，买入量4，总量8
+	assert.Equal(t, "0.5", imbalance.Value().String())
+	assert.Equal(t, "0.5", aggressor.Value().String())
+
+	// 第一笔买入移出窗口
+	imbalance.Update(flow(types.SideTypeSell, "1"))
+	aggressor.Update(flow(types.SideTypeSell, "1"))
+	assert.Equal(t, "0", imbalance.Value().String())
+	assert.Equal(t, "0.375", aggressor.Value().String())
+
+	// 与TradeAggregate的定义一致
+	agg := types.TradeAggregate{
+		BuyCount:   2,
+		SellCount:  2,
+		BuyVolume:  d("3"),
+		SellVolume: d("5"),
+	}
+	assert.Equal(t, imbalance.Value().String(), agg.ImbalanceRatio().String())
+	assert.Equal(t, aggressor.Value().String(), agg.AggressorRatio().String())
+	assert.Equal(t, "-2", agg.VolumeDelta().String())
+
+	empty := types.TradeAggregate{}
+	assert.True(t, empty.ImbalanceRatio().IsZero())
+	assert.Equal(t, "0.5", empty.AggressorRatio().String())
+}
+
+func TestWhaleDetector(t *testing.T) {
+	w, err := NewWhaleDetector(10, d("3"))
+	require.NoError(t, err)
+
+	// 预热期间不识别大单
+	for i := 0; i < 10; i++ {
+		side := types.SideTypeBuy
+		if i%2 == 1 {
+			side = types.SideTypeSell
+		}
+		size := "1"
+		if i%3 == 0 {
+			size = "1.2"
+		}
+		assert.False(t, w.Detect(flow(side, size)))
+	}
+	require.True(t, w.Ready())
+	threshold := w.Value()
+	assert.True(t, threshold.GreaterThan(d("1.2")))
+	assert.True(t, threshold.LessThan(d("2")))
+
+	assert.False(t, w.Detect(flow(types.SideTypeBuy, "1.1")))
+	assert.True(t, w.Detect(flow(types.SideTypeSell, "5")))
+	// 大单抬高阈值
+	assert.True(t, w.Value().GreaterThan(threshold))
+
+	count, volume := w.LargeSells()
+	assert.Equal(t, uint64(1), count)
+	assert.Equal(t, "5", volume.String())
+	count, _ = w.LargeBuys()
+	assert.Zero(t, count)
+}
+
+func TestWhaleDetectorNotionalMinimum(t *testing.T) {
+	w, err := NewWhaleDetector(3, d("2"), WithWhaleNotional(), WithWhaleMinimum(d("1000")))
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		w.Update(flow(types.SideTypeBuy, "1"))
+	}
+	// 成交额均为100，方差为0，阈值取下限
+	assert.Equal(t, "1000", w.Value().String())
+	assert.False(t, w.Detect(flow(types.SideTypeBuy, "9")))
+	// 900使均值变为500、标准差变为400，阈值升至1300
+	assert.Equal(t, "1300", w.Value().String())
+	assert.True(t, w.Detect(flow(types.SideTypeBuy, "14")))
+}
@@ -1,6 +1,10 @@
 package indicator
 
-import "github.com/shopspring/decimal"
+import (
+    "github.com/shopspring/decimal"
+
+    "github.com/go-gotop/gotop/types"
+)
 
 // PositionSizer 定义了一个用于计算仓位大小的接口。实现该接口的类型应根据
 // 特定的业务逻辑（例如风险控制、资金管理策略、市场条件）来计算建议持有的头寸数量。
@@ -16,9 +20,15 @@ import "github.com/shopspring/decimal"
 type PositionSizer interface {
     // CalculatePositionSize 根据实现的策略和条件计算仓位大小。
     //
+    // 参数：
+    // - req：标的物、参考价格、止损价、账户权益等计算所需的输入。
+    //
     // 返回值：
     // - decimal.Decimal：建议的仓位大小，通常为正数（表示应开仓的数量），
     //   也可能为 0（表示不增仓）或其它值（根据具体策略定义）。
     // - error：当无法正确计算时返回错误；如果计算成功则返回 nil。
-    CalculatePositionSize() (decimal.Decimal, error)
+    CalculatePositionSize(req SizingRequest) (decimal.Decimal, error)
+
+    // SizeUnit 返回 CalculatePositionSize 结果的数量单位，可直接用于下单请求。
+    SizeUnit() types.SizeUnit
 }
@@ -0,0 +1,314 @@
+package indicator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/types"
+)
+
+var (
+	_ PositionSizer = (*FixedQuantitySizer)(nil)
+	_ PositionSizer = (*FixedNotionalSizer)(nil)
+	_ PositionSizer = (*FixedFractionalSizer)(nil)
+	_ PositionSizer = (*VolatilitySizer)(nil)
+	_ PositionSizer = (*KellySizer)(nil)
+)
+
+// SizingRequest 仓位计算的输入，各仓位计算器只使用其中需要的字段
+type SizingRequest struct {
+	// Symbol 标的物，用于头寸精度、面值和最小最大头寸
+	Symbol types.Symbol
+	// Price 参考价格，通常为计划的开仓价
+	Price decimal.Decimal
+	// StopPrice 止损价，固定比例风险使用
+	StopPrice decimal.Decimal
+	// Equity 账户权益，以计价货币计
+	Equity decimal.Decimal
+	// ATR 当前平均真实波幅，波动率仓位使用，可由ATR指标得到
+	ATR decimal.Decimal
+}
+
+// SizerOption 仓位计算器选项
+type SizerOption func(*sizing)
+
+// WithSizeUnit 设置输出的数量单位，默认为币
+func WithSizeUnit(unit types.SizeUnit) SizerOption {
+	return func(s *sizing) {
+		s.unit = unit
+	}
+}
+
+// sizing 各仓位计算器共用的取整与单位换算。
+// 数量先换算为标的物的原生单位（现货为币，合约为张），按头寸精度向下取整并限制在最小最大头寸内，
+// 再换算为输出单位，因此输出的币数或计价货币金额总是整数张合约对应的数量
+type sizing struct {
+	unit types.SizeUnit
+}
+
+func newSizing(opts []SizerOption) (sizing, error) {
+	s := sizing{unit: types.SizeUnitCoin}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	switch s.unit {
+	case types.SizeUnitCoin, types.SizeUnitContract, types.SizeUnitQuote:
+		return s, nil
+	}
+	return s, fmt.Errorf("invalid size unit: %s", s.unit)
+}
+
+// SizeUnit 返回输出的数量单位
+func (s sizing) SizeUnit() types.SizeUnit {
+	return s.unit
+}
+
+// finalize 将以币计的数量取整并换算为输出单位，低于最小头寸时返回0
+func (s sizing) finalize(req SizingRequest, qty decimal.Decimal) (decimal.Decimal, error) {
+	sym := req.Symbol
+	if !qty.IsPositive() {
+		return decimal.Zero, nil
+	}
+
+	contract, inverse := false, false
+	switch sym.Type {
+	case types.MarketTypeFuturesUSDMargined, types.MarketTypePerpetualUSDMargined:
+		contract = true
+	case types.MarketTypeFuturesCoinMargined, types.MarketTypePerpetualCoinMargined:
+		contract, inverse = true, true
+	}
+	if contract && !sym.CtVal.IsPositive() {
+		return decimal.Zero, errors.New("ctVal is required")
+	}
+	if !contract && s.unit == types.SizeUnitContract {
+		return decimal.Zero, fmt.Errorf("contract size unit is not supported for market type: %s", sym.Type)
+	}
+
+	// U本位合约面值以币计，币本位合约面值以计价货币计
+	native := qty
+	switch {
+	case inverse:
+		native = qty.Mul(req.Price).Div(sym.CtVal)
+	case contract:
+		native = qty.Div(sym.CtVal)
+	}
+	native = native.RoundDown(sym.SizePrecision)
+	if sym.MaxSize.IsPositive() && native.GreaterThan(sym.MaxSize) {
+		native = sym.MaxSize.RoundDown(sym.SizePrecision)
+	}
+	if native.LessThan(sym.MinSize) || !native.IsPositive() {
+		return decimal.Zero, nil
+	}
+
+	if s.unit == types.SizeUnitContract {
+		return native, nil
+	}
+	coin, quote := native, native.Mul(req.Price)
+	switch {
+	case inverse:
+		quote = native.Mul(sym.CtVal)
+		coin = quote.Div(req.Price)
+	case contract:
+		coin = native.Mul(sym.CtVal)
+		quote = coin.Mul(req.Price)
+	}
+	if s.unit == types.SizeUnitQuote {
+		return quote, nil
+	}
+	return coin, nil
+}
+
+// checkPrice 校验参考价格
+func checkPrice(req SizingRequest) error {
+	if !req.Price.IsPositive() {
+		return errors.New("price must be positive")
+	}
+	return nil
+}
+
+// checkEquity 校验账户权益
+func checkEquity(req SizingRequest) error {
+	if !req.Equity.IsPositive() {
+		return errors.New("equity must be positive")
+	}
+	return nil
+}
+
+// FixedQuantitySizer 固定数量，每次开仓相同的币数
+type FixedQuantitySizer struct {
+	sizing
+	quantity decimal.Decimal
+}
+
+// NewFixedQuantitySizer 创建固定数量仓位计算器，quantity以币计
+func NewFixedQuantitySizer(quantity decimal.Decimal, opts ...SizerOption) (*FixedQuantitySizer, error) {
+	if !quantity.IsPositive() {
+		return nil, errors.New("quantity must be positive")
+	}
+	s, err := newSizing(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &FixedQuantitySizer{sizing: s, quantity: quantity}, nil
+}
+
+// CalculatePositionSize 计算仓位大小
+func (f *FixedQuantitySizer) CalculatePositionSize(req SizingRequest) (decimal.Decimal, error) {
+	if err := checkPrice(req); err != nil {
+		return decimal.Zero, err
+	}
+	return f.finalize(req, f.quantity)
+}
+
+// FixedNotionalSizer 固定名义价值，每次开仓相同的计价货币金额
+type FixedNotionalSizer struct {
+	sizing
+	notional decimal.Decimal
+}
+
+// NewFixedNotionalSizer 创建固定名义价值仓位计算器，notional以计价货币计
+func NewFixedNotionalSizer(notional decimal.Decimal, opts ...SizerOption) (*FixedNotionalSizer, error) {
+	if !notional.IsPositive() {
+		return nil, errors.New("notional must be positive")
+	}
+	s, err := newSizing(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &FixedNotionalSizer{sizing: s, notional: notional}, nil
+}
+
+// CalculatePositionSize 计算仓位大小
+func (f *FixedNotionalSizer) CalculatePositionSize(req SizingRequest) (decimal.Decimal, error) {
+	if err := checkPrice(req); err != nil {
+		return decimal.Zero, err
+	}
+	return f.finalize(req, f.notional.Div(req.Price))
+}
+
+// FixedFractionalSizer 固定比例风险，止损时亏损为账户权益的固定比例：
+// 数量 = 权益 × 风险比例 / |价格 - 止损价|
+type FixedFractionalSizer struct {
+	sizing
+	risk decimal.Decimal
+}
+
+// NewFixedFractionalSizer 创建固定比例风险仓位计算器，risk为小数，例如0.01表示每笔风险1%
+func NewFixedFractionalSizer(risk decimal.Decimal, opts ...SizerOption) (*FixedFractionalSizer, error) {
+	if !risk.IsPositive() || risk.GreaterThan(decimal.NewFromInt(1)) {
+		return nil, errors.New("risk must be in (0, 1]")
+	}
+	s, err := newSizing(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &FixedFractionalSizer{sizing: s, risk: risk}, nil
+}
+
+// CalculatePositionSize 计算仓位大小，需要Price、StopPrice和Equity
+func (f *FixedFractionalSizer) CalculatePositionSize(req SizingRequest) (decimal.Decimal, error) {
+	if err := checkPrice(req); err != nil {
+		return decimal.Zero, err
+	}
+	if err := checkEquity(req); err != nil {
+		return decimal.Zero, err
+	}
+	distance := req.Price.Sub(req.StopPrice).Abs()
+	if !req.StopPrice.IsPositive() || distance.IsZero() {
+		return decimal.Zero, errors.New("stop price must be positive and differ from price")
+	}
+	return f.finalize(req, req.Equity.Mul(f.risk).Div(distance))
+}
+
+// VolatilitySizer ATR波动率仓位，以ATR的倍数作为止损距离计算固定比例风险：
+// 数量 = 权益 × 风险比例 / (ATR × 倍数)，波动越大仓位越小
+type VolatilitySizer struct {
+	sizing
+	risk       decimal.Decimal
+	multiplier decimal.Decimal
+}
+
+// NewVolatilitySizer 创建ATR波动率仓位计算器，risk为小数，multiplier为ATR倍数
+func NewVolatilitySizer(risk, multiplier decimal.Decimal, opts ...SizerOption) (*VolatilitySizer, error) {
+	if !risk.IsPositive() || risk.GreaterThan(decimal.NewFromInt(1)) {
+		return nil, errors.New("risk must be in (0, 1]")
+	}
+	if !multiplier.IsPositive() {
+		return nil, errors.New("atr multiplier must be positive")
+	}
+	s, err := newSizing(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &VolatilitySizer{sizing: s, risk: risk, multiplier: multiplier}, nil
+}
+
+// CalculatePositionSize 计算仓位大小，需要Price、Equity和ATR
+func (v *VolatilitySizer) CalculatePositionSize(req SizingRequest) (decimal.Decimal, error) {
+	if err := checkPrice(req); err != nil {
+		return decimal.Zero, err
+	}
+	if err := checkEquity(req); err != nil {
+		return decimal.Zero, err
+	}
+	if !req.ATR.IsPositive() {
+		return decimal.Zero, errors.New("atr must be positive")
+	}
+	return v.finalize(req, req.Equity.Mul(v.risk).Div(req.ATR.Mul(v.multiplier)))
+}
+
+// KellySizer 限制上限的凯利公式仓位，凯利比例 f = W - (1-W)/R，
+// W为胜率，R为平均盈亏比；实际使用 f × fraction 并限制在[0, limit]内，
+// 名义价值 = 权益 × 比例。凯利比例不大于0时返回0
+type KellySizer struct {
+	sizing
+	winRate  decimal.Decimal
+	payoff   decimal.Decimal
+	fraction decimal.Decimal
+	limit    decimal.Decimal
+}
+
+// NewKellySizer 创建凯利仓位计算器，fraction为凯利比例的折扣（例如0.5为半凯利），
+// limit为权益占比上限
+func NewKellySizer(winRate, payoff, fraction, limit decimal.Decimal, opts ...SizerOption) (*KellySizer, error) {
+	one := decimal.NewFromInt(1)
+	if !winRate.IsPositive() || winRate.GreaterThanOrEqual(one) {
+		return nil, errors.New("win rate must be in (0, 1)")
+	}
+	if !payoff.IsPositive() {
+		return nil, errors.New("payoff ratio must be positive")
+	}
+	if !fraction.IsPositive() || fraction.GreaterThan(one) {
+		return nil, errors.New("kelly fraction must be in (0, 1]")
+	}
+	if !limit.IsPositive() {
+		return nil, errors.New("limit must be positive")
+	}
+	s, err := newSizing(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &KellySizer{sizing: s, winRate: winRate, payoff: payoff, fraction: fraction, limit: limit}, nil
+}
+
+// Fraction 返回折扣并限制上限后的权益占比
+func (k *KellySizer) Fraction() decimal.Decimal {
+	f := k.winRate.Sub(decimal.NewFromInt(1).Sub(k.winRate).Div(k.payoff)).Mul(k.fraction)
+	if !f.IsPositive() {
+		return decimal.Zero
+	}
+	return decimal.Min(f, k.limit)
+}
+
+// CalculatePositionSize 计算仓位大小，需要Price和Equity
+func (k *KellySizer) CalculatePositionSize(req SizingRequest) (decimal.Decimal, error) {
+	if err := checkPrice(req); err != nil {
+		return decimal.Zero, err
+	}
+	if err := checkEquity(req); err != nil {
+		return decimal.Zero, err
+	}
+	return k.finalize(req, req.Equity.Mul(k.Fraction()).Div(req.Price))
+}
@@ -0,0 +1,175 @@
+package indicator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/types"
+)
+
+var (
+	spotSymbol = types.Symbol{
+		UnifiedSymbol: "BTC-USDT",
+		Type:          types.MarketTypeSpot,
+		SizePrecision: 3,
+		MinSize:       d("0.001"),
+	}
+	linearSymbol = types.Symbol{
+		UnifiedSymbol: "ETH-USDT-SWAP",
+		Type:          types.MarketTypePerpetualUSDMargined,
+		SizePrecision: 0,
+		MinSize:       d("1"),
+		CtVal:         d("0.1"),
+	}
+	inverseSymbol = types.Symbol{
+		UnifiedSymbol: "BTC-USD-SWAP",
+		Type:          types.MarketTypePerpetualCoinMargined,
+		SizePrecision: 0,
+		MinSize:       d("1"),
+		CtVal:         d("100"),
+	}
+)
+
+func TestFixedQuantitySizer(t *testing.T) {
+	s, err := NewFixedQuantitySizer(d("0.0125"))
+	require.NoError(t, err)
+	assert.Equal(t, types.SizeUnitCoin, s.SizeUnit())
+
+	size, err := s.CalculatePositionSize(SizingRequest{Symbol: spotSymbol, Price: d("30000")})
+	require.NoError(t, err)
+	assert.Equal(t, "0.012", size.String())
+
+	// 低于最小头寸
+	s, err = NewFixedQuantitySizer(d("0.0009"))
+	require.NoError(t, err)
+	size, err = s.CalculatePositionSize(SizingRequest{Symbol: spotSymbol, Price: d("30000")})
+	require.NoError(t, err)
+	assert.True(t, size.IsZero())
+
+	// 超过最大头寸
+	capped := spotSymbol
+	capped.MaxSize = d("5")
+	s, err = NewFixedQuantitySizer(d("8"))
+	require.NoError(t, err)
+	size, err = s.CalculatePositionSize(SizingRequest{Symbol: capped, Price: d("30000")})
+	require.NoError(t, err)
+	assert.Equal(t, "5", size.String())
+}
+
+func TestFixedNotionalSizer(t *testing.T) {
+	s, err := NewFixedNotionalSizer(d("1000"), WithSizeUnit(types.SizeUnitQuote))
+	require.NoError(t, err)
+	assert.Equal(t, types.SizeUnitQuote, s.SizeUnit())
+
+	// 1000/30000=0.0333，取整为0.033币，即990计价货币
+	size, err := s.CalculatePositionSize(SizingRequest{Symbol: spotSymbol, Price: d("30000")})
+	require.NoError(t, err)
+	assert.Equal(t, "990", size.String())
+
+	_, err = s.CalculatePositionSize(SizingRequest{Symbol: spotSymbol})
+	assert.Error(t, err)
+}
+
+func TestFixedNotionalSizerInverse(t *testing.T) {
+	// 币本位合约面值100美元，1050美元取整为10张
+	for unit, want := range map[types.SizeUnit]string{
+		types.SizeUnitContract: "10",
+		types.SizeUnitCoin:     "0.02",
+		types.SizeUnitQuote:    "1000",
+	} {
+		s, err := NewFixedNotionalSizer(d("1050"), WithSizeUnit(unit))
+		require.NoError(t, err)
+		size, err := s.CalculatePositionSize(SizingRequest{Symbol: inverseSymbol, Price: d("50000")})
+		require.NoError(t, err)
+		assert.Equal(t, want, size.String(), unit.String())
+	}
+}
+
+func TestFixedFractionalSizer(t *testing.T) {
+	s, err := NewFixedFractionalSizer(d("0.01"), WithSizeUnit(types.SizeUnitContract))
+	require.NoError(t, err)
+
+	// 风险100，止损距离3，数量33.33币，面值0.1取整为333张
+	size, err := s.CalculatePositionSize(SizingRequest{
+		Symbol:    linearSymbol,
+		Price:     d("100"),
+		StopPrice: d("97"),
+		Equity:    d("10000"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "333", size.String())
+
+	s, err = NewFixedFractionalSizer(d("0.01"))
+	require.NoError(t, err)
+	size, err = s.CalculatePositionSize(SizingRequest{
+		Symbol:    linearSymbol,
+		Price:     d("100"),
+		StopPrice: d("103"),
+		Equity:    d("10000"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "33.3", size.String())
+
+	_, err = s.CalculatePositionSize(SizingRequest{Symbol: linearSymbol, Price: d("100"), StopPrice: d("100"), Equity: d("10000")})
+	assert.Error(t, err)
+	_, err = s.CalculatePositionSize(SizingRequest{Symbol: linearSymbol, Price: d("100"), StopPrice: d("97")})
+	assert.Error(t, err)
+
+	_, err = NewFixedFractionalSizer(d("1.5"))
+	assert.Error(t, err)
+}
+
+func TestVolatilitySizer(t *testing.T) {
+	s, err := NewVolatilitySizer(d("0.02"), d("2"))
+	require.NoError(t, err)
+
+	// 风险200，止损距离 4×2=8，数量25币
+	size, err := s.CalculatePositionSize(SizingRequest{Symbol: spotSymbol, Price: d("100"), Equity: d("10000"), ATR: d("4")})
+	require.NoError(t, err)
+	assert.Equal(t, "25", size.String())
+
+	_, err = s.CalculatePositionSize(SizingRequest{Symbol: spotSymbol, Price: d("100"), Equity: d("10000")})
+	assert.Error(t, err)
+}
+
+func TestKellySizer(t *testing.T) {
+	// f = 0.55 - 0.45/1.5 = 0.25，半凯利0.125，上限0.1
+	s, err := NewKellySizer(d("0.55"), d("1.5"), d("0.5"), d("0.1"))
+	require.NoError(t, err)
+	assert.Equal(t, "0.1", s.Fraction().String())
+
+	size, err := s.CalculatePositionSize(SizingRequest{Symbol: spotSymbol, Price: d("100"), Equity: d("10000")})
+	require.NoError(t, err)
+	assert.Equal(t, "10", size.String())
+
+	s, err = NewKellySizer(d("0.55"), d("1.5"), d("0.5"), d("0.2"))
+	require.NoError(t, err)
+	assert.Equal(t, "0.125", s.Fraction().String())
+
+	// 没有优势时不开仓
+	s, err = NewKellySizer(d("0.3"), d("1"), d("1"), d("0.2"))
+	require.NoError(t, err)
+	size, err = s.CalculatePositionSize(SizingRequest{Symbol: spotSymbol, Price: d("100"), Equity: d("10000")})
+	require.NoError(t, err)
+	assert.True(t, size.IsZero())
+
+	_, err = NewKellySizer(d("1"), d("1"), d("1"), d("0.2"))
+	assert.Error(t, err)
+}
+
+func TestSizerUnitErrors(t *testing.T) {
+	_, err := NewFixedQuantitySizer(d("1"), WithSizeUnit(types.SizeUnitUnknown))
+	assert.Error(t, err)
+
+	s, err := NewFixedQuantitySizer(d("1"), WithSizeUnit(types.SizeUnitContract))
+	require.NoError(t, err)
+	_, err = s.CalculatePositionSize(SizingRequest{Symbol: spotSymbol, Price: d("100")})
+	assert.Error(t, err)
+
+	noCtVal := linearSymbol
+	noCtVal.CtVal = d("0")
+	_, err = s.CalculatePositionSize(SizingRequest{Symbol: noCtVal, Price: d("100")})
+	assert.Error(t, err)
+}
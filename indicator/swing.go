@@ -0,0 +1,288 @@
+package indicator
+
+import (
+	"errors"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/types"
+)
+
+// SwingMode 摆动点识别方式
+type SwingMode int
+
+const (
+	// SwingModePercent 价格从极值反向变动超过固定百分比时确认极值
+	SwingModePercent SwingMode = iota + 1
+	// SwingModeATR 价格从极值反向变动超过ATR的倍数时确认极值
+	SwingModeATR
+	// SwingModePivot 高点（低点）高于（低于）前后各N根K线时确认，即N-bar分形
+	SwingModePivot
+)
+
+// String 返回字符串表示
+func (m SwingMode) String() string {
+	switch m {
+	case SwingModePercent:
+		return "PERCENT"
+	case SwingModeATR:
+		return "ATR"
+	case SwingModePivot:
+		return "PIVOT"
+	}
+	return "UNKNOWN"
+}
+
+// SwingDetector 从逐笔成交或K线中识别摆动高低点（ZigZag）。
+// 确认的高点Direction为PriceDirectionUp（上升段的终点），低点为PriceDirectionDown；
+// ID为确认顺序，从0开始递增；Timestamp为极值所在成交的时间或K线的开盘时间。
+// 百分比和ATR方式输出的高低点严格交替，N-bar方式可能连续输出同向的点。
+// 非并发安全
+type SwingDetector struct {
+	mode SwingMode
+	// percent 百分比方式的反转阈值，例如0.05表示5%
+	percent decimal.Decimal
+	// multiplier ATR方式的反转阈值倍数
+	multiplier decimal.Decimal
+	atr        *ATR
+	// n N-bar方式两侧的K线数
+	n    int
+	bars []swingBar
+
+	// trend 当前摆动段方向，未确认第一个极值前为Unknown
+	trend types.PriceDirection
+	// high、low 当前段的候选极值，趋势未知时同时跟踪两者
+	high, low types.PricePoint
+	started   bool
+
+	extremum types.RangeExtremum
+	last     types.PricePoint
+	count    uint64
+}
+
+// swingBar N-bar方式缓存的K线
+type swingBar struct {
+	high, low decimal.Decimal
+	ts        int64
+}
+
+// NewPercentSwing 创建百分比反转的摆动点识别，percent为小数，例如0.05表示5%
+func NewPercentSwing(percent decimal.Decimal) (*SwingDetector, error) {
+	if !percent.IsPositive() || percent.GreaterThanOrEqual(decimal.NewFromInt(1)) {
+		return nil, errors.New("percent must be in (0, 1)")
+	}
+	return &SwingDetector{mode: SwingModePercent, percent: percent}, nil
+}
+
+// NewATRSwing 创建按ATR倍数反转的摆动点识别，ATR由UpdateBar输入的K线计算，
+// ATR计算完成前不会确认极值；UpdateTrade使用最近一次计算的ATR
+func NewATRSwing(period int, multiplier decimal.Decimal) (*SwingDetector, error) {
+	if period <= 0 {
+		return nil, errors.New("atr period must be positive")
+	}
+	if !multiplier.IsPositive() {
+		return nil, errors.New("atr multiplier must be positive")
+	}
+	atr, _ := NewATR(period)
+	return &SwingDetector{mode: SwingModeATR, multiplier: multiplier, atr: atr}, nil
+}
+
+// NewPivotSwing 创建N-bar分形识别，高点需高于前后各n根K线的最高价，低点需低于前后各n根K线的最低价，
+// 在第n根后续K线到达时确认
+func NewPivotSwing(n int) (*SwingDetector, error) {
+	if n <= 0 {
+		return nil, errors.New("pivot bars must be positive")
+	}
+	return &SwingDetector{mode: SwingModePivot, n: n}, nil
+}
+
+// Mode 返回识别方式
+func (s *SwingDetector) Mode() SwingMode {
+	return s.mode
+}
+
+// UpdateTrade 输入一笔成交，返回本次确认的极值。N-bar方式中每笔成交视为一根K线
+func (s *SwingDetector) UpdateTrade(trade types.TradeEvent) (types.PricePoint, bool) {
+	if s.mode == SwingModePivot {
+		points := s.updatePivot(swingBar{high: trade.Price, low: trade.Price, ts: trade.Timestamp})
+		if len(points) == 0 {
+			return types.PricePoint{}, false
+		}
+		return points[0], true
+	}
+	return s.updatePrice(trade.Price, trade.Timestamp)
+}
+
+// UpdateBar 输入一根已完结的K线，返回本次确认的极值，按时间顺序排列。
+// 百分比和ATR方式无法得知K线内最高价与最低价的先后，阳线按先低后高、阴线按先高后低处理，
+// 先到的价格以OpenTime、后到的价格以CloseTime作为极值时间。N-bar方式以OpenTime作为极值时间
+func (s *SwingDetector) UpdateBar(bar broker.KlineEvent) []types.PricePoint {
+	if s.mode == SwingModePivot {
+		return s.updatePivot(swingBar{high: bar.High, low: bar.Low, ts: bar.OpenTime})
+	}
+	if s.atr != nil {
+		s.atr.Update(bar)
+	}
+
+	first, second := bar.High, bar.Low
+	if bar.Close.GreaterThanOrEqual(bar.Open) {
+		first, second = bar.Low, bar.High
+	}
+	var points []types.PricePoint
+	if p, ok := s.updatePrice(first, bar.OpenTime); ok {
+		points = append(points, p)
+	}
+	if p, ok := s.updatePrice(second, bar.CloseTime); ok {
+		points = append(points, p)
+	}
+	return points
+}
+
+// Extremum 返回最近确认的高点和低点
+func (s *SwingDetector) Extremum() types.RangeExtremum {
+	return s.extremum
+}
+
+// Last 返回最近确认的极值
+func (s *SwingDetector) Last() (types.PricePoint, bool) {
+	return s.last, s.count > 0
+}
+
+// Trend 返回当前摆动段的方向，最近确认的是低点时为Up，高点时为Down
+func (s *SwingDetector) Trend() types.PriceDirection {
+	return s.trend
+}
+
+// Candidate 返回当前摆动段尚未确认的极值，趋势未知时返回false
+func (s *SwingDetector) Candidate() (types.PricePoint, bool) {
+	switch s.trend {
+	case types.PriceDirectionUp:
+		return s.high, true
+	case types.PriceDirectionDown:
+		return s.low, true
+	}
+	return types.PricePoint{}, false
+}
+
+// updatePrice 百分比和ATR方式的ZigZag
+func (s *SwingDetector) updatePrice(price decimal.Decimal, ts int64) (types.PricePoint, bool) {
+	point := types.PricePoint{Price: price, Timestamp: ts}
+	if !s.started {
+		s.started = true
+		s.high, s.low = point, point
+		return types.PricePoint{}, false
+	}
+
+	switch s.trend {
+	case types.PriceDirectionUp:
+		if price.GreaterThan(s.high.Price) {
+			s.high = point
+			return types.PricePoint{}, false
+		}
+		if s.reversed(s.high.Price, price) {
+			peak := s.confirm(s.high, types.PriceDirectionUp)
+			s.trend = types.PriceDirectionDown
+			s.low = point
+			return peak, true
+		}
+	case types.PriceDirectionDown:
+		if price.LessThan(s.low.Price) {
+			s.low = point
+			return types.PricePoint{}, false
+		}
+		if s.reversed(s.low.Price, price) {
+			valley := s.confirm(s.low, types.PriceDirectionDown)
+			s.trend = types.PriceDirectionUp
+			s.high = point
+			return valley, true
+		}
+	default:
+		if price.GreaterThan(s.high.Price) {
+			s.high = point
+		}
+		if price.LessThan(s.low.Price) {
+			s.low = point
+		}
+		// 第一个极值：哪一侧先满足反转条件就确认哪一侧
+		if s.reversed(s.low.Price, price) {
+			valley := s.confirm(s.low, types.PriceDirectionDown)
+			s.trend = types.PriceDirectionUp
+			s.high = point
+			return valley, true
+		}
+		if s.reversed(s.high.Price, price) {
+			peak := s.confirm(s.high, types.PriceDirectionUp)
+			s.trend = types.PriceDirectionDown
+			s.low = point
+			return peak, true
+		}
+	}
+	return types.PricePoint{}, false
+}
+
+// reversed 价格是否从极值extreme反向变动超过阈值
+func (s *SwingDetector) reversed(extreme, price decimal.Decimal) bool {
+	move := price.Sub(extreme).Abs()
+	switch s.mode {
+	case SwingModePercent:
+		return move.GreaterThanOrEqual(extreme.Abs().Mul(s.percent))
+	case SwingModeATR:
+		return s.atr.Ready() && move.GreaterThanOrEqual(s.atr.Value().Mul(s.multiplier))
+	}
+	return false
+}
+
+// updatePivot N-bar分形，缓存2n+1根K线，中间一根为候选
+func (s *SwingDetector) updatePivot(bar swingBar) []types.PricePoint {
+	s.bars = append(s.bars, bar)
+	if len(s.bars) > 2*s.n+1 {
+		s.bars = s.bars[1:]
+	}
+	if len(s.bars) < 2*s.n+1 {
+		return nil
+	}
+
+	mid := s.bars[s.n]
+	isHigh, isLow := true, true
+	for i, b := range s.bars {
+		if i == s.n {
+			continue
+		}
+		if !mid.high.GreaterThan(b.high) {
+			isHigh = false
+		}
+		if !mid.low.LessThan(b.low) {
+			isLow = false
+		}
+	}
+
+	var points []types.PricePoint
+	if isHigh {
+		points = append(points, s.confirm(types.PricePoint{Price: mid.high, Timestamp: mid.ts}, types.PriceDirectionUp))
+	}
+	if isLow {
+		points = append(points, s.confirm(types.PricePoint{Price: mid.low, Timestamp: mid.ts}, types.PriceDirectionDown))
+	}
+	if len(points) > 0 {
+		s.trend = types.PriceDirectionDown
+		if points[len(points)-1].Direction == types.PriceDirectionDown {
+			s.trend = types.PriceDirectionUp
+		}
+	}
+	return points
+}
+
+// confirm 确认极值并更新区间
+func (s *SwingDetector) confirm(point types.PricePoint, direction types.PriceDirection) types.PricePoint {
+	point.ID = s.count
+	point.Direction = direction
+	s.count++
+	if direction == types.PriceDirectionUp {
+		s.extremum.PeakPrice = point
+	} else {
+		s.extremum.ValleyPrice = point
+	}
+	s.last = point
+	return point
+}
@@ -0,0 +1,146 @@
+package indicator
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/types"
+)
+
+func d(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func bar(ts int64, open, high, low, close string) broker.KlineEvent {
+	return broker.KlineEvent{OpenTime: ts, Open: d(open), High: d(high), Low: d(low), Close: d(close)}
+}
+
+func TestPercentSwing(t *testing.T) {
+	s, err := NewPercentSwing(d("0.1"))
+	require.NoError(t, err)
+
+	var points []types.PricePoint
+	for i, price := range []string{"100", "105", "95", "89", "95", "98", "110", "108", "99", "97", "120"} {
+		if p, ok := s.UpdateTrade(types.TradeEvent{Timestamp: int64(i), Price: d(price)}); ok {
+			points = append(points, p)
+		}
+	}
+
+	// 105回落到89确认高点，89反弹到98确认低点，110回落到99确认高点，97上涨到120确认低点
+	require.Len(t, points, 4)
+	assert.Equal(t, "105", points[0].Price.String())
+	assert.Equal(t, types.PriceDirectionUp, points[0].Direction)
+	assert.Equal(t, "89", points[1].Price.String())
+	assert.Equal(t, int64(3), points[1].Timestamp)
+	assert.Equal(t, types.PriceDirectionDown, points[1].Direction)
+	assert.Equal(t, "110", points[2].Price.String())
+	assert.Equal(t, "97", points[3].Price.String())
+	for i, p := range points {
+		assert.Equal(t, uint64(i), p.ID)
+	}
+
+	ext := s.Extremum()
+	assert.Equal(t, "110", ext.PeakPrice.Price.String())
+	assert.Equal(t, "97", ext.ValleyPrice.Price.String())
+	assert.False(t, ext.IsTrending())
+	assert.Equal(t, types.PriceDirectionUp, s.Trend())
+
+	candidate, ok := s.Candidate()
+	require.True(t, ok)
+	assert.Equal(t, "120", candidate.Price.String())
+}
+
+func TestATRSwing(t *testing.T) {
+	s, err := NewATRSwing(2, d("2"))
+	require.NoError(t, err)
+
+	// 前两根K线的真实波幅均为2，ATR=2，反转阈值为4
+	var points []types.PricePoint
+	for _, b := range []broker.KlineEvent{
+		bar(0, "10", "11", "9", "10"),
+		bar(1, "10", "12", "10", "11"),
+		bar(2, "11", "15", "11", "14"),
+		bar(3, "14", "14", "12", "12"),
+		bar(4, "12", "12", "9", "9"),
+	} {
+		b.CloseTime = b.OpenTime + 59_999
+		points = append(points, s.UpdateBar(b)...)
+	}
+
+	require.Len(t, points, 2)
+	// K线2的真实波幅为4，ATR=(2+4)/2=3，阈值6：从低点9上涨到15确认低点
+	assert.Equal(t, "9", points[0].Price.String())
+	assert.Equal(t, types.PriceDirectionDown, points[0].Direction)
+	// K线3、4后ATR依次为2.5、2.75，阈值5.5，从15回落到9确认高点，阳线的最高价在后，时间为收盘时间
+	assert.Equal(t, "15", points[1].Price.String())
+	assert.Equal(t, int64(60_001), points[1].Timestamp)
+	assert.Equal(t, types.PriceDirectionDown, s.Trend())
+}
+
+func TestSwingBarTimestamps(t *testing.T) {
+	s, err := NewPercentSwing(d("0.1"))
+	require.NoError(t, err)
+
+	b := bar(0, "100", "100", "100", "100")
+	b.CloseTime = 59_999
+	assert.Empty(t, s.UpdateBar(b))
+
+	// 阴线先高后低：上涨到120确认低点100，回落到80确认高点120，高点在开盘时间，新的低点80在收盘时间
+	b = bar(60_000, "110", "120", "80", "85")
+	b.CloseTime = 119_999
+	points := s.UpdateBar(b)
+	require.Len(t, points, 2)
+	assert.Equal(t, "100", points[0].Price.String())
+	assert.Equal(t, int64(0), points[0].Timestamp)
+	assert.Equal(t, "120", points[1].Price.String())
+	assert.Equal(t, int64(60_000), points[1].Timestamp)
+
+	// 阳线先低后高：反弹到100确认低点80
+	b = bar(120_000, "85", "100", "85", "100")
+	b.CloseTime = 179_999
+	points = s.UpdateBar(b)
+	require.Len(t, points, 1)
+	assert.Equal(t, "80", points[0].Price.String())
+	assert.Equal(t, int64(119_999), points[0].Timestamp)
+}
+
+func TestPivotSwing(t *testing.T) {
+	s, err := NewPivotSwing(2)
+	require.NoError(t, err)
+
+	var points []types.PricePoint
+	for i, hl := range [][2]string{
+		{"10", "8"}, {"11", "9"}, {"13", "10"}, {"12", "9"}, {"11", "7"}, {"12", "8"}, {"13", "9"},
+	} {
+		points = append(points, s.UpdateBar(bar(int64(i), hl[1], hl[0], hl[1], hl[0]))...)
+	}
+
+	require.Len(t, points, 2)
+	assert.Equal(t, "13", points[0].Price.String())
+	assert.Equal(t, int64(2), points[0].Timestamp)
+	assert.Equal(t, types.PriceDirectionUp, points[0].Direction)
+	assert.Equal(t, "7", points[1].Price.String())
+	assert.Equal(t, int64(4), points[1].Timestamp)
+	assert.Equal(t, types.PriceDirectionUp, s.Trend())
+
+	last, ok := s.Last()
+	require.True(t, ok)
+	assert.Equal(t, uint64(1), last.ID)
+}
+
+func TestNewSwingErrors(t *testing.T) {
+	_, err := NewPercentSwing(decimal.Zero)
+	assert.Error(t, err)
+	_, err = NewPercentSwing(d("1"))
+	assert.Error(t, err)
+	_, err = NewATRSwing(0, d("1"))
+	assert.Error(t, err)
+	_, err = NewATRSwing(14, decimal.Zero)
+	assert.Error(t, err)
+	_, err = NewPivotSwing(0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,86 @@
+package indicator
+
+import (
+	"errors"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/broker"
+)
+
+var _ Indicator[broker.KlineEvent] = (*ADX)(nil)
+
+// ADX 平均趋向指数。+DM、-DM与真实波幅从第二根K线开始计算并做Wilder平滑，
+// 得到+DI、-DI与DX，ADX为DX的Wilder平滑
+type ADX struct {
+	plusDM, minusDM, tr *EMA
+	dx                  *EMA
+	prev                broker.KlineEvent
+	started             bool
+	plusDI, minusDI     decimal.Decimal
+}
+
+// NewADX 创建平均趋向指数，常用周期为14
+func NewADX(period int) (*ADX, error) {
+	if period <= 0 {
+		return nil, errors.New("period must be positive")
+	}
+	return &ADX{
+		plusDM:  newWilder(period),
+		minusDM: newWilder(period),
+		tr:      newWilder(period),
+		dx:      newWilder(period),
+	}, nil
+}
+
+// Update 输入已完结的K线
+func (a *ADX) Update(bar broker.KlineEvent) {
+	if !a.started {
+		a.started = true
+		a.prev = bar
+		return
+	}
+
+	up := bar.High.Sub(a.prev.High)
+	down := a.prev.Low.Sub(bar.Low)
+	plus, minus := decimal.Zero, decimal.Zero
+	if up.GreaterThan(down) && up.IsPositive() {
+		plus = up
+	}
+	if down.GreaterThan(up) && down.IsPositive() {
+		minus = down
+	}
+	a.plusDM.Update(plus)
+	a.minusDM.Update(minus)
+	a.tr.Update(trueRange(bar, a.prev.Close, true))
+	a.prev = bar
+	if !a.tr.Ready() {
+		return
+	}
+
+	// 平滑值之比与Wilder原始的平滑累计值之比相同
+	tr := a.tr.Value()
+	if tr.IsPositive() {
+		a.plusDI = hundred.Mul(a.plusDM.Value()).Div(tr)
+		a.minusDI = hundred.Mul(a.minusDM.Value()).Div(tr)
+	} else {
+		a.plusDI, a.minusDI = decimal.Zero, decimal.Zero
+	}
+	dx := decimal.Zero
+	if sum := a.plusDI.Add(a.minusDI); sum.IsPositive() {
+		dx = hundred.Mul(a.plusDI.Sub(a.minusDI).Abs()).Div(sum)
+	}
+	a.dx.Update(dx)
+}
+
+// Value 返回ADX
+func (a *ADX) Value() decimal.Decimal { return a.dx.Value() }
+
+// PlusDI 返回+DI，输入period+1根K线后有值
+func (a *ADX) PlusDI() decimal.Decimal { return a.plusDI }
+
+// MinusDI 返回-DI，输入period+1根K线后有值
+func (a *ADX) MinusDI() decimal.Decimal { return a.minusDI }
+
+// Ready 输入2*period根K线后就绪
+func (a *ADX) Ready() bool { return a.dx.Ready() }
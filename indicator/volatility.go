@@ -0,0 +1,113 @@
+package indicator
+
+import (
+	"errors"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/broker"
+)
+
+var (
+	_ Indicator[broker.KlineEvent] = (*ATR)(nil)
+	_ Indicator[decimal.Decimal]   = (*Bollinger)(nil)
+)
+
+// ATR 平均真实波幅，使用Wilder平滑。第一根K线的真实波幅为最高价减最低价，
+// 之后为 max(最高-最低, |最高-昨收|, |最低-昨收|)，第一个值为前period个真实波幅的简单平均
+type ATR struct {
+	avg       *EMA
+	prevClose decimal.Decimal
+	started   bool
+}
+
+// NewATR 创建平均真实波幅，常用周期为14
+func NewATR(period int) (*ATR, error) {
+	if period <= 0 {
+		return nil, errors.New("period must be positive")
+	}
+	return &ATR{avg: newWilder(period)}, nil
+}
+
+// Update 输入已完结的K线
+func (a *ATR) Update(bar broker.KlineEvent) {
+	a.avg.Update(trueRange(bar, a.prevClose, a.started))
+	a.prevClose = bar.Close
+	a.started = true
+}
+
+// Value 返回ATR
+func (a *ATR) Value() decimal.Decimal { return a.avg.Value() }
+
+// Ready 输入period根K线后就绪
+func (a *ATR) Ready() bool { return a.avg.Ready() }
+
+// trueRange 真实波幅，hasPrev为false时为最高价减最低价
+func trueRange(bar broker.KlineEvent, prevClose decimal.Decimal, hasPrev bool) decimal.Decimal {
+	tr := bar.High.Sub(bar.Low)
+	if hasPrev {
+		tr = decimal.Max(tr, bar.High.Sub(prevClose).Abs(), bar.Low.Sub(prevClose).Abs())
+	}
+	return tr
+}
+
+// Bollinger 布林带，中轨为简单移动平均，上下轨为中轨加减k倍总体标准差
+type Bollinger struct {
+	period int
+	k      decimal.Decimal
+	window *ring
+	sum    decimal.Decimal
+	sumSq  decimal.Decimal
+	middle decimal.Decimal
+	width  decimal.Decimal
+}
+
+// NewBollinger 创建布林带，常用参数为20、2
+func NewBollinger(period int, k decimal.Decimal) (*Bollinger, error) {
+	if period <= 0 {
+		return nil, errors.New("period must be positive")
+	}
+	if !k.IsPositive() {
+		return nil, errors.New("k must be positive")
+	}
+	return &Bollinger{period: period, k: k, window: newRing(period)}, nil
+}
+
+// Update 输入新价格
+func (b *Bollinger) Update(v decimal.Decimal) {
+	b.sum = b.sum.Add(v)
+	b.sumSq = b.sumSq.Add(v.Mul(v))
+	if old, ok := b.window.push(v); ok {
+		b.sum = b.sum.Sub(old)
+		b.sumSq = b.sumSq.Sub(old.Mul(old))
+	}
+	if !b.window.full {
+		return
+	}
+
+	// decimal的加减乘为精确运算，用平方和计算方差不会产生浮点抵消误差
+	n := decimal.NewFromInt(int64(b.period))
+	b.middle = b.sum.Div(n)
+	variance := b.sumSq.Div(n).Sub(b.middle.Mul(b.middle))
+	b.width = sqrt(variance).Mul(b.k)
+}
+
+// Value 返回中轨
+func (b *Bollinger) Value() decimal.Decimal { return b.middle }
+
+// Upper 返回上轨
+func (b *Bollinger) Upper() decimal.Decimal { return b.middle.Add(b.width) }
+
+// Lower 返回下轨
+func (b *Bollinger) Lower() decimal.Decimal { return b.middle.Sub(b.width) }
+
+// PercentB 返回价格在布林带中的位置，下轨为0，上轨为1
+func (b *Bollinger) PercentB(price decimal.Decimal) decimal.Decimal {
+	if !b.width.IsPositive() {
+		return decimal.NewFromFloat(0.5)
+	}
+	return price.Sub(b.Lower()).Div(b.width.Mul(two))
+}
+
+// Ready 输入period个价格后就绪
+func (b *Bollinger) Ready() bool { return b.window.full }
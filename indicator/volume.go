@@ -0,0 +1,131 @@
+package indicator
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/types"
+)
+
+var (
+	_ Indicator[broker.KlineEvent] = (*VWAP)(nil)
+	_ Indicator[broker.KlineEvent] = (*OBV)(nil)
+)
+
+// VWAP 成交量加权平均价，K线使用典型价格(最高+最低+收盘)/3乘成交量，逐笔成交使用成交价乘成交量。
+// 时段VWAP在每个按UTC对齐的时段开始时重置，锚定VWAP从锚点开始累计且不重置
+type VWAP struct {
+	// session 时段长度（毫秒），为0表示锚定VWAP
+	session int64
+	// anchor 锚定VWAP的起始时间（毫秒）
+	anchor int64
+	// start 当前时段的开始时间
+	start   int64
+	started bool
+	pv      decimal.Decimal
+	volume  decimal.Decimal
+}
+
+// NewVWAP 创建时段VWAP，session为时段长度，例如24小时表示按UTC日重置
+func NewVWAP(session time.Duration) (*VWAP, error) {
+	if session < time.Millisecond {
+		return nil, errors.New("session must be at least 1ms")
+	}
+	return &VWAP{session: session.Milliseconds()}, nil
+}
+
+// NewAnchoredVWAP 创建锚定VWAP，anchor为起始时间（毫秒），早于锚点的数据被忽略
+func NewAnchoredVWAP(anchor int64) *VWAP {
+	return &VWAP{anchor: anchor}
+}
+
+// Update 输入已完结的K线，按开盘时间划分时段
+func (v *VWAP) Update(bar broker.KlineEvent) {
+	v.add(bar.OpenTime, TypicalPrice(bar), bar.Volume)
+}
+
+// UpdateTrade 输入一笔成交
+func (v *VWAP) UpdateTrade(trade types.TradeEvent) {
+	v.add(trade.Timestamp, trade.Price, trade.Size)
+}
+
+func (v *VWAP) add(ts int64, price, volume decimal.Decimal) {
+	if v.session == 0 {
+		if ts < v.anchor {
+			return
+		}
+	} else {
+		m := ts % v.session
+		if m < 0 {
+			m += v.session
+		}
+		start := ts - m
+		if v.started && start < v.start {
+			// 迟到的上一时段数据
+			return
+		}
+		if !v.started || start > v.start {
+			v.start, v.started = start, true
+			v.pv, v.volume = decimal.Zero, decimal.Zero
+		}
+	}
+	v.pv = v.pv.Add(price.Mul(volume))
+	v.volume = v.volume.Add(volume)
+}
+
+// Value 返回VWAP，成交量为0时返回0
+func (v *VWAP) Value() decimal.Decimal {
+	if !v.volume.IsPositive() {
+		return decimal.Zero
+	}
+	return v.pv.Div(v.volume)
+}
+
+// Volume 返回当前时段或锚点以来的累计成交量
+func (v *VWAP) Volume() decimal.Decimal { return v.volume }
+
+// SessionStart 返回当前时段的开始时间（毫秒），锚定VWAP返回锚点
+func (v *VWAP) SessionStart() int64 {
+	if v.session == 0 {
+		return v.anchor
+	}
+	return v.start
+}
+
+// Ready 累计成交量大于0时就绪
+func (v *VWAP) Ready() bool { return v.volume.IsPositive() }
+
+// OBV 能量潮，收盘价上涨时累加成交量，下跌时减去成交量，持平不变，从0开始
+type OBV struct {
+	value     decimal.Decimal
+	prevClose decimal.Decimal
+	started   bool
+}
+
+// NewOBV 创建能量潮指标
+func NewOBV() *OBV {
+	return &OBV{}
+}
+
+// Update 输入已完结的K线
+func (o *OBV) Update(bar broker.KlineEvent) {
+	if o.started {
+		switch bar.Close.Cmp(o.prevClose) {
+		case 1:
+			o.value = o.value.Add(bar.Volume)
+		case -1:
+			o.value = o.value.Sub(bar.Volume)
+		}
+	}
+	o.prevClose = bar.Close
+	o.started = true
+}
+
+// Value 返回OBV
+func (o *OBV) Value() decimal.Decimal { return o.value }
+
+// Ready 输入第一根K线后就绪
+func (o *OBV) Ready() bool { return o.started }
@@ -0,0 +1,427 @@
+// Package ledger 根据订单结果事件按（账户、交易对、持仓方向）统计仓位，
+// 计算开仓均价、已实现盈亏（FIFO或平均成本）、基于标记价格的未实现盈亏和手续费，
+// 并维护仓位状态 NewPosition → OpeningPosition → HoldingPosition → ClosingPosition → ClosedPosition
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/types"
+)
+
+var ErrInvalidEvent = errors.New("ledger: invalid event")
+
+// Handler 仓位变化回调，realized为本次事件产生的已实现盈亏（不含手续费）
+type Handler func(position Position, realized decimal.Decimal)
+
+// Position 仓位快照
+type Position struct {
+	// AccountID 账户ID
+	AccountID string
+	// Exchange 交易所
+	Exchange string
+	// Symbol 交易对
+	Symbol string
+	// PositionSide 持仓方向，单向持仓模式为PositionSideUnknown
+	PositionSide types.PositionSide
+	// PositionID 最近一个带仓位ID的事件的仓位ID
+	PositionID string
+	// MarketType 市场类型
+	MarketType types.MarketType
+	// Status 仓位状态
+	Status types.PositionStatus
+	// Size 净持仓，多为正空为负
+	Size decimal.Decimal
+	// EntryPrice 开仓均价，空仓时为0
+	EntryPrice decimal.Decimal
+	// MarkPrice 最新标记价格，没有标记价格时为0
+	MarkPrice decimal.Decimal
+	// RealizedPnL 本轮仓位的已实现盈亏，不含手续费
+	RealizedPnL decimal.Decimal
+	// UnrealizedPnL 按标记价格计算的未实现盈亏，没有标记价格时为0
+	UnrealizedPnL decimal.Decimal
+	// Fees 本轮仓位的手续费合计，正数表示支出，不区分手续费资产
+	Fees decimal.Decimal
+	// OpenTime 本轮仓位的开仓时间
+	OpenTime int64
+	// UpdateTime 最后一次变化的时间
+	UpdateTime int64
+}
+
+// NetPnL 扣除手续费后的总盈亏
+func (p Position) NetPnL() decimal.Decimal {
+	return p.RealizedPnL.Add(p.UnrealizedPnL).Sub(p.Fees)
+}
+
+// Ledger 仓位账本。订单结果事件的LatestVolume应为成交增量且已去重（例如由oms.Manager发布的事件），
+// 成交价格依次取LatestPrice、LatestQuoteVolume/LatestVolume、AvgPrice、Price。
+// 仓位从空仓开始的开仓到再次空仓为一轮，新一轮开仓时已实现盈亏和手续费重新统计。可以在多个goroutine中并发调用
+type Ledger struct {
+	mu      sync.Mutex
+	method  Method
+	handler Handler
+	symbols map[string]types.Symbol
+	marks   map[string]decimal.Decimal
+	books   map[key]*book
+}
+
+type key struct {
+	account string
+	symbol  string
+	side    types.PositionSide
+}
+
+// book 单个仓位的状态
+type book struct {
+	position Position
+	lots     []lot
+	// orders 未结束的订单，值表示是否为开仓订单
+	orders map[string]bool
+	// filled 本轮是否有过成交
+	filled bool
+}
+
+// lot 开仓批次，qty为正数
+type lot struct {
+	qty   decimal.Decimal
+	price decimal.Decimal
+}
+
+// NewLedger 创建仓位账本
+func NewLedger(opts ...Option) *Ledger {
+	l := &Ledger{
+		symbols: make(map[string]types.Symbol),
+		marks:   make(map[string]decimal.Decimal),
+		books:   make(map[key]*book),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Apply 计入一个订单结果事件，返回更新后的仓位快照
+func (l *Ledger) Apply(event broker.OrderResultEvent) (Position, error) {
+	if event.Symbol == "" {
+		return Position{}, fmt.Errorf("%w: empty symbol", ErrInvalidEvent)
+	}
+	if !event.Side.IsValid() {
+		return Position{}, fmt.Errorf("%w: invalid side %s", ErrInvalidEvent, event.Side)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	k := key{account: event.AccountID, symbol: event.Symbol, side: event.PositionSide}
+	b, ok := l.books[k]
+	if !ok {
+		b = &book{orders: make(map[string]bool)}
+		b.position = Position{
+			AccountID:    event.AccountID,
+			Symbol:       event.Symbol,
+			PositionSide: event.PositionSide,
+			Status:       types.ClosedPosition,
+		}
+		l.books[k] = b
+	}
+	p := &b.position
+	before := *p
+
+	qty := event.LatestVolume
+	if !qty.IsPositive() {
+		qty = decimal.Zero
+	}
+	signed := qty
+	if event.Side == types.SideTypeSell {
+		signed = qty.Neg()
+	}
+
+	id := event.ClientOrderID
+	if id == "" {
+		id = event.OrderID
+	}
+	if _, known := b.orders[id]; !known && id != "" && !terminal(event.State) {
+		opening := p.Size.IsZero() || p.Size.Sign() == sign(event.Side)
+		if opening && b.filled && p.Size.IsZero() {
+			b.reopen()
+		}
+		b.orders[id] = opening
+	}
+
+	realized := decimal.Zero
+	if qty.IsPositive() {
+		if b.filled && p.Size.IsZero() {
+			b.reopen()
+		}
+		if !b.filled {
+			p.OpenTime = event.TransactionTime
+		}
+		b.filled = true
+		realized = b.fill(signed, fillPrice(event), l.method, l.contract(event.Symbol))
+	}
+	if terminal(event.State) {
+		delete(b.orders, id)
+	}
+
+	if event.PositionID != "" {
+		p.PositionID = event.PositionID
+	}
+	if event.Exchange != "" {
+		p.Exchange = event.Exchange
+	}
+	if event.MarketType != types.MarketTypeUnknown {
+		p.MarketType = event.MarketType
+	}
+	p.RealizedPnL = p.RealizedPnL.Add(realized)
+	p.Fees = p.Fees.Add(event.FeeCost)
+	p.Status = b.status()
+	p.UpdateTime = event.TransactionTime
+
+	snapshot := l.snapshot(b)
+	if l.handler != nil && changed(before, *p) {
+		l.handler(snapshot, realized)
+	}
+	return snapshot, nil
+}
+
+// changed 仓位数量、已实现盈亏、手续费或状态是否变化
+func changed(before, after Position) bool {
+	return !before.Size.Equal(after.Size) ||
+		!before.RealizedPnL.Equal(after.RealizedPnL) ||
+		!before.Fees.Equal(after.Fees) ||
+		before.Status != after.Status
+}
+
+// UpdateMarkPrice 更新交易对的标记价格，用于计算未实现盈亏
+func (l *Ledger) UpdateMarkPrice(event broker.MarkPriceEvent) {
+	if !event.Price.IsPositive() {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.marks[event.Symbol] = event.Price
+}
+
+// Position 返回仓位快照
+func (l *Ledger) Position(accountID, symbol string, side types.PositionSide) (Position, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.books[key{account: accountID, symbol: symbol, side: side}]
+	if !ok {
+		return Position{}, false
+	}
+	return l.snapshot(b), true
+}
+
+// Positions 返回账户的所有仓位快照，按交易对和持仓方向排序，accountID为空时返回所有账户的仓位
+func (l *Ledger) Positions(accountID string) []Position {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var positions []Position
+	for k, b := range l.books {
+		if accountID == "" || k.account == accountID {
+			positions = append(positions, l.snapshot(b))
+		}
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		a, b := positions[i], positions[j]
+		if a.AccountID != b.AccountID {
+			return a.AccountID < b.AccountID
+		}
+		if a.Symbol != b.Symbol {
+			return a.Symbol < b.Symbol
+		}
+		return a.PositionSide < b.PositionSide
+	})
+	return positions
+}
+
+// snapshot 返回带有标记价格和未实现盈亏的仓位快照
+func (l *Ledger) snapshot(b *book) Position {
+	p := b.position
+	if mark, ok := l.marks[p.Symbol]; ok {
+		p.MarkPrice = mark
+		p.UnrealizedPnL = l.contract(p.Symbol).pnl(p.Size, p.EntryPrice, mark)
+	}
+	return p
+}
+
+func (l *Ledger) contract(symbol string) contract {
+	c := contract{multiplier: decimal.NewFromInt(1)}
+	s, ok := l.symbols[symbol]
+	if !ok {
+		return c
+	}
+	if s.CtVal.IsPositive() {
+		c.multiplier = s.CtVal
+	}
+	c.inverse = s.Type == types.MarketTypeFuturesCoinMargined || s.Type == types.MarketTypePerpetualCoinMargined
+	return c
+}
+
+// reopen 开始新一轮仓位
+func (b *book) reopen() {
+	b.position.RealizedPnL = decimal.Zero
+	b.position.Fees = decimal.Zero
+	b.position.PositionID = ""
+	b.position.OpenTime = 0
+	b.lots = nil
+	b.filled = false
+}
+
+// fill 计入一笔成交，signed买为正卖为负，返回已实现盈亏
+func (b *book) fill(signed, price decimal.Decimal, method Method, c contract) decimal.Decimal {
+	p := &b.position
+	size := p.Size
+	realized := decimal.Zero
+	if size.IsZero() || size.Sign() == signed.Sign() {
+		b.add(signed.Abs(), price, method, c)
+	} else {
+		closed := decimal.Min(signed.Abs(), size.Abs())
+		realized = b.reduce(closed, price, size.Sign(), c)
+		// 单向持仓模式下反手，剩余数量按成交价开仓
+		if rest := signed.Abs().Sub(closed); rest.IsPositive() {
+			b.lots = nil
+			b.add(rest, price, method, c)
+		}
+	}
+	p.Size = size.Add(signed)
+	p.EntryPrice = average(b.lots, c)
+	return realized
+}
+
+// add 加仓，平均成本法将所有批次合并为一个
+func (b *book) add(qty, price decimal.Decimal, method Method, c contract) {
+	b.lots = append(b.lots, lot{qty: qty, price: price})
+	if method == AverageCost && len(b.lots) > 1 {
+		b.lots = []lot{{qty: total(b.lots), price: average(b.lots, c)}}
+	}
+}
+
+// reduce 按批次先后顺序减仓qty，side为减仓前的持仓方向
+func (b *book) reduce(qty, price decimal.Decimal, side int, c contract) decimal.Decimal {
+	realized := decimal.Zero
+	signed := decimal.NewFromInt(int64(side))
+	for qty.IsPositive() && len(b.lots) > 0 {
+		first := &b.lots[0]
+		matched := decimal.Min(qty, first.qty)
+		realized = realized.Add(c.pnl(matched.Mul(signed), first.price, price))
+		first.qty = first.qty.Sub(matched)
+		qty = qty.Sub(matched)
+		if !first.qty.IsPositive() {
+			b.lots = b.lots[1:]
+		}
+	}
+	return realized
+}
+
+// status 由持仓和未结束的订单确定仓位状态
+func (b *book) status() types.PositionStatus {
+	var opening, closing bool
+	for _, o := range b.orders {
+		if o {
+			opening = true
+		} else {
+			closing = true
+		}
+	}
+	switch {
+	case b.position.Size.IsZero() && opening && !b.filled:
+		return types.NewPosition
+	case b.position.Size.IsZero():
+		return types.ClosedPosition
+	case closing:
+		return types.ClosingPosition
+	case opening:
+		return types.OpeningPosition
+	}
+	return types.HoldingPosition
+}
+
+// contract 合约的盈亏计算参数
+type contract struct {
+	multiplier decimal.Decimal
+	inverse    bool
+}
+
+// pnl 计算数量为size（多为正空为负）的仓位从entry到exit的盈亏。
+// 正向合约 size×乘数×(exit−entry)，反向合约 size×乘数×(1/entry−1/exit)
+func (c contract) pnl(size, entry, exit decimal.Decimal) decimal.Decimal {
+	if entry.IsZero() || exit.IsZero() {
+		return decimal.Zero
+	}
+	size = size.Mul(c.multiplier)
+	if c.inverse {
+		return size.Mul(div(decimal.NewFromInt(1), entry).Sub(div(decimal.NewFromInt(1), exit))).Round(precision)
+	}
+	return size.Mul(exit.Sub(entry))
+}
+
+// average 批次的开仓均价，正向合约按数量加权，反向合约按数量加权调和平均
+func average(lots []lot, c contract) decimal.Decimal {
+	qty := total(lots)
+	if qty.IsZero() {
+		return decimal.Zero
+	}
+	sum := decimal.Zero
+	for _, l := range lots {
+		if c.inverse {
+			sum = sum.Add(div(l.qty, l.price))
+		} else {
+			sum = sum.Add(l.qty.Mul(l.price))
+		}
+	}
+	if c.inverse {
+		return div(qty, sum).Round(precision)
+	}
+	return sum.Div(qty)
+}
+
+// precision 反向合约计算结果保留的小数位数，中间结果使用两倍精度以免倒数的舍入误差累积
+const precision = 16
+
+func div(a, b decimal.Decimal) decimal.Decimal {
+	return a.DivRound(b, 2*precision)
+}
+
+func total(lots []lot) decimal.Decimal {
+	qty := decimal.Zero
+	for _, l := range lots {
+		qty = qty.Add(l.qty)
+	}
+	return qty
+}
+
+// fillPrice 成交价格
+func fillPrice(event broker.OrderResultEvent) decimal.Decimal {
+	switch {
+	case event.LatestPrice.IsPositive():
+		return event.LatestPrice
+	case event.LatestQuoteVolume.IsPositive() && event.LatestVolume.IsPositive():
+		return event.LatestQuoteVolume.Div(event.LatestVolume)
+	case event.AvgPrice.IsPositive():
+		return event.AvgPrice
+	}
+	return event.Price
+}
+
+func sign(side types.SideType) int {
+	if side == types.SideTypeSell {
+		return -1
+	}
+	return 1
+}
+
+func terminal(state types.OrderState) bool {
+	switch state {
+	case types.OrderStateFilled, types.OrderStateCanceled, types.OrderStateRejected:
+		return true
+	}
+	return false
+}
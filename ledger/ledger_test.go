@@ -0,0 +1,223 @@
+package ledger
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/types"
+)
+
+func d(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+// fill 构造一个订单结果事件，volume为0表示没有成交
+func fill(id string, side types.SideType, state types.OrderState, volume, price string) broker.OrderResultEvent {
+	execution := types.ExecutionTypeTrade
+	if volume == "0" {
+		execution = types.ExecutionTypeNew
+	}
+	return broker.OrderResultEvent{
+		AccountID:     "acc",
+		ClientOrderID: id,
+		Symbol:        "BTCUSDT",
+		Side:          side,
+		PositionSide:  types.PositionSideLong,
+		ExecutionType: execution,
+		State:         state,
+		LatestVolume:  d(volume),
+		LatestPrice:   d(price),
+	}
+}
+
+func TestRealizedPnLMethods(t *testing.T) {
+	for _, tc := range []struct {
+		method   Method
+		realized string
+		entry    string
+	}{
+		{AverageCost, "15", "105"},
+		{FIFO, "20", "110"},
+	} {
+		l := NewLedger(WithMethod(tc.method))
+		for _, event := range []broker.OrderResultEvent{
+			fill("1", types.SideTypeBuy, types.OrderStateFilled, "1", "100"),
+			fill("2", types.SideTypeBuy, types.OrderStateFilled, "1", "110"),
+			fill("3", types.SideTypeSell, types.OrderStateFilled, "1", "120"),
+		} {
+			_, err := l.Apply(event)
+			require.NoError(t, err)
+		}
+		p, ok := l.Position("acc", "BTCUSDT", types.PositionSideLong)
+		require.True(t, ok)
+		assert.Equal(t, tc.realized, p.RealizedPnL.String())
+		assert.Equal(t, tc.entry, p.EntryPrice.String())
+		assert.Equal(t, "1", p.Size.String())
+	}
+}
+
+func TestPositionLifecycle(t *testing.T) {
+	var realized []string
+	l := NewLedger(WithHandler(func(position Position, pnl decimal.Decimal) {
+		realized = append(realized, pnl.String())
+	}))
+	apply := func(event broker.OrderResultEvent) Position {
+		event.FeeCost = d("0.1")
+		event.PositionID = "p1"
+		p, err := l.Apply(event)
+		require.NoError(t, err)
+		return p
+	}
+
+	assert.Equal(t, types.NewPosition, apply(fill("1", types.SideTypeBuy, types.OrderStateNew, "0", "0")).Status)
+	assert.Equal(t, types.OpeningPosition, apply(fill("1", types.SideTypeBuy, types.OrderStatePartiallyFilled, "1", "100")).Status)
+	p := apply(fill("1", types.SideTypeBuy, types.OrderStateFilled, "1", "102"))
+	assert.Equal(t, types.HoldingPosition, p.Status)
+	assert.Equal(t, "101", p.EntryPrice.String())
+
+	assert.Equal(t, types.ClosingPosition, apply(fill("2", types.SideTypeSell, types.OrderStateNew, "0", "0")).Status)
+	assert.Equal(t, types.ClosingPosition, apply(fill("2", types.SideTypeSell, types.OrderStatePartiallyFilled, "1", "111")).Status)
+	// 平仓单撤销，剩余仓位继续持有
+	assert.Equal(t, types.HoldingPosition, apply(fill("2", types.SideTypeSell, types.OrderStateCanceled, "0", "0")).Status)
+
+	p = apply(fill("3", types.SideTypeSell, types.OrderStateFilled, "1", "91"))
+	assert.Equal(t, types.ClosedPosition, p.Status)
+	assert.True(t, p.Size.IsZero())
+	assert.True(t, p.EntryPrice.IsZero())
+	assert.Equal(t, "0", p.RealizedPnL.String())
+	assert.Equal(t, "0.7", p.Fees.String())
+	assert.Equal(t, "p1", p.PositionID)
+	assert.Equal(t, []string{"0", "0", "0", "0", "10", "0", "-10"}, realized)
+
+	// 新一轮开仓重新统计
+	p = apply(fill("4", types.SideTypeBuy, types.OrderStateNew, "0", "0"))
+	assert.Equal(t, types.NewPosition, p.Status)
+	assert.True(t, p.Fees.Equal(d("0.1")))
+	p = apply(fill("4", types.SideTypeBuy, types.OrderStateFilled, "2", "50"))
+	assert.Equal(t, types.HoldingPosition, p.Status)
+	assert.Equal(t, "0.2", p.Fees.String())
+
+	// 开仓单未成交即撤销
+	l2 := NewLedger()
+	_, err := l2.Apply(fill("1", types.SideTypeBuy, types.OrderStateNew, "0", "0"))
+	require.NoError(t, err)
+	p, err = l2.Apply(fill("1", types.SideTypeBuy, types.OrderStateCanceled, "0", "0"))
+	require.NoError(t, err)
+	assert.Equal(t, types.ClosedPosition, p.Status)
+}
+
+func TestHandlerOnlyOnChange(t *testing.T) {
+	var statuses []types.PositionStatus
+	l := NewLedger(WithHandler(func(position Position, pnl decimal.Decimal) {
+		statuses = append(statuses, position.Status)
+	}))
+	apply := func(event broker.OrderResultEvent) {
+		_, err := l.Apply(event)
+		require.NoError(t, err)
+	}
+
+	apply(fill("1", types.SideTypeBuy, types.OrderStateNew, "0", "0"))
+	// 重复的NEW和没有成交的部分成交状态不改变仓位
+	apply(fill("1", types.SideTypeBuy, types.OrderStateNew, "0", "0"))
+	apply(fill("1", types.SideTypeBuy, types.OrderStatePartiallyFilled, "0", "0"))
+	apply(fill("1", types.SideTypeBuy, types.OrderStatePartiallyFilled, "1", "100"))
+	// 只有手续费的事件也会通知
+	fee := fill("1", types.SideTypeBuy, types.OrderStatePartiallyFilled, "0", "0")
+	fee.FeeCost = d("0.1")
+	apply(fee)
+	apply(fill("1", types.SideTypeBuy, types.OrderStateCanceled, "0", "0"))
+
+	assert.Equal(t, []types.PositionStatus{
+		types.NewPosition,
+		types.OpeningPosition,
+		types.OpeningPosition,
+		types.HoldingPosition,
+	}, statuses)
+}
+
+func TestUnrealizedPnL(t *testing.T) {
+	l := NewLedger()
+	short := fill("1", types.SideTypeSell, types.OrderStateFilled, "2", "100")
+	short.PositionSide = types.PositionSideShort
+	_, err := l.Apply(short)
+	require.NoError(t, err)
+
+	l.UpdateMarkPrice(broker.MarkPriceEvent{Symbol: "BTCUSDT", Price: d("90")})
+	p, ok := l.Position("acc", "BTCUSDT", types.PositionSideShort)
+	require.True(t, ok)
+	assert.Equal(t, "-2", p.Size.String())
+	assert.Equal(t, "90", p.MarkPrice.String())
+	assert.Equal(t, "20", p.UnrealizedPnL.String())
+
+	cover := fill("2", types.SideTypeBuy, types.OrderStateFilled, "1", "95")
+	cover.PositionSide = types.PositionSideShort
+	cover.FeeCost = d("1")
+	p, err = l.Apply(cover)
+	require.NoError(t, err)
+	assert.Equal(t, "5", p.RealizedPnL.String())
+	assert.Equal(t, "10", p.UnrealizedPnL.String())
+	assert.Equal(t, "14", p.NetPnL().String())
+
+	_, ok = l.Position("acc", "BTCUSDT", types.PositionSideLong)
+	assert.False(t, ok)
+	assert.Len(t, l.Positions("acc"), 1)
+	assert.Empty(t, l.Positions("other"))
+}
+
+func TestInverseContract(t *testing.T) {
+	l := NewLedger(WithSymbols(types.Symbol{
+		OriginalSymbol: "BTCUSD_PERP",
+		Type:           types.MarketTypePerpetualCoinMargined,
+		CtVal:          d("100"),
+	}))
+	buy := func(price string) broker.OrderResultEvent {
+		e := fill("", types.SideTypeBuy, types.OrderStateFilled, "10", price)
+		e.Symbol = "BTCUSD_PERP"
+		return e
+	}
+	_, err := l.Apply(buy("40000"))
+	require.NoError(t, err)
+	p, err := l.Apply(buy("60000"))
+	require.NoError(t, err)
+	// 调和平均 20 / (10/40000 + 10/60000)
+	assert.Equal(t, "48000", p.EntryPrice.String())
+
+	sell := buy("50000")
+	sell.Side = types.SideTypeSell
+	sell.LatestVolume = d("20")
+	p, err = l.Apply(sell)
+	require.NoError(t, err)
+	// 2000 × (1/48000 − 1/50000)
+	assert.Equal(t, "0.0016666666666667", p.RealizedPnL.StringFixed(16))
+	assert.Equal(t, types.ClosedPosition, p.Status)
+}
+
+func TestOneWayFlip(t *testing.T) {
+	l := NewLedger()
+	long := fill("1", types.SideTypeBuy, types.OrderStateFilled, "1", "100")
+	long.PositionSide = types.PositionSideUnknown
+	_, err := l.Apply(long)
+	require.NoError(t, err)
+
+	flip := fill("2", types.SideTypeSell, types.OrderStateFilled, "3", "0")
+	flip.PositionSide = types.PositionSideUnknown
+	flip.LatestQuoteVolume = d("330")
+	p, err := l.Apply(flip)
+	require.NoError(t, err)
+	assert.Equal(t, "-2", p.Size.String())
+	assert.Equal(t, "110", p.EntryPrice.String())
+	assert.Equal(t, "10", p.RealizedPnL.String())
+	assert.Equal(t, types.HoldingPosition, p.Status)
+}
+
+func TestApplyInvalidEvent(t *testing.T) {
+	l := NewLedger()
+	_, err := l.Apply(broker.OrderResultEvent{Side: types.SideTypeBuy})
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+	_, err = l.Apply(broker.OrderResultEvent{Symbol: "BTCUSDT"})
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+}
@@ -0,0 +1,49 @@
+package ledger
+
+import (
+	"github.com/go-gotop/gotop/types"
+)
+
+// Option 是Ledger的配置选项
+type Option func(l *Ledger)
+
+// Method 已实现盈亏的成本计算方法
+type Method int
+
+const (
+	// AverageCost 平均成本法，加仓时按数量加权合并开仓价格，与交易所显示的开仓均价一致
+	AverageCost Method = iota
+	// FIFO 先进先出法，减仓时按开仓先后顺序匹配
+	FIFO
+)
+
+// WithMethod 设置成本计算方法，默认为AverageCost
+func WithMethod(method Method) Option {
+	return func(l *Ledger) {
+		l.method = method
+	}
+}
+
+// WithSymbols 登记标的物。CtVal大于0的交易对成交数量按张计算，
+// 币本位合约（FuturesCoinMargined、PerpetualCoinMargined）按反向合约计算盈亏，盈亏以币计价。
+// 成交的Symbol与OriginalSymbol或UnifiedSymbol相同即可匹配，未登记的交易对成交数量按币计算
+func WithSymbols(symbols ...types.Symbol) Option {
+	return func(l *Ledger) {
+		for _, s := range symbols {
+			if s.OriginalSymbol != "" {
+				l.symbols[s.OriginalSymbol] = s
+			}
+			if s.UnifiedSymbol != "" {
+				l.symbols[s.UnifiedSymbol] = s
+			}
+		}
+	}
+}
+
+// WithHandler 设置仓位变化回调，订单结果事件改变了仓位数量、已实现盈亏、手续费或状态时调用一次。
+// 回调在持有锁时同步执行，不能再调用Ledger的方法
+func WithHandler(handler Handler) Option {
+	return func(l *Ledger) {
+		l.handler = handler
+	}
+}
@@ -0,0 +1,529 @@
+// Package oms 管理订单的生命周期：为策略信号生成幂等的客户订单ID并调用 OrderManager 下单，
+// 根据用户数据流的订单更新驱动订单状态机，处理部分成交、超时撤单以及重复或乱序的更新，
+// 并以 broker.OrderResultEvent 发布订单结果
+package oms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/clock"
+	"github.com/go-gotop/gotop/exchange"
+	"github.com/go-gotop/gotop/types"
+)
+
+var (
+	ErrInvalidSignal = errors.New("oms: invalid signal")
+	ErrUnknownOrder  = errors.New("oms: unknown order")
+)
+
+// ResultHandler 订单结果回调
+type ResultHandler func(event broker.OrderResultEvent)
+
+// Manager 订单管理。每个订单按以下规则发布结果：
+//  1. 交易所确认（下单请求返回或用户数据流的NEW，以先到者为准）时发布一次NEW；
+//  2. 下单请求被交易所明确拒绝（exchange.ErrOrderRejected）且用户数据流没有确认订单时发布REJECTED，
+//     其他下单错误（例如超时）无法确定订单是否存在，订单保持OrderStateUnknown，等待用户数据流确认，
+//     设置了撤单超时时超时后按ClientOrderID撤单，撤单成功时发布CANCELED；
+//  3. 用户数据流的更新按累计成交数量和状态先后去重，发布事件的LatestVolume为相对上一次发布的成交增量。
+//
+// 已结束的订单保留到调用Prune为止，用于识别重复投递的信号。可以在多个goroutine中并发调用
+type Manager struct {
+	mu          sync.Mutex
+	orders      exchange.OrderManager
+	handler     ResultHandler
+	clock       clock.Clock
+	credentials func(accountID string) (Credentials, error)
+	onError     func(event broker.FrameErrorEvent)
+	riskCheck   func(signal broker.StrategySignalEvent) error
+	symbols     map[string]types.Symbol
+	prefix      string
+	timeout     time.Duration
+	interval    time.Duration
+
+	byClientID map[string]*Order
+	byOrderID  map[string]*Order
+}
+
+// NewManager 创建订单管理器，handler在持有锁时同步执行，不能再调用Manager的方法
+func NewManager(orders exchange.OrderManager, handler ResultHandler, opts ...Option) (*Manager, error) {
+	if orders == nil {
+		return nil, errors.New("order manager cannot be nil")
+	}
+	if handler == nil {
+		return nil, errors.New("handler cannot be nil")
+	}
+	m := &Manager{
+		orders:     orders,
+		handler:    handler,
+		symbols:    make(map[string]types.Symbol),
+		prefix:     "gt",
+		interval:   time.Second,
+		byClientID: make(map[string]*Order),
+		byOrderID:  make(map[string]*Order),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.interval <= 0 {
+		return nil, errors.New("sweep interval must be positive")
+	}
+	m.clock = clock.OrReal(m.clock)
+	return m, nil
+}
+
+// ClientOrderID 返回信号对应的客户订单ID。信号带有ClientOrderID时原样返回，
+// 否则由信号内容生成，同一信号重复投递时得到相同的ID
+func (m *Manager) ClientOrderID(signal broker.StrategySignalEvent) string {
+	if signal.ClientOrderID != "" {
+		return signal.ClientOrderID
+	}
+	return clientOrderID(m.prefix, signal)
+}
+
+// Submit 提交策略信号。客户订单ID已存在时不会重复下单，直接返回已有订单的快照。
+// 设置了风控检查时，信号在生成客户订单ID后检查，未通过时不下单并返回检查的错误。
+// 下单被拒绝时订单状态为Rejected并返回错误，其他下单错误返回错误且订单状态保持Unknown
+func (m *Manager) Submit(ctx context.Context, signal broker.StrategySignalEvent) (Order, error) {
+	if !signal.Size.IsPositive() {
+		return Order{}, fmt.Errorf("%w: size must be positive", ErrInvalidSignal)
+	}
+	if signal.Symbol == "" {
+		return Order{}, fmt.Errorf("%w: empty symbol", ErrInvalidSignal)
+	}
+	signal.ClientOrderID = m.ClientOrderID(signal)
+	credentials, err := m.credentialsFor(signal.AccountID)
+	if err != nil {
+		return Order{}, err
+	}
+
+	m.mu.Lock()
+	if o, ok := m.byClientID[signal.ClientOrderID]; ok {
+		snapshot := *o
+		m.mu.Unlock()
+		return snapshot, nil
+	}
+	if m.riskCheck != nil {
+		if err := m.riskCheck(signal); err != nil {
+			m.mu.Unlock()
+			return Order{}, err
+		}
+	}
+	now := m.now()
+	o := &Order{ClientOrderID: signal.ClientOrderID, Signal: signal, CreatedAt: now, UpdatedAt: now}
+	m.byClientID[o.ClientOrderID] = o
+	req := &exchange.CreateOrderRequest{
+		APIKey:        credentials.APIKey,
+		SecretKey:     credentials.SecretKey,
+		Passphrase:    credentials.Passphrase,
+		ClientOrderID: o.ClientOrderID,
+		OrderTime:     now,
+		Symbol:        m.symbol(signal),
+		OrderType:     signal.OrderType,
+		MarketType:    signal.MarketType,
+		Side:          signal.Side,
+		PositionSide:  signal.PositionSide,
+		Price:         signal.Price,
+		Size:          signal.Size,
+		SizeUnit:      signal.SizeUnit,
+		TimeInForce:   signal.TimeInForce,
+	}
+	m.mu.Unlock()
+
+	resp, err := m.orders.CreateOrder(ctx, req)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		if o.State != types.OrderStateUnknown {
+			// 下单请求超时等情况下，用户数据流已经确认了订单
+			return *o, nil
+		}
+		err = fmt.Errorf("create order %s: %w", o.ClientOrderID, err)
+		m.fail(o, err)
+		if !errors.Is(err, exchange.ErrOrderRejected) {
+			// 订单可能已被交易所接受，以用户数据流为准
+			return *o, err
+		}
+		o.State = types.OrderStateRejected
+		o.rejected = true
+		o.UpdatedAt = m.now()
+		m.handler(m.event(o, types.ExecutionTypeRejected))
+		return *o, err
+	}
+	if resp != nil && resp.OrderID != "" && o.OrderID == "" {
+		m.setOrderID(o, resp.OrderID)
+	}
+	if o.State == types.OrderStateUnknown {
+		o.State = types.OrderStateNew
+		o.UpdatedAt = m.now()
+		m.handler(m.event(o, types.ExecutionTypeNew))
+	}
+	return *o, nil
+}
+
+// Update 处理用户数据流的订单更新，按ClientOrderID或OrderID匹配订单。
+// 更新需带有累计成交数量FilledVolume；累计成交数量减少或状态没有推进的更新视为重复或乱序而丢弃，
+// 订单结束后只接受累计成交数量增加的更新（迟到的成交），订单状态保持不变。
+// 例外是本地判定为Rejected的订单收到交易所的确认时，以交易所为准恢复订单。
+// 带有TradeID的更新按成交ID去重计入手续费，因乱序被丢弃时也会计入，并发布一个成交数量为0的TRADE事件
+func (m *Manager) Update(update broker.OrderResultEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o := m.lookup(update)
+	if o == nil {
+		return fmt.Errorf("%w: client order id %q, order id %q", ErrUnknownOrder, update.ClientOrderID, update.OrderID)
+	}
+	if update.OrderID != "" && o.OrderID == "" {
+		m.setOrderID(o, update.OrderID)
+	}
+
+	fee := update.FeeCost
+	if update.TradeID != "" {
+		if _, ok := o.trades[update.TradeID]; ok {
+			fee = decimal.Zero
+		} else {
+			if o.trades == nil {
+				o.trades = make(map[string]struct{})
+			}
+			o.trades[update.TradeID] = struct{}{}
+		}
+	}
+
+	filled := update.FilledVolume
+	if filled.LessThan(o.FilledVolume) {
+		m.lateFee(o, update, fee)
+		return nil
+	}
+	increased := filled.GreaterThan(o.FilledVolume)
+	state := update.State
+	if state == types.OrderStateUnknown {
+		state = stateOf(update.ExecutionType)
+	}
+	switch {
+	case o.rejected && state != types.OrderStateUnknown:
+		o.rejected = false
+		if state == types.OrderStateRejected {
+			return nil
+		}
+		o.State = state
+	case terminal(o.State):
+		if !increased {
+			m.lateFee(o, update, fee)
+			return nil
+		}
+	case rank(state) > rank(o.State):
+		o.State = state
+	case !increased:
+		m.lateFee(o, update, fee)
+		return nil
+	}
+	if rank(o.State) < rank(types.OrderStatePartiallyFilled) && filled.IsPositive() {
+		o.State = types.OrderStatePartiallyFilled
+	}
+
+	delta := filled.Sub(o.FilledVolume)
+	quoteDelta := update.LatestPrice.Mul(delta)
+	if update.FilledQuoteVolume.GreaterThan(o.FilledQuoteVolume) {
+		quoteDelta = update.FilledQuoteVolume.Sub(o.FilledQuoteVolume)
+	}
+	o.FilledVolume = filled
+	o.FilledQuoteVolume = o.FilledQuoteVolume.Add(quoteDelta)
+	o.FeeCost = o.FeeCost.Add(fee)
+	o.UpdatedAt = m.now()
+
+	m.handler(m.merge(o, update, fee, delta, quoteDelta))
+	return nil
+}
+
+// lateFee 计入被丢弃的更新中尚未计入的手续费，只有带TradeID的更新可以去重
+func (m *Manager) lateFee(o *Order, update broker.OrderResultEvent, fee decimal.Decimal) {
+	if update.TradeID == "" || fee.IsZero() {
+		return
+	}
+	o.FeeCost = o.FeeCost.Add(fee)
+	o.UpdatedAt = m.now()
+	e := m.merge(o, update, fee, decimal.Zero, decimal.Zero)
+	e.ExecutionType = types.ExecutionTypeTrade
+	m.handler(e)
+}
+
+// CancelExpired 撤销提交后超过撤单超时仍未结束的订单，返回发出撤单请求的订单数。
+// 已发出撤单请求的订单不会重复撤单，撤单失败时在下一次调用时重试。订单以用户数据流的CANCELED为准结束，
+// 例外是交易所始终没有确认的订单（OrderStateUnknown）按ClientOrderID撤单，撤单成功时直接发布CANCELED
+func (m *Manager) CancelExpired(ctx context.Context) int {
+	if m.timeout <= 0 {
+		return 0
+	}
+
+	type cancel struct {
+		order   *Order
+		unknown bool
+		req     exchange.CancelOrderRequest
+	}
+	var cancels []cancel
+	m.mu.Lock()
+	deadline := m.now() - m.timeout.Milliseconds()
+	for _, o := range m.byClientID {
+		if terminal(o.State) || o.CancelRequested || o.CreatedAt > deadline {
+			continue
+		}
+		o.CancelRequested = true
+		cancels = append(cancels, cancel{order: o, unknown: o.State == types.OrderStateUnknown, req: exchange.CancelOrderRequest{
+			Symbol:        m.symbol(o.Signal),
+			MarketType:    o.Signal.MarketType,
+			OrderID:       o.OrderID,
+			ClientOrderID: o.ClientOrderID,
+		}})
+	}
+	m.mu.Unlock()
+
+	for _, c := range cancels {
+		credentials, err := m.credentialsFor(c.order.Signal.AccountID)
+		if err == nil {
+			c.req.APIKey = credentials.APIKey
+			c.req.SecretKey = credentials.SecretKey
+			c.req.Passphrase = credentials.Passphrase
+			var resp *exchange.CancelOrderResponse
+			resp, err = m.orders.CancelOrder(ctx, &c.req)
+			if err == nil && c.unknown {
+				m.canceled(c.order, resp)
+			}
+		}
+		if err != nil {
+			m.mu.Lock()
+			c.order.CancelRequested = false
+			m.fail(c.order, fmt.Errorf("cancel order %s: %w", c.order.ClientOrderID, err))
+			m.mu.Unlock()
+		}
+	}
+	return len(cancels)
+}
+
+// canceled 交易所没有确认的订单撤单成功，说明订单存在且已被撤销，
+// 用户数据流仍没有确认时结束订单，之后迟到的成交仍会计入
+func (m *Manager) canceled(o *Order, resp *exchange.CancelOrderResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if o.State != types.OrderStateUnknown {
+		return
+	}
+	if resp != nil && resp.OrderID != "" && o.OrderID == "" {
+		m.setOrderID(o, resp.OrderID)
+	}
+	o.State = types.OrderStateCanceled
+	o.UpdatedAt = m.now()
+	m.handler(m.event(o, types.ExecutionTypeCanceled))
+}
+
+// Run 按WithSweepInterval设置的间隔撤销超时订单，直到ctx结束
+func (m *Manager) Run(ctx context.Context) error {
+	ticker := m.clock.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+			m.CancelExpired(ctx)
+		}
+	}
+}
+
+// Get 返回订单快照
+func (m *Manager) Get(clientOrderID string) (Order, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	o, ok := m.byClientID[clientOrderID]
+	if !ok {
+		return Order{}, false
+	}
+	return *o, true
+}
+
+// Open 返回所有未结束订单的快照，按提交时间升序
+func (m *Manager) Open() []Order {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var orders []Order
+	for _, o := range m.byClientID {
+		if !terminal(o.State) {
+			orders = append(orders, *o)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].CreatedAt < orders[j].CreatedAt
+	})
+	return orders
+}
+
+// Prune 删除结束时间不晚于age之前的订单，返回删除的订单数。删除后同一信号可以再次提交
+func (m *Manager) Prune(age time.Duration) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	before := m.now() - age.Milliseconds()
+	n := 0
+	for id, o := range m.byClientID {
+		if terminal(o.State) && o.UpdatedAt <= before {
+			delete(m.byClientID, id)
+			if o.OrderID != "" {
+				delete(m.byOrderID, o.OrderID)
+			}
+			n++
+		}
+	}
+	return n
+}
+
+func (m *Manager) now() int64 {
+	return m.clock.Now().UnixMilli()
+}
+
+func (m *Manager) credentialsFor(accountID string) (Credentials, error) {
+	if m.credentials == nil {
+		return Credentials{}, nil
+	}
+	credentials, err := m.credentials(accountID)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("credentials for account %s: %w", accountID, err)
+	}
+	return credentials, nil
+}
+
+func (m *Manager) symbol(signal broker.StrategySignalEvent) types.Symbol {
+	if s, ok := m.symbols[signal.Symbol]; ok {
+		return s
+	}
+	return types.Symbol{OriginalSymbol: signal.Symbol, Type: signal.MarketType}
+}
+
+func (m *Manager) lookup(update broker.OrderResultEvent) *Order {
+	if o, ok := m.byClientID[update.ClientOrderID]; ok {
+		return o
+	}
+	if update.OrderID != "" {
+		return m.byOrderID[update.OrderID]
+	}
+	return nil
+}
+
+func (m *Manager) setOrderID(o *Order, orderID string) {
+	o.OrderID = orderID
+	m.byOrderID[orderID] = o
+}
+
+// fail 调用错误回调
+func (m *Manager) fail(o *Order, err error) {
+	if m.onError == nil {
+		return
+	}
+	m.onError(broker.FrameErrorEvent{
+		Error:         err.Error(),
+		PositionID:    o.Signal.PositionID,
+		TransactionID: o.Signal.TransactionID,
+		AccountID:     o.Signal.AccountID,
+		Timestamp:     m.now(),
+		ClientOrderID: o.ClientOrderID,
+	})
+}
+
+// event 由订单和信号生成订单结果事件
+func (m *Manager) event(o *Order, execution types.ExecutionType) broker.OrderResultEvent {
+	s := o.Signal
+	return broker.OrderResultEvent{
+		AccountID:         s.AccountID,
+		TransactionID:     s.TransactionID,
+		Exchange:          s.Exchange,
+		PositionID:        s.PositionID,
+		ClientOrderID:     o.ClientOrderID,
+		Symbol:            s.Symbol,
+		OrderID:           o.OrderID,
+		TransactionTime:   o.UpdatedAt,
+		CreatedBy:         s.CreatedBy,
+		MarketType:        s.MarketType,
+		ExecutionType:     execution,
+		State:             o.State,
+		PositionSide:      s.PositionSide,
+		Side:              s.Side,
+		Type:              s.OrderType,
+		Volume:            s.Size,
+		Price:             s.Price,
+		FilledVolume:      o.FilledVolume,
+		FilledQuoteVolume: o.FilledQuoteVolume,
+		AvgPrice:          o.AvgPrice(),
+	}
+}
+
+// merge 以交易所的更新为准，缺少的字段由信号补全，成交数量、手续费和状态使用去重后的结果
+func (m *Manager) merge(o *Order, update broker.OrderResultEvent, fee, delta, quoteDelta decimal.Decimal) broker.OrderResultEvent {
+	e := m.event(o, update.ExecutionType)
+	if e.ExecutionType == types.ExecutionTypeUnknown {
+		e.ExecutionType = executionOf(o.State, delta)
+	}
+	if update.Exchange != "" {
+		e.Exchange = update.Exchange
+	}
+	if update.TransactionTime != 0 {
+		e.TransactionTime = update.TransactionTime
+	}
+	if update.MarketType != types.MarketTypeUnknown {
+		e.MarketType = update.MarketType
+	}
+	if !update.Volume.IsZero() {
+		e.Volume = update.Volume
+	}
+	if !update.Price.IsZero() {
+		e.Price = update.Price
+	}
+	if !update.QuoteVolume.IsZero() {
+		e.QuoteVolume = update.QuoteVolume
+	}
+	if !update.AvgPrice.IsZero() {
+		e.AvgPrice = update.AvgPrice
+	}
+	e.Status = update.Status
+	e.FeeAsset = update.FeeAsset
+	e.TradeID = update.TradeID
+	e.FeeCost = fee
+	e.By = update.By
+	e.LatestPrice = update.LatestPrice
+	e.LatestVolume = delta
+	e.LatestQuoteVolume = quoteDelta
+	return e
+}
+
+// stateOf 更新没有订单状态时由执行类型推断
+func stateOf(execution types.ExecutionType) types.OrderState {
+	switch execution {
+	case types.ExecutionTypeNew:
+		return types.OrderStateNew
+	case types.ExecutionTypeTrade:
+		return types.OrderStatePartiallyFilled
+	case types.ExecutionTypeCanceled, types.ExecutionTypeExpired:
+		return types.OrderStateCanceled
+	case types.ExecutionTypeRejected:
+		return types.OrderStateRejected
+	}
+	return types.OrderStateUnknown
+}
+
+// executionOf 更新没有执行类型时由订单状态和成交增量推断
+func executionOf(state types.OrderState, delta decimal.Decimal) types.ExecutionType {
+	switch {
+	case delta.IsPositive():
+		return types.ExecutionTypeTrade
+	case state == types.OrderStateCanceled:
+		return types.ExecutionTypeCanceled
+	case state == types.OrderStateRejected:
+		return types.ExecutionTypeRejected
+	}
+	return types.ExecutionTypeNew
+}
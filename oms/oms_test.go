@@ -0,0 +1,503 @@
+package oms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/clock"
+	"github.com/go-gotop/gotop/exchange"
+	"github.com/go-gotop/gotop/risk"
+	"github.com/go-gotop/gotop/types"
+)
+
+type fakeOrders struct {
+	mu        sync.Mutex
+	creates   []*exchange.CreateOrderRequest
+	cancels   []*exchange.CancelOrderRequest
+	createErr error
+	cancelErr error
+	// onCreate 在下单请求返回前调用，模拟用户数据流先于下单响应到达
+	onCreate func(req *exchange.CreateOrderRequest)
+}
+
+func (f *fakeOrders) CreateOrder(ctx context.Context, req *exchange.CreateOrderRequest) (*exchange.CreateOrderResponse, error) {
+	f.mu.Lock()
+	f.creates = append(f.creates, req)
+	f.mu.Unlock()
+	if f.onCreate != nil {
+		f.onCreate(req)
+	}
+	if f.createErr != nil {
+		return nil, f.createErr
+	}
+	return &exchange.CreateOrderResponse{Symbol: req.Symbol.OriginalSymbol, OrderID: "1001", ClientOrderID: req.ClientOrderID}, nil
+}
+
+func (f *fakeOrders) CancelOrder(ctx context.Context, req *exchange.CancelOrderRequest) (*exchange.CancelOrderResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cancels = append(f.cancels, req)
+	if f.cancelErr != nil {
+		return nil, f.cancelErr
+	}
+	return &exchange.CancelOrderResponse{OrderID: req.OrderID, ClientOrderID: req.ClientOrderID}, nil
+}
+
+func (f *fakeOrders) GetOrder(ctx context.Context, req *exchange.GetOrderRequest) (*exchange.GetOrderResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func d(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func testSignal() broker.StrategySignalEvent {
+	return broker.StrategySignalEvent{
+		AccountID:     "acc",
+		StrategyID:    "grid",
+		TransactionID: "tx1",
+		Timestamp:     1_000,
+		Symbol:        "BTCUSDT",
+		Side:          types.SideTypeBuy,
+		OrderType:     types.OrderTypeLimit,
+		MarketType:    types.MarketTypePerpetualUSDMargined,
+		Size:          d("3"),
+		SizeUnit:      types.SizeUnitCoin,
+		Price:         d("100"),
+	}
+}
+
+func newTestManager(t *testing.T, orders *fakeOrders, opts ...Option) (*Manager, *[]broker.OrderResultEvent) {
+	t.Helper()
+	var events []broker.OrderResultEvent
+	m, err := NewManager(orders, func(event broker.OrderResultEvent) {
+		events = append(events, event)
+	}, opts...)
+	require.NoError(t, err)
+	return m, &events
+}
+
+func TestSubmitIdempotent(t *testing.T) {
+	orders := &fakeOrders{}
+	m, events := newTestManager(t, orders,
+		WithCredentials(func(accountID string) (Credentials, error) {
+			return Credentials{APIKey: accountID + "-key"}, nil
+		}),
+		WithSymbols(types.Symbol{OriginalSymbol: "BTCUSDT", UnifiedSymbol: "BTC-USDT-SWAP", PricePrecision: 1}),
+	)
+
+	signal := testSignal()
+	id := m.ClientOrderID(signal)
+	assert.Len(t, id, clientOrderIDLength)
+	assert.Regexp(t, "^gt[0-9a-f]+$", id)
+
+	o, err := m.Submit(context.Background(), signal)
+	require.NoError(t, err)
+	assert.Equal(t, id, o.ClientOrderID)
+	assert.Equal(t, "1001", o.OrderID)
+	assert.Equal(t, types.OrderStateNew, o.State)
+
+	// 重复投递的信号不会再次下单
+	again, err := m.Submit(context.Background(), signal)
+	require.NoError(t, err)
+	assert.Equal(t, o, again)
+	require.Len(t, orders.creates, 1)
+
+	req := orders.creates[0]
+	assert.Equal(t, "acc-key", req.APIKey)
+	assert.Equal(t, int32(1), req.Symbol.PricePrecision)
+	assert.Equal(t, "3", req.Size.String())
+
+	require.Len(t, *events, 1)
+	assert.Equal(t, types.ExecutionTypeNew, (*events)[0].ExecutionType)
+	assert.Equal(t, "tx1", (*events)[0].TransactionID)
+
+	// 信号内容不同时生成不同的ID，指定的ClientOrderID原样使用
+	other := testSignal()
+	other.Timestamp = 2_000
+	assert.NotEqual(t, id, m.ClientOrderID(other))
+	other.ClientOrderID = "custom"
+	assert.Equal(t, "custom", m.ClientOrderID(other))
+
+	_, err = m.Submit(context.Background(), broker.StrategySignalEvent{Symbol: "BTCUSDT"})
+	assert.ErrorIs(t, err, ErrInvalidSignal)
+}
+
+func TestSubmitRejected(t *testing.T) {
+	var errs []broker.FrameErrorEvent
+	orders := &fakeOrders{createErr: fmt.Errorf("%w: insufficient margin", exchange.ErrOrderRejected)}
+	m, events := newTestManager(t, orders, WithErrorHandler(func(event broker.FrameErrorEvent) {
+		errs = append(errs, event)
+	}))
+
+	o, err := m.Submit(context.Background(), testSignal())
+	require.Error(t, err)
+	assert.Equal(t, types.OrderStateRejected, o.State)
+	require.Len(t, *events, 1)
+	assert.Equal(t, types.ExecutionTypeRejected, (*events)[0].ExecutionType)
+	assert.Equal(t, types.OrderStateRejected, (*events)[0].State)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error, "insufficient margin")
+	assert.Equal(t, o.ClientOrderID, errs[0].ClientOrderID)
+
+	_, err = m.Submit(context.Background(), testSignal())
+	assert.NoError(t, err)
+	assert.Len(t, orders.creates, 1)
+
+	// 清理后可以重新提交
+	assert.Equal(t, 1, m.Prune(0))
+	orders.createErr = nil
+	o, err = m.Submit(context.Background(), testSignal())
+	require.NoError(t, err)
+	assert.Equal(t, types.OrderStateNew, o.State)
+}
+
+func TestSubmitAmbiguous(t *testing.T) {
+	var errs []broker.FrameErrorEvent
+	orders := &fakeOrders{createErr: context.DeadlineExceeded}
+	m, events := newTestManager(t, orders, WithErrorHandler(func(event broker.FrameErrorEvent) {
+		errs = append(errs, event)
+	}))
+
+	// 超时无法确定订单是否存在，订单保持待确认
+	o, err := m.Submit(context.Background(), testSignal())
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, types.OrderStateUnknown, o.State)
+	assert.Empty(t, *events)
+	require.Len(t, errs, 1)
+	assert.Len(t, m.Open(), 1)
+
+	require.NoError(t, m.Update(broker.OrderResultEvent{
+		ClientOrderID: o.ClientOrderID,
+		OrderID:       "1001",
+		ExecutionType: types.ExecutionTypeNew,
+		State:         types.OrderStateNew,
+	}))
+	require.Len(t, *events, 1)
+	assert.Equal(t, types.ExecutionTypeNew, (*events)[0].ExecutionType)
+}
+
+func TestSubmitRiskCheck(t *testing.T) {
+	orders := &fakeOrders{}
+	limits := risk.NewManager(risk.WithAccountLimits("acc", risk.Limits{MaxOpenOrders: 1}))
+	m, _ := newTestManager(t, orders, WithRiskCheck(limits.Allow))
+
+	// 风控按生成的客户订单ID跟踪挂单
+	o, err := m.Submit(context.Background(), testSignal())
+	require.NoError(t, err)
+	_, err = m.Submit(context.Background(), testSignal())
+	require.NoError(t, err)
+
+	other := testSignal()
+	other.Timestamp = 2_000
+	_, err = m.Submit(context.Background(), other)
+	require.ErrorIs(t, err, risk.ErrMaxOpenOrders)
+	assert.Len(t, orders.creates, 1)
+	_, ok := m.Get(m.ClientOrderID(other))
+	assert.False(t, ok)
+
+	limits.UpdateOrder(broker.OrderResultEvent{
+		ClientOrderID: o.ClientOrderID,
+		ExecutionType: types.ExecutionTypeCanceled,
+		State:         types.OrderStateCanceled,
+	})
+	_, err = m.Submit(context.Background(), other)
+	require.NoError(t, err)
+	assert.Len(t, orders.creates, 2)
+}
+
+func TestUpdateRevivesRejected(t *testing.T) {
+	orders := &fakeOrders{createErr: fmt.Errorf("%w: status code: 400", exchange.ErrOrderRejected)}
+	m, events := newTestManager(t, orders)
+	o, err := m.Submit(context.Background(), testSignal())
+	require.ErrorIs(t, err, exchange.ErrOrderRejected)
+	assert.Equal(t, types.OrderStateRejected, o.State)
+
+	// 交易所随后确认了订单，以交易所为准
+	require.NoError(t, m.Update(broker.OrderResultEvent{
+		ClientOrderID: o.ClientOrderID,
+		OrderID:       "1001",
+		ExecutionType: types.ExecutionTypeNew,
+		State:         types.OrderStateNew,
+	}))
+	require.NoError(t, m.Update(broker.OrderResultEvent{
+		OrderID:       "1001",
+		ExecutionType: types.ExecutionTypeTrade,
+		State:         types.OrderStateFilled,
+		FilledVolume:  d("3"),
+		LatestPrice:   d("100"),
+	}))
+	require.Len(t, *events, 3)
+	assert.Equal(t, types.OrderStateNew, (*events)[1].State)
+	assert.Equal(t, types.OrderStateFilled, (*events)[2].State)
+	assert.Equal(t, "3", (*events)[2].LatestVolume.String())
+
+	// 交易所确认的REJECTED不会被之后乱序到达的NEW恢复
+	signal := testSignal()
+	signal.ClientOrderID = "second"
+	orders.createErr = nil
+	o, err = m.Submit(context.Background(), signal)
+	require.NoError(t, err)
+	require.NoError(t, m.Update(broker.OrderResultEvent{ClientOrderID: "second", ExecutionType: types.ExecutionTypeRejected}))
+	require.NoError(t, m.Update(broker.OrderResultEvent{ClientOrderID: "second", ExecutionType: types.ExecutionTypeNew}))
+	o, _ = m.Get("second")
+	assert.Equal(t, types.OrderStateRejected, o.State)
+}
+
+func TestUpdatePartialFills(t *testing.T) {
+	orders := &fakeOrders{}
+	m, events := newTestManager(t, orders)
+	// 用户数据流的NEW先于下单响应到达
+	orders.onCreate = func(req *exchange.CreateOrderRequest) {
+		require.NoError(t, m.Update(broker.OrderResultEvent{
+			ClientOrderID: req.ClientOrderID,
+			OrderID:       "1001",
+			ExecutionType: types.ExecutionTypeNew,
+			State:         types.OrderStateNew,
+		}))
+	}
+
+	o, err := m.Submit(context.Background(), testSignal())
+	require.NoError(t, err)
+	id := o.ClientOrderID
+
+	fill := func(execution types.ExecutionType, state types.OrderState, filled, quote string) broker.OrderResultEvent {
+		return broker.OrderResultEvent{
+			OrderID:           "1001",
+			ExecutionType:     execution,
+			State:             state,
+			FilledVolume:      d(filled),
+			FilledQuoteVolume: d(quote),
+			FeeCost:           d("0.1"),
+		}
+	}
+	for _, update := range []broker.OrderResultEvent{
+		fill(types.ExecutionTypeNew, types.OrderStateNew, "0", "0"),
+		fill(types.ExecutionTypeTrade, types.OrderStatePartiallyFilled, "1", "100"),
+		// 重复推送
+		fill(types.ExecutionTypeTrade, types.OrderStatePartiallyFilled, "1", "100"),
+		// 乱序：第二笔成交的更新晚于全部成交到达
+		fill(types.ExecutionTypeTrade, types.OrderStateFilled, "3", "297"),
+		fill(types.ExecutionTypeTrade, types.OrderStatePartiallyFilled, "2", "199"),
+		fill(types.ExecutionTypeCanceled, types.OrderStateCanceled, "3", "297"),
+	} {
+		require.NoError(t, m.Update(update))
+	}
+
+	require.Len(t, *events, 3)
+	assert.Equal(t, types.ExecutionTypeNew, (*events)[0].ExecutionType)
+	assert.Equal(t, "1", (*events)[1].LatestVolume.String())
+	assert.Equal(t, "100", (*events)[1].LatestQuoteVolume.String())
+	assert.Equal(t, types.OrderStatePartiallyFilled, (*events)[1].State)
+	last := (*events)[2]
+	assert.Equal(t, types.OrderStateFilled, last.State)
+	assert.Equal(t, "2", last.LatestVolume.String())
+	assert.Equal(t, "3", last.FilledVolume.String())
+	assert.Equal(t, id, last.ClientOrderID)
+	assert.Equal(t, "acc", last.AccountID)
+	assert.Equal(t, types.SideTypeBuy, last.Side)
+
+	o, ok := m.Get(id)
+	require.True(t, ok)
+	assert.True(t, o.Terminal())
+	assert.True(t, o.Remaining().IsZero())
+	assert.Equal(t, "99", o.AvgPrice().String())
+	assert.Equal(t, "0.2", o.FeeCost.String())
+	assert.Empty(t, m.Open())
+
+	assert.ErrorIs(t, m.Update(broker.OrderResultEvent{ClientOrderID: "missing"}), ErrUnknownOrder)
+}
+
+func TestUpdateLateFees(t *testing.T) {
+	m, events := newTestManager(t, &fakeOrders{})
+	o, err := m.Submit(context.Background(), testSignal())
+	require.NoError(t, err)
+
+	trade := func(tradeID string, state types.OrderState, filled, fee string) broker.OrderResultEvent {
+		return broker.OrderResultEvent{
+			ClientOrderID: o.ClientOrderID,
+			TradeID:       tradeID,
+			ExecutionType: types.ExecutionTypeTrade,
+			State:         state,
+			FilledVolume:  d(filled),
+			LatestPrice:   d("100"),
+			FeeCost:       d(fee),
+		}
+	}
+	for _, update := range []broker.OrderResultEvent{
+		trade("t1", types.OrderStatePartiallyFilled, "1", "0.1"),
+		// 第三笔成交先于第二笔到达
+		trade("t3", types.OrderStateFilled, "3", "0.3"),
+		trade("t2", types.OrderStatePartiallyFilled, "2", "0.2"),
+		// 重复推送
+		trade("t2", types.OrderStatePartiallyFilled, "2", "0.2"),
+		trade("t3", types.OrderStateFilled, "3", "0.3"),
+	} {
+		require.NoError(t, m.Update(update))
+	}
+
+	require.Len(t, *events, 4)
+	late := (*events)[3]
+	assert.Equal(t, types.ExecutionTypeTrade, late.ExecutionType)
+	assert.Equal(t, "t2", late.TradeID)
+	assert.Equal(t, "0.2", late.FeeCost.String())
+	assert.True(t, late.LatestVolume.IsZero())
+	assert.Equal(t, types.OrderStateFilled, late.State)
+
+	o, _ = m.Get(o.ClientOrderID)
+	assert.Equal(t, "0.6", o.FeeCost.String())
+	assert.Equal(t, "3", o.FilledVolume.String())
+}
+
+func TestUpdateWithoutState(t *testing.T) {
+	m, events := newTestManager(t, &fakeOrders{})
+	o, err := m.Submit(context.Background(), testSignal())
+	require.NoError(t, err)
+
+	require.NoError(t, m.Update(broker.OrderResultEvent{
+		ClientOrderID: o.ClientOrderID,
+		ExecutionType: types.ExecutionTypeTrade,
+		FilledVolume:  d("1"),
+		LatestPrice:   d("101"),
+	}))
+	require.NoError(t, m.Update(broker.OrderResultEvent{
+		ClientOrderID: o.ClientOrderID,
+		ExecutionType: types.ExecutionTypeExpired,
+		FilledVolume:  d("1"),
+	}))
+
+	require.Len(t, *events, 3)
+	assert.Equal(t, types.OrderStatePartiallyFilled, (*events)[1].State)
+	assert.Equal(t, "101", (*events)[1].LatestQuoteVolume.String())
+	assert.Equal(t, types.OrderStateCanceled, (*events)[2].State)
+	assert.True(t, (*events)[2].LatestVolume.IsZero())
+}
+
+func TestCancelExpired(t *testing.T) {
+	c := clock.NewSimulated(time.UnixMilli(0))
+	var errs []broker.FrameErrorEvent
+	orders := &fakeOrders{cancelErr: errors.New("timeout")}
+	m, _ := newTestManager(t, orders,
+		WithClock(c),
+		WithCancelTimeout(time.Minute),
+		WithErrorHandler(func(event broker.FrameErrorEvent) {
+			errs = append(errs, event)
+		}),
+	)
+
+	first, err := m.Submit(context.Background(), testSignal())
+	require.NoError(t, err)
+	c.Advance(30 * time.Second)
+	signal := testSignal()
+	signal.ClientOrderID = "second"
+	_, err = m.Submit(context.Background(), signal)
+	require.NoError(t, err)
+
+	c.Advance(30 * time.Second)
+	assert.Equal(t, 1, m.CancelExpired(context.Background()))
+	require.Len(t, orders.cancels, 1)
+	assert.Equal(t, first.ClientOrderID, orders.cancels[0].ClientOrderID)
+	assert.Equal(t, "1001", orders.cancels[0].OrderID)
+	require.Len(t, errs, 1)
+
+	// 撤单失败后重试，成功后不再重复撤单
+	orders.cancelErr = nil
+	assert.Equal(t, 1, m.CancelExpired(context.Background()))
+	assert.Equal(t, 0, m.CancelExpired(context.Background()))
+	o, _ := m.Get(first.ClientOrderID)
+	assert.True(t, o.CancelRequested)
+	assert.Len(t, m.Open(), 2)
+
+	require.NoError(t, m.Update(broker.OrderResultEvent{
+		ClientOrderID: first.ClientOrderID,
+		ExecutionType: types.ExecutionTypeCanceled,
+		State:         types.OrderStateCanceled,
+	}))
+	c.Advance(30 * time.Second)
+	assert.Equal(t, 1, m.CancelExpired(context.Background()))
+	assert.Equal(t, "second", orders.cancels[2].ClientOrderID)
+}
+
+func TestCancelExpiredUnknown(t *testing.T) {
+	c := clock.NewSimulated(time.UnixMilli(0))
+	orders := &fakeOrders{createErr: errors.New("timeout"), cancelErr: errors.New("timeout")}
+	m, events := newTestManager(t, orders, WithClock(c), WithCancelTimeout(time.Minute))
+
+	o, err := m.Submit(context.Background(), testSignal())
+	require.Error(t, err)
+	require.Equal(t, types.OrderStateUnknown, o.State)
+
+	// 超时前不撤单
+	assert.Equal(t, 0, m.CancelExpired(context.Background()))
+
+	// 交易所始终没有确认的订单超时后按ClientOrderID撤单，撤单失败时保持Unknown并重试
+	c.Advance(time.Minute)
+	assert.Equal(t, 1, m.CancelExpired(context.Background()))
+	require.Len(t, orders.cancels, 1)
+	assert.Equal(t, o.ClientOrderID, orders.cancels[0].ClientOrderID)
+	assert.Empty(t, orders.cancels[0].OrderID)
+	o, _ = m.Get(o.ClientOrderID)
+	assert.Equal(t, types.OrderStateUnknown, o.State)
+	assert.Empty(t, *events)
+
+	// 撤单成功说明订单存在且已撤销，直接结束订单
+	orders.cancelErr = nil
+	assert.Equal(t, 1, m.CancelExpired(context.Background()))
+	o, _ = m.Get(o.ClientOrderID)
+	assert.Equal(t, types.OrderStateCanceled, o.State)
+	require.Len(t, *events, 1)
+	assert.Equal(t, types.ExecutionTypeCanceled, (*events)[0].ExecutionType)
+	assert.Empty(t, m.Open())
+
+	// 用户数据流迟到的CANCELED不会重复发布
+	require.NoError(t, m.Update(broker.OrderResultEvent{
+		ClientOrderID: o.ClientOrderID,
+		OrderID:       "1001",
+		ExecutionType: types.ExecutionTypeCanceled,
+		State:         types.OrderStateCanceled,
+	}))
+	assert.Len(t, *events, 1)
+	assert.Equal(t, 0, m.CancelExpired(context.Background()))
+}
+
+func TestRun(t *testing.T) {
+	c := clock.NewSimulated(time.UnixMilli(0))
+	orders := &fakeOrders{}
+	m, _ := newTestManager(t, orders, WithClock(c), WithCancelTimeout(time.Second), WithSweepInterval(time.Second))
+	_, err := m.Submit(context.Background(), testSignal())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- m.Run(ctx)
+	}()
+	c.BlockUntil(1)
+	c.Advance(time.Second)
+	assert.Eventually(t, func() bool {
+		orders.mu.Lock()
+		defer orders.mu.Unlock()
+		return len(orders.cancels) == 1
+	}, time.Second, time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestNewManagerErrors(t *testing.T) {
+	handler := func(broker.OrderResultEvent) {}
+	_, err := NewManager(nil, handler)
+	assert.Error(t, err)
+	_, err = NewManager(&fakeOrders{}, nil)
+	assert.Error(t, err)
+	_, err = NewManager(&fakeOrders{}, handler, WithSweepInterval(0))
+	assert.Error(t, err)
+}
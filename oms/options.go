@@ -0,0 +1,84 @@
+package oms
+
+import (
+	"time"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/clock"
+	"github.com/go-gotop/gotop/types"
+)
+
+// Option 是Manager的配置选项
+type Option func(m *Manager)
+
+// Credentials 下单和撤单使用的API凭证
+type Credentials struct {
+	APIKey     string
+	SecretKey  string
+	Passphrase string
+}
+
+// WithClock 设置时钟，用于订单时间戳和撤单超时，默认为系统时钟
+func WithClock(c clock.Clock) Option {
+	return func(m *Manager) {
+		m.clock = c
+	}
+}
+
+// WithCredentials 设置按账户获取API凭证的方法，默认使用空凭证
+func WithCredentials(credentials func(accountID string) (Credentials, error)) Option {
+	return func(m *Manager) {
+		m.credentials = credentials
+	}
+}
+
+// WithSymbols 登记标的物，下单请求需要完整的 types.Symbol。
+// 信号的Symbol与OriginalSymbol或UnifiedSymbol相同即可匹配，未登记的交易对以信号的Symbol作为OriginalSymbol
+func WithSymbols(symbols ...types.Symbol) Option {
+	return func(m *Manager) {
+		for _, s := range symbols {
+			if s.OriginalSymbol != "" {
+				m.symbols[s.OriginalSymbol] = s
+			}
+			if s.UnifiedSymbol != "" {
+				m.symbols[s.UnifiedSymbol] = s
+			}
+		}
+	}
+}
+
+// WithClientOrderIDPrefix 设置生成的客户订单ID前缀，只能包含字母和数字，默认为"gt"
+func WithClientOrderIDPrefix(prefix string) Option {
+	return func(m *Manager) {
+		m.prefix = prefix
+	}
+}
+
+// WithCancelTimeout 设置撤单超时，提交后超过timeout仍未结束的订单会被撤销，默认不撤销
+func WithCancelTimeout(timeout time.Duration) Option {
+	return func(m *Manager) {
+		m.timeout = timeout
+	}
+}
+
+// WithSweepInterval 设置Run检查超时订单的间隔，默认为1秒
+func WithSweepInterval(interval time.Duration) Option {
+	return func(m *Manager) {
+		m.interval = interval
+	}
+}
+
+// WithErrorHandler 设置错误回调，下单或撤单失败时调用。回调在持有锁时同步执行，不能再调用Manager的方法
+func WithErrorHandler(handler func(event broker.FrameErrorEvent)) Option {
+	return func(m *Manager) {
+		m.onError = handler
+	}
+}
+
+// WithRiskCheck 设置下单前的风控检查，例如 risk.Manager 的Allow。检查在生成客户订单ID之后、下单之前执行，
+// 重复投递的信号不会再次检查。检查在持有锁时同步执行，不能再调用Manager的方法
+func WithRiskCheck(check func(signal broker.StrategySignalEvent) error) Option {
+	return func(m *Manager) {
+		m.riskCheck = check
+	}
+}
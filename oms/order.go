@@ -0,0 +1,100 @@
+package oms
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/types"
+)
+
+// Order 订单快照
+type Order struct {
+	// ClientOrderID 客户订单ID
+	ClientOrderID string
+	// OrderID 交易所订单号，交易所确认前为空
+	OrderID string
+	// Signal 生成订单的策略信号
+	Signal broker.StrategySignalEvent
+	// State 订单状态，OrderStateUnknown 表示已提交但交易所尚未确认
+	State types.OrderState
+	// FilledVolume 累计成交数量
+	FilledVolume decimal.Decimal
+	// FilledQuoteVolume 累计成交金额
+	FilledQuoteVolume decimal.Decimal
+	// FeeCost 累计手续费
+	FeeCost decimal.Decimal
+	// CreatedAt 提交时间（毫秒）
+	CreatedAt int64
+	// UpdatedAt 最后一次状态变化的时间（毫秒）
+	UpdatedAt int64
+	// CancelRequested 是否已因超时发出撤单请求
+	CancelRequested bool
+
+	// rejected 是否由下单请求的错误判定为Rejected，交易所随后确认订单时可以恢复
+	rejected bool
+	// trades 已计入手续费的成交ID
+	trades map[string]struct{}
+}
+
+// Terminal 订单是否已结束
+func (o Order) Terminal() bool {
+	return terminal(o.State)
+}
+
+// Remaining 剩余未成交数量，单位与信号的Size相同
+func (o Order) Remaining() decimal.Decimal {
+	if o.Terminal() {
+		return decimal.Zero
+	}
+	return decimal.Max(o.Signal.Size.Sub(o.FilledVolume), decimal.Zero)
+}
+
+// AvgPrice 平均成交价格，没有成交或缺少成交金额时为0
+func (o Order) AvgPrice() decimal.Decimal {
+	if o.FilledVolume.IsZero() {
+		return decimal.Zero
+	}
+	return o.FilledQuoteVolume.Div(o.FilledVolume)
+}
+
+// terminal 判断订单状态是否为终态
+func terminal(state types.OrderState) bool {
+	switch state {
+	case types.OrderStateFilled, types.OrderStateCanceled, types.OrderStateRejected:
+		return true
+	}
+	return false
+}
+
+// rank 订单状态的先后顺序，状态只能向更大的rank转移
+func rank(state types.OrderState) int {
+	switch state {
+	case types.OrderStateNew:
+		return 1
+	case types.OrderStatePartiallyFilled:
+		return 2
+	case types.OrderStateFilled, types.OrderStateCanceled, types.OrderStateRejected:
+		return 3
+	}
+	return 0
+}
+
+// clientOrderIDLength 生成的客户订单ID长度，OKX要求不超过32位字母数字，币安要求不超过36位
+const clientOrderIDLength = 32
+
+// clientOrderID 由信号内容生成客户订单ID，同一信号重复投递时得到相同的ID
+func clientOrderID(prefix string, signal broker.StrategySignalEvent) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%s|%d|%s|%s|%s|%s|%s|%s|%s",
+		signal.AccountID, signal.StrategyID, signal.PositionID, signal.TransactionID, signal.Timestamp,
+		signal.Exchange, signal.Symbol, signal.Side, signal.PositionSide, signal.OrderType,
+		signal.Size, signal.Price)))
+	id := prefix + hex.EncodeToString(sum[:])
+	if len(id) > clientOrderIDLength {
+		id = id[:clientOrderIDLength]
+	}
+	return id
+}
@@ -0,0 +1,35 @@
+package portfolio
+
+import (
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/clock"
+)
+
+// Option 是Portfolio的配置选项
+type Option func(p *Portfolio)
+
+// WithSources 添加账户数据来源
+func WithSources(sources ...Source) Option {
+	return func(p *Portfolio) {
+		p.sources = append(p.sources, sources...)
+	}
+}
+
+// WithPegged 设置与计价资产1:1折算的资产，例如以USDT计价时的USDC
+func WithPegged(assets ...string) Option {
+	return func(p *Portfolio) {
+		for _, asset := range assets {
+			p.prices[strings.ToUpper(asset)] = decimal.NewFromInt(1)
+		}
+	}
+}
+
+// WithClock 设置时钟，用于估值时间戳，默认为系统时钟
+func WithClock(c clock.Clock) Option {
+	return func(p *Portfolio) {
+		p.clock = c
+	}
+}
@@ -0,0 +1,212 @@
+// Package portfolio 汇总多个交易所的账户，按最新价格将余额、持仓盈亏和保证金折算为同一计价资产
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/clock"
+	"github.com/go-gotop/gotop/types"
+)
+
+// ErrMissingPrice 有资产缺少价格，估值不完整
+var ErrMissingPrice = errors.New("portfolio: missing price")
+
+// Valuation 组合估值
+type Valuation struct {
+	// Quote 计价资产
+	Quote string
+	// Equity 总权益
+	Equity decimal.Decimal
+	// MarginUsed 已用保证金合计
+	MarginUsed decimal.Decimal
+	// MarginRatio 总保证金率，已用保证金 / 总权益
+	MarginRatio decimal.Decimal
+	// Accounts 各账户快照，按交易所和账户ID排序，Equity、MarginUsed、MarginRatio已折算为Quote
+	Accounts []types.Account
+	// Missing 没有价格而未计入估值的资产，不为空时Equity和MarginUsed偏低
+	Missing []string
+	// Timestamp 估值时间（毫秒）
+	Timestamp int64
+}
+
+// Portfolio 多账户组合。账户快照通过Refresh从Source拉取，价格通过UpdatePrice推送，
+// 账户的计算规则为：
+//  1. 交易所提供了Equity（HasEquity）时，按QuoteAsset的价格折算，否则为各资产余额与持仓未实现盈亏的折算值之和；
+//  2. 交易所提供了MarginUsed（HasMarginUsed）时按QuoteAsset的价格折算，否则为各持仓占用保证金的折算值之和；
+//  3. 持仓的未实现盈亏和占用保证金按持仓的SettleAsset折算，例如币本位合约按BTC的价格，为空时按账户的QuoteAsset。
+//
+// 没有价格的资产无法折算，不计入估值并记录在Valuation.Missing中。可以在多个goroutine中并发调用
+type Portfolio struct {
+	mu      sync.Mutex
+	quote   string
+	clock   clock.Clock
+	sources []Source
+	// prices 各资产以quote计价的最新价格
+	prices map[string]decimal.Decimal
+	// accounts 各Source最近一次成功拉取的快照，与sources一一对应
+	accounts []*types.Account
+}
+
+// NewPortfolio 创建组合，quote为计价资产，例如 USDT。
+// quote为USDT或USDC时USD默认按1:1折算（OKX账户的权益以USD计价），可以通过UpdatePrice覆盖，
+// 其他稳定币需要通过WithPegged设置
+func NewPortfolio(quote string, opts ...Option) (*Portfolio, error) {
+	if quote == "" {
+		return nil, errors.New("quote asset cannot be empty")
+	}
+	p := &Portfolio{
+		quote:  strings.ToUpper(quote),
+		prices: make(map[string]decimal.Decimal),
+	}
+	p.prices[p.quote] = decimal.NewFromInt(1)
+	if p.quote == "USDT" || p.quote == "USDC" {
+		p.prices["USD"] = decimal.NewFromInt(1)
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.clock = clock.OrReal(p.clock)
+	p.accounts = make([]*types.Account, len(p.sources))
+	return p, nil
+}
+
+// UpdatePrice 更新资产以计价资产计价的最新价格，例如 UpdatePrice("BTC", 60000)
+func (p *Portfolio) UpdatePrice(asset string, price decimal.Decimal) {
+	if !price.IsPositive() {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prices[strings.ToUpper(asset)] = price
+}
+
+// Refresh 并发拉取所有账户并返回估值。拉取失败的账户使用上一次成功的快照，
+// 从未成功的账户不计入估值，返回的错误汇总了所有失败的Source，有资产缺少价格时还包含ErrMissingPrice
+func (p *Portfolio) Refresh(ctx context.Context) (Valuation, error) {
+	accounts := make([]types.Account, len(p.sources))
+	errs := make([]error, len(p.sources))
+	var wg sync.WaitGroup
+	for i, source := range p.sources {
+		wg.Add(1)
+		go func(i int, source Source) {
+			defer wg.Done()
+			accounts[i], errs[i] = source.Account(ctx)
+		}(i, source)
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range p.sources {
+		if errs[i] != nil {
+			errs[i] = fmt.Errorf("account source %d: %w", i, errs[i])
+			continue
+		}
+		account := accounts[i]
+		p.accounts[i] = &account
+	}
+	v := p.value()
+	if len(v.Missing) > 0 {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrMissingPrice, strings.Join(v.Missing, ", ")))
+	}
+	return v, errors.Join(errs...)
+}
+
+// Value 使用已拉取的账户快照和最新价格计算估值
+func (p *Portfolio) Value() Valuation {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.value()
+}
+
+func (p *Portfolio) value() Valuation {
+	v := Valuation{
+		Quote:     p.quote,
+		Timestamp: p.clock.Now().UnixMilli(),
+	}
+	missing := make(map[string]struct{})
+	convert := func(amount decimal.Decimal, asset string) decimal.Decimal {
+		if amount.IsZero() {
+			return decimal.Zero
+		}
+		price, ok := p.prices[strings.ToUpper(asset)]
+		if !ok {
+			missing[strings.ToUpper(asset)] = struct{}{}
+			return decimal.Zero
+		}
+		return amount.Mul(price)
+	}
+
+	for _, cached := range p.accounts {
+		if cached == nil {
+			continue
+		}
+		a := *cached
+		a.Balances = append([]types.Asset(nil), a.Balances...)
+		a.Positions = append([]types.AccountPosition(nil), a.Positions...)
+		quote := a.QuoteAsset
+		if quote == "" {
+			quote = p.quote
+		}
+
+		equity := convert(a.Equity, quote)
+		if !a.HasEquity {
+			for _, b := range a.Balances {
+				equity = equity.Add(convert(b.Total(), b.AssetName))
+			}
+			for _, pos := range a.Positions {
+				equity = equity.Add(convert(pos.UnrealizedPnL, settle(pos, quote)))
+			}
+		}
+		margin := convert(a.MarginUsed, quote)
+		if !a.HasMarginUsed {
+			for _, pos := range a.Positions {
+				margin = margin.Add(convert(pos.Margin, settle(pos, quote)))
+			}
+		}
+
+		a.QuoteAsset = p.quote
+		a.Equity, a.HasEquity = equity, true
+		a.MarginUsed, a.HasMarginUsed = margin, true
+		a.MarginRatio = ratio(margin, equity)
+		v.Accounts = append(v.Accounts, a)
+		v.Equity = v.Equity.Add(equity)
+		v.MarginUsed = v.MarginUsed.Add(margin)
+	}
+	v.MarginRatio = ratio(v.MarginUsed, v.Equity)
+
+	sort.Slice(v.Accounts, func(i, j int) bool {
+		if v.Accounts[i].Exchange != v.Accounts[j].Exchange {
+			return v.Accounts[i].Exchange < v.Accounts[j].Exchange
+		}
+		return v.Accounts[i].ID < v.Accounts[j].ID
+	})
+	for asset := range missing {
+		v.Missing = append(v.Missing, asset)
+	}
+	sort.Strings(v.Missing)
+	return v
+}
+
+// settle 返回持仓的结算资产，持仓没有设置时为账户的计价资产
+func settle(pos types.AccountPosition, quote string) string {
+	if pos.SettleAsset != "" {
+		return pos.SettleAsset
+	}
+	return quote
+}
+
+// ratio 保证金率，权益不为正时为0
+func ratio(margin, equity decimal.Decimal) decimal.Decimal {
+	if !equity.IsPositive() {
+		return decimal.Zero
+	}
+	return margin.Div(equity)
+}
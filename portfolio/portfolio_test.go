@@ -0,0 +1,222 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/clock"
+	"github.com/go-gotop/gotop/exchange"
+	"github.com/go-gotop/gotop/types"
+)
+
+type fakeBalances struct {
+	balances []exchange.Balance
+	err      error
+	auth     exchange.AuthInfo
+}
+
+func (f *fakeBalances) GetBalances(ctx context.Context, authInfo exchange.AuthInfo) (*exchange.GetBalancesResponse, error) {
+	f.auth = authInfo
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &exchange.GetBalancesResponse{Balances: f.balances}, nil
+}
+
+func (f *fakeBalances) GetBalance(ctx context.Context, authInfo exchange.AuthInfo, asset string) (*exchange.GetBalanceResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func d(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func TestPortfolioRefresh(t *testing.T) {
+	binance := &fakeBalances{balances: []exchange.Balance{
+		{Asset: "BTC", Available: d("1"), Locked: d("0.5")},
+		{Asset: "USDT", Available: d("1000")},
+		{Asset: "DOGE", Available: d("100")},
+	}}
+	// OKX统一账户由交易所提供以USD计价的权益和保证金
+	okx := SourceFunc(func(ctx context.Context) (types.Account, error) {
+		return types.Account{
+			ID:            "okx-1",
+			Exchange:      exchange.ExchangeOKX,
+			Type:          types.AccountTypeUnified,
+			QuoteAsset:    "USD",
+			Equity:        d("20000"),
+			HasEquity:     true,
+			MarginUsed:    d("5000"),
+			HasMarginUsed: true,
+		}, nil
+	})
+	// 以USDT计价的合约账户，权益由余额和未实现盈亏估算
+	futures := SourceFunc(func(ctx context.Context) (types.Account, error) {
+		return types.Account{
+			ID:       "bn-futures",
+			Exchange: exchange.ExchangeBinance,
+			Type:     types.AccountTypeClassic,
+			Balances: []types.Asset{{AssetName: "USDT", Free: d("3000"), Locked: d("1000")}},
+			Positions: []types.AccountPosition{
+				{Symbol: "BTCUSDT", Size: d("0.1"), UnrealizedPnL: d("-500"), Margin: d("600")},
+				{Symbol: "ETHUSDT", Size: d("-2"), UnrealizedPnL: d("100"), Margin: d("400")},
+			},
+		}, nil
+	})
+
+	c := clock.NewSimulated(time.UnixMilli(1_000))
+	p, err := NewPortfolio("usdt",
+		WithClock(c),
+		WithPegged("USD"),
+		WithSources(
+			NewBalanceSource(types.Account{ID: "bn-spot", Exchange: exchange.ExchangeBinance, Type: types.AccountTypeClassic},
+				binance, exchange.AuthInfo{APIKey: "key"}),
+			okx,
+			futures,
+		),
+	)
+	require.NoError(t, err)
+	p.UpdatePrice("btc", d("60000"))
+
+	// DOGE没有价格，估值不完整
+	v, err := p.Refresh(context.Background())
+	require.ErrorIs(t, err, ErrMissingPrice)
+	assert.Contains(t, err.Error(), "DOGE")
+	assert.Equal(t, "key", binance.auth.APIKey)
+	assert.Equal(t, "USDT", v.Quote)
+	assert.Equal(t, int64(1_000), v.Timestamp)
+	// 91000 + 20000 + 3600
+	assert.Equal(t, "114600", v.Equity.String())
+	assert.Equal(t, "6000", v.MarginUsed.String())
+	assert.Equal(t, []string{"DOGE"}, v.Missing)
+
+	require.Len(t, v.Accounts, 3)
+	assert.Equal(t, "bn-futures", v.Accounts[0].ID)
+	assert.Equal(t, "3600", v.Accounts[0].Equity.String())
+	assert.Equal(t, "1000", v.Accounts[0].MarginUsed.String())
+	assert.Equal(t, "bn-spot", v.Accounts[1].ID)
+	assert.Equal(t, "91000", v.Accounts[1].Equity.String())
+	assert.Equal(t, exchange.ExchangeBinance, v.Accounts[1].Balances[0].Exchange)
+	assert.Equal(t, "USDT", v.Accounts[1].QuoteAsset)
+	assert.Equal(t, "0.25", v.Accounts[2].MarginRatio.String())
+
+	// 价格变化后无需重新拉取
+	p.UpdatePrice("BTC", d("40000"))
+	p.UpdatePrice("DOGE", d("0.1"))
+	v = p.Value()
+	assert.Equal(t, "84610", v.Equity.String())
+	assert.Empty(t, v.Missing)
+}
+
+func TestPortfolioRefreshErrors(t *testing.T) {
+	balances := &fakeBalances{balances: []exchange.Balance{{Asset: "USDT", Available: d("100")}}}
+	failing := SourceFunc(func(ctx context.Context) (types.Account, error) {
+		return types.Account{}, errors.New("timeout")
+	})
+	p, err := NewPortfolio("USDT", WithSources(
+		NewBalanceSource(types.Account{ID: "a"}, balances, exchange.AuthInfo{}),
+		failing,
+	))
+	require.NoError(t, err)
+
+	v, err := p.Refresh(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "account source 1: timeout")
+	require.Len(t, v.Accounts, 1)
+	assert.Equal(t, "100", v.Equity.String())
+
+	// 拉取失败时保留上一次成功的快照
+	balances.err = errors.New("rate limited")
+	v, err = p.Refresh(context.Background())
+	require.Error(t, err)
+	require.Len(t, v.Accounts, 1)
+	assert.Equal(t, "100", v.Equity.String())
+
+	_, err = NewPortfolio("")
+	assert.Error(t, err)
+}
+
+func TestPortfolioReportedZero(t *testing.T) {
+	// 交易所提供的权益和保证金为0时不再由余额和持仓估算
+	p, err := NewPortfolio("USDT", WithSources(SourceFunc(func(ctx context.Context) (types.Account, error) {
+		return types.Account{
+			ID:            "a",
+			QuoteAsset:    "USDT",
+			HasEquity:     true,
+			HasMarginUsed: true,
+			Balances:      []types.Asset{{AssetName: "USDT", Free: d("100")}},
+			Positions:     []types.AccountPosition{{Symbol: "BTCUSDT", UnrealizedPnL: d("-100"), Margin: d("50")}},
+		}, nil
+	})))
+	require.NoError(t, err)
+
+	v, err := p.Refresh(context.Background())
+	require.NoError(t, err)
+	assert.True(t, v.Equity.IsZero())
+	assert.True(t, v.MarginUsed.IsZero())
+}
+
+func TestPortfolioSettleAsset(t *testing.T) {
+	// 币本位持仓的盈亏和保证金以BTC计，U本位持仓未设置结算资产时按账户的USD计
+	p, err := NewPortfolio("USDT", WithSources(SourceFunc(func(ctx context.Context) (types.Account, error) {
+		return types.Account{
+			ID:         "okx-1",
+			QuoteAsset: "USD",
+			Balances:   []types.Asset{{AssetName: "BTC", Free: d("1")}},
+			Positions: []types.AccountPosition{
+				{Symbol: "BTC-USD-SWAP", UnrealizedPnL: d("-0.01"), Margin: d("0.1"), SettleAsset: "BTC"},
+				{Symbol: "BTC-USDT-SWAP", UnrealizedPnL: d("100"), Margin: d("500")},
+			},
+		}, nil
+	})))
+	require.NoError(t, err)
+	p.UpdatePrice("BTC", d("60000"))
+
+	// 以USDT计价时USD默认1:1折算
+	v, err := p.Refresh(context.Background())
+	require.NoError(t, err)
+	// 60000 - 600 + 100
+	assert.Equal(t, "59500", v.Equity.String())
+	// 6000 + 500
+	assert.Equal(t, "6500", v.MarginUsed.String())
+
+	// USD的价格可以覆盖
+	p.UpdatePrice("USD", d("0.5"))
+	v = p.Value()
+	assert.Equal(t, "59450", v.Equity.String())
+
+	// 以其他资产计价时USD不会默认1:1折算
+	p, err = NewPortfolio("BTC")
+	require.NoError(t, err)
+	_, ok := p.prices["USD"]
+	assert.False(t, ok)
+}
+
+func TestAccountSource(t *testing.T) {
+	var auth exchange.AuthInfo
+	source := &accountSource{
+		id: "bn-futures",
+		get: func(ctx context.Context, authInfo exchange.AuthInfo) (*types.Account, error) {
+			auth = authInfo
+			return &types.Account{Exchange: exchange.ExchangeBinance, Equity: d("10"), HasEquity: true}, nil
+		},
+		auth: exchange.AuthInfo{APIKey: "key"},
+	}
+	account, err := source.Account(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "key", auth.APIKey)
+	assert.Equal(t, "bn-futures", account.ID)
+	assert.Equal(t, "10", account.Equity.String())
+
+	source.get = func(ctx context.Context, authInfo exchange.AuthInfo) (*types.Account, error) {
+		return nil, errors.New("timeout")
+	}
+	_, err = source.Account(context.Background())
+	assert.EqualError(t, err, "timeout")
+}
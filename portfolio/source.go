@@ -0,0 +1,82 @@
+package portfolio
+
+import (
+	"context"
+
+	"github.com/go-gotop/gotop/exchange"
+	"github.com/go-gotop/gotop/exchange/bnexc"
+	"github.com/go-gotop/gotop/exchange/okxexc"
+	"github.com/go-gotop/gotop/types"
+)
+
+// Source 账户数据来源
+type Source interface {
+	// Account 返回账户的最新快照
+	Account(ctx context.Context) (types.Account, error)
+}
+
+// SourceFunc 函数形式的Source
+type SourceFunc func(ctx context.Context) (types.Account, error)
+
+// Account 实现Source
+func (f SourceFunc) Account(ctx context.Context) (types.Account, error) {
+	return f(ctx)
+}
+
+// balanceSource 通过 exchange.AccountManager 查询余额的Source
+type balanceSource struct {
+	account types.Account
+	manager exchange.AccountManager
+	auth    exchange.AuthInfo
+}
+
+// NewBalanceSource 创建通过 AccountManager.GetBalances 查询余额的Source，
+// account提供ID、交易所和账户类型，返回的快照只包含余额
+func NewBalanceSource(account types.Account, manager exchange.AccountManager, auth exchange.AuthInfo) Source {
+	return &balanceSource{account: account, manager: manager, auth: auth}
+}
+
+func (s *balanceSource) Account(ctx context.Context) (types.Account, error) {
+	resp, err := s.manager.GetBalances(ctx, s.auth)
+	if err != nil {
+		return types.Account{}, err
+	}
+	account := s.account
+	account.Balances = make([]types.Asset, 0, len(resp.Balances))
+	for _, b := range resp.Balances {
+		account.Balances = append(account.Balances, types.Asset{
+			AssetName: b.Asset,
+			Exchange:  account.Exchange,
+			Free:      b.Available,
+			Locked:    b.Locked,
+		})
+	}
+	return account, nil
+}
+
+// accountSource 通过交易所账户接口查询包含持仓、权益和保证金的完整快照
+type accountSource struct {
+	id   string
+	get  func(ctx context.Context, authInfo exchange.AuthInfo) (*types.Account, error)
+	auth exchange.AuthInfo
+}
+
+// NewBinanceFuturesSource 创建Binance U本位合约账户的Source，余额、持仓、权益和已用保证金由交易所提供，以USDT计价
+func NewBinanceFuturesSource(id string, manager *bnexc.BnAccountManager, auth exchange.AuthInfo) Source {
+	return &accountSource{id: id, get: manager.GetFuturesAccount, auth: auth}
+}
+
+// NewOKXSource 创建OKX统一账户的Source，余额、持仓、权益和已用保证金由交易所提供，权益和已用保证金以USD计价，
+// 持仓按各自的结算资产计价，组合不以USDT或USDC计价时需要通过UpdatePrice设置USD的价格
+func NewOKXSource(id string, manager *okxexc.OkxAccountManager, auth exchange.AuthInfo) Source {
+	return &accountSource{id: id, get: manager.GetAccount, auth: auth}
+}
+
+func (s *accountSource) Account(ctx context.Context) (types.Account, error) {
+	account, err := s.get(ctx, s.auth)
+	if err != nil {
+		return types.Account{}, err
+	}
+	account.ID = s.id
+	return *account, nil
+}
@@ -3,6 +3,7 @@ package binance
 import (
     "time"
 
+    "github.com/go-gotop/gotop/clock"
     "github.com/go-gotop/gotop/ratelimiter"	
 )
 
@@ -12,14 +13,33 @@ type BinanceAlgorithm struct {
     // 这里用内存map模拟（非线程安全示例）
     counters map[string]int
     expiries map[string]time.Time
+    // clock 判断窗口过期使用的时钟
+    clock clock.Clock
+}
+
+// Option 是BinanceAlgorithm的配置选项
+type Option func(b *BinanceAlgorithm)
+
+// WithClock 设置判断窗口过期使用的时钟，默认为系统时钟
+func WithClock(c clock.Clock) Option {
+    return func(b *BinanceAlgorithm) {
+        if c != nil {
+            b.clock = c
+        }
+    }
 }
 
 // NewBinanceAlgorithm 创建一个示例算法实例
-func NewBinanceAlgorithm() *BinanceAlgorithm {
-    return &BinanceAlgorithm{
+func NewBinanceAlgorithm(opts ...Option) *BinanceAlgorithm {
+    b := &BinanceAlgorithm{
         counters: make(map[string]int),
         expiries: make(map[string]time.Time),
+        clock:    clock.Real(),
     }
+    for _, opt := range opts {
+        opt(b)
+    }
+    return b
 }
 
 func (b *BinanceAlgorithm) Check(key string) (ratelimiter.RateLimitDecision, error) {
@@ -35,27 +55,14 @@ func (b *BinanceAlgorithm) Check(key string) (ratelimiter.RateLimitDecision, err
     retryAfter := time.Duration(0)
     reason := ""
 
-    now := time.Now()
-
-    var window time.Duration
-    var limit int
-
-    if key == "binance:global:http" {
-        window = time.Second
-        limit = 10
-    } else if len(key) > len("binance:api_key:") && key[:len("binance:api_key:")] == "binance:api_key:" {
-        window = time.Minute
-        limit = 1200
-    } else {
+    window, limit, ok := rule(key)
+    if !ok {
         // 不认识的key不处理，直接允许
         return ratelimiter.RateLimitDecision{Allowed: true}, nil
     }
 
     // 清理过期
-    if exp, ok := b.expiries[key]; ok && now.After(exp) {
-        delete(b.counters, key)
-        delete(b.expiries, key)
-    }
+    b.expire(key, b.clock.Now())
 
     count := b.counters[key]
 
@@ -73,6 +80,37 @@ func (b *BinanceAlgorithm) Check(key string) (ratelimiter.RateLimitDecision, err
     }, nil
 }
 
+// Record 计入一次请求，窗口从过期后的第一次请求开始计时
 func (b *BinanceAlgorithm) Record(key string) error {
+    window, _, ok := rule(key)
+    if !ok {
+        return nil
+    }
+
+    now := b.clock.Now()
+    b.expire(key, now)
+    if _, ok := b.expiries[key]; !ok {
+        b.expiries[key] = now.Add(window)
+    }
+    b.counters[key]++
     return nil
 }
+
+// expire 清理已过期窗口的计数
+func (b *BinanceAlgorithm) expire(key string, now time.Time) {
+    if exp, ok := b.expiries[key]; ok && now.After(exp) {
+        delete(b.counters, key)
+        delete(b.expiries, key)
+    }
+}
+
+// rule 返回key对应的时间窗口和上限，不认识的key返回false
+func rule(key string) (time.Duration, int, bool) {
+    if key == "binance:global:http" {
+        return time.Second, 10, true
+    }
+    if len(key) > len("binance:api_key:") && key[:len("binance:api_key:")] == "binance:api_key:" {
+        return time.Minute, 1200, true
+    }
+    return 0, 0, false
+}
@@ -0,0 +1,52 @@
+package binance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/clock"
+)
+
+func TestBinanceAlgorithmWindow(t *testing.T) {
+	sim := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := NewBinanceAlgorithm(WithClock(sim))
+	key := "binance:global:http"
+
+	for i := 0; i < 10; i++ {
+		decision, err := b.Check(key)
+		require.NoError(t, err)
+		require.True(t, decision.Allowed)
+		require.NoError(t, b.Record(key))
+	}
+
+	// 窗口内超过上限
+	decision, err := b.Check(key)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, time.Second, decision.RetryAfter)
+
+	// 窗口过期由模拟时钟判断，到期时刻仍在窗口内
+	sim.Advance(time.Second)
+	decision, _ = b.Check(key)
+	assert.False(t, decision.Allowed)
+	sim.Advance(time.Millisecond)
+	decision, _ = b.Check(key)
+	assert.True(t, decision.Allowed)
+
+	// 新窗口从过期后的第一次请求开始计时
+	require.NoError(t, b.Record(key))
+	sim.Advance(time.Second)
+	for i := 0; i < 9; i++ {
+		require.NoError(t, b.Record(key))
+	}
+	decision, _ = b.Check(key)
+	assert.False(t, decision.Allowed)
+
+	// 不认识的key直接允许
+	require.NoError(t, b.Record("unknown"))
+	decision, _ = b.Check("unknown")
+	assert.True(t, decision.Allowed)
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
-	"time"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -13,6 +12,7 @@ import (
 	"net/url"
 	"sort"
 	"strconv"
+	"github.com/go-gotop/gotop/clock"
 	"github.com/go-gotop/gotop/requests"
 )
 
@@ -20,13 +20,30 @@ import (
 // 假设用户传入的 URL 是全路径（例如："https://api.binance.com/api/v3/order"），在 BuildRequest 中会自动拼接成完整URL。
 // 对于需要鉴权的请求（即 req.Auth 存在且 SecretKey 不为空），会注入签名相关的参数及头信息。
 type BinanceAdapter struct {
-
+	// clock 生成签名timestamp使用的时钟
+	clock clock.Clock
 }
 
-func NewBinanceAdapter() *BinanceAdapter {
-	return &BinanceAdapter{
+// Option 是BinanceAdapter的配置选项
+type Option func(b *BinanceAdapter)
+
+// WithClock 设置生成签名timestamp使用的时钟，默认为系统时钟
+func WithClock(c clock.Clock) Option {
+	return func(b *BinanceAdapter) {
+		if c != nil {
+			b.clock = c
+		}
+	}
+}
 
+func NewBinanceAdapter(opts ...Option) *BinanceAdapter {
+	b := &BinanceAdapter{
+		clock: clock.Real(),
+	}
+	for _, opt := range opts {
+		opt(b)
 	}
+	return b
 }
 
 // BuildRequest 根据输入参数构建一个完整的 PreparedRequest。
@@ -73,7 +90,7 @@ func (b *BinanceAdapter) BuildRequest(req *requests.Request) (*requests.Prepared
 		queryParams = convertParamsToQuery(params)
 		if needsSignature {
 			// Binance需要添加timestamp
-			timestamp := strconv.FormatInt(time.Now().UnixNano()/1e6, 10)
+			timestamp := strconv.FormatInt(clock.OrReal(b.clock).Now().UnixMilli(), 10)
 			queryParams.Set("timestamp", timestamp)
 			// 对参数进行签名
 			queryStr := queryParams.Encode()
@@ -89,7 +106,7 @@ func (b *BinanceAdapter) BuildRequest(req *requests.Request) (*requests.Prepared
 		// 否则使用JSON序列化参数至body中。
 		if needsSignature {
 			queryParams = convertParamsToQuery(params)
-			timestamp := strconv.FormatInt(time.Now().UnixNano()/1e6, 10)
+			timestamp := strconv.FormatInt(clock.OrReal(b.clock).Now().UnixMilli(), 10)
 			queryParams.Set("timestamp", timestamp)
 
 			queryStr := queryParams.Encode()
@@ -4,7 +4,9 @@ import (
 	"net/http"
 	"net/url"
 	"testing"
+	"time"
 
+	"github.com/go-gotop/gotop/clock"
 	"github.com/go-gotop/gotop/requests"
 )
 
@@ -163,4 +165,49 @@ func TestBinanceAdapter_sign(t *testing.T) {
 			}
 		})
 	}
-} 
\ No newline at end of file
+} 
+func TestBinanceAdapter_WithClock(t *testing.T) {
+	sim := clock.NewSimulated(time.UnixMilli(1578963600000))
+	adapter := NewBinanceAdapter(WithClock(sim))
+	auth := &requests.AuthInfo{APIKey: "testApiKey", SecretKey: "testSecretKey"}
+
+	prepared, err := adapter.BuildRequest(&requests.Request{
+		Method: http.MethodGet,
+		URL:    "/api/v3/order",
+		Params: map[string]interface{}{"symbol": "BTCUSDT"},
+		Auth:   auth,
+	})
+	if err != nil {
+		t.Fatalf("BuildRequest() error = %v", err)
+	}
+	u, err := url.Parse(prepared.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %v", err)
+	}
+	params := u.Query()
+	if got := params.Get("timestamp"); got != "1578963600000" {
+		t.Errorf("BuildRequest() timestamp = %v, want 1578963600000", got)
+	}
+	// 签名包含模拟时钟的timestamp
+	if got, want := params.Get("signature"), sign("symbol=BTCUSDT&timestamp=1578963600000", "testSecretKey"); got != want {
+		t.Errorf("BuildRequest() signature = %v, want %v", got, want)
+	}
+
+	sim.Advance(time.Second)
+	prepared, err = adapter.BuildRequest(&requests.Request{
+		Method: http.MethodPost,
+		URL:    "/api/v3/order",
+		Params: map[string]interface{}{"symbol": "BTCUSDT"},
+		Auth:   auth,
+	})
+	if err != nil {
+		t.Fatalf("BuildRequest() error = %v", err)
+	}
+	params, err = url.ParseQuery(string(prepared.Body))
+	if err != nil {
+		t.Fatalf("Failed to parse body: %v", err)
+	}
+	if got := params.Get("timestamp"); got != "1578963601000" {
+		t.Errorf("BuildRequest() timestamp = %v, want 1578963601000", got)
+	}
+}
@@ -11,19 +11,36 @@ import (
 	"net/url"
 	"sort"
 	"strings"
-	"time"
 
+	"github.com/go-gotop/gotop/clock"
 	"github.com/go-gotop/gotop/requests"
 )
 
 // NewOKXAdapter 创建一个新的 OKXAdapter 实例。
-func NewOKXAdapter() *OKXAdapter {
-	return &OKXAdapter{}
+func NewOKXAdapter(opts ...Option) *OKXAdapter {
+	o := &OKXAdapter{clock: clock.Real()}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Option 是OKXAdapter的配置选项
+type Option func(o *OKXAdapter)
+
+// WithClock 设置生成签名时间戳使用的时钟，默认为系统时钟
+func WithClock(c clock.Clock) Option {
+	return func(o *OKXAdapter) {
+		if c != nil {
+			o.clock = c
+		}
+	}
 }
 
 // OKXAdapter 实现了 ExchangeAdapter 接口，用于根据 OKX 的 HTTP 请求签名流程构建请求。
 type OKXAdapter struct {
-	// 如果有其他通用配置项可在此处扩展。例如日志记录器、调试开关等。
+	// clock 生成签名时间戳使用的时钟
+	clock clock.Clock
 }
 
 // BuildRequest 根据 OKX 的要求构建一个完整的请求。
@@ -111,7 +128,7 @@ func (o *OKXAdapter) BuildRequest(req *requests.Request) (*requests.PreparedRequ
 			pathWithQuery += "?" + parsedURL.RawQuery
 		}
 
-		timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+		timestamp := clock.OrReal(o.clock).Now().UTC().Format("2006-01-02T15:04:05.000Z")
 		var signData string
 		if len(bodyBytes) > 0 {
 			signData = timestamp + method + pathWithQuery + string(bodyBytes)
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-gotop/gotop/clock"
 	"github.com/go-gotop/gotop/requests"
 )
 
@@ -409,4 +410,25 @@ func TestOKXAdapter_Integration_WithAuth(t *testing.T) {
 	if result["code"] != "0" {
 		t.Errorf("Expected code 0, got %v", result["code"])
 	}
-} 
\ No newline at end of file
+}
+
+func TestOKXAdapter_WithClock(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 678e6, time.UTC)
+	adapter := NewOKXAdapter(WithClock(clock.NewSimulated(now)))
+
+	prepared, err := adapter.BuildRequest(&requests.Request{
+		Method: http.MethodGet,
+		URL:    "/api/v5/account/balance",
+		Auth: &requests.AuthInfo{
+			APIKey:     "test-api-key",
+			SecretKey:  "test-secret-key",
+			Passphrase: "test-passphrase",
+		},
+	})
+	if err != nil {
+		t.Fatalf("BuildRequest() error = %v", err)
+	}
+	if got := prepared.Headers.Get("OK-ACCESS-TIMESTAMP"); got != "2024-01-02T03:04:05.678Z" {
+		t.Errorf("BuildRequest() OK-ACCESS-TIMESTAMP = %v, want 2024-01-02T03:04:05.678Z", got)
+	}
+}
@@ -0,0 +1,53 @@
+package risk
+
+import (
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/clock"
+	"github.com/go-gotop/gotop/types"
+)
+
+// Option 是Manager的配置选项
+type Option func(m *Manager)
+
+// WithClock 设置时钟，用于下单频率、单日亏损的日期切换和拒绝事件的时间戳，默认为系统时钟
+func WithClock(c clock.Clock) Option {
+	return func(m *Manager) {
+		m.clock = c
+	}
+}
+
+// WithAccountLimits 设置账户的风控限制
+func WithAccountLimits(accountID string, limits Limits) Option {
+	return func(m *Manager) {
+		m.scope(m.accounts, accountID).limits = limits
+	}
+}
+
+// WithStrategyLimits 设置策略的风控限制
+func WithStrategyLimits(strategyID string, limits Limits) Option {
+	return func(m *Manager) {
+		m.scope(m.strategies, strategyID).limits = limits
+	}
+}
+
+// WithSymbols 登记标的物，以张为单位的信号需要合约面值来计算名义价值。
+// 信号的Symbol与OriginalSymbol或UnifiedSymbol相同即可匹配
+func WithSymbols(symbols ...types.Symbol) Option {
+	return func(m *Manager) {
+		for _, s := range symbols {
+			if s.OriginalSymbol != "" {
+				m.symbols[s.OriginalSymbol] = s
+			}
+			if s.UnifiedSymbol != "" {
+				m.symbols[s.UnifiedSymbol] = s
+			}
+		}
+	}
+}
+
+// WithRejectHandler 设置拒绝回调，例如将拒绝事件发布到消息队列。回调在持有锁时同步执行，不能再调用Manager的方法
+func WithRejectHandler(handler func(event broker.FrameErrorEvent)) Option {
+	return func(m *Manager) {
+		m.onReject = handler
+	}
+}
@@ -0,0 +1,514 @@
+// Package risk 在策略信号提交到 OrderManager.CreateOrder 之前进行风控检查，
+// 支持按账户和按策略设置的持仓、名义价值、挂单数、下单频率、价格偏离、单日亏损限制以及紧急停止
+package risk
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/clock"
+	"github.com/go-gotop/gotop/types"
+)
+
+var (
+	ErrInvalidSignal    = errors.New("risk: invalid signal")
+	ErrKillSwitch       = errors.New("risk: kill switch engaged")
+	ErrDailyLoss        = errors.New("risk: max daily loss reached")
+	ErrMaxOpenOrders    = errors.New("risk: max open orders exceeded")
+	ErrOrderRate        = errors.New("risk: max order rate exceeded")
+	ErrMaxPosition      = errors.New("risk: max position size exceeded")
+	ErrMaxNotional      = errors.New("risk: max notional exceeded")
+	ErrPriceBand        = errors.New("risk: price outside band")
+	ErrNoReferencePrice = errors.New("risk: no reference price")
+	ErrUnknownSymbol    = errors.New("risk: unknown symbol")
+)
+
+// Limits 风控限制，零值表示不限制
+type Limits struct {
+	// MaxPositionSize 单个交易对单个持仓方向的最大净持仓，包括已挂出未成交的数量，单位与信号的Size相同。
+	// 双向持仓模式下多头和空头分别计算，减少持仓的信号不受限制
+	MaxPositionSize decimal.Decimal
+	// MaxNotional 单笔订单的最大名义价值（计价货币）
+	MaxNotional decimal.Decimal
+	// MaxOpenOrders 最大挂单数，只统计带ClientOrderID的订单
+	MaxOpenOrders int
+	// MaxOrders、RateWindow 在RateWindow时间内最多通过MaxOrders个信号
+	MaxOrders  int
+	RateWindow time.Duration
+	// PriceBand 限价单价格相对参考价格的最大偏离比例，例如0.05表示5%。
+	// 参考价格优先使用标记价格，没有时使用最新成交价
+	PriceBand decimal.Decimal
+	// MaxDailyLoss 按UTC日统计的最大已实现亏损（正数），达到后只允许减少持仓的信号
+	MaxDailyLoss decimal.Decimal
+}
+
+// Manager 下单前的风控检查。信号需同时满足所属账户和所属策略的限制，
+// 通过检查的信号计入挂单数和下单频率，订单的后续状态通过UpdateOrder反馈。
+// 挂单只能按ClientOrderID跟踪，由 oms.Manager 生成ID时应通过 oms.WithRiskCheck(m.Allow) 在生成ID后检查。
+// 可以在多个goroutine中并发调用
+type Manager struct {
+	mu       sync.Mutex
+	clock    clock.Clock
+	onReject func(event broker.FrameErrorEvent)
+	symbols  map[string]types.Symbol
+
+	accounts   map[string]*scope
+	strategies map[string]*scope
+	// killed 全局紧急停止的原因，为空表示未停止
+	killed string
+	prices map[string]*reference
+	orders map[string]*order
+}
+
+// scope 账户或策略的风控状态
+type scope struct {
+	limits Limits
+	killed string
+	// openOrders 未结束的订单数
+	openOrders int
+	// accepted 最近通过的信号时间（毫秒），按时间升序
+	accepted []int64
+	// positions 各交易对和持仓方向已成交的净持仓，pending 已挂出未成交的净数量，买为正卖为负
+	positions map[positionKey]decimal.Decimal
+	pending   map[positionKey]decimal.Decimal
+	// day 已实现盈亏所在的UTC日期，pnl 当日已实现盈亏
+	day int64
+	pnl decimal.Decimal
+}
+
+// positionKey 持仓按交易对和持仓方向区分，单向持仓模式的方向为PositionSideUnknown
+type positionKey struct {
+	symbol string
+	side   types.PositionSide
+}
+
+// reference 交易对的参考价格
+type reference struct {
+	mark, last decimal.Decimal
+}
+
+// order 通过检查的订单
+type order struct {
+	key       positionKey
+	account   *scope
+	strategy  *scope
+	remaining decimal.Decimal
+}
+
+// NewManager 创建风控管理器，没有设置限制的账户和策略只受紧急停止约束
+func NewManager(opts ...Option) *Manager {
+	m := &Manager{
+		symbols:    make(map[string]types.Symbol),
+		accounts:   make(map[string]*scope),
+		strategies: make(map[string]*scope),
+		prices:     make(map[string]*reference),
+		orders:     make(map[string]*order),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.clock = clock.OrReal(m.clock)
+	return m
+}
+
+// SetAccountLimits 设置账户的风控限制
+func (m *Manager) SetAccountLimits(accountID string, limits Limits) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scope(m.accounts, accountID).limits = limits
+}
+
+// SetStrategyLimits 设置策略的风控限制
+func (m *Manager) SetStrategyLimits(strategyID string, limits Limits) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scope(m.strategies, strategyID).limits = limits
+}
+
+// Kill 启动全局紧急停止，拒绝所有信号直到调用Resume
+func (m *Manager) Kill(reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.killed = killReason(reason)
+}
+
+// Resume 解除全局紧急停止
+func (m *Manager) Resume() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.killed = ""
+}
+
+// KillAccount 停止账户的所有信号
+func (m *Manager) KillAccount(accountID, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scope(m.accounts, accountID).killed = killReason(reason)
+}
+
+// ResumeAccount 解除账户的停止
+func (m *Manager) ResumeAccount(accountID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scope(m.accounts, accountID).killed = ""
+}
+
+// KillStrategy 停止策略的所有信号
+func (m *Manager) KillStrategy(strategyID, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scope(m.strategies, strategyID).killed = killReason(reason)
+}
+
+// ResumeStrategy 解除策略的停止
+func (m *Manager) ResumeStrategy(strategyID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scope(m.strategies, strategyID).killed = ""
+}
+
+// UpdateMarkPrice 更新标记价格
+func (m *Manager) UpdateMarkPrice(event broker.MarkPriceEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reference(event.Symbol).mark = event.Price
+}
+
+// UpdateTrade 更新最新成交价
+func (m *Manager) UpdateTrade(event broker.TradeEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reference(event.Symbol).last = event.Price
+}
+
+// RecordPnL 计入一笔已实现盈亏，亏损为负数，同时计入账户和策略的当日盈亏
+func (m *Manager) RecordPnL(accountID, strategyID string, pnl decimal.Decimal) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	day := m.today()
+	for _, s := range []*scope{m.scope(m.accounts, accountID), m.scope(m.strategies, strategyID)} {
+		s.rollDay(day)
+		s.pnl = s.pnl.Add(pnl)
+	}
+}
+
+// DailyPnL 返回账户当日的已实现盈亏
+func (m *Manager) DailyPnL(accountID string) decimal.Decimal {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.scope(m.accounts, accountID).dailyPnL(m.today())
+}
+
+// Position 返回账户在交易对和持仓方向上已成交的净持仓，单向持仓模式的side为PositionSideUnknown
+func (m *Manager) Position(accountID, symbol string, side types.PositionSide) decimal.Decimal {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.scope(m.accounts, accountID).positions[positionKey{symbol, side}]
+}
+
+// UpdateOrder 根据订单结果更新持仓和挂单数。成交按LatestVolume计入持仓，
+// 订单全部成交、取消、拒绝或过期后不再计入挂单数
+func (m *Manager) UpdateOrder(event broker.OrderResultEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.orders[event.ClientOrderID]
+	if !ok {
+		return
+	}
+	if event.ExecutionType == types.ExecutionTypeTrade && event.LatestVolume.IsPositive() {
+		filled := decimal.Min(event.LatestVolume, o.remaining.Abs())
+		if o.remaining.IsNegative() {
+			filled = filled.Neg()
+		}
+		o.remaining = o.remaining.Sub(filled)
+		for _, s := range []*scope{o.account, o.strategy} {
+			s.positions[o.key] = s.positions[o.key].Add(filled)
+			s.pending[o.key] = s.pending[o.key].Sub(filled)
+		}
+	}
+
+	switch {
+	case event.State == types.OrderStateFilled,
+		event.State == types.OrderStateCanceled,
+		event.State == types.OrderStateRejected,
+		event.ExecutionType == types.ExecutionTypeCanceled,
+		event.ExecutionType == types.ExecutionTypeRejected,
+		event.ExecutionType == types.ExecutionTypeExpired:
+		m.release(event.ClientOrderID, o)
+	}
+}
+
+// Release 释放通过检查但未能提交到交易所的订单，使其不再计入挂单数
+func (m *Manager) Release(clientOrderID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if o, ok := m.orders[clientOrderID]; ok {
+		m.release(clientOrderID, o)
+	}
+}
+
+// Check 检查信号，通过时返回nil并计入挂单数和下单频率，拒绝时返回包含原因的帧错误事件
+func (m *Manager) Check(signal broker.StrategySignalEvent) *broker.FrameErrorEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	event, _ := m.admit(signal)
+	return event
+}
+
+// Allow 与Check相同，拒绝时返回原因，可以作为 oms.WithRiskCheck 的检查函数
+func (m *Manager) Allow(signal broker.StrategySignalEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := m.admit(signal)
+	return err
+}
+
+// admit 检查信号并在通过时计入挂单数和下单频率
+func (m *Manager) admit(signal broker.StrategySignalEvent) (*broker.FrameErrorEvent, error) {
+	now := m.clock.Now()
+	if err := m.check(signal, now); err != nil {
+		event := broker.FrameErrorEvent{
+			Error:         err.Error(),
+			PositionID:    signal.PositionID,
+			TransactionID: signal.TransactionID,
+			AccountID:     signal.AccountID,
+			Timestamp:     now.UnixMilli(),
+			ClientOrderID: signal.ClientOrderID,
+		}
+		if m.onReject != nil {
+			m.onReject(event)
+		}
+		return &event, err
+	}
+
+	account := m.scope(m.accounts, signal.AccountID)
+	strategy := m.scope(m.strategies, signal.StrategyID)
+	delta := signed(signal)
+	key := positionKey{signal.Symbol, signal.PositionSide}
+	// 所有范围都通过检查后才更新状态，被策略范围拒绝的信号不会改变账户范围的状态
+	for _, s := range []*scope{account, strategy} {
+		if s.limits.MaxOrders > 0 && s.limits.RateWindow > 0 {
+			cutoff := now.UnixMilli() - s.limits.RateWindow.Milliseconds()
+			s.accepted = append(s.accepted[s.expired(cutoff):], now.UnixMilli())
+		}
+		if signal.ClientOrderID != "" {
+			s.openOrders++
+			s.pending[key] = s.pending[key].Add(delta)
+		}
+	}
+	if signal.ClientOrderID != "" {
+		m.orders[signal.ClientOrderID] = &order{
+			key:       key,
+			account:   account,
+			strategy:  strategy,
+			remaining: delta,
+		}
+	}
+	return nil, nil
+}
+
+func (m *Manager) check(signal broker.StrategySignalEvent, now time.Time) error {
+	if !signal.Size.IsPositive() {
+		return fmt.Errorf("%w: size must be positive", ErrInvalidSignal)
+	}
+	if signal.Side != types.SideTypeBuy && signal.Side != types.SideTypeSell {
+		return fmt.Errorf("%w: unknown side %s", ErrInvalidSignal, signal.Side)
+	}
+	if _, ok := m.orders[signal.ClientOrderID]; ok && signal.ClientOrderID != "" {
+		return fmt.Errorf("%w: duplicate client order id %s", ErrInvalidSignal, signal.ClientOrderID)
+	}
+	if m.killed != "" {
+		return fmt.Errorf("%w: %s", ErrKillSwitch, m.killed)
+	}
+
+	scopes := []struct {
+		name string
+		s    *scope
+	}{
+		{"account " + signal.AccountID, m.scope(m.accounts, signal.AccountID)},
+		{"strategy " + signal.StrategyID, m.scope(m.strategies, signal.StrategyID)},
+	}
+	day := m.today()
+	for _, sc := range scopes {
+		if err := m.checkScope(sc.s, signal, now, day); err != nil {
+			return fmt.Errorf("%w (%s)", err, sc.name)
+		}
+	}
+	return nil
+}
+
+// checkScope 按单个账户或策略的限制检查信号，只读取状态不做修改
+func (m *Manager) checkScope(s *scope, signal broker.StrategySignalEvent, now time.Time, day int64) error {
+	l := s.limits
+	if s.killed != "" {
+		return fmt.Errorf("%w: %s", ErrKillSwitch, s.killed)
+	}
+
+	key := positionKey{signal.Symbol, signal.PositionSide}
+	exposure := s.positions[key].Add(s.pending[key])
+	projected := exposure.Add(signed(signal))
+	increasing := projected.Abs().GreaterThan(exposure.Abs())
+
+	pnl := s.dailyPnL(day)
+	if l.MaxDailyLoss.IsPositive() && increasing && pnl.Neg().GreaterThanOrEqual(l.MaxDailyLoss) {
+		return fmt.Errorf("%w: pnl %s, limit %s", ErrDailyLoss, pnl, l.MaxDailyLoss)
+	}
+	if l.MaxOpenOrders > 0 && signal.ClientOrderID != "" && s.openOrders >= l.MaxOpenOrders {
+		return fmt.Errorf("%w: %d open orders", ErrMaxOpenOrders, s.openOrders)
+	}
+	if l.MaxOrders > 0 && l.RateWindow > 0 {
+		cutoff := now.UnixMilli() - l.RateWindow.Milliseconds()
+		if n := len(s.accepted) - s.expired(cutoff); n >= l.MaxOrders {
+			return fmt.Errorf("%w: %d orders in %s", ErrOrderRate, n, l.RateWindow)
+		}
+	}
+	if l.MaxPositionSize.IsPositive() && increasing && projected.Abs().GreaterThan(l.MaxPositionSize) {
+		return fmt.Errorf("%w: projected %s, limit %s", ErrMaxPosition, projected, l.MaxPositionSize)
+	}
+
+	ref := m.referencePrice(signal.Symbol)
+	if l.PriceBand.IsPositive() && signal.Price.IsPositive() {
+		if !ref.IsPositive() {
+			return fmt.Errorf("%w for %s", ErrNoReferencePrice, signal.Symbol)
+		}
+		deviation := signal.Price.Sub(ref).Abs().Div(ref)
+		if deviation.GreaterThan(l.PriceBand) {
+			return fmt.Errorf("%w: price %s deviates %s from reference %s", ErrPriceBand, signal.Price, deviation.StringFixed(4), ref)
+		}
+	}
+	if l.MaxNotional.IsPositive() {
+		price := signal.Price
+		if !price.IsPositive() {
+			price = ref
+		}
+		notional, err := m.notional(signal, price)
+		if err != nil {
+			return err
+		}
+		if notional.GreaterThan(l.MaxNotional) {
+			return fmt.Errorf("%w: notional %s, limit %s", ErrMaxNotional, notional, l.MaxNotional)
+		}
+	}
+	return nil
+}
+
+// notional 信号的名义价值
+func (m *Manager) notional(signal broker.StrategySignalEvent, price decimal.Decimal) (decimal.Decimal, error) {
+	switch signal.SizeUnit {
+	case types.SizeUnitQuote:
+		return signal.Size, nil
+	case types.SizeUnitContract:
+		sym, ok := m.symbols[signal.Symbol]
+		if !ok || !sym.CtVal.IsPositive() {
+			return decimal.Zero, fmt.Errorf("%w: contract value of %s", ErrUnknownSymbol, signal.Symbol)
+		}
+		// 币本位合约面值以计价货币计
+		if sym.Type == types.MarketTypeFuturesCoinMargined || sym.Type == types.MarketTypePerpetualCoinMargined {
+			return signal.Size.Mul(sym.CtVal), nil
+		}
+		if !price.IsPositive() {
+			return decimal.Zero, fmt.Errorf("%w for %s", ErrNoReferencePrice, signal.Symbol)
+		}
+		return signal.Size.Mul(sym.CtVal).Mul(price), nil
+	}
+	if !price.IsPositive() {
+		return decimal.Zero, fmt.Errorf("%w for %s", ErrNoReferencePrice, signal.Symbol)
+	}
+	return signal.Size.Mul(price), nil
+}
+
+// referencePrice 标记价格优先，没有时使用最新成交价
+func (m *Manager) referencePrice(symbol string) decimal.Decimal {
+	ref, ok := m.prices[symbol]
+	if !ok {
+		return decimal.Zero
+	}
+	if ref.mark.IsPositive() {
+		return ref.mark
+	}
+	return ref.last
+}
+
+func (m *Manager) reference(symbol string) *reference {
+	ref, ok := m.prices[symbol]
+	if !ok {
+		ref = &reference{}
+		m.prices[symbol] = ref
+	}
+	return ref
+}
+
+func (m *Manager) scope(scopes map[string]*scope, id string) *scope {
+	s, ok := scopes[id]
+	if !ok {
+		s = &scope{
+			positions: make(map[positionKey]decimal.Decimal),
+			pending:   make(map[positionKey]decimal.Decimal),
+		}
+		scopes[id] = s
+	}
+	return s
+}
+
+// release 订单结束，未成交的数量不再计入持仓
+func (m *Manager) release(clientOrderID string, o *order) {
+	for _, s := range []*scope{o.account, o.strategy} {
+		s.openOrders--
+		s.pending[o.key] = s.pending[o.key].Sub(o.remaining)
+	}
+	delete(m.orders, clientOrderID)
+}
+
+// today 当前UTC日期，为1970-01-01以来的天数
+func (m *Manager) today() int64 {
+	return m.clock.Now().UTC().Unix() / 86400
+}
+
+// dailyPnL 返回day的已实现盈亏，盈亏不是当日的时为0
+func (s *scope) dailyPnL(day int64) decimal.Decimal {
+	if s.day != day {
+		return decimal.Zero
+	}
+	return s.pnl
+}
+
+// expired 返回accepted中不晚于cutoff、已移出限速窗口的信号数量
+func (s *scope) expired(cutoff int64) int {
+	i := 0
+	for i < len(s.accepted) && s.accepted[i] <= cutoff {
+		i++
+	}
+	return i
+}
+
+// rollDay 跨日时清零当日盈亏
+func (s *scope) rollDay(day int64) {
+	if s.day != day {
+		s.day = day
+		s.pnl = decimal.Zero
+	}
+}
+
+// signed 信号的带方向数量，买为正卖为负
+func signed(signal broker.StrategySignalEvent) decimal.Decimal {
+	if signal.Side == types.SideTypeSell {
+		return signal.Size.Neg()
+	}
+	return signal.Size
+}
+
+func killReason(reason string) string {
+	if reason == "" {
+		return "manual"
+	}
+	return reason
+}
@@ -0,0 +1,248 @@
+package risk
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/clock"
+	"github.com/go-gotop/gotop/types"
+)
+
+func d(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func signal(id string, side types.SideType, size, price string) broker.StrategySignalEvent {
+	return broker.StrategySignalEvent{
+		AccountID:     "acc",
+		StrategyID:    "grid",
+		ClientOrderID: id,
+		Symbol:        "BTCUSDT",
+		Side:          side,
+		OrderType:     types.OrderTypeLimit,
+		Size:          d(size),
+		SizeUnit:      types.SizeUnitCoin,
+		Price:         d(price),
+	}
+}
+
+func assertRejected(t *testing.T, event *broker.FrameErrorEvent, err error) {
+	t.Helper()
+	require.NotNil(t, event)
+	assert.True(t, strings.HasPrefix(event.Error, err.Error()), event.Error)
+}
+
+func TestPriceBand(t *testing.T) {
+	m := NewManager(WithAccountLimits("acc", Limits{PriceBand: d("0.05")}))
+
+	// 没有参考价格时拒绝限价单
+	assertRejected(t, m.Check(signal("1", types.SideTypeBuy, "1", "100")), ErrNoReferencePrice)
+
+	m.UpdateTrade(broker.TradeEvent{Symbol: "BTCUSDT", Price: d("100")})
+	assert.Nil(t, m.Check(signal("2", types.SideTypeBuy, "1", "104")))
+
+	// 标记价格优先于最新成交价
+	m.UpdateMarkPrice(broker.MarkPriceEvent{Symbol: "BTCUSDT", Price: d("110")})
+	assert.Nil(t, m.Check(signal("3", types.SideTypeBuy, "1", "114")))
+
+	// 多输了一个0
+	event := m.Check(signal("4", types.SideTypeBuy, "1", "1100"))
+	assertRejected(t, event, ErrPriceBand)
+	assert.Equal(t, "acc", event.AccountID)
+	assert.Equal(t, "4", event.ClientOrderID)
+	assert.Contains(t, event.Error, "account acc")
+
+	// 市价单不检查价格偏离
+	market := signal("5", types.SideTypeBuy, "1", "0")
+	market.OrderType = types.OrderTypeMarket
+	assert.Nil(t, m.Check(market))
+}
+
+func TestMaxNotional(t *testing.T) {
+	m := NewManager(
+		WithStrategyLimits("grid", Limits{MaxNotional: d("1000")}),
+		WithSymbols(types.Symbol{
+			OriginalSymbol: "BTCUSDT",
+			Type:           types.MarketTypePerpetualUSDMargined,
+			CtVal:          d("0.01"),
+		}),
+	)
+	m.UpdateTrade(broker.TradeEvent{Symbol: "BTCUSDT", Price: d("200")})
+
+	assertRejected(t, m.Check(signal("1", types.SideTypeBuy, "6", "200")), ErrMaxNotional)
+
+	quote := signal("2", types.SideTypeBuy, "1500", "0")
+	quote.SizeUnit = types.SizeUnitQuote
+	assertRejected(t, m.Check(quote), ErrMaxNotional)
+
+	// 400张 × 0.01 × 200 = 800
+	contract := signal("3", types.SideTypeBuy, "400", "200")
+	contract.SizeUnit = types.SizeUnitContract
+	assert.Nil(t, m.Check(contract))
+	contract.ClientOrderID = "4"
+	contract.Size = d("600")
+	assertRejected(t, m.Check(contract), ErrMaxNotional)
+
+	// 未登记的标的无法换算张数
+	contract.Symbol = "ETHUSDT"
+	assertRejected(t, m.Check(contract), ErrUnknownSymbol)
+}
+
+func TestMaxPosition(t *testing.T) {
+	m := NewManager(WithStrategyLimits("grid", Limits{MaxPositionSize: d("5")}))
+
+	assert.Nil(t, m.Check(signal("3", types.SideTypeBuy, "2", "200")))
+	m.Release("3")
+
+	assert.Nil(t, m.Check(signal("4", types.SideTypeBuy, "3", "200")))
+	// 已挂出3，再买3超过5
+	assertRejected(t, m.Check(signal("5", types.SideTypeBuy, "3", "200")), ErrMaxPosition)
+
+	m.UpdateOrder(broker.OrderResultEvent{
+		ClientOrderID: "4",
+		ExecutionType: types.ExecutionTypeTrade,
+		State:         types.OrderStateFilled,
+		LatestVolume:  d("3"),
+	})
+	assert.Equal(t, "0", m.Position("acc", "ETHUSDT", types.PositionSideUnknown).String())
+	assert.Equal(t, "3", m.Position("acc", "BTCUSDT", types.PositionSideUnknown).String())
+	assert.Nil(t, m.Check(signal("6", types.SideTypeBuy, "2", "200")))
+	assertRejected(t, m.Check(signal("7", types.SideTypeBuy, "1", "200")), ErrMaxPosition)
+	// 减少持仓不受限制
+	assert.Nil(t, m.Check(signal("8", types.SideTypeSell, "4", "200")))
+}
+
+func TestOpenOrdersAndRate(t *testing.T) {
+	c := clock.NewSimulated(time.UnixMilli(0))
+	m := NewManager(
+		WithClock(c),
+		WithAccountLimits("acc", Limits{MaxOpenOrders: 2, MaxOrders: 3, RateWindow: time.Second}),
+	)
+
+	assert.Nil(t, m.Check(signal("1", types.SideTypeBuy, "1", "0")))
+	assert.Nil(t, m.Check(signal("2", types.SideTypeBuy, "1", "0")))
+	assertRejected(t, m.Check(signal("3", types.SideTypeBuy, "1", "0")), ErrMaxOpenOrders)
+	assertRejected(t, m.Check(signal("2", types.SideTypeBuy, "1", "0")), ErrInvalidSignal)
+
+	m.UpdateOrder(broker.OrderResultEvent{ClientOrderID: "1", ExecutionType: types.ExecutionTypeCanceled, State: types.OrderStateCanceled})
+	assert.Nil(t, m.Check(signal("3", types.SideTypeBuy, "1", "0")))
+
+	m.UpdateOrder(broker.OrderResultEvent{ClientOrderID: "2", ExecutionType: types.ExecutionTypeExpired})
+	// 1秒内已通过3个信号
+	event := m.Check(signal("4", types.SideTypeBuy, "1", "0"))
+	assertRejected(t, event, ErrOrderRate)
+	assert.Equal(t, int64(0), event.Timestamp)
+
+	c.Advance(time.Second)
+	assert.Nil(t, m.Check(signal("4", types.SideTypeBuy, "1", "0")))
+}
+
+func TestDailyLoss(t *testing.T) {
+	c := clock.NewSimulated(time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC))
+	m := NewManager(WithClock(c), WithAccountLimits("acc", Limits{MaxDailyLoss: d("100")}))
+
+	assert.Nil(t, m.Check(signal("1", types.SideTypeBuy, "1", "0")))
+	m.UpdateOrder(broker.OrderResultEvent{ClientOrderID: "1", ExecutionType: types.ExecutionTypeTrade, State: types.OrderStateFilled, LatestVolume: d("1")})
+
+	m.RecordPnL("acc", "grid", d("-60"))
+	m.RecordPnL("acc", "other", d("-40"))
+	assert.Equal(t, "-100", m.DailyPnL("acc").String())
+
+	assertRejected(t, m.Check(signal("2", types.SideTypeBuy, "1", "0")), ErrDailyLoss)
+	// 平仓仍然允许
+	assert.Nil(t, m.Check(signal("3", types.SideTypeSell, "1", "0")))
+
+	// UTC日切换后重置
+	c.Advance(time.Hour)
+	assert.True(t, m.DailyPnL("acc").IsZero())
+	assert.Nil(t, m.Check(signal("4", types.SideTypeBuy, "1", "0")))
+}
+
+func TestRejectKeepsScopeState(t *testing.T) {
+	c := clock.NewSimulated(time.Date(2024, 1, 1, 23, 59, 59, 0, time.UTC))
+	m := NewManager(
+		WithClock(c),
+		WithAccountLimits("acc", Limits{MaxOrders: 1, RateWindow: time.Second}),
+		WithStrategyLimits("grid", Limits{MaxPositionSize: d("1")}),
+	)
+
+	assert.Nil(t, m.Check(signal("1", types.SideTypeBuy, "1", "0")))
+	m.RecordPnL("acc", "grid", d("-10"))
+	account := m.accounts["acc"]
+	day := account.day
+
+	// 账户范围通过、策略范围拒绝时，账户范围的限速窗口和当日盈亏不变
+	c.Advance(2 * time.Second)
+	assertRejected(t, m.Check(signal("2", types.SideTypeBuy, "5", "0")), ErrMaxPosition)
+	assert.Equal(t, []int64{c.Now().Add(-2 * time.Second).UnixMilli()}, account.accepted)
+	assert.Equal(t, day, account.day)
+	assert.Equal(t, "-10", account.pnl.String())
+	assert.True(t, m.DailyPnL("acc").IsZero())
+
+	// 通过后才移出过期的信号
+	assert.Nil(t, m.Check(signal("3", types.SideTypeSell, "1", "0")))
+	assert.Equal(t, []int64{c.Now().UnixMilli()}, account.accepted)
+}
+
+func TestKillSwitch(t *testing.T) {
+	var rejected []broker.FrameErrorEvent
+	m := NewManager(WithRejectHandler(func(event broker.FrameErrorEvent) {
+		rejected = append(rejected, event)
+	}))
+
+	m.Kill("exchange outage")
+	event := m.Check(signal("1", types.SideTypeSell, "1", "0"))
+	assertRejected(t, event, ErrKillSwitch)
+	assert.Contains(t, event.Error, "exchange outage")
+	m.Resume()
+	assert.Nil(t, m.Check(signal("1", types.SideTypeSell, "1", "0")))
+
+	m.KillStrategy("grid", "")
+	assertRejected(t, m.Check(signal("2", types.SideTypeSell, "1", "0")), ErrKillSwitch)
+	other := signal("3", types.SideTypeSell, "1", "0")
+	other.StrategyID = "trend"
+	assert.Nil(t, m.Check(other))
+	m.ResumeStrategy("grid")
+
+	other.ClientOrderID = "5"
+	m.KillAccount("acc", "margin call")
+	assertRejected(t, m.Check(other), ErrKillSwitch)
+	m.ResumeAccount("acc")
+
+	assertRejected(t, m.Check(signal("4", types.SideTypeBuy, "0", "0")), ErrInvalidSignal)
+	assert.Len(t, rejected, 4)
+}
+
+func TestPositionSide(t *testing.T) {
+	m := NewManager(WithAccountLimits("acc", Limits{MaxPositionSize: d("5")}))
+
+	long := signal("1", types.SideTypeBuy, "4", "100")
+	long.PositionSide = types.PositionSideLong
+	assert.Nil(t, m.Check(long))
+	m.UpdateOrder(broker.OrderResultEvent{
+		ClientOrderID: "1",
+		ExecutionType: types.ExecutionTypeTrade,
+		State:         types.OrderStateFilled,
+		LatestVolume:  d("4"),
+	})
+	assert.Equal(t, "4", m.Position("acc", "BTCUSDT", types.PositionSideLong).String())
+	assert.Equal(t, "0", m.Position("acc", "BTCUSDT", types.PositionSideShort).String())
+
+	// 双向持仓的空头开仓不会被当作多头平仓
+	short := signal("2", types.SideTypeSell, "6", "100")
+	short.PositionSide = types.PositionSideShort
+	assertRejected(t, m.Check(short), ErrMaxPosition)
+	short.Size = d("5")
+	assert.Nil(t, m.Check(short))
+
+	// 多头平仓减少持仓，不受限制
+	closeLong := signal("3", types.SideTypeSell, "4", "100")
+	closeLong.PositionSide = types.PositionSideLong
+	assert.Nil(t, m.Check(closeLong))
+}
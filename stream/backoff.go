@@ -0,0 +1,73 @@
+package stream
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff 重连退避策略
+type Backoff interface {
+	// Next 返回第attempt次（从1开始）重连失败后的等待时长，ok为false表示放弃重连
+	Next(attempt int) (wait time.Duration, ok bool)
+}
+
+// ExponentialBackoff 指数退避，等待时长为 Initial * Multiplier^(attempt-1)，不超过Max，
+// 并在此基础上增加 ±Jitter 比例的随机抖动，避免大量连接同时重连。
+type ExponentialBackoff struct {
+	// Initial 首次等待时长
+	Initial time.Duration
+	// Max 最大等待时长
+	Max time.Duration
+	// Multiplier 每次失败后的增长倍数
+	Multiplier float64
+	// Jitter 随机抖动比例，取值[0,1]
+	Jitter float64
+	// MaxAttempts 最大重连次数，0表示无限重连
+	MaxAttempts int
+}
+
+// DefaultBackoff 默认退避策略：1秒起，翻倍增长，最大1分钟，20%抖动，无限重连
+func DefaultBackoff() *ExponentialBackoff {
+	return &ExponentialBackoff{
+		Initial:    time.Second,
+		Max:        time.Minute,
+		Multiplier: 2,
+		Jitter:     0.2,
+	}
+}
+
+// Next 实现Backoff接口
+func (e *ExponentialBackoff) Next(attempt int) (time.Duration, bool) {
+	if e.MaxAttempts > 0 && attempt >= e.MaxAttempts {
+		return 0, false
+	}
+	multiplier := e.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	wait := float64(e.Initial) * math.Pow(multiplier, float64(attempt-1))
+	if e.Max > 0 && wait > float64(e.Max) {
+		wait = float64(e.Max)
+	}
+	if e.Jitter > 0 {
+		wait += wait * e.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(wait), true
+}
+
+// ConstantBackoff 固定间隔重连
+type ConstantBackoff struct {
+	// Interval 重连间隔
+	Interval time.Duration
+	// MaxAttempts 最大重连次数，0表示无限重连
+	MaxAttempts int
+}
+
+// Next 实现Backoff接口
+func (c *ConstantBackoff) Next(attempt int) (time.Duration, bool) {
+	if c.MaxAttempts > 0 && attempt >= c.MaxAttempts {
+		return 0, false
+	}
+	return c.Interval, true
+}
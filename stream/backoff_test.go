@@ -0,0 +1,44 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	b := &ExponentialBackoff{
+		Initial:     100 * time.Millisecond,
+		Max:         time.Second,
+		Multiplier:  2,
+		MaxAttempts: 6,
+	}
+
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+	}
+	for i, want := range expected {
+		wait, ok := b.Next(i + 1)
+		require.True(t, ok)
+		require.Equal(t, want, wait)
+	}
+
+	// 达到最大次数后放弃
+	_, ok := b.Next(6)
+	require.False(t, ok)
+}
+
+func TestExponentialBackoffJitter(t *testing.T) {
+	b := DefaultBackoff()
+	for attempt := 1; attempt < 100; attempt++ {
+		wait, ok := b.Next(attempt)
+		require.True(t, ok, "default backoff never gives up")
+		require.LessOrEqual(t, wait, time.Duration(float64(b.Max)*(1+b.Jitter)))
+		require.GreaterOrEqual(t, wait, time.Duration(float64(b.Initial)*(1-b.Jitter)))
+	}
+}
@@ -1,105 +1,155 @@
 package binance
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"log/slog"
-	"net/http"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/gorilla/websocket"
+	"github.com/go-gotop/gotop/clock"
+	"github.com/go-gotop/gotop/stream"
+	"github.com/go-gotop/gotop/stream/ws"
 	"github.com/go-gotop/gotop/types"
 )
 
 // BinanceRequest Binance Stream的配置参数
 type BinanceRequest struct {
 	// WebSocket请求的URL
+	// 使用组合流时传入组合流的基础地址，例如"wss://stream.binance.com:9443/stream"
 	URL string
+	// Streams 组合流初始订阅的流名称列表，例如"btcusdt@trade"
+	// 不为空时连接地址为 URL?streams=<stream1>/<stream2>...，收到的消息格式为 {"stream":"<name>","data":<payload>}
+	Streams []string
 	// Logger 可选的日志记录器，用于调试
 	Logger *slog.Logger
 	// Handler 数据处理函数
 	Handler func(data []byte)
 	// ErrorHandler 错误处理函数
 	ErrorHandler func(err error)
+	// SequenceFunc 可选，从消息中提取去重键和序列号（如成交ID），用于重连时去重
+	SequenceFunc stream.SequenceFunc
+	// BackfillFunc 可选，非无缝重连成功后回补断线期间缺失的数据。
+	// last 为各键已投递的最大序列号，返回的消息格式需与推送消息一致，
+	// 回补期间实时消息会被暂存，回补消息与暂存消息去重后按顺序投递。
+	BackfillFunc func(ctx context.Context, last map[string]int64) ([][]byte, error)
 }
 
-// dialFunc定义用于方便在测试时mock连接的逻辑
-type dialFunc func(urlStr string, requestHeader http.Header) (*websocket.Conn, *http.Response, error)
+// maxControlMessages 每秒最多发送的SUBSCRIBE/UNSUBSCRIBE请求数，Binance限制单个连接每秒5条消息
+const maxControlMessages = 5
 
-// BinanceStream 是Binance Stream的核心结构体
+// controlRequest 待发送的SUBSCRIBE/UNSUBSCRIBE请求，相同方法的连续请求合并为一条
+type controlRequest struct {
+	method string
+	params []string
+	done   chan struct{}
+	err    error
+}
+
+// BinanceStream 是Binance Stream的适配器，连接管理由ws.Client完成，
+// 这里只负责组合流地址、订阅请求和订阅响应的过滤。
 type BinanceStream struct {
 	mu     sync.Mutex
 	id     string
 	st     types.StreamType
 	cfg    BinanceRequest
-	conn   *websocket.Conn
-	ctx    context.Context
-	cancel context.CancelFunc
+	client *ws.Client
 
 	// 用于重连的dial函数，可在测试中mock
-	dialer dialFunc
+	dialer ws.DialFunc
 
 	// 心跳与超时配置
 	pingInterval time.Duration
 	pongWait     time.Duration
 	writeWait    time.Duration
 
-	// 用于在24小时后重连的ticker和控制重连的通道
+	// 定时重连间隔，Binance的连接24小时后会被断开
 	reconnectInterval time.Duration
-	reconnectTicker   *time.Ticker
 
-	// 用于等待后台goroutine的结束
-	wg sync.WaitGroup
+	// seamlessOverlap 无缝重连时新旧连接并行的时长，为0时定时重连采用先断后连
+	seamlessOverlap time.Duration
+
+	// backoff 重连退避策略
+	backoff stream.Backoff
 
-	// 用于标识后台goroutine的完成，以便Disconnect时等待
-	doneCh chan struct{}
+	// stateHandler 连接状态变化回调
+	stateHandler stream.StateHandler
 
-	// 用于记录数据序列的占位变量，未来可用于无缝重连的数据去重处理
-	lastSequence int64
+	// clientOpts 额外传给ws.Client的配置，例如有界队列
+	clientOpts []ws.Option
 
-	// 是否正在尝试重连
-	reconnecting bool
+	// subscriptions 当前组合流订阅的流名称，重连时据此重新构建连接地址
+	subscriptions map[string]struct{}
+
+	// requestID SUBSCRIBE/UNSUBSCRIBE 请求的自增ID
+	requestID int64
+
+	// clock 时钟，用于控制请求限速
+	clock clock.Clock
+	// sendMu 保证同一时间只有一个goroutine发送控制请求
+	sendMu sync.Mutex
+	// pending 等待发送的控制请求
+	pending []*controlRequest
+	// sent 最近发送控制请求的时间，用于限速
+	sent []time.Time
 }
 
 // NewBinanceStream 创建一个新的BinanceStream
 func NewBinanceStream(id string, st types.StreamType, opts ...Option) *BinanceStream {
 	b := &BinanceStream{
-		id:     id,
-		st:     st,
-		dialer:            defaultDialer,
+		id:                id,
+		st:                st,
+		dialer:            ws.DefaultDialer,
 		pingInterval:      30 * time.Second,
 		pongWait:          60 * time.Second,
 		writeWait:         5 * time.Second,
 		reconnectInterval: 23 * time.Hour, // 24小时自动重连周期
+		backoff:           stream.DefaultBackoff(),
 	}
 	applyOptions(b, opts...)
+	b.clock = clock.OrReal(b.clock)
 	return b
 }
 
-// defaultDialer 默认的dial函数
-func defaultDialer(urlStr string, requestHeader http.Header) (*websocket.Conn, *http.Response, error) {
-	var d websocket.Dialer
-	return d.Dial(urlStr, requestHeader)
-}
-
 // Connect 连接到Binance Stream
 func (b *BinanceStream) Connect(ctx context.Context, cfg BinanceRequest) error {
-	b.mu.Lock()
-	defer b.mu.Unlock()
+	opts := append([]ws.Option{
+		ws.WithDialer(b.dialer),
+		ws.WithPingInterval(b.pingInterval),
+		ws.WithPongWait(b.pongWait),
+		ws.WithWriteWait(b.writeWait),
+		ws.WithReconnectInterval(b.reconnectInterval),
+		ws.WithSeamlessReconnect(b.seamlessOverlap),
+		ws.WithBackoff(b.backoff),
+		ws.WithStateHandler(b.stateHandler),
+		ws.WithClock(b.clock),
+	}, b.clientOpts...)
+	client := ws.NewClient(b.id, ws.Hooks{
+		URL:    b.dialURL,
+		Filter: b.filter,
+	}, opts...)
 
+	b.mu.Lock()
 	b.cfg = cfg
-	b.ctx, b.cancel = context.WithCancel(ctx)
-	b.reconnecting = false
-
-	if err := b.connect(); err != nil {
-		return err
+	b.client = client
+	b.subscriptions = make(map[string]struct{}, len(cfg.Streams))
+	for _, s := range cfg.Streams {
+		b.subscriptions[s] = struct{}{}
 	}
+	b.mu.Unlock()
 
-	// 启动后台goroutine
-	b.startGoroutines()
-
-	b.log("Connected successfully")
-	return nil
+	return client.Connect(ctx, ws.Config{
+		URL:          cfg.URL,
+		Logger:       cfg.Logger,
+		Handler:      cfg.Handler,
+		ErrorHandler: cfg.ErrorHandler,
+		SequenceFunc: cfg.SequenceFunc,
+		BackfillFunc: cfg.BackfillFunc,
+	})
 }
 
 // ID 返回当前连接的ID
@@ -115,199 +165,198 @@ func (b *BinanceStream) Type() types.StreamType {
 // Disconnect 断开当前连接
 func (b *BinanceStream) Disconnect() error {
 	b.mu.Lock()
-	// 取消当前上下文
-	if b.cancel != nil {
-		b.cancel()
-	}
-	// 关闭当前连接
-	if b.conn != nil {
-		_ = b.conn.Close()
-		b.conn = nil
-	}
+	client := b.client
 	b.mu.Unlock()
 
-	// 等待所有goroutine结束
-	b.wg.Wait()
-	b.log("Disconnected")
-	return nil
-}
-
-func (b *BinanceStream) connect() error {
-	c, _, err := b.dialer(b.cfg.URL, nil)
-	if err != nil {
-		b.handleErr(err)
-		return err
-	}
-
-	b.conn = c
-	b.conn.SetReadDeadline(time.Now().Add(b.pongWait))
-	b.conn.SetPongHandler(func(string) error {
-		b.conn.SetReadDeadline(time.Now().Add(b.pongWait))
+	if client == nil {
 		return nil
-	})
-	return nil
-}
-
-// startGoroutines 启动后台goroutine
-func (b *BinanceStream) startGoroutines() {
-	// 开始readLoop
-	b.wg.Add(1)
-	go b.readLoop()
-
-	// 开始pingLoop
-	b.wg.Add(1)
-	go b.pingLoop()
-
-	// 开始autoReconnectLoop
-	b.wg.Add(1)
-	go b.autoReconnectLoop()
+	}
+	return client.Disconnect()
 }
 
-// readLoop 读取数据
-func (b *BinanceStream) readLoop() {
-	defer b.wg.Done()
+// State 返回当前连接状态
+func (b *BinanceStream) State() stream.State {
+	b.mu.Lock()
+	client := b.client
+	b.mu.Unlock()
 
-	for {
-		select {
-		case <-b.ctx.Done():
-			return
-		default:
-			b.mu.Lock()
-			conn := b.conn
-			b.mu.Unlock()
-
-			if conn == nil {
-				// 如果没有连接，稍等等待重连
-				time.Sleep(500 * time.Millisecond)
-				continue
-			}
-
-			_, msg, err := conn.ReadMessage()
-			if err != nil {
-				// 通知错误
-				b.handleErr(err)
-
-				// 异步尝试重连，readLoop立即返回，防止死锁
-				go b.attemptReconnect()
-
-				return // 结束当前readLoop
-			}
-
-			if b.cfg.Handler != nil {
-				b.cfg.Handler(msg)
-			}
-		}
+	if client == nil {
+		return stream.StateIdle
 	}
+	return client.State()
 }
 
-func (b *BinanceStream) pingLoop() {
-	defer b.wg.Done()
-
-	ticker := time.NewTicker(b.pingInterval)
-	defer ticker.Stop()
+// Reconnects 返回累计成功重连次数
+func (b *BinanceStream) Reconnects() int64 {
+	b.mu.Lock()
+	client := b.client
+	b.mu.Unlock()
 
-	for {
-		select {
-		case <-b.ctx.Done():
-			return
-		case <-ticker.C:
-			b.mu.Lock()
-			conn := b.conn
-			b.mu.Unlock()
-
-			if conn == nil {
-				continue
-			}
-
-			conn.SetWriteDeadline(time.Now().Add(b.writeWait))
-			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-				b.handleErr(err)
-				// 异步重连
-				go b.attemptReconnect()
-				return
-			}
-		}
+	if client == nil {
+		return 0
 	}
+	return client.Reconnects()
 }
 
-// autoReconnectLoop 自动重连循环
-func (b *BinanceStream) autoReconnectLoop() {
-	defer b.wg.Done()
-
-	ticker := time.NewTicker(b.reconnectInterval)
-	defer ticker.Stop()
+// QueueStats 返回读循环与Handler之间队列的深度与丢弃计数
+func (b *BinanceStream) QueueStats() ws.QueueStats {
+	b.mu.Lock()
+	client := b.client
+	b.mu.Unlock()
 
-	for {
-		select {
-		case <-b.ctx.Done():
-			return
-		case <-ticker.C:
-			b.log("Time-based reconnect triggered")
-			go b.attemptReconnect()
-			return
-		}
+	if client == nil {
+		return ws.QueueStats{}
 	}
+	return client.QueueStats()
 }
 
-func (b *BinanceStream) attemptReconnect() {
+// Subscribe 在当前连接上订阅新的流，仅适用于组合流连接
+// 订阅会被记录下来，重连后自动恢复
+func (b *BinanceStream) Subscribe(streams ...string) error {
+	if len(streams) == 0 {
+		return nil
+	}
 	b.mu.Lock()
-	if b.reconnecting {
-		b.mu.Unlock()
-		return
+	if b.subscriptions == nil {
+		b.subscriptions = make(map[string]struct{}, len(streams))
+	}
+	for _, s := range streams {
+		b.subscriptions[s] = struct{}{}
 	}
-	b.reconnecting = true
 	b.mu.Unlock()
 
-	b.log("Attempting reconnect...")
+	return b.sendRequest("SUBSCRIBE", streams)
+}
 
-	// 停止当前上下文，等待goroutine全部退出
+// Unsubscribe 在当前连接上取消订阅流
+func (b *BinanceStream) Unsubscribe(streams ...string) error {
+	if len(streams) == 0 {
+		return nil
+	}
 	b.mu.Lock()
-	if b.cancel != nil {
-		b.cancel()
+	for _, s := range streams {
+		delete(b.subscriptions, s)
 	}
-	conn := b.conn
-	b.conn = nil
 	b.mu.Unlock()
 
-	if conn != nil {
-		conn.Close()
-	}
+	return b.sendRequest("UNSUBSCRIBE", streams)
+}
 
-	// 等待所有当前goroutine结束
-	b.wg.Wait()
+// Subscriptions 返回当前订阅的流名称列表
+func (b *BinanceStream) Subscriptions() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.streamNames()
+}
 
-	// 创建新上下文
-	ctx, cancel := context.WithCancel(context.Background())
+// streamNames 返回排序后的订阅流名称，调用方需持有锁
+func (b *BinanceStream) streamNames() []string {
+	names := make([]string, 0, len(b.subscriptions))
+	for s := range b.subscriptions {
+		names = append(names, s)
+	}
+	sort.Strings(names)
+	return names
+}
 
+// sendRequest 在当前连接上发送SUBSCRIBE/UNSUBSCRIBE请求
+// 并发的同类请求合并为一条，params包含所有流名称，发送频率不超过maxControlMessages条每秒。
+// 连接尚未建立或正在重连时直接返回，重连时会通过连接地址恢复订阅
+func (b *BinanceStream) sendRequest(method string, params []string) error {
 	b.mu.Lock()
-	b.ctx = ctx
-	b.cancel = cancel
-	b.reconnecting = false
+	var req *controlRequest
+	if n := len(b.pending); n > 0 && b.pending[n-1].method == method {
+		req = b.pending[n-1]
+		req.params = append(req.params, params...)
+	} else {
+		req = &controlRequest{
+			method: method,
+			params: append([]string(nil), params...),
+			done:   make(chan struct{}),
+		}
+		b.pending = append(b.pending, req)
+	}
 	b.mu.Unlock()
 
-	// 简单重试机制
-	for i := 0; i < 5; i++ { // 尝试重连5次
-		if err := b.connect(); err == nil {
-			b.log("Reconnected successfully")
-			b.startGoroutines()
-			return
+	b.sendMu.Lock()
+	defer b.sendMu.Unlock()
+	// 依次发送排在前面的请求，直到本请求被发送（可能已由其他goroutine合并发送）
+	for {
+		select {
+		case <-req.done:
+			return req.err
+		default:
 		}
-		time.Sleep(5 * time.Second)
+
+		b.mu.Lock()
+		next := b.pending[0]
+		b.pending = b.pending[1:]
+		b.requestID++
+		id := b.requestID
+		client := b.client
+		b.mu.Unlock()
+
+		next.err = b.write(client, id, next)
+		close(next.done)
 	}
+}
 
-	b.log("Failed to reconnect after 5 attempts")
-	// 如果5次都失败，可以选择再次尝试或者直接返回上层处理
+// write 发送单条控制请求，超过限速时等待
+func (b *BinanceStream) write(client *ws.Client, id int64, req *controlRequest) error {
+	if client == nil {
+		return nil
+	}
+	conn := client.Conn()
+	if conn == nil {
+		return nil
+	}
+	if len(b.sent) == maxControlMessages {
+		if wait := time.Second - b.clock.Since(b.sent[0]); wait > 0 {
+			<-b.clock.After(wait)
+		}
+		b.sent = b.sent[1:]
+	}
+	b.sent = append(b.sent, b.clock.Now())
+	return conn.WriteJSON(map[string]any{
+		"method": req.method,
+		"params": req.params,
+		"id":     id,
+	})
 }
 
-func (b *BinanceStream) handleErr(err error) {
-	if b.cfg.ErrorHandler != nil {
-		b.cfg.ErrorHandler(err)
+// dialURL 返回连接地址，组合流会把当前订阅拼接到地址中
+func (b *BinanceStream) dialURL() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.subscriptions) == 0 {
+		return b.cfg.URL
+	}
+	sep := "?"
+	if strings.Contains(b.cfg.URL, "?") {
+		sep = "&"
 	}
-	b.log("Error: " + err.Error())
+	return b.cfg.URL + sep + "streams=" + strings.Join(b.streamNames(), "/")
 }
 
-func (b *BinanceStream) log(msg string) {
-	if b.cfg.Logger != nil {
-		b.cfg.Logger.Info(msg)
+// filter 过滤SUBSCRIBE/UNSUBSCRIBE的响应，不传递给Handler
+// 响应格式如 {"result":null,"id":1} 或 {"error":{...},"id":1}
+func (b *BinanceStream) filter(_ *ws.Conn, msg []byte) (bool, error) {
+	if !bytes.HasPrefix(msg, []byte(`{"result"`)) && !bytes.HasPrefix(msg, []byte(`{"error"`)) && !bytes.HasPrefix(msg, []byte(`{"id"`)) {
+		return true, nil
+	}
+	var resp struct {
+		ID    *int64 `json:"id"`
+		Error *struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(msg, &resp); err != nil || resp.ID == nil {
+		return true, nil
+	}
+	if resp.Error != nil {
+		return false, errors.New(resp.Error.Msg)
 	}
+	return false, nil
 }
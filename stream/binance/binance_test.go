@@ -2,6 +2,8 @@ package binance
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
@@ -13,6 +15,8 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/require"
 
+	"github.com/go-gotop/gotop/clock"
+	"github.com/go-gotop/gotop/stream"
 	"github.com/go-gotop/gotop/types"
 )
 
@@ -227,3 +231,330 @@ func TestTimeBasedReconnect(t *testing.T) {
 
 	bs.Disconnect()
 }
+
+func TestCombinedStreamSubscribe(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	var mu sync.Mutex
+	var rawQuery string
+	requests := make(chan map[string]any, 4)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		rawQuery = r.URL.RawQuery
+		mu.Unlock()
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		for {
+			var req map[string]any
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			requests <- req
+			// 回复订阅响应，然后推送一条组合流消息
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"result":null,"id":1}`))
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"stream":"ethusdt@trade","data":{"e":"trade"}}`))
+		}
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/stream"
+
+	received := make(chan []byte, 4)
+	bs := NewBinanceStream("combined_test", types.StreamTypeTrade)
+	err := bs.Connect(context.Background(), BinanceRequest{
+		URL:     wsURL,
+		Streams: []string{"btcusdt@trade"},
+		Handler: func(data []byte) {
+			received <- data
+		},
+	})
+	require.NoError(t, err)
+	defer bs.Disconnect()
+
+	mu.Lock()
+	require.Equal(t, "streams=btcusdt@trade", rawQuery)
+	mu.Unlock()
+
+	require.NoError(t, bs.Subscribe("ethusdt@trade"))
+
+	select {
+	case req := <-requests:
+		require.Equal(t, "SUBSCRIBE", req["method"])
+		require.Equal(t, []any{"ethusdt@trade"}, req["params"])
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for SUBSCRIBE request")
+	}
+
+	// 订阅响应不会传递给Handler，只会收到组合流消息
+	select {
+	case data := <-received:
+		require.JSONEq(t, `{"stream":"ethusdt@trade","data":{"e":"trade"}}`, string(data))
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for combined message")
+	}
+	require.Equal(t, []string{"btcusdt@trade", "ethusdt@trade"}, bs.Subscriptions())
+
+	require.NoError(t, bs.Unsubscribe("btcusdt@trade"))
+	select {
+	case req := <-requests:
+		require.Equal(t, "UNSUBSCRIBE", req["method"])
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for UNSUBSCRIBE request")
+	}
+	require.Equal(t, []string{"ethusdt@trade"}, bs.Subscriptions())
+}
+
+func TestSubscribeRateLimit(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	requests := make(chan []any, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		for {
+			var req map[string]any
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			requests <- req["params"].([]any)
+		}
+	}))
+	defer server.Close()
+
+	sim := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	bs := NewBinanceStream("rate_test", types.StreamTypeTrade, WithClock(sim))
+	require.NoError(t, bs.Connect(context.Background(), BinanceRequest{
+		URL:     "ws" + strings.TrimPrefix(server.URL, "http") + "/stream",
+		Streams: []string{"btcusdt@trade"},
+	}))
+	defer bs.Disconnect()
+
+	// 一秒内前5条请求立即发送
+	for i := 0; i < maxControlMessages; i++ {
+		require.NoError(t, bs.Subscribe(fmt.Sprintf("s%d@trade", i)))
+		require.Equal(t, []any{fmt.Sprintf("s%d@trade", i)}, <-requests)
+	}
+
+	// 第6条等待限速，等待期间的请求合并为一条
+	errs := make(chan error, 3)
+	go func() { errs <- bs.Subscribe("a@trade") }()
+	sim.BlockUntil(3) // 心跳、定时重连和限速等待
+	go func() { errs <- bs.Subscribe("b@trade") }()
+	go func() { errs <- bs.Subscribe("c@trade") }()
+	require.Eventually(t, func() bool {
+		bs.mu.Lock()
+		defer bs.mu.Unlock()
+		return len(bs.pending) == 1 && len(bs.pending[0].params) == 2
+	}, time.Second, time.Millisecond)
+	select {
+	case <-requests:
+		t.Fatal("request sent before rate window elapsed")
+	default:
+	}
+
+	sim.Advance(time.Second)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, <-errs)
+	}
+	require.Equal(t, []any{"a@trade"}, <-requests)
+	require.ElementsMatch(t, []any{"b@trade", "c@trade"}, <-requests)
+}
+
+func TestSeamlessReconnectDeduplicates(t *testing.T) {
+	// 服务端按时间生成递增的成交ID，所有连接推送相同的序列
+	upgrader := websocket.Upgrader{}
+	start := time.Now()
+	currentID := func() int64 {
+		return int64(time.Since(start) / (5 * time.Millisecond))
+	}
+	var connectCount int
+	var mu sync.Mutex
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		connectCount++
+		mu.Unlock()
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		sent := currentID()
+		for {
+			time.Sleep(2 * time.Millisecond)
+			for id := currentID(); sent < id; {
+				sent++
+				msg := fmt.Sprintf(`{"stream":"btcusdt@trade","data":{"t":%d}}`, sent)
+				if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+					return
+				}
+			}
+		}
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	var received []int64
+	bs := NewBinanceStream("seamless_test", types.StreamTypeTrade, WithSeamlessReconnect(100*time.Millisecond))
+	bs.reconnectInterval = 200 * time.Millisecond
+	err := bs.Connect(context.Background(), BinanceRequest{
+		URL: wsURL,
+		SequenceFunc: func(data []byte) (string, int64, bool) {
+			var m struct {
+				Data struct {
+					T int64 `json:"t"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(data, &m); err != nil {
+				return "", 0, false
+			}
+			return "btcusdt@trade", m.Data.T, true
+		},
+		Handler: func(data []byte) {
+			var m struct {
+				Data struct {
+					T int64 `json:"t"`
+				} `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(data, &m))
+			mu.Lock()
+			received = append(received, m.Data.T)
+			mu.Unlock()
+		},
+	})
+	require.NoError(t, err)
+
+	time.Sleep(time.Second)
+	require.NoError(t, bs.Disconnect())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.GreaterOrEqual(t, connectCount, 3, "should have rolled over at least twice")
+	require.NotEmpty(t, received)
+	// 轮换过程中成交ID连续递增，没有重复也没有缺失
+	for i := 1; i < len(received); i++ {
+		require.Equal(t, received[i-1]+1, received[i], "gap or duplicate at index %d", i)
+	}
+}
+
+func TestReconnectStateEvents(t *testing.T) {
+	// 第1次连接建立后立即断开，第2次连接被拒绝，第3次连接成功
+	upgrader := websocket.Upgrader{}
+	var connectCount int
+	var mu sync.Mutex
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		connectCount++
+		n := connectCount
+		mu.Unlock()
+
+		if n == 2 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		if n == 3 {
+			time.Sleep(time.Second)
+		}
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	var events []stream.StateEvent
+	bs := NewBinanceStream("state_test", types.StreamTypeTrade,
+		WithBackoff(&stream.ConstantBackoff{Interval: 20 * time.Millisecond}),
+		WithStateHandler(func(event stream.StateEvent) {
+			mu.Lock()
+			events = append(events, event)
+			mu.Unlock()
+		}),
+	)
+	require.NoError(t, bs.Connect(context.Background(), BinanceRequest{URL: wsURL}))
+
+	require.Eventually(t, func() bool {
+		return bs.Reconnects() == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, stream.StateConnected, bs.State())
+
+	require.NoError(t, bs.Disconnect())
+	require.Equal(t, stream.StateDisconnected, bs.State())
+
+	mu.Lock()
+	defer mu.Unlock()
+	states := make([]stream.State, 0, len(events))
+	for _, e := range events {
+		states = append(states, e.State)
+	}
+	require.Equal(t, []stream.State{
+		stream.StateConnecting,
+		stream.StateConnected,
+		stream.StateReconnecting,
+		stream.StateReconnecting,
+		stream.StateConnected,
+		stream.StateDisconnected,
+	}, states)
+	// 第1次重连失败的事件带有错误，第2次重连成功
+	require.Equal(t, 1, events[3].Attempt)
+	require.Error(t, events[3].Err)
+	require.Equal(t, 2, events[4].Attempt)
+	require.EqualValues(t, 1, events[4].Reconnects)
+}
+
+func TestReconnectGivesUp(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	var connectCount int
+	var mu sync.Mutex
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		connectCount++
+		n := connectCount
+		mu.Unlock()
+
+		// 只接受首次连接，随后断开，之后的连接全部拒绝
+		if n > 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		conn.Close()
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	failed := make(chan stream.StateEvent, 1)
+	bs := NewBinanceStream("give_up_test", types.StreamTypeTrade,
+		WithBackoff(&stream.ConstantBackoff{Interval: 10 * time.Millisecond, MaxAttempts: 3}),
+		WithStateHandler(func(event stream.StateEvent) {
+			if event.State == stream.StateFailed {
+				failed <- event
+			}
+		}),
+	)
+	require.NoError(t, bs.Connect(context.Background(), BinanceRequest{URL: wsURL}))
+	defer bs.Disconnect()
+
+	select {
+	case event := <-failed:
+		require.Equal(t, 3, event.Attempt)
+		require.Error(t, event.Err)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for failed state")
+	}
+	require.Equal(t, stream.StateFailed, bs.State())
+	require.Zero(t, bs.Reconnects())
+
+	mu.Lock()
+	require.Equal(t, 4, connectCount)
+	mu.Unlock()
+}
@@ -2,7 +2,7 @@ package file
 
 import (
 	"context"
-	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -14,39 +14,63 @@ import (
 	"sync"
 	"sync/atomic"
 
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/clock"
+	"github.com/go-gotop/gotop/stream"
 	"github.com/go-gotop/gotop/types"
 	"github.com/shopspring/decimal"
 )
 
+var _ stream.Stream[*CSVFileRequest] = (*CSVFile)(nil)
+
 // CSVFileRequest 请求参数
 type CSVFileRequest struct {
 	// Dir 数据目录
 	Dir string
+	// Exchange 可选，不为空时覆盖事件的Exchange字段
+	Exchange string
 	// Start 开始时间
 	Start int64
 	// End 结束时间
 	End int64
-	// Handler 处理函数，用于处理每一个TradeEvent
+	// Mode 回放速度模式，默认尽可能快地回放
+	Mode ReplayMode
+	// Speed ReplayScaled模式下的倍速，例如10表示10倍速
+	Speed float64
+	// Clock 可选，回调每个事件前推进到事件时间，例如 clock.NewEvent() 或 clock.NewSimulated()
+	Clock clock.Observer
+	// WallClock 可选，ReplayRealTime、ReplayScaled模式下控制回放节奏的时钟，默认为系统时钟
+	WallClock clock.Clock
+	// Handler 处理函数，用于处理每一个TradeEvent，StreamTypeTrade时必填
 	Handler func(trade types.TradeEvent)
+	// KlineHandler K线处理函数，StreamTypeKline时必填，按开盘时间回放
+	KlineHandler func(kline broker.KlineEvent)
+	// DepthHandler 深度快照处理函数，StreamTypeDepth时必填
+	DepthHandler func(depth broker.DepthEvent)
 	// ErrorHandler 错误处理函数
 	ErrorHandler func(err error)
 	// CloseHandler 关闭处理函数
 	CloseHandler func()
 }
 
-// CSVFile CSV文件逐笔数据流
+// CSVFile CSV文件数据流，根据StreamType回放逐笔成交、K线或深度快照文件
 type CSVFile struct {
 	id      string
+	st      types.StreamType
 	ctx     context.Context
 	cancel  context.CancelFunc
 	request *CSVFileRequest
 	eventID uint64 // 新增，用于自增事件ID
+	pacer   *pacer
 }
 
-// NewCSVFile 创建CSV数据流
-func NewCSVFile() *CSVFile {
+// NewCSVFile 创建CSV数据流，st支持StreamTypeTrade、StreamTypeKline、StreamTypeDepth
+func NewCSVFile(id string, st types.StreamType) *CSVFile {
 	return &CSVFile{
-		ctx: context.Background(),
+		id:    id,
+		st:    st,
+		ctx:   context.Background(),
+		pacer: newPacer(ReplayMaxSpeed, 0, nil),
 	}
 }
 
@@ -55,18 +79,35 @@ func (f *CSVFile) ID() string {
 	return f.id
 }
 
+// Type 返回该Stream的类型
+func (f *CSVFile) Type() types.StreamType {
+	return f.st
+}
+
 // Connect 流式读取CSV文件
-func (f *CSVFile) Connect(ctx context.Context, id string, request *CSVFileRequest) error {
+func (f *CSVFile) Connect(ctx context.Context, request *CSVFileRequest) error {
 	if request == nil {
 		return fmt.Errorf("request cannot be nil")
 	}
-	if request.Handler == nil {
-		return fmt.Errorf("request.Handler cannot be nil")
+	switch f.st {
+	case types.StreamTypeTrade:
+		if request.Handler == nil {
+			return fmt.Errorf("request.Handler cannot be nil")
+		}
+	case types.StreamTypeKline:
+		if request.KlineHandler == nil {
+			return fmt.Errorf("request.KlineHandler cannot be nil")
+		}
+	case types.StreamTypeDepth:
+		if request.DepthHandler == nil {
+			return fmt.Errorf("request.DepthHandler cannot be nil")
+		}
+	default:
+		return fmt.Errorf("unsupported stream type: %s", f.st)
+	}
+	if request.Mode == ReplayScaled && request.Speed <= 0 {
+		return fmt.Errorf("request.Speed must be positive in %s mode", request.Mode)
 	}
-
-	f.id = id
-	f.ctx, f.cancel = context.WithCancel(ctx)
-	f.request = request
 
 	files, err := readCSVFileNames(request.Dir, request.Start, request.End)
 	if err != nil {
@@ -78,7 +119,31 @@ func (f *CSVFile) Connect(ctx context.Context, id string, request *CSVFileReques
 		return fmt.Errorf("no CSV files found in the given time range")
 	}
 
-	go f.readCSVFiles(files)
+	f.ctx, f.cancel = context.WithCancel(ctx)
+	f.request = request
+	f.pacer.configure(request.Mode, request.Speed, request.WallClock)
+
+	switch f.st {
+	case types.StreamTypeTrade:
+		go replay(f, files, f.tradeSource())
+	case types.StreamTypeKline:
+		go replay(f, files, source[broker.KlineEvent]{
+			open:    openKlines,
+			time:    func(k *broker.KlineEvent) int64 { return k.OpenTime },
+			handler: request.KlineHandler,
+		})
+	case types.StreamTypeDepth:
+		go replay(f, files, source[broker.DepthEvent]{
+			open: openDepth,
+			time: func(d *broker.DepthEvent) int64 { return d.Timestamp },
+			prepare: func(d *broker.DepthEvent) {
+				if request.Exchange != "" {
+					d.Exchange = request.Exchange
+				}
+			},
+			handler: request.DepthHandler,
+		})
+	}
 	return nil
 }
 
@@ -93,9 +158,67 @@ func (f *CSVFile) Disconnect() error {
 	return nil
 }
 
-// readCSVFiles 读取CSV文件
-func (f *CSVFile) readCSVFiles(files []string) {
-	eventChan := make(chan types.TradeEvent, 10)
+// Pause 暂停回放，尽可能快回放时已缓冲的少量事件仍会被处理
+func (f *CSVFile) Pause() {
+	f.pacer.pause()
+}
+
+// Resume 恢复回放
+func (f *CSVFile) Resume() {
+	f.pacer.resume()
+}
+
+// Paused 是否处于暂停状态
+func (f *CSVFile) Paused() bool {
+	return f.pacer.isPaused()
+}
+
+// SeekTo 跳转到timestamp及之后的第一条数据，可向前或向后跳转。
+// 根据文件名中的时间戳定位到可能包含该时间的文件，再跳过文件中早于该时间的行。
+// 暂停状态下跳转后仍保持暂停。Connect之前调用Pause或SeekTo时，回放开始后生效
+func (f *CSVFile) SeekTo(timestamp int64) {
+	f.pacer.seek(timestamp)
+}
+
+// source 一种数据类型的回放方式
+type source[T any] struct {
+	// open 打开数据文件
+	open func(path string, start, end int64) (iterator[T], error)
+	// time 返回用于时间范围筛选和回放节奏的时间
+	time func(*T) int64
+	// prepare 可选，发送前补充字段
+	prepare func(*T)
+	// handler 处理函数
+	handler func(T)
+}
+
+// tradeSource 逐笔成交的回放方式
+func (f *CSVFile) tradeSource() source[types.TradeEvent] {
+	s := source[types.TradeEvent]{
+		open: openTrades,
+		time: func(t *types.TradeEvent) int64 { return t.Timestamp },
+		prepare: func(t *types.TradeEvent) {
+			if f.request != nil && f.request.Exchange != "" {
+				t.Exchange = f.request.Exchange
+			}
+			// 为每个事件分配自增ID
+			t.ID = atomic.AddUint64(&f.eventID, 1) - 1
+		},
+	}
+	if f.request != nil {
+		s.handler = f.request.Handler
+	}
+	return s
+}
+
+// replay 按顺序读取文件并回调处理函数
+func replay[T any](f *CSVFile, files []string, src source[T]) {
+	// 控制回放节奏时不缓冲，保证暂停、跳转立即生效
+	size := 10
+	if f.request.Mode != ReplayMaxSpeed {
+		size = 0
+	}
+	eventChan := make(chan T, size)
 	errorChan := make(chan error, 1)
 	var wg sync.WaitGroup
 
@@ -104,8 +227,9 @@ func (f *CSVFile) readCSVFiles(files []string) {
 	go func() {
 		defer wg.Done()
 		for event := range eventChan {
-			if f.request.Handler != nil {
-				f.request.Handler(event)
+			clock.Observe(f.request.Clock, src.time(&event))
+			if src.handler != nil {
+				src.handler(event)
 			}
 		}
 	}()
@@ -115,16 +239,23 @@ func (f *CSVFile) readCSVFiles(files []string) {
 		defer close(eventChan)
 		defer close(errorChan)
 
-		for _, file := range files {
-			select {
-			case <-f.ctx.Done():
+		start := f.request.Start
+		for i := 0; i < len(files); {
+			if f.ctx.Err() != nil {
+				return
+			}
+			err := replayFile(f, files[i], eventChan, start, f.request.End, src)
+			if errors.Is(err, errSeek) {
+				ts, _ := f.pacer.takeSeek()
+				i = seekIndex(files, ts)
+				start = max(ts, f.request.Start)
+				continue
+			}
+			if err != nil {
+				errorChan <- fmt.Errorf("process file %s error: %w", files[i], err)
 				return
-			default:
-				if err := f.processFile(file, eventChan, f.request.Start, f.request.End); err != nil {
-					errorChan <- fmt.Errorf("process file %s error: %w", file, err)
-					return
-				}
 			}
+			i++
 		}
 
 		// 正常完成时，不往errorChan写入任何错误，这样errorChan会在结束时被关闭
@@ -162,34 +293,45 @@ func (f *CSVFile) readCSVFiles(files []string) {
 	}
 }
 
+// processFile 逐行读取逐笔数据文件并发送事件
 func (f *CSVFile) processFile(filePath string, eventChan chan<- types.TradeEvent, start int64, end int64) error {
-	data, err := readCSVFile(filePath)
+	return replayFile(f, filePath, eventChan, start, end, f.tradeSource())
+}
+
+// replayFile 逐行读取文件并发送事件，不会将整个文件载入内存
+func replayFile[T any](f *CSVFile, filePath string, eventChan chan<- T, start int64, end int64, src source[T]) error {
+	r, err := src.open(filePath, start, end)
 	if err != nil {
 		return fmt.Errorf("read file %s error: %w", filePath, err)
 	}
+	defer r.Close()
+
+	for {
+		event, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read file %s error: %w", filePath, err)
+		}
+		ts := src.time(&event)
+		if !isInTimeRange(ts, start, end) {
+			continue
+		}
+
+		if err := f.pacer.wait(f.ctx, ts); err != nil {
+			return err
+		}
+		if src.prepare != nil {
+			src.prepare(&event)
+		}
 
-	for _, v := range data {
 		select {
 		case <-f.ctx.Done():
 			return f.ctx.Err()
-		default:
-			if !isInTimeRange(v.TradedAt, start, end) {
-				continue
-			}
-
-			tradeEvent, err := convertToTradeEvent(v)
-			if err != nil {
-				return fmt.Errorf("convert tick error: %w", err)
-			}
-
-			// 为每个事件分配自增ID
-			// types.TradeEvent 有一个 uint64 类型的 ID 字段
-			tradeEvent.ID = atomic.AddUint64(&f.eventID, 1) - 1
-
-			eventChan <- tradeEvent
+		case eventChan <- event:
 		}
 	}
-	return nil
 }
 
 type tradeData struct {
@@ -202,49 +344,31 @@ type tradeData struct {
 	TradedAt int64
 }
 
+// readCSVFile 读取整个文件，大文件请使用newTradeReader逐行读取
 func readCSVFile(f string) ([]*tradeData, error) {
-	file, err := os.Open(f)
+	r, err := newTradeReader(f)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-
-	r := csv.NewReader(file)
-
-	headers, err := r.Read()
-	if err != nil {
-		if err == io.EOF {
-			// 空文件
-			return []*tradeData{}, nil
-		}
-		return nil, fmt.Errorf("read header error: %w", err)
-	}
-
-	// 基于期望的列名进行检查（可选）
-	expectedHeaders := []string{"trade_id", "size", "price", "side", "quote", "traded_at"}
-	if !validateHeaders(headers, expectedHeaders) {
-		return nil, fmt.Errorf("invalid or missing headers, got: %v, expected at least: %v", headers, expectedHeaders)
-	}
+	defer r.Close()
 
 	rows := make([]*tradeData, 0, 3000)
 	for {
-		record, err := r.Read()
+		row, err := r.Next()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("read record error: %w", err)
-		}
-
-		row, err := toTradeData(headers, record)
-		if err != nil {
-			return nil, fmt.Errorf("convert record error: %w", err)
+			return nil, err
 		}
 		rows = append(rows, row)
 	}
 	return rows, nil
 }
 
+// fileNameRegexp 数据文件名格式 <timestamp>.csv、<timestamp>.csv.gz、<timestamp>.csv.zst、<timestamp>.parquet
+var fileNameRegexp = regexp.MustCompile(`^(\d+)\.(csv(\.gz|\.zst)?|parquet)$`)
+
 func readCSVFileNames(path string, start, end int64) ([]string, error) {
 	var fileNames []string
 
@@ -258,15 +382,9 @@ func readCSVFileNames(path string, start, end int64) ([]string, error) {
 			return nil
 		}
 
-		// 检查是否是 CSV 文件
-		if !strings.HasSuffix(strings.ToLower(info.Name()), ".csv") {
-			return nil
-		}
-
-		// 匹配文件名格式
-		re := regexp.MustCompile(`^(\d+)\.csv$`)
-		match := re.FindStringSubmatch(info.Name())
-		if len(match) != 2 {
+		// 匹配文件名格式，支持gzip、zstd压缩和Parquet
+		match := fileNameRegexp.FindStringSubmatch(info.Name())
+		if match == nil {
 			return nil
 		}
 
@@ -292,6 +410,23 @@ func readCSVFileNames(path string, start, end int64) ([]string, error) {
 	return fileNames, nil
 }
 
+// seekIndex 返回可能包含ts的第一个文件，即文件名时间戳不大于ts的最后一个文件
+func seekIndex(files []string, ts int64) int {
+	return max(sort.Search(len(files), func(i int) bool {
+		return fileTimestamp(files[i]) > ts
+	})-1, 0)
+}
+
+// fileTimestamp 从文件名中解析时间戳
+func fileTimestamp(path string) int64 {
+	match := fileNameRegexp.FindStringSubmatch(filepath.Base(path))
+	if match == nil {
+		return 0
+	}
+	ts, _ := strconv.ParseInt(match[1], 10, 64)
+	return ts
+}
+
 func toTradeData(headers []string, record []string) (*tradeData, error) {
 	row := &tradeData{}
 	for i, value := range record {
@@ -308,6 +443,8 @@ func toTradeData(headers []string, record []string) (*tradeData, error) {
 			row.Price = value
 		case "side":
 			row.Side = value
+		case "symbol":
+			row.Symbol = value
 		case "quote":
 			row.Quote = value
 		case "traded_at":
@@ -345,7 +482,9 @@ func convertToTradeEvent(data *tradeData) (types.TradeEvent, error) {
 
 	return types.TradeEvent{
 		// 将ID赋值由调用者统一完成
+		TradeID:   data.TradeID,
 		Timestamp: data.TradedAt,
+		Symbol:    data.Symbol,
 		Price:     price,
 		Size:      size,
 		Side:      parseSide(data.Side),
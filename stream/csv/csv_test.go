@@ -55,7 +55,7 @@ func TestCSVFile_Subscribe(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Logf("Test case: %s, start: %d, end: %d", tt.name, tt.start, tt.end)
 			
-			csvFile := NewCSVFile()
+			csvFile := NewCSVFile(tt.id, types.StreamTypeTrade)
 			require.NotNil(t, csvFile)
 
 			var mu sync.Mutex
@@ -96,7 +96,7 @@ func TestCSVFile_Subscribe(t *testing.T) {
 			}
 
 			// 连接数据流
-			err := csvFile.Connect(tt.ctx, tt.id, request)
+			err := csvFile.Connect(tt.ctx, request)
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -166,7 +166,7 @@ func TestCSVFile_ProcessFile(t *testing.T) {
 			tmpFile := createTempCSV(t, tt.data)
 			defer os.Remove(tmpFile)
 
-			csvFile := NewCSVFile()
+			csvFile := NewCSVFile("test", types.StreamTypeTrade)
 			eventChan := make(chan types.TradeEvent, 1)
 
 			err := csvFile.processFile(tmpFile, eventChan, 0, time.Now().UnixMilli())
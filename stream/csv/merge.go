@@ -0,0 +1,265 @@
+package file
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-gotop/gotop/clock"
+	"github.com/go-gotop/gotop/stream"
+	"github.com/go-gotop/gotop/types"
+)
+
+// ReplaySource 一路回放数据
+type ReplaySource struct {
+	// Exchange 交易所，写入事件的Exchange字段，为空时使用文件中的值
+	Exchange string
+	// Symbol 交易对，写入事件的Symbol字段，为空时使用文件中的symbol列
+	Symbol string
+	// Market 市场类型，写入事件的Type字段，为空时使用文件中的值
+	Market types.MarketType
+	// Dir 数据目录，文件格式与CSVFile相同
+	Dir string
+}
+
+// MergedReplayRequest 请求参数
+type MergedReplayRequest struct {
+	// Sources 回放的数据源，至少一个
+	Sources []ReplaySource
+	// Start 开始时间
+	Start int64
+	// End 结束时间
+	End int64
+	// Clock 可选，回调每个事件前推进到事件时间
+	Clock clock.Observer
+	// Handler 处理函数，事件按时间顺序回调，时间相同时按Sources中的顺序
+	Handler func(trade types.TradeEvent)
+	// ErrorHandler 错误处理函数
+	ErrorHandler func(err error)
+	// CloseHandler 关闭处理函数
+	CloseHandler func()
+}
+
+var _ stream.Stream[*MergedReplayRequest] = (*MergedReplay)(nil)
+
+// MergedReplay 将多个交易所、多个交易对的数据目录按时间归并为一个有序的逐笔数据流
+type MergedReplay struct {
+	id      string
+	cancel  context.CancelFunc
+	done    chan struct{}
+	request *MergedReplayRequest
+	eventID uint64
+}
+
+// NewMergedReplay 创建多数据源归并回放
+func NewMergedReplay(id string) *MergedReplay {
+	return &MergedReplay{id: id}
+}
+
+// ID 返回该Stream的唯一ID
+func (m *MergedReplay) ID() string {
+	return m.id
+}
+
+// Type 返回该Stream的类型
+func (m *MergedReplay) Type() types.StreamType {
+	return types.StreamTypeTrade
+}
+
+// Connect 开始回放
+func (m *MergedReplay) Connect(ctx context.Context, request *MergedReplayRequest) error {
+	if request == nil {
+		return errors.New("request cannot be nil")
+	}
+	if request.Handler == nil {
+		return errors.New("request.Handler cannot be nil")
+	}
+	if len(request.Sources) == 0 {
+		return errors.New("request.Sources cannot be empty")
+	}
+
+	cursors := make([]*sourceCursor, 0, len(request.Sources))
+	total := 0
+	for i, src := range request.Sources {
+		files, err := readCSVFileNames(src.Dir, request.Start, request.End)
+		if err != nil {
+			return fmt.Errorf("read file names of %s error: %w", src.Dir, err)
+		}
+		total += len(files)
+		cursors = append(cursors, &sourceCursor{
+			src:   src,
+			index: i,
+			files: files,
+			start: request.Start,
+			end:   request.End,
+		})
+	}
+	if total == 0 {
+		return errors.New("no CSV files found in the given time range")
+	}
+
+	m.request = request
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.done = make(chan struct{})
+
+	go m.run(ctx, cursors)
+	return nil
+}
+
+// Disconnect 停止回放
+func (m *MergedReplay) Disconnect() error {
+	if m.cancel != nil {
+		m.cancel()
+		<-m.done
+	}
+	return nil
+}
+
+// run k路归并，每个数据源只在内存中保留当前一行
+func (m *MergedReplay) run(ctx context.Context, cursors []*sourceCursor) {
+	defer close(m.done)
+
+	err := m.merge(ctx, cursors)
+	for _, c := range cursors {
+		c.close()
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		if m.request.ErrorHandler != nil {
+			m.request.ErrorHandler(err)
+		}
+		return
+	}
+	if m.request.CloseHandler != nil {
+		m.request.CloseHandler()
+	}
+}
+
+func (m *MergedReplay) merge(ctx context.Context, cursors []*sourceCursor) error {
+	h := make(cursorHeap, 0, len(cursors))
+	for _, c := range cursors {
+		ok, err := c.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			h = append(h, c)
+		}
+	}
+	heap.Init(&h)
+
+	for h.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		c := h[0]
+		event := c.head
+		event.ID = m.eventID
+		m.eventID++
+		clock.Observe(m.request.Clock, event.Timestamp)
+		m.request.Handler(event)
+
+		ok, err := c.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+	return nil
+}
+
+// sourceCursor 单个数据源的读取位置
+type sourceCursor struct {
+	src   ReplaySource
+	index int
+	files []string
+	// reader 当前正在读取的文件
+	reader iterator[types.TradeEvent]
+	// head 下一个待发送的事件
+	head  types.TradeEvent
+	start int64
+	end   int64
+}
+
+// next 读取下一个时间范围内的事件到head，数据源读取完毕时返回false
+func (c *sourceCursor) next() (bool, error) {
+	for {
+		if c.reader == nil {
+			if len(c.files) == 0 {
+				return false, nil
+			}
+			r, err := openTrades(c.files[0], c.start, c.end)
+			if err != nil {
+				return false, fmt.Errorf("read file %s error: %w", c.files[0], err)
+			}
+			c.reader = r
+			c.files = c.files[1:]
+		}
+
+		event, err := c.reader.Next()
+		if err == io.EOF {
+			c.close()
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if !isInTimeRange(event.Timestamp, c.start, c.end) {
+			continue
+		}
+
+		if c.src.Symbol != "" {
+			event.Symbol = c.src.Symbol
+		}
+		if c.src.Exchange != "" {
+			event.Exchange = c.src.Exchange
+		}
+		if c.src.Market != types.MarketTypeUnknown {
+			event.Type = c.src.Market
+		}
+		c.head = event
+		return true, nil
+	}
+}
+
+func (c *sourceCursor) close() {
+	if c.reader != nil {
+		c.reader.Close()
+		c.reader = nil
+	}
+}
+
+// cursorHeap 按head时间排序的最小堆，时间相同时按数据源顺序
+type cursorHeap []*sourceCursor
+
+func (h cursorHeap) Len() int { return len(h) }
+
+func (h cursorHeap) Less(i, j int) bool {
+	if h[i].head.Timestamp != h[j].head.Timestamp {
+		return h[i].head.Timestamp < h[j].head.Timestamp
+	}
+	return h[i].index < h[j].index
+}
+
+func (h cursorHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *cursorHeap) Push(x any) { *h = append(*h, x.(*sourceCursor)) }
+
+func (h *cursorHeap) Pop() any {
+	old := *h
+	n := len(old)
+	c := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return c
+}
@@ -0,0 +1,120 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/clock"
+	"github.com/go-gotop/gotop/types"
+)
+
+func writeCSV(t *testing.T, dir, name, data string) {
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644))
+}
+
+func TestMergedReplay(t *testing.T) {
+	root := t.TempDir()
+	header := "trade_id,size,price,side,symbol,quote,traded_at\n"
+
+	bnBTC := filepath.Join(root, "binance", "BTCUSDT")
+	writeCSV(t, bnBTC, "1000.csv", header+
+		"1,1,100,BUY,BTCUSDT,USDT,1000\n"+
+		"2,1,101,SELL,BTCUSDT,USDT,3000\n")
+	writeCSV(t, bnBTC, "5000.csv", header+
+		"3,1,102,BUY,BTCUSDT,USDT,5000\n")
+
+	bnETH := filepath.Join(root, "binance", "ETHUSDT")
+	writeCSV(t, bnETH, "2000.csv", header+
+		"10,2,10,BUY,ETHUSDT,USDT,2000\n"+
+		"11,2,11,BUY,ETHUSDT,USDT,3000\n")
+
+	okxBTC := filepath.Join(root, "okx", "BTC-USDT")
+	writeCSV(t, okxBTC, "500.csv", header+
+		"20,1,99,SELL,BTC-USDT,USDT,500\n"+
+		"21,1,99,SELL,BTC-USDT,USDT,4000\n")
+
+	var events []types.TradeEvent
+	done := make(chan struct{})
+	clk := clock.NewEvent()
+	m := NewMergedReplay("merged")
+	err := m.Connect(context.Background(), &MergedReplayRequest{
+		Sources: []ReplaySource{
+			{Exchange: types.BinanceExchange, Dir: bnBTC, Market: types.MarketTypeSpot},
+			{Exchange: types.BinanceExchange, Dir: bnETH, Market: types.MarketTypeSpot},
+			{Exchange: types.OkxExchange, Symbol: "BTC-USDT-SPOT", Dir: okxBTC, Market: types.MarketTypeSpot},
+		},
+		End:   4500,
+		Clock: clk,
+		Handler: func(trade types.TradeEvent) {
+			// 回调时时钟已推进到事件时间
+			assert.Equal(t, trade.Timestamp, clk.Now().UnixMilli())
+			events = append(events, trade)
+		},
+		ErrorHandler: func(err error) { t.Error(err) },
+		CloseHandler: func() { close(done) },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "merged", m.ID())
+	assert.Equal(t, types.StreamTypeTrade, m.Type())
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for replay")
+	}
+	require.NoError(t, m.Disconnect())
+
+	var ids []uint64
+	for i, e := range events {
+		ids = append(ids, e.TradeID)
+		assert.Equal(t, uint64(i), e.ID)
+		if i > 0 {
+			assert.LessOrEqual(t, events[i-1].Timestamp, e.Timestamp)
+		}
+	}
+	// 时间相同时按数据源顺序，超过End的事件不回放
+	assert.Equal(t, []uint64{20, 1, 10, 2, 11, 21}, ids)
+
+	assert.Equal(t, "BTCUSDT", events[1].Symbol)
+	assert.Equal(t, types.BinanceExchange, events[1].Exchange)
+	assert.Equal(t, "ETHUSDT", events[2].Symbol)
+	assert.Equal(t, "BTC-USDT-SPOT", events[0].Symbol)
+	assert.Equal(t, types.OkxExchange, events[0].Exchange)
+	assert.Equal(t, types.MarketTypeSpot, events[0].Type)
+}
+
+func TestMergedReplayErrors(t *testing.T) {
+	m := NewMergedReplay("m")
+	handler := func(types.TradeEvent) {}
+
+	require.Error(t, m.Connect(context.Background(), nil))
+	require.Error(t, m.Connect(context.Background(), &MergedReplayRequest{Handler: handler}))
+	require.Error(t, m.Connect(context.Background(), &MergedReplayRequest{
+		Sources: []ReplaySource{{Dir: t.TempDir()}},
+		Handler: handler,
+	}))
+
+	// 文件内容错误时回调ErrorHandler
+	dir := t.TempDir()
+	writeCSV(t, dir, "1.csv", "trade_id,size,price,side,symbol,quote,traded_at\n1,1,bad,BUY,BTCUSDT,USDT,1\n")
+	errCh := make(chan error, 1)
+	require.NoError(t, m.Connect(context.Background(), &MergedReplayRequest{
+		Sources:      []ReplaySource{{Dir: dir}},
+		Handler:      handler,
+		ErrorHandler: func(err error) { errCh <- err },
+	}))
+	select {
+	case err := <-errCh:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for error")
+	}
+	require.NoError(t, m.Disconnect())
+}
@@ -0,0 +1,90 @@
+package file
+
+import "time"
+
+// FileFormat 录制文件格式
+type FileFormat int
+
+const (
+	// FormatCSV CSV格式
+	FormatCSV FileFormat = iota
+	// FormatParquet Parquet格式，文件完成时由CSV转换，写入过程中仍以CSV保证崩溃安全
+	FormatParquet
+)
+
+// RecorderOption 录制器配置
+type RecorderOption func(*recorderOptions)
+
+type recorderOptions struct {
+	// rotateInterval 文件轮换间隔，按成交时间对齐
+	rotateInterval time.Duration
+	// syncInterval 刷新缓冲并fsync的间隔
+	syncInterval time.Duration
+	// finalizeDelay 时间段结束后等待迟到成交的时长，超过后即使没有新成交也完成文件
+	finalizeDelay time.Duration
+	// quoteFunc 根据交易对返回计价币种
+	quoteFunc func(symbol string) string
+	// errorHandler 后台同步、解析失败时的错误处理函数
+	errorHandler func(err error)
+	// format 完成文件的格式
+	format FileFormat
+}
+
+func applyRecorderOptions(opts ...RecorderOption) *recorderOptions {
+	o := &recorderOptions{
+		rotateInterval: time.Hour,
+		syncInterval:   time.Second,
+		finalizeDelay:  time.Minute,
+		quoteFunc:      defaultQuote,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithRotateInterval 设置文件轮换间隔，默认1小时，为0时不轮换
+func WithRotateInterval(d time.Duration) RecorderOption {
+	return func(o *recorderOptions) {
+		o.rotateInterval = d
+	}
+}
+
+// WithSyncInterval 设置fsync间隔，默认1秒
+func WithSyncInterval(d time.Duration) RecorderOption {
+	return func(o *recorderOptions) {
+		if d > 0 {
+			o.syncInterval = d
+		}
+	}
+}
+
+// WithFinalizeDelay 设置时间段结束后等待迟到成交的时长，默认1分钟
+func WithFinalizeDelay(d time.Duration) RecorderOption {
+	return func(o *recorderOptions) {
+		o.finalizeDelay = d
+	}
+}
+
+// WithQuoteFunc 设置计价币种的解析函数，默认根据交易对后缀推断
+func WithQuoteFunc(f func(symbol string) string) RecorderOption {
+	return func(o *recorderOptions) {
+		if f != nil {
+			o.quoteFunc = f
+		}
+	}
+}
+
+// WithRecorderErrorHandler 设置错误处理函数
+func WithRecorderErrorHandler(f func(err error)) RecorderOption {
+	return func(o *recorderOptions) {
+		o.errorHandler = f
+	}
+}
+
+// WithFormat 设置完成文件的格式，默认CSV
+func WithFormat(format FileFormat) RecorderOption {
+	return func(o *recorderOptions) {
+		o.format = format
+	}
+}
@@ -0,0 +1,193 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-gotop/gotop/clock"
+)
+
+// ReplayMode 回放速度模式
+type ReplayMode int
+
+const (
+	// ReplayMaxSpeed 尽可能快地回放
+	ReplayMaxSpeed ReplayMode = iota
+	// ReplayRealTime 按traded_at的间隔实时回放
+	ReplayRealTime
+	// ReplayScaled 按traded_at的间隔以Speed倍速回放
+	ReplayScaled
+)
+
+// String 返回模式名称
+func (m ReplayMode) String() string {
+	switch m {
+	case ReplayMaxSpeed:
+		return "MAX_SPEED"
+	case ReplayRealTime:
+		return "REAL_TIME"
+	case ReplayScaled:
+		return "SCALED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// errSeek 收到跳转请求，读取方需要重新定位
+var errSeek = errors.New("seek requested")
+
+// pacer 控制回放节奏，并处理暂停、恢复与跳转请求
+type pacer struct {
+	mu    sync.Mutex
+	clock clock.Clock
+	speed float64 // 为0时不等待
+
+	paused  bool
+	resumed chan struct{}
+	// wake 暂停、跳转时唤醒正在等待的回放
+	wake chan struct{}
+
+	seeking bool
+	seekTo  int64
+
+	// 节奏基准：事件时间baseTs对应墙钟时间baseWall
+	based    bool
+	baseTs   int64
+	baseWall time.Time
+}
+
+// newPacer 创建回放节奏控制，c为nil时使用系统时钟
+func newPacer(mode ReplayMode, speed float64, c clock.Clock) *pacer {
+	p := &pacer{wake: make(chan struct{}, 1)}
+	p.configure(mode, speed, c)
+	return p
+}
+
+// configure 设置回放速度和时钟，保留暂停状态和尚未处理的跳转请求
+func (p *pacer) configure(mode ReplayMode, speed float64, c clock.Clock) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clock = clock.OrReal(c)
+	p.speed = 0
+	switch mode {
+	case ReplayRealTime:
+		p.speed = 1
+	case ReplayScaled:
+		p.speed = speed
+	}
+	p.based = false
+}
+
+// wait 等待到事件时间ts应当回放的时刻，暂停时阻塞直到恢复，收到跳转请求时返回errSeek
+func (p *pacer) wait(ctx context.Context, ts int64) error {
+	for {
+		p.mu.Lock()
+		if p.seeking {
+			p.mu.Unlock()
+			return errSeek
+		}
+		if p.paused {
+			resumed := p.resumed
+			p.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-resumed:
+			}
+			continue
+		}
+		if p.speed <= 0 {
+			p.mu.Unlock()
+			return nil
+		}
+		if !p.based {
+			p.based = true
+			p.baseTs = ts
+			p.baseWall = p.clock.Now()
+		}
+		due := p.baseWall.Add(time.Duration(float64(ts-p.baseTs) / p.speed * float64(time.Millisecond)))
+		delay := due.Sub(p.clock.Now())
+		p.mu.Unlock()
+
+		if delay <= 0 {
+			return nil
+		}
+		timer := p.clock.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-p.wake:
+			timer.Stop()
+		case <-timer.C():
+			return nil
+		}
+	}
+}
+
+// pause 暂停回放
+func (p *pacer) pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.paused {
+		return
+	}
+	p.paused = true
+	p.resumed = make(chan struct{})
+	p.notify()
+}
+
+// resume 恢复回放，恢复后从下一个事件开始重新计时，不会追赶暂停期间的时间
+func (p *pacer) resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.paused {
+		return
+	}
+	p.paused = false
+	p.based = false
+	close(p.resumed)
+}
+
+// isPaused 是否处于暂停状态
+func (p *pacer) isPaused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+// seek 请求跳转到ts
+func (p *pacer) seek(ts int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.seeking = true
+	p.seekTo = ts
+	p.based = false
+	p.notify()
+}
+
+// takeSeek 取出待处理的跳转请求
+func (p *pacer) takeSeek() (int64, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.seeking {
+		return 0, false
+	}
+	p.seeking = false
+	return p.seekTo, true
+}
+
+// notify 唤醒正在等待的回放，调用方需持有锁
+func (p *pacer) notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,204 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/clock"
+	"github.com/go-gotop/gotop/types"
+)
+
+// setupReplayData 创建3个文件，每个文件10笔成交，间隔100ms
+func setupReplayData(t *testing.T) string {
+	dir := t.TempDir()
+	for f := 0; f < 3; f++ {
+		var b strings.Builder
+		b.WriteString("trade_id,size,price,side,symbol,quote,traded_at\n")
+		base := int64(f * 1000)
+		for i := 0; i < 10; i++ {
+			fmt.Fprintf(&b, "%d,1,100,BUY,BTCUSDT,USDT,%d\n", f*10+i, base+int64(i*100))
+		}
+		writeCSV(t, dir, fmt.Sprintf("%d.csv", base), b.String())
+	}
+	return dir
+}
+
+func TestCSVFileReplayScaled(t *testing.T) {
+	dir := setupReplayData(t)
+
+	var times []time.Time
+	done := make(chan struct{})
+	f := NewCSVFile("scaled", types.StreamTypeTrade)
+	err := f.Connect(context.Background(), &CSVFileRequest{
+		Dir:          filepath.Clean(dir),
+		End:          1000,
+		Mode:         ReplayScaled,
+		Speed:        10,
+		Handler:      func(types.TradeEvent) { times = append(times, time.Now()) },
+		CloseHandler: func() { close(done) },
+	})
+	require.NoError(t, err)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for replay")
+	}
+	// 0~1000ms的11笔成交以10倍速回放约需100ms
+	require.Len(t, times, 11)
+	elapsed := times[len(times)-1].Sub(times[0])
+	assert.GreaterOrEqual(t, elapsed, 90*time.Millisecond)
+	assert.Less(t, elapsed, 500*time.Millisecond)
+
+	require.Error(t, NewCSVFile("bad", types.StreamTypeTrade).Connect(context.Background(), &CSVFileRequest{
+		Dir:     dir,
+		Mode:    ReplayScaled,
+		Handler: func(types.TradeEvent) {},
+	}))
+}
+
+func TestCSVFileReplayWallClock(t *testing.T) {
+	dir := setupReplayData(t)
+
+	c := clock.NewSimulated(time.UnixMilli(0))
+	events := make(chan types.TradeEvent, 10)
+	done := make(chan struct{})
+	f := NewCSVFile("wall", types.StreamTypeTrade)
+	err := f.Connect(context.Background(), &CSVFileRequest{
+		Dir:          dir,
+		End:          200,
+		Mode:         ReplayRealTime,
+		WallClock:    c,
+		Handler:      func(trade types.TradeEvent) { events <- trade },
+		CloseHandler: func() { close(done) },
+	})
+	require.NoError(t, err)
+
+	// 第一笔成交立即回放，之后按模拟时钟等待
+	assert.Equal(t, int64(0), (<-events).Timestamp)
+	c.BlockUntil(1)
+	assert.Empty(t, events)
+	c.Advance(99 * time.Millisecond)
+	assert.Empty(t, events)
+	c.Advance(time.Millisecond)
+	assert.Equal(t, int64(100), (<-events).Timestamp)
+
+	c.BlockUntil(1)
+	c.Advance(100 * time.Millisecond)
+	assert.Equal(t, int64(200), (<-events).Timestamp)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for replay")
+	}
+}
+
+func TestCSVFilePauseSeek(t *testing.T) {
+	dir := setupReplayData(t)
+
+	events := make(chan types.TradeEvent, 100)
+	done := make(chan struct{})
+	f := NewCSVFile("seek", types.StreamTypeTrade)
+	err := f.Connect(context.Background(), &CSVFileRequest{
+		Dir:          dir,
+		Mode:         ReplayScaled,
+		Speed:        20,
+		Handler:      func(trade types.TradeEvent) { events <- trade },
+		CloseHandler: func() { close(done) },
+	})
+	require.NoError(t, err)
+
+	next := func() types.TradeEvent {
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for trade")
+			return types.TradeEvent{}
+		}
+	}
+
+	assert.Equal(t, uint64(0), next().TradeID)
+	f.Pause()
+	assert.True(t, f.Paused())
+	// 暂停期间可能有一笔已在发送中
+	time.Sleep(50 * time.Millisecond)
+	for len(events) > 0 {
+		<-events
+	}
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, events)
+
+	// 暂停时向后跳转，恢复后从第三个文件中间开始
+	f.SeekTo(2450)
+	f.Resume()
+	assert.False(t, f.Paused())
+	e := next()
+	assert.Equal(t, uint64(25), e.TradeID)
+	assert.Equal(t, int64(2500), e.Timestamp)
+
+	// 向前跳转
+	f.SeekTo(1000)
+	for {
+		e = next()
+		if e.TradeID == 10 {
+			break
+		}
+		require.Greater(t, e.TradeID, uint64(25))
+	}
+	assert.Equal(t, uint64(11), next().TradeID)
+
+	f.SeekTo(2900)
+	for e.TradeID != 29 {
+		e = next()
+	}
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for replay")
+	}
+}
+
+func TestCSVFilePauseSeekBeforeConnect(t *testing.T) {
+	dir := setupReplayData(t)
+
+	events := make(chan types.TradeEvent, 100)
+	f := NewCSVFile("before", types.StreamTypeTrade)
+	f.Pause()
+	f.SeekTo(2450)
+	err := f.Connect(context.Background(), &CSVFileRequest{
+		Dir:     dir,
+		Handler: func(trade types.TradeEvent) { events <- trade },
+	})
+	require.NoError(t, err)
+	defer f.Disconnect()
+
+	// Connect之前的暂停和跳转在回放开始后仍然生效
+	assert.True(t, f.Paused())
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, events)
+
+	f.Resume()
+	select {
+	case e := <-events:
+		assert.Equal(t, int64(2500), e.Timestamp)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for trade")
+	}
+}
+
+func TestSeekIndex(t *testing.T) {
+	files := []string{"/a/1000.csv", "/a/2000.csv.gz", "/a/3000.csv"}
+	assert.Equal(t, 0, seekIndex(files, 500))
+	assert.Equal(t, 0, seekIndex(files, 1000))
+	assert.Equal(t, 0, seekIndex(files, 1999))
+	assert.Equal(t, 1, seekIndex(files, 2000))
+	assert.Equal(t, 2, seekIndex(files, 9000))
+}
@@ -0,0 +1,438 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	pq "github.com/parquet-go/parquet-go"
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/types"
+)
+
+// parquetExt Parquet文件扩展名
+const parquetExt = ".parquet"
+
+// defaultRowGroupSize 默认每个行组的行数，行组越小按时间范围筛选越精确，压缩率越低
+const defaultRowGroupSize = 64 * 1024
+
+// parquetBatchSize 每次从行组读取的行数
+const parquetBatchSize = 1024
+
+// decimalValue 以整数系数和十进制指数存储decimal，读取时无需解析字符串
+type decimalValue struct {
+	Value int64 `parquet:"value,delta"`
+	Exp   int32 `parquet:"exp"`
+}
+
+func toDecimalValue(d decimal.Decimal) (decimalValue, error) {
+	coef := d.Coefficient()
+	if !coef.IsInt64() {
+		return decimalValue{}, fmt.Errorf("decimal out of range: %s", d.String())
+	}
+	return decimalValue{Value: coef.Int64(), Exp: d.Exponent()}, nil
+}
+
+func (v decimalValue) decimal() decimal.Decimal {
+	return decimal.New(v.Value, v.Exp)
+}
+
+// tradeRow 成交在Parquet文件中的行格式，按traded_at升序写入
+type tradeRow struct {
+	TradedAt int64        `parquet:"traded_at,delta"`
+	TradeID  uint64       `parquet:"trade_id,delta"`
+	Symbol   string       `parquet:"symbol,dict"`
+	Exchange string       `parquet:"exchange,dict"`
+	Market   string       `parquet:"market,dict"`
+	Side     string       `parquet:"side,dict"`
+	Price    decimalValue `parquet:"price"`
+	Size     decimalValue `parquet:"size"`
+}
+
+// klineRow K线在Parquet文件中的行格式，按open_time升序写入
+type klineRow struct {
+	OpenTime                 int64        `parquet:"open_time,delta"`
+	CloseTime                int64        `parquet:"close_time,delta"`
+	Symbol                   string       `parquet:"symbol,dict"`
+	Open                     decimalValue `parquet:"open"`
+	High                     decimalValue `parquet:"high"`
+	Low                      decimalValue `parquet:"low"`
+	Close                    decimalValue `parquet:"close"`
+	Volume                   decimalValue `parquet:"volume"`
+	QuoteAssetVolume         decimalValue `parquet:"quote_volume"`
+	NumberOfTrades           int64        `parquet:"trades"`
+	TakerBuyBaseAssetVolume  decimalValue `parquet:"taker_buy_volume"`
+	TakerBuyQuoteAssetVolume decimalValue `parquet:"taker_buy_quote_volume"`
+	Confirm                  string       `parquet:"confirm,dict"`
+}
+
+// ParquetOption Parquet写入配置
+type ParquetOption func(*parquetOptions)
+
+type parquetOptions struct {
+	rowGroupSize int64
+}
+
+// WithRowGroupSize 设置每个行组的行数，默认65536
+func WithRowGroupSize(n int64) ParquetOption {
+	return func(o *parquetOptions) {
+		if n > 0 {
+			o.rowGroupSize = n
+		}
+	}
+}
+
+func newParquetWriter[T any](w io.Writer, opts ...ParquetOption) *pq.GenericWriter[T] {
+	o := &parquetOptions{rowGroupSize: defaultRowGroupSize}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return pq.NewGenericWriter[T](w,
+		pq.MaxRowsPerRowGroup(o.rowGroupSize),
+		pq.Compression(&pq.Zstd),
+	)
+}
+
+// TradeWriter 将成交写入Parquet文件，成交需按时间升序写入
+type TradeWriter struct {
+	w   *pq.GenericWriter[tradeRow]
+	buf []tradeRow
+}
+
+// NewTradeWriter 创建成交Parquet写入器，写入完成后需调用Close写入文件尾
+func NewTradeWriter(w io.Writer, opts ...ParquetOption) *TradeWriter {
+	return &TradeWriter{w: newParquetWriter[tradeRow](w, opts...)}
+}
+
+// Write 写入成交
+func (t *TradeWriter) Write(trades ...types.TradeEvent) error {
+	t.buf = t.buf[:0]
+	for _, trade := range trades {
+		price, err := toDecimalValue(trade.Price)
+		if err != nil {
+			return fmt.Errorf("price: %w", err)
+		}
+		size, err := toDecimalValue(trade.Size)
+		if err != nil {
+			return fmt.Errorf("size: %w", err)
+		}
+		row := tradeRow{
+			TradedAt: trade.Timestamp,
+			TradeID:  trade.TradeID,
+			Symbol:   trade.Symbol,
+			Exchange: trade.Exchange,
+			Side:     trade.Side.String(),
+			Price:    price,
+			Size:     size,
+		}
+		if trade.Type != types.MarketTypeUnknown {
+			row.Market = trade.Type.String()
+		}
+		t.buf = append(t.buf, row)
+	}
+	_, err := t.w.Write(t.buf)
+	return err
+}
+
+// Close 写入剩余数据和文件尾，不会关闭底层io.Writer
+func (t *TradeWriter) Close() error {
+	return t.w.Close()
+}
+
+// KlineWriter 将K线写入Parquet文件，K线需按开盘时间升序写入
+type KlineWriter struct {
+	w   *pq.GenericWriter[klineRow]
+	buf []klineRow
+}
+
+// NewKlineWriter 创建K线Parquet写入器，写入完成后需调用Close写入文件尾
+func NewKlineWriter(w io.Writer, opts ...ParquetOption) *KlineWriter {
+	return &KlineWriter{w: newParquetWriter[klineRow](w, opts...)}
+}
+
+// Write 写入K线
+func (k *KlineWriter) Write(klines ...broker.KlineEvent) error {
+	k.buf = k.buf[:0]
+	for _, kline := range klines {
+		row := klineRow{
+			OpenTime:       kline.OpenTime,
+			CloseTime:      kline.CloseTime,
+			Symbol:         kline.Symbol,
+			NumberOfTrades: kline.NumberOfTrades,
+			Confirm:        kline.Confirm,
+		}
+		for _, f := range []struct {
+			dst *decimalValue
+			src decimal.Decimal
+		}{
+			{&row.Open, kline.Open},
+			{&row.High, kline.High},
+			{&row.Low, kline.Low},
+			{&row.Close, kline.Close},
+			{&row.Volume, kline.Volume},
+			{&row.QuoteAssetVolume, kline.QuoteAssetVolume},
+			{&row.TakerBuyBaseAssetVolume, kline.TakerBuyBaseAssetVolume},
+			{&row.TakerBuyQuoteAssetVolume, kline.TakerBuyQuoteAssetVolume},
+		} {
+			v, err := toDecimalValue(f.src)
+			if err != nil {
+				return err
+			}
+			*f.dst = v
+		}
+		k.buf = append(k.buf, row)
+	}
+	_, err := k.w.Write(k.buf)
+	return err
+}
+
+// Close 写入剩余数据和文件尾，不会关闭底层io.Writer
+func (k *KlineWriter) Close() error {
+	return k.w.Close()
+}
+
+// parquetReader 按行组读取Parquet文件，跳过时间统计不在范围内的行组
+type parquetReader[T any] struct {
+	file   *os.File
+	groups []pq.RowGroup
+	reader *pq.GenericReader[T]
+	buf    []T
+	pos    int
+	n      int
+}
+
+func newParquetReader[T any](path, timeColumn string, start, end int64) (*parquetReader[T], error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	pf, err := pq.OpenFile(file, stat.Size())
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("open parquet error: %w", err)
+	}
+
+	groups, err := selectRowGroups(pf, timeColumn, start, end)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &parquetReader[T]{
+		file:   file,
+		groups: groups,
+		buf:    make([]T, parquetBatchSize),
+	}, nil
+}
+
+// selectRowGroups 根据时间列的最小、最大值统计筛选与[start, end]有交集的行组
+func selectRowGroups(pf *pq.File, timeColumn string, start, end int64) ([]pq.RowGroup, error) {
+	leaf, ok := pf.Schema().Lookup(timeColumn)
+	if !ok {
+		return nil, fmt.Errorf("missing column %s", timeColumn)
+	}
+
+	var groups []pq.RowGroup
+	for _, rg := range pf.RowGroups() {
+		chunk, ok := rg.ColumnChunks()[leaf.ColumnIndex].(*pq.FileColumnChunk)
+		if ok && (start != 0 || end != 0) {
+			if lo, hi, ok := chunk.Bounds(); ok {
+				if (start != 0 && hi.Int64() < start) || (end != 0 && lo.Int64() > end) {
+					continue
+				}
+			}
+		}
+		groups = append(groups, rg)
+	}
+	return groups, nil
+}
+
+// next 读取下一行，读取完毕时返回io.EOF
+func (p *parquetReader[T]) next() (*T, error) {
+	for p.pos >= p.n {
+		if p.reader == nil {
+			if len(p.groups) == 0 {
+				return nil, io.EOF
+			}
+			p.reader = pq.NewGenericRowGroupReader[T](p.groups[0])
+			p.groups = p.groups[1:]
+		}
+
+		n, err := p.reader.Read(p.buf)
+		p.pos, p.n = 0, n
+		if errors.Is(err, io.EOF) {
+			p.reader.Close()
+			p.reader = nil
+		} else if err != nil {
+			return nil, fmt.Errorf("read parquet error: %w", err)
+		}
+	}
+	row := &p.buf[p.pos]
+	p.pos++
+	return row, nil
+}
+
+// Close 关闭文件
+func (p *parquetReader[T]) Close() error {
+	if p.reader != nil {
+		p.reader.Close()
+		p.reader = nil
+	}
+	return p.file.Close()
+}
+
+// parquetTrades Parquet文件的成交迭代器
+type parquetTrades struct {
+	*parquetReader[tradeRow]
+}
+
+func newParquetTradeReader(path string, start, end int64) (*parquetTrades, error) {
+	r, err := newParquetReader[tradeRow](path, "traded_at", start, end)
+	if err != nil {
+		return nil, err
+	}
+	return &parquetTrades{r}, nil
+}
+
+func (p *parquetTrades) Next() (types.TradeEvent, error) {
+	row, err := p.next()
+	if err != nil {
+		return types.TradeEvent{}, err
+	}
+	event := types.TradeEvent{
+		Timestamp: row.TradedAt,
+		TradeID:   row.TradeID,
+		Symbol:    row.Symbol,
+		Exchange:  row.Exchange,
+		Price:     row.Price.decimal(),
+		Size:      row.Size.decimal(),
+		Side:      parseSide(row.Side),
+	}
+	if row.Market != "" {
+		if market, err := types.ParseMarketType(row.Market); err == nil {
+			event.Type = market
+		}
+	}
+	return event, nil
+}
+
+// parquetKlines Parquet文件的K线迭代器
+type parquetKlines struct {
+	*parquetReader[klineRow]
+}
+
+func newParquetKlineReader(path string, start, end int64) (*parquetKlines, error) {
+	r, err := newParquetReader[klineRow](path, "open_time", start, end)
+	if err != nil {
+		return nil, err
+	}
+	return &parquetKlines{r}, nil
+}
+
+func (p *parquetKlines) Next() (broker.KlineEvent, error) {
+	row, err := p.next()
+	if err != nil {
+		return broker.KlineEvent{}, err
+	}
+	return broker.KlineEvent{
+		Symbol:                   row.Symbol,
+		OpenTime:                 row.OpenTime,
+		Open:                     row.Open.decimal(),
+		High:                     row.High.decimal(),
+		Low:                      row.Low.decimal(),
+		Close:                    row.Close.decimal(),
+		Volume:                   row.Volume.decimal(),
+		CloseTime:                row.CloseTime,
+		QuoteAssetVolume:         row.QuoteAssetVolume.decimal(),
+		NumberOfTrades:           row.NumberOfTrades,
+		TakerBuyBaseAssetVolume:  row.TakerBuyBaseAssetVolume.decimal(),
+		TakerBuyQuoteAssetVolume: row.TakerBuyQuoteAssetVolume.decimal(),
+		Confirm:                  row.Confirm,
+	}, nil
+}
+
+// ReadParquetKlines 按时间顺序读取Parquet文件中开盘时间在[start, end]内的K线，start、end为0时不限制
+func ReadParquetKlines(path string, start, end int64, fn func(kline broker.KlineEvent) error) error {
+	r, err := newParquetKlineReader(path, start, end)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for {
+		kline, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !isInTimeRange(kline.OpenTime, start, end) {
+			continue
+		}
+		if err := fn(kline); err != nil {
+			return err
+		}
+	}
+}
+
+// ConvertToParquet 将CSV逐笔数据文件（可压缩）转换为Parquet文件，先写入dst.tmp再重命名
+func ConvertToParquet(src, dst string, opts ...ParquetOption) error {
+	r, err := openTrades(src, 0, 0)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tmp := dst + tmpSuffix
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	defer out.Close()
+
+	w := NewTradeWriter(out, opts...)
+	batch := make([]types.TradeEvent, 0, parquetBatchSize)
+	for {
+		trade, err := r.Next()
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if err == nil {
+			batch = append(batch, trade)
+		}
+		if len(batch) == cap(batch) || (err == io.EOF && len(batch) > 0) {
+			if werr := w.Write(batch...); werr != nil {
+				return fmt.Errorf("write parquet error: %w", werr)
+			}
+			batch = batch[:0]
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("close parquet error: %w", err)
+	}
+	if err := out.Sync(); err != nil {
+		return fmt.Errorf("fsync error: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// isParquet 是否为Parquet文件
+func isParquet(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), parquetExt)
+}
@@ -0,0 +1,171 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pq "github.com/parquet-go/parquet-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/types"
+)
+
+func writeParquetTrades(t *testing.T, path string, n int, opts ...ParquetOption) {
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	w := NewTradeWriter(f, opts...)
+	for i := 0; i < n; i++ {
+		side := types.SideTypeBuy
+		if i%2 == 1 {
+			side = types.SideTypeSell
+		}
+		require.NoError(t, w.Write(types.TradeEvent{
+			Timestamp: int64(1000 + i*10),
+			TradeID:   uint64(i),
+			Symbol:    "BTCUSDT",
+			Exchange:  types.BinanceExchange,
+			Type:      types.MarketTypePerpetualUSDMargined,
+			Price:     decimal.RequireFromString("50000.12").Add(decimal.NewFromInt(int64(i))),
+			Size:      decimal.RequireFromString("0.001"),
+			Side:      side,
+		}))
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+}
+
+func TestParquetTrades(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "1000.parquet")
+	writeParquetTrades(t, path, 100, WithRowGroupSize(10))
+
+	// 按行组时间统计筛选
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	stat, err := f.Stat()
+	require.NoError(t, err)
+	pf, err := pq.OpenFile(f, stat.Size())
+	require.NoError(t, err)
+	require.Len(t, pf.RowGroups(), 10)
+	groups, err := selectRowGroups(pf, "traded_at", 1250, 1450)
+	require.NoError(t, err)
+	assert.Len(t, groups, 3)
+
+	r, err := openTrades(path, 1250, 1450)
+	require.NoError(t, err)
+	var trades []types.TradeEvent
+	for {
+		trade, err := r.Next()
+		if err != nil {
+			break
+		}
+		trades = append(trades, trade)
+	}
+	require.NoError(t, r.Close())
+	// 只读取了筛选出的3个行组
+	require.Len(t, trades, 30)
+	assert.Equal(t, uint64(20), trades[0].TradeID)
+	assert.Equal(t, int64(1250), trades[5].Timestamp)
+	assert.Equal(t, "50025.12", trades[5].Price.String())
+	assert.Equal(t, types.SideTypeSell, trades[5].Side)
+	assert.Equal(t, types.MarketTypePerpetualUSDMargined, trades[5].Type)
+	assert.Equal(t, "0.001", trades[5].Size.String())
+
+	// CSVFile可直接回放Parquet文件
+	var replayed []types.TradeEvent
+	done := make(chan struct{})
+	csvFile := NewCSVFile("parquet", types.StreamTypeTrade)
+	require.NoError(t, csvFile.Connect(context.Background(), &CSVFileRequest{
+		Dir:          dir,
+		Start:        1000,
+		End:          1095,
+		Handler:      func(trade types.TradeEvent) { replayed = append(replayed, trade) },
+		CloseHandler: func() { close(done) },
+	}))
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for replay")
+	}
+	require.Len(t, replayed, 10)
+	assert.Equal(t, types.BinanceExchange, replayed[0].Exchange)
+	assert.Equal(t, uint64(9), replayed[9].TradeID)
+}
+
+func TestParquetKlines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "klines.parquet")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	w := NewKlineWriter(f, WithRowGroupSize(2))
+	for i := int64(0); i < 5; i++ {
+		require.NoError(t, w.Write(broker.KlineEvent{
+			Symbol:                   "ETHUSDT",
+			OpenTime:                 i * 60000,
+			CloseTime:                i*60000 + 59999,
+			Open:                     decimal.RequireFromString("2000.5"),
+			High:                     decimal.RequireFromString("2010"),
+			Low:                      decimal.RequireFromString("1990.25"),
+			Close:                    decimal.NewFromInt(2000 + i),
+			Volume:                   decimal.RequireFromString("12.345"),
+			QuoteAssetVolume:         decimal.RequireFromString("24690"),
+			NumberOfTrades:           100 + i,
+			TakerBuyBaseAssetVolume:  decimal.RequireFromString("6"),
+			TakerBuyQuoteAssetVolume: decimal.RequireFromString("12000"),
+			Confirm:                  "1",
+		}))
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+
+	var klines []broker.KlineEvent
+	require.NoError(t, ReadParquetKlines(path, 60000, 180000, func(kline broker.KlineEvent) error {
+		klines = append(klines, kline)
+		return nil
+	}))
+	require.Len(t, klines, 3)
+	assert.Equal(t, int64(60000), klines[0].OpenTime)
+	assert.Equal(t, int64(119999), klines[0].CloseTime)
+	assert.Equal(t, "1990.25", klines[0].Low.String())
+	assert.Equal(t, "2003", klines[2].Close.String())
+	assert.Equal(t, int64(103), klines[2].NumberOfTrades)
+	assert.Equal(t, "1", klines[2].Confirm)
+}
+
+func TestRecorderParquet(t *testing.T) {
+	dir := t.TempDir()
+	symbolDir := filepath.Join(dir, "BTCUSDT")
+	require.NoError(t, os.MkdirAll(symbolDir, 0o755))
+	// 崩溃遗留的文件恢复时转换为Parquet
+	require.NoError(t, os.WriteFile(filepath.Join(symbolDir, "500.csv.tmp"), []byte(
+		"trade_id,size,price,side,symbol,quote,traded_at\n1,1,100,BUY,BTCUSDT,USDT,500\n2,1,1"), 0o644))
+
+	r, err := NewRecorder(dir, WithFormat(FormatParquet))
+	require.NoError(t, err)
+	require.NoError(t, r.Write(types.TradeEvent{TradeID: 3, Symbol: "BTCUSDT", Timestamp: 1000, Price: decimal.NewFromInt(101), Size: decimal.NewFromInt(2), Side: types.SideTypeSell}))
+	require.NoError(t, r.Close())
+
+	files, err := readCSVFileNames(dir, 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(symbolDir, "1000.parquet"), filepath.Join(symbolDir, "500.parquet")}, files)
+
+	it, err := openTrades(files[1], 0, 0)
+	require.NoError(t, err)
+	defer it.Close()
+	trade, err := it.Next()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), trade.TradeID)
+	assert.Equal(t, "BTCUSDT", trade.Symbol)
+	_, err = it.Next()
+	assert.Error(t, err)
+
+	entries, err := os.ReadDir(symbolDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
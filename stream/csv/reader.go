@@ -0,0 +1,349 @@
+package file
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/types"
+)
+
+var (
+	// expectedHeaders 逐笔数据文件必需的列
+	expectedHeaders = []string{"trade_id", "size", "price", "side", "quote", "traded_at"}
+	// klineHeaders K线文件必需的列，可选列：symbol、quote_volume、trades、taker_buy_volume、taker_buy_quote_volume
+	klineHeaders = []string{"open_time", "open", "high", "low", "close", "volume", "close_time"}
+	// depthHeaders 深度快照文件必需的列，每行一个档位，时间相同的连续行组成一个快照，可选列：symbol
+	depthHeaders = []string{"timestamp", "side", "price", "size"}
+)
+
+// iterator 逐条读取数据文件
+type iterator[T any] interface {
+	// Next 读取下一条数据，读取完毕时返回io.EOF
+	Next() (T, error)
+	// Close 关闭文件
+	Close() error
+}
+
+// csvReader 逐行读取CSV文件，根据扩展名自动解压.gz、.zst文件，内存占用与文件大小无关
+type csvReader struct {
+	file    *os.File
+	closers []io.Closer
+	reader  *csv.Reader
+	headers []string
+	// index 列名到列序号
+	index map[string]int
+}
+
+// newCSVReader 打开文件并读取表头，表头缺少required中的列时返回错误。
+// 空文件返回的csvReader在第一次调用Next时返回io.EOF
+func newCSVReader(path string, required []string) (*csvReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &csvReader{file: file}
+
+	var src io.Reader = bufio.NewReaderSize(file, 64*1024)
+	switch {
+	case strings.HasSuffix(strings.ToLower(path), ".gz"):
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			file.Close()
+			if errors.Is(err, io.EOF) {
+				return c, nil
+			}
+			return nil, fmt.Errorf("open gzip error: %w", err)
+		}
+		c.closers = append(c.closers, gz)
+		src = gz
+	case strings.HasSuffix(strings.ToLower(path), ".zst"):
+		zr, err := zstd.NewReader(src, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("open zstd error: %w", err)
+		}
+		c.closers = append(c.closers, zr.IOReadCloser())
+		src = zr
+	}
+
+	c.reader = csv.NewReader(src)
+	c.reader.ReuseRecord = true
+
+	headers, err := c.reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			// 空文件
+			return c, nil
+		}
+		c.Close()
+		return nil, fmt.Errorf("read header error: %w", err)
+	}
+	// 开启ReuseRecord后表头切片会被覆盖，需要复制
+	c.headers = make([]string, len(headers))
+	c.index = make(map[string]int, len(headers))
+	for i, h := range headers {
+		c.headers[i] = strings.ToLower(strings.TrimSpace(h))
+		c.index[c.headers[i]] = i
+	}
+
+	if !validateHeaders(c.headers, required) {
+		c.Close()
+		return nil, fmt.Errorf("invalid or missing headers, got: %v, expected at least: %v", headers, required)
+	}
+	return c, nil
+}
+
+// Next 读取下一行，返回的切片在下一次调用时会被覆盖，读取完毕时返回io.EOF
+func (c *csvReader) Next() ([]string, error) {
+	if c.headers == nil {
+		return nil, io.EOF
+	}
+	record, err := c.reader.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read record error: %w", err)
+	}
+	return record, nil
+}
+
+// column 返回指定列的值，列不存在时返回空字符串
+func (c *csvReader) column(record []string, name string) string {
+	if i, ok := c.index[name]; ok && i < len(record) {
+		return record[i]
+	}
+	return ""
+}
+
+// Close 关闭解压器和文件
+func (c *csvReader) Close() error {
+	for i := len(c.closers) - 1; i >= 0; i-- {
+		c.closers[i].Close()
+	}
+	c.closers = nil
+	return c.file.Close()
+}
+
+// tradeReader 逐行读取逐笔数据文件
+type tradeReader struct {
+	*csvReader
+}
+
+// newTradeReader 打开逐笔数据文件并读取表头
+func newTradeReader(path string) (*tradeReader, error) {
+	c, err := newCSVReader(path, expectedHeaders)
+	if err != nil {
+		return nil, err
+	}
+	return &tradeReader{c}, nil
+}
+
+// Next 读取下一行，读取完毕时返回io.EOF
+func (t *tradeReader) Next() (*tradeData, error) {
+	record, err := t.csvReader.Next()
+	if err != nil {
+		return nil, err
+	}
+	row, err := toTradeData(t.headers, record)
+	if err != nil {
+		return nil, fmt.Errorf("convert record error: %w", err)
+	}
+	return row, nil
+}
+
+// openTrades 根据扩展名打开逐笔数据文件，Parquet文件会根据行组统计信息跳过不在[start, end]内的行组
+func openTrades(path string, start, end int64) (iterator[types.TradeEvent], error) {
+	if isParquet(path) {
+		return newParquetTradeReader(path, start, end)
+	}
+	r, err := newTradeReader(path)
+	if err != nil {
+		return nil, err
+	}
+	return &csvTrades{r}, nil
+}
+
+// csvTrades CSV文件的成交迭代器
+type csvTrades struct {
+	*tradeReader
+}
+
+func (c *csvTrades) Next() (types.TradeEvent, error) {
+	row, err := c.tradeReader.Next()
+	if err != nil {
+		return types.TradeEvent{}, err
+	}
+	event, err := convertToTradeEvent(row)
+	if err != nil {
+		return types.TradeEvent{}, fmt.Errorf("convert tick error: %w", err)
+	}
+	return event, nil
+}
+
+// openKlines 根据扩展名打开K线文件
+func openKlines(path string, start, end int64) (iterator[broker.KlineEvent], error) {
+	if isParquet(path) {
+		return newParquetKlineReader(path, start, end)
+	}
+	c, err := newCSVReader(path, klineHeaders)
+	if err != nil {
+		return nil, err
+	}
+	return &csvKlines{c}, nil
+}
+
+// csvKlines CSV文件的K线迭代器
+type csvKlines struct {
+	*csvReader
+}
+
+func (c *csvKlines) Next() (broker.KlineEvent, error) {
+	record, err := c.csvReader.Next()
+	if err != nil {
+		return broker.KlineEvent{}, err
+	}
+
+	kline := broker.KlineEvent{
+		Symbol: c.column(record, "symbol"),
+		// 历史数据中的K线均已完结
+		Confirm: "1",
+	}
+	for _, f := range []struct {
+		name string
+		dst  *int64
+	}{
+		{"open_time", &kline.OpenTime},
+		{"close_time", &kline.CloseTime},
+		{"trades", &kline.NumberOfTrades},
+	} {
+		value := c.column(record, f.name)
+		if value == "" {
+			continue
+		}
+		if *f.dst, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return broker.KlineEvent{}, fmt.Errorf("parse %s error: %w", f.name, err)
+		}
+	}
+	for _, f := range []struct {
+		name string
+		dst  *decimal.Decimal
+	}{
+		{"open", &kline.Open},
+		{"high", &kline.High},
+		{"low", &kline.Low},
+		{"close", &kline.Close},
+		{"volume", &kline.Volume},
+		{"quote_volume", &kline.QuoteAssetVolume},
+		{"taker_buy_volume", &kline.TakerBuyBaseAssetVolume},
+		{"taker_buy_quote_volume", &kline.TakerBuyQuoteAssetVolume},
+	} {
+		value := c.column(record, f.name)
+		if value == "" {
+			continue
+		}
+		if *f.dst, err = decimal.NewFromString(value); err != nil {
+			return broker.KlineEvent{}, fmt.Errorf("parse %s error: %w", f.name, err)
+		}
+	}
+	return kline, nil
+}
+
+// openDepth 打开深度快照文件，仅支持CSV
+func openDepth(path string, _, _ int64) (iterator[broker.DepthEvent], error) {
+	if isParquet(path) {
+		return nil, errors.New("parquet depth files are not supported")
+	}
+	c, err := newCSVReader(path, depthHeaders)
+	if err != nil {
+		return nil, err
+	}
+	return &csvDepth{csvReader: c}, nil
+}
+
+// depthRow 深度文件中的一行
+type depthRow struct {
+	timestamp int64
+	symbol    string
+	bid       bool
+	level     broker.DepthLevel
+}
+
+// csvDepth CSV文件的深度快照迭代器，将时间和交易对相同的连续行合并为一个快照
+type csvDepth struct {
+	*csvReader
+	// pending 已读取但属于下一个快照的行
+	pending *depthRow
+}
+
+func (c *csvDepth) Next() (broker.DepthEvent, error) {
+	var event broker.DepthEvent
+	started := false
+	for {
+		row := c.pending
+		c.pending = nil
+		if row == nil {
+			var err error
+			if row, err = c.readRow(); err == io.EOF {
+				if started {
+					return event, nil
+				}
+				return broker.DepthEvent{}, io.EOF
+			} else if err != nil {
+				return broker.DepthEvent{}, err
+			}
+		}
+
+		if started && (row.timestamp != event.Timestamp || row.symbol != event.Symbol) {
+			c.pending = row
+			return event, nil
+		}
+		if !started {
+			started = true
+			event.Timestamp = row.timestamp
+			event.Symbol = row.symbol
+		}
+		if row.bid {
+			event.Bids = append(event.Bids, row.level)
+		} else {
+			event.Asks = append(event.Asks, row.level)
+		}
+	}
+}
+
+func (c *csvDepth) readRow() (*depthRow, error) {
+	record, err := c.csvReader.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	row := &depthRow{symbol: c.column(record, "symbol")}
+	if row.timestamp, err = strconv.ParseInt(c.column(record, "timestamp"), 10, 64); err != nil {
+		return nil, fmt.Errorf("parse timestamp error: %w", err)
+	}
+	switch strings.ToUpper(c.column(record, "side")) {
+	case "BID", "BUY":
+		row.bid = true
+	case "ASK", "SELL":
+	default:
+		return nil, fmt.Errorf("invalid depth side: %s", c.column(record, "side"))
+	}
+	if row.level.Price, err = decimal.NewFromString(c.column(record, "price")); err != nil {
+		return nil, fmt.Errorf("parse price error: %w", err)
+	}
+	if row.level.Size, err = decimal.NewFromString(c.column(record, "size")); err != nil {
+		return nil, fmt.Errorf("parse size error: %w", err)
+	}
+	return row, nil
+}
@@ -0,0 +1,150 @@
+package file
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/types"
+)
+
+const compressedCSV = `trade_id,size,price,side,symbol,quote,traded_at
+1,0.1,50000.00,BUY,BTCUSDT,USDT,1657670400000
+2,0.2,50100.00,SELL,BTCUSDT,USDT,1657670401000
+`
+
+func TestTradeReaderCompressed(t *testing.T) {
+	dir := t.TempDir()
+
+	gzFile, err := os.Create(filepath.Join(dir, "1657670400000.csv.gz"))
+	require.NoError(t, err)
+	gw := gzip.NewWriter(gzFile)
+	_, err = gw.Write([]byte(compressedCSV))
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+	require.NoError(t, gzFile.Close())
+
+	zstFile, err := os.Create(filepath.Join(dir, "1657670500000.csv.zst"))
+	require.NoError(t, err)
+	zw, err := zstd.NewWriter(zstFile)
+	require.NoError(t, err)
+	_, err = zw.Write([]byte(compressedCSV))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	require.NoError(t, zstFile.Close())
+
+	// 不符合命名格式的文件会被忽略
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "1657670600000.csv.bz2"), []byte(compressedCSV), 0o644))
+
+	files, err := readCSVFileNames(dir, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	for _, file := range files {
+		rows, err := readCSVFile(file)
+		require.NoError(t, err)
+		require.Len(t, rows, 2)
+		assert.Equal(t, &tradeData{TradeID: 2, Size: "0.2", Price: "50100.00", Side: "SELL", Symbol: "BTCUSDT", Quote: "USDT", TradedAt: 1657670401000}, rows[1])
+	}
+
+	f := NewCSVFile("test", types.StreamTypeTrade)
+	f.ctx, f.cancel = context.WithCancel(context.Background())
+	defer f.cancel()
+
+	eventChan := make(chan types.TradeEvent, 10)
+	require.NoError(t, f.processFile(files[1], eventChan, 1657670400500, 0))
+	close(eventChan)
+
+	var events []types.TradeEvent
+	for e := range eventChan {
+		events = append(events, e)
+	}
+	require.Len(t, events, 1)
+	assert.Equal(t, uint64(2), events[0].TradeID)
+}
+
+func TestTradeReaderInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	empty := filepath.Join(dir, "1.csv.gz")
+	require.NoError(t, os.WriteFile(empty, nil, 0o644))
+	rows, err := readCSVFile(empty)
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+
+	corrupt := filepath.Join(dir, "2.csv.zst")
+	require.NoError(t, os.WriteFile(corrupt, []byte(compressedCSV), 0o644))
+	_, err = readCSVFile(corrupt)
+	assert.Error(t, err)
+
+	badHeader := filepath.Join(dir, "3.csv")
+	require.NoError(t, os.WriteFile(badHeader, []byte("a,b\n1,2\n"), 0o644))
+	_, err = readCSVFile(badHeader)
+	assert.Error(t, err)
+}
+
+func TestCSVFileKlineAndDepth(t *testing.T) {
+	klineDir := t.TempDir()
+	klineHeader := "open_time,open,high,low,close,volume,close_time,trades,symbol\n"
+	writeCSV(t, klineDir, "0.csv", klineHeader+"0,100,110,90,105,12.5,59999,42,BTCUSDT\n")
+	writeCSV(t, klineDir, "60000.csv", klineHeader+"60000,105,106,100,101,3,119999,7,BTCUSDT\n")
+
+	var klines []broker.KlineEvent
+	done := make(chan struct{})
+	f := NewCSVFile("klines", types.StreamTypeKline)
+	assert.Equal(t, types.StreamTypeKline, f.Type())
+	require.Error(t, f.Connect(context.Background(), &CSVFileRequest{Dir: klineDir, Handler: func(types.TradeEvent) {}}))
+	require.NoError(t, f.Connect(context.Background(), &CSVFileRequest{
+		Dir:          klineDir,
+		Start:        60000,
+		KlineHandler: func(kline broker.KlineEvent) { klines = append(klines, kline) },
+		ErrorHandler: func(err error) { t.Error(err) },
+		CloseHandler: func() { close(done) },
+	}))
+	<-done
+	require.Len(t, klines, 1)
+	assert.Equal(t, "BTCUSDT", klines[0].Symbol)
+	assert.Equal(t, int64(60000), klines[0].OpenTime)
+	assert.Equal(t, int64(119999), klines[0].CloseTime)
+	assert.Equal(t, "101", klines[0].Close.String())
+	assert.Equal(t, int64(7), klines[0].NumberOfTrades)
+	assert.True(t, klines[0].QuoteAssetVolume.IsZero())
+
+	depthDir := t.TempDir()
+	writeCSV(t, depthDir, "1000.csv", "timestamp,symbol,side,price,size\n"+
+		"1000,BTCUSDT,bid,99,1\n"+
+		"1000,BTCUSDT,bid,98,2\n"+
+		"1000,BTCUSDT,ask,101,3\n"+
+		"1000,ETHUSDT,ask,11,1\n"+
+		"2000,BTCUSDT,bid,100,1\n")
+
+	var depths []broker.DepthEvent
+	done = make(chan struct{})
+	f = NewCSVFile("depth", types.StreamTypeDepth)
+	require.NoError(t, f.Connect(context.Background(), &CSVFileRequest{
+		Dir:          depthDir,
+		Exchange:     types.BinanceExchange,
+		DepthHandler: func(depth broker.DepthEvent) { depths = append(depths, depth) },
+		ErrorHandler: func(err error) { t.Error(err) },
+		CloseHandler: func() { close(done) },
+	}))
+	<-done
+	require.Len(t, depths, 3)
+	assert.Equal(t, "BTCUSDT", depths[0].Symbol)
+	assert.Equal(t, types.BinanceExchange, depths[0].Exchange)
+	require.Len(t, depths[0].Bids, 2)
+	require.Len(t, depths[0].Asks, 1)
+	assert.Equal(t, "98", depths[0].Bids[1].Price.String())
+	assert.Equal(t, "ETHUSDT", depths[1].Symbol)
+	assert.Empty(t, depths[1].Bids)
+	assert.Equal(t, int64(2000), depths[2].Timestamp)
+
+	require.Error(t, NewCSVFile("ticker", types.StreamTypeTicker).Connect(context.Background(), &CSVFileRequest{Dir: depthDir}))
+}
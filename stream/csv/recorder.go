@@ -0,0 +1,437 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-gotop/gotop/types"
+)
+
+// tmpSuffix 正在写入的文件后缀，完成后重命名为 <timestamp>.csv
+const tmpSuffix = ".tmp"
+
+// csvHeaders 录制文件的表头，与readCSVFile的期望一致
+var csvHeaders = []string{"trade_id", "size", "price", "side", "symbol", "quote", "traded_at"}
+
+// ParseFunc 将数据源推送的原始数据解析为成交，例如 binance.TradeParser、okx.ParseTrades
+type ParseFunc func(data []byte) ([]types.TradeEvent, error)
+
+// segment 某个交易对正在写入的文件
+type segment struct {
+	tmpPath string
+	path    string
+	file    *os.File
+	buf     *bufio.Writer
+	writer  *csv.Writer
+	// end 该文件覆盖的时间段结束时间（毫秒，不含），成交时间达到该值时轮换
+	end int64
+	// dirty 自上次同步后是否有新的写入
+	dirty bool
+}
+
+// Recorder 将实时成交录制为CSVFile可回放的文件。
+// 每个交易对一个子目录 <dir>/<symbol>/，文件按时间段轮换，写入时为 <timestamp>.csv.tmp，
+// 轮换或关闭时同步到磁盘后重命名为 <timestamp>.csv，timestamp为文件中第一笔成交的时间，
+// 轮换出的文件由后台goroutine完成，失败时通过ErrorHandler通知，不影响新文件的写入。
+// 使用FormatParquet时完成的文件会转换为 <timestamp>.parquet。
+// 进程崩溃遗留的.tmp文件会在下次创建Recorder时截断不完整的行后完成重命名或转换。
+type Recorder struct {
+	mu       sync.Mutex
+	dir      string
+	opts     *recorderOptions
+	segments map[string]*segment
+	closed   bool
+	stop     chan struct{}
+	wg       sync.WaitGroup
+	// finished 等待后台完成的文件，completing 正在完成的文件数量
+	finished   []*segment
+	completing int
+	// cond 通知后台有待完成的文件，以及等待方文件已完成
+	cond *sync.Cond
+}
+
+// NewRecorder 创建录制器，dir不存在时自动创建，并恢复上次崩溃遗留的文件
+func NewRecorder(dir string, opts ...RecorderOption) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create dir error: %w", err)
+	}
+	o := applyRecorderOptions(opts...)
+	if err := Recover(dir, o.format); err != nil {
+		return nil, err
+	}
+
+	r := &Recorder{
+		dir:      dir,
+		opts:     o,
+		segments: make(map[string]*segment),
+		stop:     make(chan struct{}),
+	}
+	r.cond = sync.NewCond(&r.mu)
+
+	r.wg.Add(2)
+	go r.syncLoop()
+	go r.completeLoop()
+	return r, nil
+}
+
+// Handler 返回可直接用于DataFeed订阅请求的数据处理函数，解析失败时调用ErrorHandler
+func (r *Recorder) Handler(parse ParseFunc) func(data []byte) {
+	return func(data []byte) {
+		trades, err := parse(data)
+		if err != nil {
+			r.handleErr(fmt.Errorf("parse trade error: %w", err))
+			return
+		}
+		for _, trade := range trades {
+			if err := r.Write(trade); err != nil {
+				r.handleErr(err)
+			}
+		}
+	}
+}
+
+// Write 写入一笔成交
+func (r *Recorder) Write(trade types.TradeEvent) error {
+	if trade.Symbol == "" {
+		return errors.New("trade symbol is empty")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return errors.New("recorder is closed")
+	}
+
+	seg := r.segments[trade.Symbol]
+	if seg != nil && trade.Timestamp >= seg.end {
+		r.rotate(trade.Symbol, seg)
+		seg = nil
+	}
+	if seg == nil {
+		var err error
+		if seg, err = r.open(trade.Symbol, trade.Timestamp); err != nil {
+			return err
+		}
+	}
+
+	if err := seg.writer.Write([]string{
+		strconv.FormatUint(trade.TradeID, 10),
+		trade.Size.String(),
+		trade.Price.String(),
+		trade.Side.String(),
+		trade.Symbol,
+		r.opts.quoteFunc(trade.Symbol),
+		strconv.FormatInt(trade.Timestamp, 10),
+	}); err != nil {
+		return fmt.Errorf("write record error: %w", err)
+	}
+	seg.dirty = true
+	return nil
+}
+
+// Sync 将所有正在写入的文件同步到磁盘，并等待已轮换的文件在后台完成
+func (r *Recorder) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var firstErr error
+	for _, seg := range r.segments {
+		if err := seg.sync(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for len(r.finished) > 0 || r.completing > 0 {
+		r.cond.Wait()
+	}
+	return firstErr
+}
+
+// Close 完成所有正在写入的文件并停止后台同步
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.stop)
+	r.cond.Broadcast()
+	r.mu.Unlock()
+
+	// 等待后台完成已轮换的文件后，完成剩余正在写入的文件
+	r.wg.Wait()
+	var firstErr error
+	for symbol, seg := range r.segments {
+		delete(r.segments, symbol)
+		if err := seg.finalize(r.opts.format); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// open 为交易对创建新文件，调用方需持有锁
+func (r *Recorder) open(symbol string, ts int64) (*segment, error) {
+	dir := filepath.Join(r.dir, symbol)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create dir error: %w", err)
+	}
+
+	// 同一毫秒内重启可能产生同名文件，顺延文件名时间戳，不影响文件内容
+	name := ts
+	for {
+		base := filepath.Join(dir, strconv.FormatInt(name, 10))
+		if !exists(base+".csv") && !exists(base+".csv"+tmpSuffix) && !exists(base+parquetExt) {
+			break
+		}
+		name++
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%d.csv", name))
+
+	file, err := os.OpenFile(path+tmpSuffix, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("create file error: %w", err)
+	}
+	buf := bufio.NewWriter(file)
+	seg := &segment{
+		tmpPath: path + tmpSuffix,
+		path:    path,
+		file:    file,
+		buf:     buf,
+		writer:  csv.NewWriter(buf),
+		end:     r.segmentEnd(ts),
+	}
+	if err := seg.writer.Write(csvHeaders); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("write header error: %w", err)
+	}
+	seg.dirty = true
+
+	r.segments[symbol] = seg
+	return seg, nil
+}
+
+// segmentEnd 返回ts所在时间段的结束时间，时间段按轮换间隔对齐
+func (r *Recorder) segmentEnd(ts int64) int64 {
+	interval := r.opts.rotateInterval.Milliseconds()
+	if interval <= 0 {
+		return int64(^uint64(0) >> 1)
+	}
+	return (ts/interval + 1) * interval
+}
+
+// rotate 将文件移出正在写入的列表，交给后台完成，调用方需持有锁
+func (r *Recorder) rotate(symbol string, seg *segment) {
+	delete(r.segments, symbol)
+	r.finished = append(r.finished, seg)
+	r.cond.Broadcast()
+}
+
+// completeLoop 在后台完成已轮换的文件，同步和格式转换不阻塞Write。
+// Close后完成剩余的文件再退出
+func (r *Recorder) completeLoop() {
+	defer r.wg.Done()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for {
+		for len(r.finished) == 0 && !r.closed {
+			r.cond.Wait()
+		}
+		if len(r.finished) == 0 {
+			return
+		}
+		finished := r.finished
+		r.finished = nil
+		r.completing = len(finished)
+		r.mu.Unlock()
+
+		for _, seg := range finished {
+			if err := seg.finalize(r.opts.format); err != nil {
+				r.handleErr(err)
+			}
+		}
+
+		r.mu.Lock()
+		r.completing = 0
+		r.cond.Broadcast()
+	}
+}
+
+// finalize 同步并关闭文件，然后重命名为正式文件
+func (s *segment) finalize(format FileFormat) error {
+	if err := s.sync(); err != nil {
+		s.file.Close()
+		return err
+	}
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("close file error: %w", err)
+	}
+	return complete(s.tmpPath, format)
+}
+
+// complete 将已同步的.tmp文件重命名为正式文件，或转换为Parquet后删除
+func complete(tmpPath string, format FileFormat) error {
+	path := strings.TrimSuffix(tmpPath, tmpSuffix)
+	if format == FormatParquet {
+		dst := strings.TrimSuffix(path, ".csv") + parquetExt
+		if err := ConvertToParquet(tmpPath, dst); err != nil {
+			return fmt.Errorf("convert %s error: %w", tmpPath, err)
+		}
+		if err := os.Remove(tmpPath); err != nil {
+			return fmt.Errorf("remove file error: %w", err)
+		}
+	} else if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("rename file error: %w", err)
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncLoop 按fsync间隔同步文件，并完成长时间没有新成交但已过期的文件
+func (r *Recorder) syncLoop() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.opts.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case now := <-ticker.C:
+			r.mu.Lock()
+			if r.closed {
+				r.mu.Unlock()
+				return
+			}
+			for symbol, seg := range r.segments {
+				if now.UnixMilli() >= seg.end+r.opts.finalizeDelay.Milliseconds() {
+					r.rotate(symbol, seg)
+				} else if err := seg.sync(); err != nil {
+					r.handleErr(err)
+				}
+			}
+			r.mu.Unlock()
+		}
+	}
+}
+
+func (r *Recorder) handleErr(err error) {
+	if r.opts.errorHandler != nil {
+		r.opts.errorHandler(err)
+	}
+}
+
+// sync 刷新缓冲区并同步到磁盘
+func (s *segment) sync() error {
+	if !s.dirty {
+		return nil
+	}
+	s.writer.Flush()
+	if err := s.writer.Error(); err != nil {
+		return fmt.Errorf("flush error: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("fsync error: %w", err)
+	}
+	s.dirty = false
+	return nil
+}
+
+// Recover 完成dir下崩溃遗留的.tmp文件：截断最后一行不完整的记录后按format重命名或转换为正式文件，
+// 只有表头或为空的文件会被删除
+func Recover(dir string, format FileFormat) error {
+	var tmpFiles []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(info.Name(), ".csv"+tmpSuffix) {
+			tmpFiles = append(tmpFiles, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("walk directory error: %w", err)
+	}
+
+	for _, path := range tmpFiles {
+		if err := recoverFile(path, format); err != nil {
+			return fmt.Errorf("recover %s error: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// recoverFile 恢复单个.tmp文件
+func recoverFile(tmpPath string, format FileFormat) error {
+	data, err := os.ReadFile(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	// 只保留完整的行
+	if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+		data = data[:i+1]
+	} else {
+		data = nil
+	}
+	if bytes.Count(data, []byte{'\n'}) <= 1 {
+		return os.Remove(tmpPath)
+	}
+
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return err
+	}
+	f, err := os.Open(tmpPath)
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	f.Close()
+	if err != nil {
+		return err
+	}
+	return complete(tmpPath, format)
+}
+
+// syncDir 同步目录，保证重命名持久化
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("fsync dir error: %w", err)
+	}
+	return nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// defaultQuote 从交易对中推断计价币种，例如"BTC-USDT"、"BTCUSDT"均返回"USDT"
+func defaultQuote(symbol string) string {
+	if parts := strings.Split(symbol, "-"); len(parts) >= 2 {
+		return parts[1]
+	}
+	for _, quote := range []string{"USDT", "USDC", "FDUSD", "BUSD", "TUSD", "USD", "BTC", "ETH", "BNB", "EUR", "TRY"} {
+		if strings.HasSuffix(symbol, quote) && len(symbol) > len(quote) {
+			return quote
+		}
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/types"
+)
+
+func TestRecorderRotate(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewRecorder(dir, WithRotateInterval(time.Hour), WithSyncInterval(time.Hour))
+	require.NoError(t, err)
+
+	base := int64(1657670400000) // 整点
+	trades := []types.TradeEvent{
+		{TradeID: 1, Symbol: "BTCUSDT", Timestamp: base + 1000, Price: decimal.NewFromInt(100), Size: decimal.NewFromFloat(0.5), Side: types.SideTypeBuy},
+		{TradeID: 2, Symbol: "BTCUSDT", Timestamp: base + 2000, Price: decimal.NewFromInt(101), Size: decimal.NewFromInt(1), Side: types.SideTypeSell},
+		{TradeID: 3, Symbol: "BTCUSDT", Timestamp: base + 3600*1000 + 5, Price: decimal.NewFromInt(102), Size: decimal.NewFromInt(2), Side: types.SideTypeBuy},
+		{TradeID: 7, Symbol: "BTC-USDT", Timestamp: base + 10, Price: decimal.NewFromInt(99), Size: decimal.NewFromInt(3), Side: types.SideTypeSell},
+	}
+	for _, trade := range trades {
+		require.NoError(t, r.Write(trade))
+	}
+
+	// 跨过整点后第一个文件已完成
+	require.NoError(t, r.Sync())
+	_, err = os.Stat(filepath.Join(dir, "BTCUSDT", "1657670401000.csv"))
+	require.NoError(t, err)
+
+	require.NoError(t, r.Close())
+	require.Error(t, r.Write(trades[0]))
+
+	files, err := readCSVFileNames(dir, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+
+	rows, err := readCSVFile(filepath.Join(dir, "BTCUSDT", "1657670401000.csv"))
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, &tradeData{TradeID: 1, Size: "0.5", Price: "100", Side: "BUY", Symbol: "BTCUSDT", Quote: "USDT", TradedAt: base + 1000}, rows[0])
+	assert.Equal(t, "SELL", rows[1].Side)
+
+	rows, err = readCSVFile(filepath.Join(dir, "BTCUSDT", "1657674000005.csv"))
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, uint64(3), rows[0].TradeID)
+
+	rows, err = readCSVFile(filepath.Join(dir, "BTC-USDT", "1657670400010.csv"))
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "USDT", rows[0].Quote)
+
+	event, err := convertToTradeEvent(rows[0])
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), event.TradeID)
+}
+
+func TestRecorderRotateError(t *testing.T) {
+	dir := t.TempDir()
+	errs := make(chan error, 4)
+	r, err := NewRecorder(dir,
+		WithRotateInterval(time.Hour),
+		WithSyncInterval(time.Hour),
+		WithRecorderErrorHandler(func(err error) { errs <- err }),
+	)
+	require.NoError(t, err)
+	defer r.Close()
+
+	base := int64(1657670400000)
+	require.NoError(t, r.Write(types.TradeEvent{TradeID: 1, Symbol: "BTCUSDT", Timestamp: base, Price: decimal.NewFromInt(1), Size: decimal.NewFromInt(1)}))
+	require.NoError(t, r.Sync())
+	// 删除正在写入的文件，轮换时重命名失败
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "BTCUSDT")))
+
+	// 轮换失败通过ErrorHandler通知，新成交仍写入新文件
+	require.NoError(t, r.Write(types.TradeEvent{TradeID: 2, Symbol: "BTCUSDT", Timestamp: base + 3600*1000, Price: decimal.NewFromInt(2), Size: decimal.NewFromInt(1)}))
+	require.NoError(t, r.Sync())
+	require.Len(t, errs, 1)
+	assert.Contains(t, (<-errs).Error(), "rename file error")
+
+	require.NoError(t, r.Close())
+	rows, err := readCSVFile(filepath.Join(dir, "BTCUSDT", "1657674000000.csv"))
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, uint64(2), rows[0].TradeID)
+}
+
+func TestRecorderRecover(t *testing.T) {
+	dir := t.TempDir()
+	symbolDir := filepath.Join(dir, "ETHUSDT")
+	require.NoError(t, os.MkdirAll(symbolDir, 0o755))
+
+	header := "trade_id,size,price,side,symbol,quote,traded_at\n"
+	// 最后一行写到一半时崩溃
+	partial := header +
+		"1,1,2000,BUY,ETHUSDT,USDT,1657670400000\n" +
+		"2,1,2001,SELL,ETHUSDT,USDT,1657670400100\n" +
+		"3,1,20"
+	require.NoError(t, os.WriteFile(filepath.Join(symbolDir, "1657670400000.csv.tmp"), []byte(partial), 0o644))
+	// 只有表头的文件会被删除
+	require.NoError(t, os.WriteFile(filepath.Join(symbolDir, "1657670500000.csv.tmp"), []byte(header), 0o644))
+
+	r, err := NewRecorder(dir)
+	require.NoError(t, err)
+	defer r.Close()
+
+	entries, err := os.ReadDir(symbolDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "1657670400000.csv", entries[0].Name())
+
+	rows, err := readCSVFile(filepath.Join(symbolDir, "1657670400000.csv"))
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, uint64(2), rows[1].TradeID)
+
+	// 与已有文件同名时顺延文件名
+	require.NoError(t, r.Write(types.TradeEvent{TradeID: 4, Symbol: "ETHUSDT", Timestamp: 1657670400000, Price: decimal.NewFromInt(1), Size: decimal.NewFromInt(1)}))
+	require.NoError(t, r.Sync())
+	_, err = os.Stat(filepath.Join(symbolDir, "1657670400001.csv.tmp"))
+	require.NoError(t, err)
+}
@@ -0,0 +1,107 @@
+package stream
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// SequenceFunc 从原始消息中提取去重键和序列号，例如 (流名称, 成交ID)。
+// ok 为 false 表示该消息没有序列号，不参与基于序列号的去重。
+type SequenceFunc func(data []byte) (key string, seq int64, ok bool)
+
+// Deduplicator 用于重连期间的数据去重。
+// 1. 配置了 SequenceFunc 时，按键记录已投递的最大序列号，丢弃序列号不大于该值的消息；
+// 2. 处于重叠窗口时，记录主连接已投递消息的摘要，用于丢弃新连接上内容相同的消息。
+type Deduplicator struct {
+	mu       sync.Mutex
+	sequence SequenceFunc
+	last     map[string]int64
+	overlap  map[uint64]struct{}
+}
+
+// NewDeduplicator 创建去重器，sequence 可以为空
+func NewDeduplicator(sequence SequenceFunc) *Deduplicator {
+	return &Deduplicator{
+		sequence: sequence,
+		last:     make(map[string]int64),
+	}
+}
+
+// Accept 判断消息是否需要投递，需要投递时同时更新去重状态
+func (d *Deduplicator) Accept(msg []byte) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.sequence != nil {
+		if key, seq, ok := d.sequence(msg); ok {
+			if last, exists := d.last[key]; exists && seq <= last {
+				return false
+			}
+			d.last[key] = seq
+		}
+	}
+	if d.overlap != nil {
+		d.overlap[digest(msg)] = struct{}{}
+	}
+	return true
+}
+
+// Seen 判断消息是否已经在重叠窗口内投递过，或序列号已投递过
+func (d *Deduplicator) Seen(msg []byte) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.overlap != nil {
+		if _, ok := d.overlap[digest(msg)]; ok {
+			return true
+		}
+	}
+	if d.sequence != nil {
+		if key, seq, ok := d.sequence(msg); ok {
+			if last, exists := d.last[key]; exists && seq <= last {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// BeginOverlap 开始重叠窗口，记录之后投递的消息摘要
+func (d *Deduplicator) BeginOverlap() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.overlap = make(map[uint64]struct{})
+}
+
+// EndOverlap 结束重叠窗口
+func (d *Deduplicator) EndOverlap() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.overlap = nil
+}
+
+// Last 返回各键已投递的最大序列号快照
+func (d *Deduplicator) Last() map[string]int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	last := make(map[string]int64, len(d.last))
+	for k, v := range d.last {
+		last[k] = v
+	}
+	return last
+}
+
+// Reset 清空去重状态
+func (d *Deduplicator) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.last = make(map[string]int64)
+	d.overlap = nil
+}
+
+// digest 计算消息摘要
+func digest(msg []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(msg)
+	return h.Sum64()
+}
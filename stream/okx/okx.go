@@ -4,13 +4,15 @@ import (
 	"context"
 	"errors"
 	"log/slog"
-	"net/http"
 	"sync"
 	"time"
 
 	"github.com/bitly/go-simplejson"
 	"github.com/gorilla/websocket"
 
+	"github.com/go-gotop/gotop/clock"
+	"github.com/go-gotop/gotop/stream"
+	"github.com/go-gotop/gotop/stream/ws"
 	"github.com/go-gotop/gotop/types"
 )
 
@@ -25,44 +27,70 @@ type OkxRequest struct {
 	Handler func(data []byte)
 	// ErrorHandler 错误处理函数
 	ErrorHandler func(err error)
+	// Args 连接建立后订阅的频道列表，重连后会自动重新订阅
+	Args []Arg
+	// SequenceFunc 可选，从消息中提取去重键和序列号（如成交ID），用于重连时去重
+	SequenceFunc stream.SequenceFunc
+	// BackfillFunc 可选，非无缝重连成功后回补断线期间缺失的数据。
+	// last 为各键已投递的最大序列号，返回的消息格式需与推送消息一致，
+	// 回补期间实时消息会被暂存，回补消息与暂存消息去重后按顺序投递。
+	BackfillFunc func(ctx context.Context, last map[string]int64) ([][]byte, error)
 }
 
-// dialFunc定义用于方便在测试时mock连接的逻辑
-type dialFunc func(urlStr string, requestHeader http.Header) (*websocket.Conn, *http.Response, error)
+// Arg OKX订阅频道参数
+type Arg struct {
+	// Channel 频道名，例如"trades"
+	Channel string `json:"channel"`
+	// InstType 产品类型，例如"SPOT"、"SWAP"
+	InstType string `json:"instType,omitempty"`
+	// InstFamily 交易品种
+	InstFamily string `json:"instFamily,omitempty"`
+	// InstID 产品ID，例如"BTC-USDT"
+	InstID string `json:"instId,omitempty"`
+}
+
+// Key 返回频道参数的唯一标识，用于消息路由
+func (a Arg) Key() string {
+	return a.Channel + ":" + a.InstType + ":" + a.InstFamily + ":" + a.InstID
+}
 
-// OkxStream 是Okx Stream的核心结构体
+// OkxStream 是Okx Stream的适配器，连接管理由ws.Client完成，
+// 这里只负责频道订阅、文本心跳和事件消息的过滤。
 type OkxStream struct {
 	mu     sync.Mutex
 	id     string
 	st     types.StreamType
 	cfg    OkxRequest
-	conn   *websocket.Conn
-	ctx    context.Context
-	cancel context.CancelFunc
+	client *ws.Client
 
 	// 用于重连的dial函数，可在测试中mock
-	dialer dialFunc
+	dialer ws.DialFunc
 
 	// 心跳与超时配置
 	pingInterval time.Duration
 	pongWait     time.Duration
 	writeWait    time.Duration
 
-	// 用于在24小时后重连的ticker和控制重连的通道
+	// 定时重连间隔
 	reconnectInterval time.Duration
-	reconnectTicker   *time.Ticker
 
-	// 用于等待后台goroutine的结束
-	wg sync.WaitGroup
+	// seamlessOverlap 无缝重连时新旧连接并行的时长，为0时定时重连采用先断后连
+	seamlessOverlap time.Duration
+
+	// backoff 重连退避策略
+	backoff stream.Backoff
 
-	// 用于标识后台goroutine的完成，以便Disconnect时等待
-	doneCh chan struct{}
+	// stateHandler 连接状态变化回调
+	stateHandler stream.StateHandler
 
-	// 用于记录数据序列的占位变量，未来可用于无缝重连的数据去重处理
-	lastSequence int64
+	// clientOpts 额外传给ws.Client的配置，例如有界队列
+	clientOpts []ws.Option
 
-	// 是否正在尝试重连
-	reconnecting bool
+	// clock 时钟，用于底层ws.Client的心跳和重连定时器
+	clock clock.Clock
+
+	// subscriptions 当前订阅的频道，重连后据此重新订阅
+	subscriptions map[string]Arg
 }
 
 // NewOkxStream 创建一个新的OkxStream
@@ -70,39 +98,54 @@ func NewOkxStream(id string, st types.StreamType, opts ...Option) *OkxStream {
 	b := &OkxStream{
 		id:                id,
 		st:                st,
-		dialer:            defaultDialer,
+		dialer:            ws.DefaultDialer,
 		pingInterval:      10 * time.Second,
 		pongWait:          60 * time.Second,
 		writeWait:         5 * time.Second,
 		reconnectInterval: 23 * time.Hour, // 24小时自动重连周期
+		backoff:           stream.DefaultBackoff(),
 	}
 	applyOptions(b, opts...)
+	b.clock = clock.OrReal(b.clock)
 	return b
 }
 
-// defaultDialer 默认的dial函数
-func defaultDialer(urlStr string, requestHeader http.Header) (*websocket.Conn, *http.Response, error) {
-	var d websocket.Dialer
-	return d.Dial(urlStr, requestHeader)
-}
-
 // Connect 连接到Okx Stream
 func (b *OkxStream) Connect(ctx context.Context, cfg OkxRequest) error {
-	b.mu.Lock()
-	defer b.mu.Unlock()
+	opts := append([]ws.Option{
+		ws.WithDialer(b.dialer),
+		ws.WithPingInterval(b.pingInterval),
+		ws.WithPongWait(b.pongWait),
+		ws.WithWriteWait(b.writeWait),
+		ws.WithReconnectInterval(b.reconnectInterval),
+		ws.WithSeamlessReconnect(b.seamlessOverlap),
+		ws.WithBackoff(b.backoff),
+		ws.WithStateHandler(b.stateHandler),
+		ws.WithClock(b.clock),
+	}, b.clientOpts...)
+	client := ws.NewClient(b.id, ws.Hooks{
+		OnConnect: b.onConnect,
+		Ping:      b.ping,
+		Filter:    b.filter,
+	}, opts...)
 
+	b.mu.Lock()
 	b.cfg = cfg
-	b.ctx, b.cancel = context.WithCancel(ctx)
-	b.reconnecting = false
-
-	if err := b.connect(); err != nil {
-		return err
+	b.client = client
+	b.subscriptions = make(map[string]Arg, len(cfg.Args))
+	for _, arg := range cfg.Args {
+		b.subscriptions[arg.Key()] = arg
 	}
-	// 启动后台goroutine
-	b.startGoroutines()
+	b.mu.Unlock()
 
-	b.log("Connected successfully")
-	return nil
+	return client.Connect(ctx, ws.Config{
+		URL:          cfg.URL,
+		Logger:       cfg.Logger,
+		Handler:      cfg.Handler,
+		ErrorHandler: cfg.ErrorHandler,
+		SequenceFunc: cfg.SequenceFunc,
+		BackfillFunc: cfg.BackfillFunc,
+	})
 }
 
 // ID 返回当前连接的ID
@@ -118,232 +161,161 @@ func (b *OkxStream) Type() types.StreamType {
 // Disconnect 断开当前连接
 func (b *OkxStream) Disconnect() error {
 	b.mu.Lock()
-	// 取消当前上下文
-	if b.cancel != nil {
-		b.cancel()
-	}
-	// 关闭当前连接
-	if b.conn != nil {
-		_ = b.conn.Close()
-	}
+	client := b.client
 	b.mu.Unlock()
 
-	// 等待所有goroutine结束
-	b.wg.Wait()
-	b.log("Disconnected")
-	return nil
+	if client == nil {
+		return nil
+	}
+	return client.Disconnect()
 }
 
-// connect 函数用于建立WebSocket连接
-// 主要功能:
-// 1. 使用dialer建立WebSocket连接
-// 2. 设置连接的读取超时时间
-// 3. 设置pong消息的处理函数
-func (b *OkxStream) connect() error {
-	// 使用dialer建立WebSocket连接
-	c, _, err := b.dialer(b.cfg.URL, nil)
-	if err != nil {
-		// 如果连接失败,调用错误处理函数并返回错误
-		b.handleErr(err)
-		return err
+// State 返回当前连接状态
+func (b *OkxStream) State() stream.State {
+	b.mu.Lock()
+	client := b.client
+	b.mu.Unlock()
+
+	if client == nil {
+		return stream.StateIdle
 	}
+	return client.State()
+}
 
-	// 保存连接实例
-	b.conn = c
-	// 设置读取超时时间为pongWait
-	b.conn.SetReadDeadline(time.Now().Add(b.pongWait))
+// Reconnects 返回累计成功重连次数
+func (b *OkxStream) Reconnects() int64 {
+	b.mu.Lock()
+	client := b.client
+	b.mu.Unlock()
 
-	// 调用连接成功处理函数
-	if b.cfg.ConnectedHandler != nil {
-		b.cfg.ConnectedHandler(b.conn)
+	if client == nil {
+		return 0
 	}
+	return client.Reconnects()
+}
+
+// QueueStats 返回读循环与Handler之间队列的深度与丢弃计数
+func (b *OkxStream) QueueStats() ws.QueueStats {
+	b.mu.Lock()
+	client := b.client
+	b.mu.Unlock()
 
-	return nil
+	if client == nil {
+		return ws.QueueStats{}
+	}
+	return client.QueueStats()
 }
 
-func (b *OkxStream) startGoroutines() {
-	// 开始readLoop
-	b.wg.Add(1)
-	go b.readLoop()
+// onConnect 新连接建立后调用连接成功处理函数，并恢复已记录的订阅
+func (b *OkxStream) onConnect(conn *ws.Conn) error {
+	b.mu.Lock()
+	handler := b.cfg.ConnectedHandler
+	args := b.subscribedArgs()
+	b.mu.Unlock()
 
-	// 开始pingLoop
-	b.wg.Add(1)
-	go b.pingLoop()
+	if handler != nil {
+		handler(conn.Raw())
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return sendOp(conn, "subscribe", args)
+}
 
-	// 开始autoReconnectLoop
-	b.wg.Add(1)
-	go b.autoReconnectLoop()
+// ping OKX使用文本"ping"作为心跳，服务端回复文本"pong"
+func (b *OkxStream) ping(conn *ws.Conn) error {
+	return conn.WriteMessage(websocket.TextMessage, []byte("ping"))
 }
 
-func (b *OkxStream) readLoop() {
-	defer b.wg.Done()
-
-	for {
-		select {
-		case <-b.ctx.Done():
-			return
-		default:
-			b.mu.Lock()
-			conn := b.conn
-			b.mu.Unlock()
-
-			if conn == nil {
-				// 如果没有连接，稍等等待重连
-				time.Sleep(500 * time.Millisecond)
-				continue
-			}
-
-			_, msg, err := conn.ReadMessage()
-			if err != nil {
-				// 通知错误
-				b.handleErr(err)
-
-				// 异步尝试重连，readLoop立即返回，防止死锁
-				go b.attemptReconnect()
-
-				return // 结束当前readLoop
-			}
-
-			// 检查是否是 pong 消息
-			if string(msg) == "pong" {
-				// 如果是 pong 消息，更新读取超时时间
-				conn.SetReadDeadline(time.Now().Add(b.pongWait))
-				continue // 不需要传递给 Handler
-			}
-
-			j, err := simplejson.NewJson(msg)
-			if err != nil {
-				b.handleErr(err)
-				continue
-			}
-
-			// 获取event
-			event := j.Get("event").MustString()
-			if event == "error" {
-				b.handleErr(errors.New(j.Get("msg").MustString()))
-				continue
-			}
-
-			if event != "" {
-				continue
-			}
-
-			if b.cfg.Handler != nil {
-				b.cfg.Handler(msg)
-			}
-		}
+// filter 过滤pong和事件消息（订阅响应、错误等），只有推送数据传递给Handler
+func (b *OkxStream) filter(conn *ws.Conn, msg []byte) (bool, error) {
+	// 检查是否是 pong 消息
+	if string(msg) == "pong" {
+		// 如果是 pong 消息，更新读取超时时间
+		conn.ExtendReadDeadline()
+		return false, nil
 	}
-}
 
-func (b *OkxStream) pingLoop() {
-	defer b.wg.Done()
-
-	ticker := time.NewTicker(b.pingInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-b.ctx.Done():
-			return
-		case <-ticker.C:
-			b.mu.Lock()
-			conn := b.conn
-			b.mu.Unlock()
-
-			if conn == nil {
-				continue
-			}
-
-			conn.SetWriteDeadline(time.Now().Add(b.writeWait))
-			if err := conn.WriteMessage(websocket.TextMessage, []byte("ping")); err != nil {
-				b.handleErr(err)
-				// 异步重连
-				go b.attemptReconnect()
-				return
-			}
-		}
+	j, err := simplejson.NewJson(msg)
+	if err != nil {
+		return false, err
 	}
-}
 
-func (b *OkxStream) autoReconnectLoop() {
-	defer b.wg.Done()
-
-	ticker := time.NewTicker(b.reconnectInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-b.ctx.Done():
-			return
-		case <-ticker.C:
-			b.log("Time-based reconnect triggered")
-			go b.attemptReconnect()
-			return
-		}
+	// 获取event
+	event := j.Get("event").MustString()
+	if event == "error" {
+		return false, errors.New(j.Get("msg").MustString())
 	}
+	return event == "", nil
 }
 
-func (b *OkxStream) attemptReconnect() {
+// Subscribe 在当前连接上批量订阅频道，订阅会被记录下来，重连后自动恢复
+func (b *OkxStream) Subscribe(args ...Arg) error {
+	if len(args) == 0 {
+		return nil
+	}
 	b.mu.Lock()
-	if b.reconnecting {
-		b.mu.Unlock()
-		return
+	if b.subscriptions == nil {
+		b.subscriptions = make(map[string]Arg, len(args))
+	}
+	for _, arg := range args {
+		b.subscriptions[arg.Key()] = arg
 	}
-	b.reconnecting = true
 	b.mu.Unlock()
 
-	b.log("Attempting reconnect...")
+	return b.send("subscribe", args)
+}
 
-	// 停止当前上下文，等待goroutine全部退出
+// Unsubscribe 在当前连接上批量取消订阅频道
+func (b *OkxStream) Unsubscribe(args ...Arg) error {
+	if len(args) == 0 {
+		return nil
+	}
 	b.mu.Lock()
-	if b.cancel != nil {
-		b.cancel()
+	for _, arg := range args {
+		delete(b.subscriptions, arg.Key())
 	}
-	conn := b.conn
-	b.conn = nil
 	b.mu.Unlock()
 
-	if conn != nil {
-		conn.Close()
-	}
+	return b.send("unsubscribe", args)
+}
 
-	// 等待所有当前goroutine结束
-	b.wg.Wait()
+// Subscriptions 返回当前订阅的频道列表
+func (b *OkxStream) Subscriptions() []Arg {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.subscribedArgs()
+}
 
-	// 创建新上下文
-	ctx, cancel := context.WithCancel(context.Background())
+// subscribedArgs 返回当前订阅的频道，调用方需持有锁
+func (b *OkxStream) subscribedArgs() []Arg {
+	args := make([]Arg, 0, len(b.subscriptions))
+	for _, arg := range b.subscriptions {
+		args = append(args, arg)
+	}
+	return args
+}
 
+// send 在当前连接上发送订阅类操作，连接尚未建立或正在重连时直接返回，重连后会自动订阅
+func (b *OkxStream) send(op string, args []Arg) error {
 	b.mu.Lock()
-	b.ctx = ctx
-	b.cancel = cancel
-	b.reconnecting = false
+	client := b.client
 	b.mu.Unlock()
 
-	b.log("Reconnecting...")
-
-	// 简单重试机制
-	for i := 0; i < 5; i++ { // 尝试重连5次
-		if err := b.connect(); err == nil {
-			b.log("Reconnected successfully")
-			b.startGoroutines()
-			return
-		}
-		time.Sleep(5 * time.Second)
+	if client == nil {
+		return nil
 	}
-
-	b.log("Failed to reconnect after 5 attempts")
-	// 如果5次都失败，可以选择再次尝试或者直接返回上层处理
-}
-
-func (b *OkxStream) handleErr(err error) {
-	if b.cfg.ErrorHandler != nil {
-		b.cfg.ErrorHandler(err)
+	conn := client.Conn()
+	if conn == nil {
+		return nil
 	}
-	b.log("Error: " + err.Error())
+	return sendOp(conn, op, args)
 }
 
-func (b *OkxStream) log(msg string) {
-	if b.cfg.Logger != nil {
-		b.cfg.Logger.Info(msg)
-	}
+// sendOp 发送订阅类操作消息: {"op":"subscribe","args":[...]}
+func sendOp(conn *ws.Conn, op string, args []Arg) error {
+	return conn.WriteJSON(map[string]any{
+		"op":   op,
+		"args": args,
+	})
 }
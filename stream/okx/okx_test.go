@@ -0,0 +1,206 @@
+package okx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/clock"
+	"github.com/go-gotop/gotop/types"
+)
+
+type opRequest struct {
+	Op   string `json:"op"`
+	Args []Arg  `json:"args"`
+}
+
+func TestSubscribeAndUnsubscribe(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	requests := make(chan opRequest, 4)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if string(msg) == "ping" {
+				conn.WriteMessage(websocket.TextMessage, []byte("pong"))
+				continue
+			}
+			var req opRequest
+			require.NoError(t, json.Unmarshal(msg, &req))
+			requests <- req
+			// 回复订阅响应，然后为每个频道推送一条消息
+			for _, arg := range req.Args {
+				event, _ := json.Marshal(map[string]any{"event": req.Op, "arg": arg})
+				conn.WriteMessage(websocket.TextMessage, event)
+				if req.Op == "subscribe" {
+					push, _ := json.Marshal(map[string]any{"arg": arg, "data": []any{}})
+					conn.WriteMessage(websocket.TextMessage, push)
+				}
+			}
+		}
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	btc := Arg{Channel: "trades", InstID: "BTC-USDT"}
+	eth := Arg{Channel: "trades", InstID: "ETH-USDT"}
+	received := make(chan Arg, 4)
+	s := NewOkxStream("subscribe_test", types.StreamTypeTrade)
+	err := s.Connect(context.Background(), OkxRequest{
+		URL:  wsURL,
+		Args: []Arg{btc},
+		Handler: func(data []byte) {
+			var m struct {
+				Arg Arg `json:"arg"`
+			}
+			require.NoError(t, json.Unmarshal(data, &m))
+			received <- m.Arg
+		},
+	})
+	require.NoError(t, err)
+	defer s.Disconnect()
+
+	next := func() opRequest {
+		select {
+		case req := <-requests:
+			return req
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for request")
+			return opRequest{}
+		}
+	}
+	nextPush := func() Arg {
+		select {
+		case arg := <-received:
+			return arg
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for push message")
+			return Arg{}
+		}
+	}
+
+	// 连接建立后订阅Args
+	require.Equal(t, opRequest{Op: "subscribe", Args: []Arg{btc}}, next())
+	require.Equal(t, btc, nextPush())
+
+	require.NoError(t, s.Subscribe(eth))
+	require.Equal(t, opRequest{Op: "subscribe", Args: []Arg{eth}}, next())
+	// 订阅响应不会传递给Handler，只会收到推送消息
+	require.Equal(t, eth, nextPush())
+	require.ElementsMatch(t, []Arg{btc, eth}, s.Subscriptions())
+
+	require.NoError(t, s.Unsubscribe(btc))
+	require.Equal(t, opRequest{Op: "unsubscribe", Args: []Arg{btc}}, next())
+	require.Equal(t, []Arg{eth}, s.Subscriptions())
+
+	select {
+	case arg := <-received:
+		t.Fatalf("unexpected push message for %v", arg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestErrorEvent(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"event":"error","code":"60018","msg":"Invalid instId"}`))
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	errs := make(chan error, 1)
+	s := NewOkxStream("error_test", types.StreamTypeTrade)
+	err := s.Connect(context.Background(), OkxRequest{
+		URL:  wsURL,
+		Args: []Arg{{Channel: "trades", InstID: "UNKNOWN"}},
+		Handler: func(data []byte) {
+			t.Errorf("unexpected message: %s", data)
+		},
+		ErrorHandler: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+	require.NoError(t, err)
+	defer s.Disconnect()
+
+	select {
+	case err := <-errs:
+		require.EqualError(t, err, "Invalid instId")
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for error")
+	}
+}
+
+func TestWithClock(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	pings := make(chan struct{}, 8)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if string(msg) == "ping" {
+				pings <- struct{}{}
+				conn.WriteMessage(websocket.TextMessage, []byte("pong"))
+			}
+		}
+	}))
+	defer server.Close()
+
+	sim := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := NewOkxStream("clock_test", types.StreamTypeTrade, WithClock(sim), WithPingInterval(10*time.Second))
+	require.NoError(t, s.Connect(context.Background(), OkxRequest{
+		URL:  "ws" + strings.TrimPrefix(server.URL, "http"),
+		Args: []Arg{{Channel: "trades", InstID: "BTC-USDT"}},
+	}))
+	defer s.Disconnect()
+
+	// 心跳由模拟时钟驱动，真实时间经过不会发送ping
+	select {
+	case <-pings:
+		t.Fatal("unexpected ping before the simulated clock advanced")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.Eventually(t, func() bool {
+		sim.Advance(10 * time.Second)
+		select {
+		case <-pings:
+			return true
+		case <-time.After(10 * time.Millisecond):
+			return false
+		}
+	}, time.Second, time.Millisecond)
+}
@@ -5,6 +5,10 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+
+	"github.com/go-gotop/gotop/clock"
+	"github.com/go-gotop/gotop/stream"
+	"github.com/go-gotop/gotop/stream/ws"
 )
 
 type Option func(*OkxStream)
@@ -49,3 +53,40 @@ func WithDialer(dialer func(urlStr string, requestHeader http.Header) (*websocke
 		b.dialer = dialer
 	}
 }
+
+// WithSeamlessReconnect 开启无缝重连，定时重连时先建立新连接，新旧连接并行overlap时长并去重后再断开旧连接
+func WithSeamlessReconnect(overlap time.Duration) Option {
+	return func(b *OkxStream) {
+		b.seamlessOverlap = overlap
+	}
+}
+
+// WithBackoff 设置重连退避策略，默认为 stream.DefaultBackoff()（指数退避、无限重连）
+func WithBackoff(backoff stream.Backoff) Option {
+	return func(b *OkxStream) {
+		if backoff != nil {
+			b.backoff = backoff
+		}
+	}
+}
+
+// WithStateHandler 设置连接状态变化回调，回调在Stream内部goroutine中同步执行，不应阻塞
+func WithStateHandler(handler stream.StateHandler) Option {
+	return func(b *OkxStream) {
+		b.stateHandler = handler
+	}
+}
+
+// WithClientOptions 设置额外传给底层ws.Client的配置，例如 ws.WithQueue 启用有界队列
+func WithClientOptions(opts ...ws.Option) Option {
+	return func(b *OkxStream) {
+		b.clientOpts = append(b.clientOpts, opts...)
+	}
+}
+
+// WithClock 设置底层ws.Client的心跳和重连定时器使用的时钟，默认为系统时钟
+func WithClock(c clock.Clock) Option {
+	return func(b *OkxStream) {
+		b.clock = c
+	}
+}
@@ -0,0 +1,60 @@
+package stream
+
+import "time"
+
+// State 连接状态
+type State int32
+
+const (
+	// StateIdle 尚未连接
+	StateIdle State = iota
+	// StateConnecting 正在建立首次连接
+	StateConnecting
+	// StateConnected 已连接
+	StateConnected
+	// StateReconnecting 连接断开，正在重连
+	StateReconnecting
+	// StateFailed 连接失败且不再重试，需要上层介入
+	StateFailed
+	// StateDisconnected 调用方主动断开
+	StateDisconnected
+)
+
+// String 返回状态名称
+func (s State) String() string {
+	switch s {
+	case StateIdle:
+		return "IDLE"
+	case StateConnecting:
+		return "CONNECTING"
+	case StateConnected:
+		return "CONNECTED"
+	case StateReconnecting:
+		return "RECONNECTING"
+	case StateFailed:
+		return "FAILED"
+	case StateDisconnected:
+		return "DISCONNECTED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// StateEvent 连接状态变化事件
+type StateEvent struct {
+	// ID Stream的ID
+	ID string
+	// State 新状态
+	State State
+	// Attempt 当前重连的尝试次数，仅在重连过程中有效
+	Attempt int
+	// Reconnects 累计成功重连次数
+	Reconnects int64
+	// Err 导致状态变化的错误，可能为空
+	Err error
+	// Time 状态变化时间
+	Time time.Time
+}
+
+// StateHandler 连接状态变化回调
+type StateHandler func(event StateEvent)
@@ -0,0 +1,331 @@
+package vision
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/types"
+)
+
+// DataType 历史数据文件类型
+type DataType int
+
+const (
+	// DataTypeUnknown 未知类型
+	DataTypeUnknown DataType = iota
+	// DataTypeTrades 逐笔成交 trades
+	DataTypeTrades
+	// DataTypeAggTrades 归集成交 aggTrades
+	DataTypeAggTrades
+	// DataTypeKlines K线 klines
+	DataTypeKlines
+	// DataTypeBookTicker 最优盘口 bookTicker，仅合约提供
+	DataTypeBookTicker
+)
+
+// String 返回文件名中使用的类型名称
+func (d DataType) String() string {
+	switch d {
+	case DataTypeTrades:
+		return "trades"
+	case DataTypeAggTrades:
+		return "aggTrades"
+	case DataTypeKlines:
+		return "klines"
+	case DataTypeBookTicker:
+		return "bookTicker"
+	default:
+		return "unknown"
+	}
+}
+
+// StreamType 返回该类型数据对应的数据流类型
+func (d DataType) StreamType() types.StreamType {
+	switch d {
+	case DataTypeTrades, DataTypeAggTrades:
+		return types.StreamTypeTrade
+	case DataTypeKlines:
+		return types.StreamTypeKline
+	case DataTypeBookTicker:
+		return types.StreamTypeBookTicker
+	default:
+		return types.StreamTypeUnknown
+	}
+}
+
+// FileInfo 从文件名解析出的信息
+type FileInfo struct {
+	// Path 文件路径
+	Path string
+	// Symbol 交易对，例如BTCUSDT
+	Symbol string
+	// DataType 数据类型
+	DataType DataType
+	// Interval K线周期，例如1m，仅K线文件有值
+	Interval string
+	// Date 文件日期，日文件为2006-01-02，月文件为2006-01
+	Date string
+}
+
+// fileNameRegexp 文件名格式，例如：
+// BTCUSDT-trades-2024-01-01.zip、BTCUSDT-aggTrades-2024-01.zip、BTCUSDT-1m-2024-01-01.csv、BTCUSDT-1mo-2024-01.zip、
+// BTCUSDT-bookTicker-2024-01-01.zip
+var fileNameRegexp = regexp.MustCompile(`^([A-Z0-9]+)-(trades|aggTrades|bookTicker|\d+(?:mo|[smhdwM]))-(\d{4}-\d{2}(?:-\d{2})?)\.(zip|csv)$`)
+
+// ParseFileName 解析data.binance.vision的文件名
+func ParseFileName(path string) (FileInfo, error) {
+	match := fileNameRegexp.FindStringSubmatch(filepath.Base(path))
+	if match == nil {
+		return FileInfo{}, fmt.Errorf("invalid file name: %s", filepath.Base(path))
+	}
+
+	info := FileInfo{Path: path, Symbol: match[1], Date: match[3]}
+	switch match[2] {
+	case "trades":
+		info.DataType = DataTypeTrades
+	case "aggTrades":
+		info.DataType = DataTypeAggTrades
+	case "bookTicker":
+		info.DataType = DataTypeBookTicker
+	default:
+		info.DataType = DataTypeKlines
+		info.Interval = match[2]
+	}
+	return info, nil
+}
+
+// rowReader 逐行读取zip或csv文件，zip文件读取其中第一个csv文件
+type rowReader struct {
+	closers []io.Closer
+	reader  *csv.Reader
+	// first 预读的第一行，用于判断文件是否带有表头
+	first []string
+}
+
+func newRowReader(path string) (*rowReader, error) {
+	r := &rowReader{}
+
+	var src io.Reader
+	if strings.HasSuffix(strings.ToLower(path), ".zip") {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, fmt.Errorf("open zip error: %w", err)
+		}
+		r.closers = append(r.closers, zr)
+
+		var entry *zip.File
+		for _, f := range zr.File {
+			if strings.HasSuffix(strings.ToLower(f.Name), ".csv") {
+				entry = f
+				break
+			}
+		}
+		if entry == nil {
+			r.Close()
+			return nil, errors.New("no csv file in zip")
+		}
+		rc, err := entry.Open()
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("open zip entry error: %w", err)
+		}
+		r.closers = append(r.closers, rc)
+		src = rc
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		r.closers = append(r.closers, file)
+		src = file
+	}
+
+	r.reader = csv.NewReader(bufio.NewReaderSize(src, 64*1024))
+	r.reader.FieldsPerRecord = -1
+	r.reader.ReuseRecord = true
+
+	// 合约文件带有表头，现货文件没有，第一列不是数字时视为表头跳过
+	record, err := r.reader.Read()
+	if err != nil && err != io.EOF {
+		r.Close()
+		return nil, fmt.Errorf("read record error: %w", err)
+	}
+	if err == nil {
+		if _, perr := strconv.ParseInt(record[0], 10, 64); perr == nil {
+			r.first = append([]string(nil), record...)
+		}
+	}
+	return r, nil
+}
+
+// Next 读取下一行，读取完毕时返回io.EOF
+func (r *rowReader) Next() ([]string, error) {
+	if r.first != nil {
+		record := r.first
+		r.first = nil
+		return record, nil
+	}
+	record, err := r.reader.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read record error: %w", err)
+	}
+	return record, nil
+}
+
+// Close 关闭文件
+func (r *rowReader) Close() error {
+	for i := len(r.closers) - 1; i >= 0; i-- {
+		r.closers[i].Close()
+	}
+	r.closers = nil
+	return nil
+}
+
+// normalizeTime 统一为毫秒时间戳，现货数据自2025年起使用微秒时间戳
+func normalizeTime(ts int64) int64 {
+	if ts > 1e15 {
+		return ts / 1000
+	}
+	return ts
+}
+
+// parseTrade 解析trades/aggTrades行
+//
+//	trades:    id,price,qty,quoteQty,time,isBuyerMaker[,isBestMatch]
+//	aggTrades: aggId,price,qty,firstId,lastId,time,isBuyerMaker[,isBestMatch]
+func parseTrade(info FileInfo, market types.MarketType, record []string) (types.TradeEvent, error) {
+	timeIdx, makerIdx := 4, 5
+	if info.DataType == DataTypeAggTrades {
+		timeIdx, makerIdx = 5, 6
+	}
+	if len(record) <= makerIdx {
+		return types.TradeEvent{}, fmt.Errorf("invalid %s record: %v", info.DataType, record)
+	}
+
+	id, err := strconv.ParseUint(record[0], 10, 64)
+	if err != nil {
+		return types.TradeEvent{}, fmt.Errorf("parse trade id error: %w", err)
+	}
+	price, err := decimal.NewFromString(record[1])
+	if err != nil {
+		return types.TradeEvent{}, fmt.Errorf("parse price error: %w", err)
+	}
+	size, err := decimal.NewFromString(record[2])
+	if err != nil {
+		return types.TradeEvent{}, fmt.Errorf("parse size error: %w", err)
+	}
+	ts, err := strconv.ParseInt(record[timeIdx], 10, 64)
+	if err != nil {
+		return types.TradeEvent{}, fmt.Errorf("parse time error: %w", err)
+	}
+
+	// 买方是挂单方时，主动成交方向为卖
+	side := types.SideTypeBuy
+	if strings.EqualFold(record[makerIdx], "true") {
+		side = types.SideTypeSell
+	}
+
+	return types.TradeEvent{
+		Timestamp: normalizeTime(ts),
+		Symbol:    info.Symbol,
+		Exchange:  types.BinanceExchange,
+		TradeID:   id,
+		Size:      size,
+		Price:     price,
+		Side:      side,
+		Type:      market,
+	}, nil
+}
+
+// parseKline 解析klines行
+//
+//	openTime,open,high,low,close,volume,closeTime,quoteVolume,count,takerBuyVolume,takerBuyQuoteVolume,ignore
+func parseKline(info FileInfo, record []string) (broker.KlineEvent, error) {
+	if len(record) < 11 {
+		return broker.KlineEvent{}, fmt.Errorf("invalid klines record: %v", record)
+	}
+
+	var (
+		ints [3]int64
+		decs [8]decimal.Decimal
+		err  error
+	)
+	for i, idx := range []int{0, 6, 8} {
+		if ints[i], err = strconv.ParseInt(record[idx], 10, 64); err != nil {
+			return broker.KlineEvent{}, fmt.Errorf("parse column %d error: %w", idx, err)
+		}
+	}
+	for i, idx := range []int{1, 2, 3, 4, 5, 7, 9, 10} {
+		if decs[i], err = decimal.NewFromString(record[idx]); err != nil {
+			return broker.KlineEvent{}, fmt.Errorf("parse column %d error: %w", idx, err)
+		}
+	}
+
+	return broker.KlineEvent{
+		Symbol:                   info.Symbol,
+		OpenTime:                 normalizeTime(ints[0]),
+		Open:                     decs[0],
+		High:                     decs[1],
+		Low:                      decs[2],
+		Close:                    decs[3],
+		Volume:                   decs[4],
+		CloseTime:                normalizeTime(ints[1]),
+		QuoteAssetVolume:         decs[5],
+		NumberOfTrades:           ints[2],
+		TakerBuyBaseAssetVolume:  decs[6],
+		TakerBuyQuoteAssetVolume: decs[7],
+		// 历史数据中的K线均已完结
+		Confirm: "1",
+	}, nil
+}
+
+// parseBookTicker 解析bookTicker行
+//
+//	update_id,best_bid_price,best_bid_qty,best_ask_price,best_ask_qty,transaction_time,event_time
+func parseBookTicker(info FileInfo, market types.MarketType, record []string) (broker.BookTickerEvent, error) {
+	if len(record) < 6 {
+		return broker.BookTickerEvent{}, fmt.Errorf("invalid bookTicker record: %v", record)
+	}
+
+	updateID, err := strconv.ParseInt(record[0], 10, 64)
+	if err != nil {
+		return broker.BookTickerEvent{}, fmt.Errorf("parse update id error: %w", err)
+	}
+	var decs [4]decimal.Decimal
+	for i := range decs {
+		if decs[i], err = decimal.NewFromString(record[i+1]); err != nil {
+			return broker.BookTickerEvent{}, fmt.Errorf("parse column %d error: %w", i+1, err)
+		}
+	}
+	ts, err := strconv.ParseInt(record[5], 10, 64)
+	if err != nil {
+		return broker.BookTickerEvent{}, fmt.Errorf("parse time error: %w", err)
+	}
+
+	return broker.BookTickerEvent{
+		Timestamp:  normalizeTime(ts),
+		UpdateID:   updateID,
+		Symbol:     info.Symbol,
+		Exchange:   types.BinanceExchange,
+		BidPrice:   decs[0],
+		BidSize:    decs[1],
+		AskPrice:   decs[2],
+		AskSize:    decs[3],
+		MarketType: market,
+	}, nil
+}
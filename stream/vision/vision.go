@@ -0,0 +1,434 @@
+// Package vision 读取已下载到本地的Binance公开历史数据（https://data.binance.vision），
+// 支持trades、aggTrades、klines、bookTicker的zip和csv文件，按时间顺序回放为数据流。
+package vision
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/clock"
+	"github.com/go-gotop/gotop/stream"
+	"github.com/go-gotop/gotop/types"
+)
+
+var _ stream.Stream[*ArchiveRequest] = (*Archive)(nil)
+
+// ArchiveRequest 请求参数
+type ArchiveRequest struct {
+	// Dir 数据目录，递归查找符合命名格式的文件
+	Dir string
+	// Files 指定文件列表，不为空时忽略Dir
+	Files []string
+	// DataType 读取的数据类型，为空时根据数据流类型选择：trade为trades，kline为klines，bookTicker为bookTicker
+	DataType DataType
+	// Market 市场类型，文件中不包含该信息，需要调用方指定
+	Market types.MarketType
+	// Symbols 可选，只读取指定交易对
+	Symbols []string
+	// Interval 可选，只读取指定周期的K线，例如1m
+	Interval string
+	// Start 开始时间（毫秒），为0时不限制
+	Start int64
+	// End 结束时间（毫秒），为0时不限制
+	End int64
+	// Clock 可选，回调每个事件前推进到事件时间
+	Clock clock.Observer
+	// TradeHandler 成交处理函数，trades、aggTrades使用
+	TradeHandler func(trade types.TradeEvent)
+	// KlineHandler K线处理函数
+	KlineHandler func(kline broker.KlineEvent)
+	// BookTickerHandler 最优盘口处理函数
+	BookTickerHandler func(ticker broker.BookTickerEvent)
+	// ErrorHandler 错误处理函数
+	ErrorHandler func(err error)
+	// CloseHandler 关闭处理函数
+	CloseHandler func()
+}
+
+// Archive Binance历史数据文件数据流。每个交易对（K线还区分周期）的文件按日期顺序读取，
+// 多个交易对按时间k路归并，时间相同时按交易对名称顺序
+type Archive struct {
+	id        string
+	st        types.StreamType
+	cancel    context.CancelFunc
+	done      chan struct{}
+	request   *ArchiveRequest
+	eventID   uint64
+	closeOnce sync.Once
+}
+
+// NewArchive 创建历史数据文件数据流
+func NewArchive(id string, st types.StreamType) *Archive {
+	return &Archive{
+		id: id,
+		st: st,
+	}
+}
+
+// ID 返回该Stream的唯一ID
+func (a *Archive) ID() string {
+	return a.id
+}
+
+// Type 返回该Stream的类型
+func (a *Archive) Type() types.StreamType {
+	return a.st
+}
+
+// Connect 开始读取文件
+func (a *Archive) Connect(ctx context.Context, request *ArchiveRequest) error {
+	if request == nil {
+		return errors.New("request cannot be nil")
+	}
+
+	dataType := request.DataType
+	if dataType == DataTypeUnknown {
+		dataType = defaultDataType(a.st)
+	}
+	if dataType.StreamType() != a.st {
+		return fmt.Errorf("data type %s does not match stream type %s", dataType, a.st)
+	}
+	switch dataType {
+	case DataTypeTrades, DataTypeAggTrades:
+		if request.TradeHandler == nil {
+			return errors.New("request.TradeHandler cannot be nil")
+		}
+	case DataTypeKlines:
+		if request.KlineHandler == nil {
+			return errors.New("request.KlineHandler cannot be nil")
+		}
+	case DataTypeBookTicker:
+		if request.BookTickerHandler == nil {
+			return errors.New("request.BookTickerHandler cannot be nil")
+		}
+	}
+
+	files, err := findFiles(request, dataType)
+	if err != nil {
+		return fmt.Errorf("find files error: %w", err)
+	}
+	if len(files) == 0 {
+		return errors.New("no archive files found in the given time range")
+	}
+
+	ctx, a.cancel = context.WithCancel(ctx)
+	a.done = make(chan struct{})
+	a.request = request
+
+	go a.run(ctx, files)
+	return nil
+}
+
+// Disconnect 停止读取
+func (a *Archive) Disconnect() error {
+	if a.cancel != nil {
+		a.cancel()
+		<-a.done
+	}
+	a.close()
+	return nil
+}
+
+// run 按时间归并读取所有文件，每个交易对只在内存中保留当前一行
+func (a *Archive) run(ctx context.Context, files []FileInfo) {
+	defer close(a.done)
+
+	cursors := a.cursors(files)
+	err := a.merge(ctx, cursors)
+	for _, c := range cursors {
+		c.close()
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		if a.request.ErrorHandler != nil {
+			a.request.ErrorHandler(err)
+		}
+		return
+	}
+	a.close()
+}
+
+// cursors 按交易对和K线周期分组，files已按日期、交易对排序
+func (a *Archive) cursors(files []FileInfo) []*archiveCursor {
+	var cursors []*archiveCursor
+	index := make(map[string]*archiveCursor)
+	for _, file := range files {
+		key := file.Symbol + "-" + file.Interval
+		c, ok := index[key]
+		if !ok {
+			c = &archiveCursor{archive: a}
+			index[key] = c
+			cursors = append(cursors, c)
+		}
+		c.files = append(c.files, file)
+	}
+	sort.Slice(cursors, func(i, j int) bool {
+		fi, fj := cursors[i].files[0], cursors[j].files[0]
+		if fi.Symbol != fj.Symbol {
+			return fi.Symbol < fj.Symbol
+		}
+		return fi.Interval < fj.Interval
+	})
+	for i, c := range cursors {
+		c.index = i
+	}
+	return cursors
+}
+
+func (a *Archive) merge(ctx context.Context, cursors []*archiveCursor) error {
+	h := make(archiveHeap, 0, len(cursors))
+	for _, c := range cursors {
+		ok, err := c.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			h = append(h, c)
+		}
+	}
+	heap.Init(&h)
+
+	for h.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		c := h[0]
+		a.emit(c.head)
+
+		ok, err := c.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+	return nil
+}
+
+// emit 回调单个事件
+func (a *Archive) emit(e archiveEvent) {
+	clock.Observe(a.request.Clock, e.ts)
+	switch e.dataType {
+	case DataTypeTrades, DataTypeAggTrades:
+		e.trade.ID = a.eventID
+		a.eventID++
+		a.request.TradeHandler(e.trade)
+	case DataTypeKlines:
+		a.request.KlineHandler(e.kline)
+	case DataTypeBookTicker:
+		a.request.BookTickerHandler(e.ticker)
+	}
+}
+
+// parse 解析一行记录
+func (a *Archive) parse(file FileInfo, record []string) (archiveEvent, error) {
+	e := archiveEvent{dataType: file.DataType}
+	var err error
+	switch file.DataType {
+	case DataTypeTrades, DataTypeAggTrades:
+		e.trade, err = parseTrade(file, a.request.Market, record)
+		e.ts = e.trade.Timestamp
+	case DataTypeKlines:
+		e.kline, err = parseKline(file, record)
+		e.ts = e.kline.OpenTime
+	case DataTypeBookTicker:
+		e.ticker, err = parseBookTicker(file, a.request.Market, record)
+		e.ts = e.ticker.Timestamp
+	}
+	return e, err
+}
+
+func (a *Archive) inRange(ts int64) bool {
+	return (a.request.Start == 0 || ts >= a.request.Start) && (a.request.End == 0 || ts <= a.request.End)
+}
+
+func (a *Archive) close() {
+	a.closeOnce.Do(func() {
+		if a.request != nil && a.request.CloseHandler != nil {
+			a.request.CloseHandler()
+		}
+	})
+}
+
+// archiveEvent 解析后的一行记录
+type archiveEvent struct {
+	dataType DataType
+	ts       int64
+	trade    types.TradeEvent
+	kline    broker.KlineEvent
+	ticker   broker.BookTickerEvent
+}
+
+// archiveCursor 单个交易对的读取位置
+type archiveCursor struct {
+	archive *Archive
+	index   int
+	files   []FileInfo
+	// file、reader 当前正在读取的文件
+	file   FileInfo
+	reader *rowReader
+	// head 下一个待发送的事件
+	head archiveEvent
+}
+
+// next 读取下一个时间范围内的事件到head，文件读取完毕时返回false
+func (c *archiveCursor) next() (bool, error) {
+	for {
+		if c.reader == nil {
+			if len(c.files) == 0 {
+				return false, nil
+			}
+			c.file = c.files[0]
+			c.files = c.files[1:]
+			r, err := newRowReader(c.file.Path)
+			if err != nil {
+				return false, fmt.Errorf("process file %s error: %w", c.file.Path, err)
+			}
+			c.reader = r
+		}
+
+		record, err := c.reader.Next()
+		if err == io.EOF {
+			c.close()
+			continue
+		}
+		if err == nil {
+			c.head, err = c.archive.parse(c.file, record)
+		}
+		if err != nil {
+			return false, fmt.Errorf("process file %s error: %w", c.file.Path, err)
+		}
+		if c.archive.inRange(c.head.ts) {
+			return true, nil
+		}
+	}
+}
+
+func (c *archiveCursor) close() {
+	if c.reader != nil {
+		c.reader.Close()
+		c.reader = nil
+	}
+}
+
+// archiveHeap 按下一个事件的时间排序，时间相同时按交易对顺序
+type archiveHeap []*archiveCursor
+
+func (h archiveHeap) Len() int { return len(h) }
+
+func (h archiveHeap) Less(i, j int) bool {
+	if h[i].head.ts != h[j].head.ts {
+		return h[i].head.ts < h[j].head.ts
+	}
+	return h[i].index < h[j].index
+}
+
+func (h archiveHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *archiveHeap) Push(x any) { *h = append(*h, x.(*archiveCursor)) }
+
+func (h *archiveHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// defaultDataType 返回数据流类型默认读取的数据类型
+func defaultDataType(st types.StreamType) DataType {
+	switch st {
+	case types.StreamTypeTrade:
+		return DataTypeTrades
+	case types.StreamTypeKline:
+		return DataTypeKlines
+	case types.StreamTypeBookTicker:
+		return DataTypeBookTicker
+	default:
+		return DataTypeUnknown
+	}
+}
+
+// findFiles 查找符合条件的文件并按日期、交易对排序
+func findFiles(request *ArchiveRequest, dataType DataType) ([]FileInfo, error) {
+	paths := request.Files
+	if len(paths) == 0 {
+		err := filepath.Walk(request.Dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("walk directory error: %w", err)
+		}
+	}
+
+	symbols := make(map[string]bool, len(request.Symbols))
+	for _, s := range request.Symbols {
+		symbols[s] = true
+	}
+
+	var files []FileInfo
+	for _, path := range paths {
+		info, err := ParseFileName(path)
+		if err != nil {
+			if len(request.Files) > 0 {
+				return nil, err
+			}
+			continue
+		}
+		if info.DataType != dataType {
+			continue
+		}
+		if len(symbols) > 0 && !symbols[info.Symbol] {
+			continue
+		}
+		if request.Interval != "" && info.Interval != request.Interval {
+			continue
+		}
+		if !overlaps(info.Date, request.Start, request.End) {
+			continue
+		}
+		files = append(files, info)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Date != files[j].Date {
+			return files[i].Date < files[j].Date
+		}
+		return files[i].Symbol < files[j].Symbol
+	})
+	return files, nil
+}
+
+// overlaps 判断文件日期覆盖的时间段是否与[start, end]有交集
+func overlaps(date string, start, end int64) bool {
+	var from, to time.Time
+	if t, err := time.Parse("2006-01-02", date); err == nil {
+		from, to = t, t.AddDate(0, 0, 1)
+	} else if t, err := time.Parse("2006-01", date); err == nil {
+		from, to = t, t.AddDate(0, 1, 0)
+	} else {
+		return true
+	}
+	return (start == 0 || to.UnixMilli() > start) && (end == 0 || from.UnixMilli() <= end)
+}
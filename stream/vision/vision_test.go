@@ -0,0 +1,200 @@
+package vision
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/broker"
+	"github.com/go-gotop/gotop/types"
+)
+
+func writeZip(t *testing.T, path, name, content string) {
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	zw := zip.NewWriter(f)
+	w, err := zw.Create(name)
+	require.NoError(t, err)
+	_, err = w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	require.NoError(t, f.Close())
+}
+
+// collect 启动数据流并等待读取完毕
+func collect(t *testing.T, st types.StreamType, request *ArchiveRequest) {
+	done := make(chan struct{})
+	request.CloseHandler = func() { close(done) }
+	request.ErrorHandler = func(err error) { t.Error(err) }
+
+	a := NewArchive("test", st)
+	require.NoError(t, a.Connect(context.Background(), request))
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for archive")
+	}
+	require.NoError(t, a.Disconnect())
+}
+
+func TestParseFileName(t *testing.T) {
+	info, err := ParseFileName("/data/BTCUSDT-trades-2024-01-02.zip")
+	require.NoError(t, err)
+	assert.Equal(t, FileInfo{Path: "/data/BTCUSDT-trades-2024-01-02.zip", Symbol: "BTCUSDT", DataType: DataTypeTrades, Date: "2024-01-02"}, info)
+
+	info, err = ParseFileName("ETHUSDT-1m-2024-01.csv")
+	require.NoError(t, err)
+	assert.Equal(t, DataTypeKlines, info.DataType)
+	assert.Equal(t, "1m", info.Interval)
+	assert.Equal(t, "2024-01", info.Date)
+
+	// 月K线周期为1mo
+	info, err = ParseFileName("BTCUSDT-1mo-2024-01.zip")
+	require.NoError(t, err)
+	assert.Equal(t, FileInfo{Path: "BTCUSDT-1mo-2024-01.zip", Symbol: "BTCUSDT", DataType: DataTypeKlines, Interval: "1mo", Date: "2024-01"}, info)
+
+	info, err = ParseFileName("BTCUSDT-aggTrades-2024-01-01.zip")
+	require.NoError(t, err)
+	assert.Equal(t, DataTypeAggTrades, info.DataType)
+
+	_, err = ParseFileName("BTCUSDT-trades-2024-01-01.zip.CHECKSUM")
+	assert.Error(t, err)
+}
+
+func TestArchiveTrades(t *testing.T) {
+	dir := t.TempDir()
+	// 现货文件无表头，2025年起为微秒时间戳
+	writeZip(t, filepath.Join(dir, "BTCUSDT-trades-2025-01-02.zip"), "BTCUSDT-trades-2025-01-02.csv",
+		"103,94000.10,0.5,47000.05,1735776000000000,True,True\n"+
+			"104,94000.20,0.1,9400.02,1735776000500000,False,True\n")
+	writeZip(t, filepath.Join(dir, "BTCUSDT-trades-2025-01-01.zip"), "BTCUSDT-trades-2025-01-01.csv",
+		"101,93000.10,1.0,93000.10,1735689600000,true,true\n"+
+			"102,93000.20,2.0,186000.40,1735689601000,false,true\n")
+	// 其它类型和无关文件会被忽略
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "BTCUSDT-aggTrades-2025-01-01.csv"), []byte("1,1,1,1,1,1735689600000,true,true\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "BTCUSDT-trades-2025-01-01.zip.CHECKSUM"), []byte("x"), 0o644))
+
+	var trades []types.TradeEvent
+	collect(t, types.StreamTypeTrade, &ArchiveRequest{
+		Dir:          dir,
+		Market:       types.MarketTypeSpot,
+		Start:        1735689601000,
+		TradeHandler: func(trade types.TradeEvent) { trades = append(trades, trade) },
+	})
+
+	require.Len(t, trades, 3)
+	assert.Equal(t, uint64(102), trades[0].TradeID)
+	assert.Equal(t, types.SideTypeBuy, trades[0].Side)
+	assert.Equal(t, "BTCUSDT", trades[0].Symbol)
+	assert.Equal(t, types.BinanceExchange, trades[0].Exchange)
+	assert.Equal(t, types.MarketTypeSpot, trades[0].Type)
+	assert.Equal(t, uint64(0), trades[0].ID)
+
+	assert.Equal(t, uint64(103), trades[1].TradeID)
+	assert.Equal(t, int64(1735776000000), trades[1].Timestamp)
+	assert.Equal(t, types.SideTypeSell, trades[1].Side)
+	assert.Equal(t, "94000.1", trades[1].Price.String())
+	assert.Equal(t, uint64(2), trades[2].ID)
+}
+
+func TestArchiveMergesSymbols(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "BTCUSDT-trades-2024-01-01.csv"), []byte(
+		"1,1,1,1,1704067200000,true,true\n"+
+			"2,1,1,1,1704067202000,true,true\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "BTCUSDT-trades-2024-01-02.csv"), []byte(
+		"3,1,1,1,1704153600000,true,true\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ETHUSDT-trades-2024-01-01.csv"), []byte(
+		"11,1,1,1,1704067200000,true,true\n"+
+			"12,1,1,1,1704067201000,true,true\n"+
+			"13,1,1,1,1704153600500,true,true\n"), 0o644))
+
+	var trades []types.TradeEvent
+	collect(t, types.StreamTypeTrade, &ArchiveRequest{
+		Dir:          dir,
+		Market:       types.MarketTypeSpot,
+		TradeHandler: func(trade types.TradeEvent) { trades = append(trades, trade) },
+	})
+
+	// 同一时间段的多个交易对按时间交错回放，时间相同时按交易对名称顺序
+	var ids []uint64
+	for i, trade := range trades {
+		ids = append(ids, trade.TradeID)
+		assert.Equal(t, uint64(i), trade.ID)
+	}
+	assert.Equal(t, []uint64{1, 11, 12, 2, 3, 13}, ids)
+}
+
+func TestArchiveAggTradesWithHeader(t *testing.T) {
+	dir := t.TempDir()
+	// 合约文件带有表头
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "BTCUSDT-aggTrades-2024-01-01.csv"), []byte(
+		"agg_trade_id,price,quantity,first_trade_id,last_trade_id,transact_time,is_buyer_maker\n"+
+			"1,42000.1,0.010,10,12,1704067200000,true\n"), 0o644))
+
+	var trades []types.TradeEvent
+	collect(t, types.StreamTypeTrade, &ArchiveRequest{
+		Dir:          dir,
+		DataType:     DataTypeAggTrades,
+		Market:       types.MarketTypeFuturesUSDMargined,
+		TradeHandler: func(trade types.TradeEvent) { trades = append(trades, trade) },
+	})
+
+	require.Len(t, trades, 1)
+	assert.Equal(t, uint64(1), trades[0].TradeID)
+	assert.Equal(t, int64(1704067200000), trades[0].Timestamp)
+	assert.Equal(t, types.SideTypeSell, trades[0].Side)
+}
+
+func TestArchiveKlinesAndBookTicker(t *testing.T) {
+	dir := t.TempDir()
+	writeZip(t, filepath.Join(dir, "BTCUSDT-1m-2024-01-01.zip"), "BTCUSDT-1m-2024-01-01.csv",
+		"1704067200000,42283.58,42298.62,42261.02,42298.61,35.92,1704067259999,1518993.05,1327,24.95,1055236.11,0\n")
+	writeZip(t, filepath.Join(dir, "BTCUSDT-5m-2024-01-01.zip"), "BTCUSDT-5m-2024-01-01.csv",
+		"1704067200000,1,1,1,1,1,1704067499999,1,1,1,1,0\n")
+
+	var klines []broker.KlineEvent
+	collect(t, types.StreamTypeKline, &ArchiveRequest{
+		Dir:          dir,
+		Interval:     "1m",
+		KlineHandler: func(kline broker.KlineEvent) { klines = append(klines, kline) },
+	})
+	require.Len(t, klines, 1)
+	assert.Equal(t, int64(1704067259999), klines[0].CloseTime)
+	assert.Equal(t, int64(1327), klines[0].NumberOfTrades)
+	assert.Equal(t, "42298.61", klines[0].Close.String())
+	assert.Equal(t, "1055236.11", klines[0].TakerBuyQuoteAssetVolume.String())
+	assert.Equal(t, "1", klines[0].Confirm)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "BTCUSDT-bookTicker-2024-01-01.csv"), []byte(
+		"update_id,best_bid_price,best_bid_qty,best_ask_price,best_ask_qty,transaction_time,event_time\n"+
+			"3904361573453,42313.90,8.893,42314.00,3.455,1704067200004,1704067200009\n"), 0o644))
+
+	var tickers []broker.BookTickerEvent
+	collect(t, types.StreamTypeBookTicker, &ArchiveRequest{
+		Dir:               dir,
+		Market:            types.MarketTypeFuturesUSDMargined,
+		BookTickerHandler: func(ticker broker.BookTickerEvent) { tickers = append(tickers, ticker) },
+	})
+	require.Len(t, tickers, 1)
+	assert.Equal(t, int64(3904361573453), tickers[0].UpdateID)
+	assert.Equal(t, int64(1704067200004), tickers[0].Timestamp)
+	assert.Equal(t, "3.455", tickers[0].AskSize.String())
+}
+
+func TestArchiveConnectErrors(t *testing.T) {
+	dir := t.TempDir()
+	a := NewArchive("test", types.StreamTypeTrade)
+
+	require.Error(t, a.Connect(context.Background(), nil))
+	require.Error(t, a.Connect(context.Background(), &ArchiveRequest{Dir: dir}))
+	require.Error(t, a.Connect(context.Background(), &ArchiveRequest{Dir: dir, DataType: DataTypeKlines, KlineHandler: func(broker.KlineEvent) {}}))
+	// 没有符合条件的文件
+	require.Error(t, a.Connect(context.Background(), &ArchiveRequest{Dir: dir, TradeHandler: func(types.TradeEvent) {}}))
+}
@@ -0,0 +1,64 @@
+package ws
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Conn 对单个WebSocket连接的封装，串行化写操作并统一设置写超时
+type Conn struct {
+	conn      *websocket.Conn
+	writeMu   sync.Mutex
+	writeWait time.Duration
+	pongWait  time.Duration
+}
+
+func newConn(conn *websocket.Conn, writeWait, pongWait time.Duration) *Conn {
+	c := &Conn{
+		conn:      conn,
+		writeWait: writeWait,
+		pongWait:  pongWait,
+	}
+	c.ExtendReadDeadline()
+	// 收到pong控制帧时延长读超时
+	conn.SetPongHandler(func(string) error {
+		c.ExtendReadDeadline()
+		return nil
+	})
+	return c
+}
+
+// WriteMessage 发送消息，可在多个goroutine中并发调用
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(c.writeWait))
+	return c.conn.WriteMessage(messageType, data)
+}
+
+// WriteJSON 将v序列化为JSON后以文本消息发送
+func (c *Conn) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(websocket.TextMessage, data)
+}
+
+// ExtendReadDeadline 延长读超时，使用应用层心跳时在收到pong后调用
+func (c *Conn) ExtendReadDeadline() {
+	c.conn.SetReadDeadline(time.Now().Add(c.pongWait))
+}
+
+// Raw 返回底层连接，写操作请使用WriteMessage以避免并发写
+func (c *Conn) Raw() *websocket.Conn {
+	return c.conn
+}
+
+// Close 关闭连接
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
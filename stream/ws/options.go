@@ -0,0 +1,137 @@
+package ws
+
+import (
+	"time"
+
+	"github.com/go-gotop/gotop/clock"
+	"github.com/go-gotop/gotop/stream"
+)
+
+type options struct {
+	// dialer 建立连接的函数
+	dialer DialFunc
+	// 心跳与超时配置
+	pingInterval time.Duration
+	pongWait     time.Duration
+	writeWait    time.Duration
+	// reconnectInterval 定时轮换连接的间隔
+	reconnectInterval time.Duration
+	// seamlessOverlap 无缝重连时新旧连接并行的时长，为0时定时重连采用先断后连
+	seamlessOverlap time.Duration
+	// backoff 重连退避策略
+	backoff stream.Backoff
+	// stateHandler 连接状态变化回调
+	stateHandler stream.StateHandler
+	// queueSize 读循环与Handler之间的队列容量，0表示不使用队列
+	queueSize int
+	// overflow 队列已满时的处理策略
+	overflow OverflowPolicy
+	// conflateKey OverflowConflate策略使用的合并键
+	conflateKey KeyFunc
+	// clock 心跳、定时重连与退避等待使用的时钟
+	clock clock.Clock
+}
+
+func applyOptions(opts ...Option) *options {
+	o := &options{
+		dialer:            DefaultDialer,
+		pingInterval:      30 * time.Second,
+		pongWait:          60 * time.Second,
+		writeWait:         5 * time.Second,
+		reconnectInterval: 23 * time.Hour,
+		backoff:           stream.DefaultBackoff(),
+		clock:             clock.Real(),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Option 是Client的配置选项
+type Option func(o *options)
+
+// WithPingInterval 设置Ping间隔
+func WithPingInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.pingInterval = d
+	}
+}
+
+// WithPongWait 设置Pong等待时间
+func WithPongWait(d time.Duration) Option {
+	return func(o *options) {
+		o.pongWait = d
+	}
+}
+
+// WithWriteWait 设置写等待时间
+func WithWriteWait(d time.Duration) Option {
+	return func(o *options) {
+		o.writeWait = d
+	}
+}
+
+// WithReconnectInterval 设置定时轮换连接的间隔
+func WithReconnectInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.reconnectInterval = d
+	}
+}
+
+// WithDialer 设置自定义的dial函数
+func WithDialer(dialer DialFunc) Option {
+	return func(o *options) {
+		if dialer != nil {
+			o.dialer = dialer
+		}
+	}
+}
+
+// WithSeamlessReconnect 开启无缝重连，定时重连时先建立新连接，新旧连接并行overlap时长并去重后再断开旧连接
+func WithSeamlessReconnect(overlap time.Duration) Option {
+	return func(o *options) {
+		o.seamlessOverlap = overlap
+	}
+}
+
+// WithBackoff 设置重连退避策略，默认为 stream.DefaultBackoff()（指数退避、无限重连）
+func WithBackoff(backoff stream.Backoff) Option {
+	return func(o *options) {
+		if backoff != nil {
+			o.backoff = backoff
+		}
+	}
+}
+
+// WithStateHandler 设置连接状态变化回调，回调在Client内部goroutine中同步执行，不应阻塞
+func WithStateHandler(handler stream.StateHandler) Option {
+	return func(o *options) {
+		o.stateHandler = handler
+	}
+}
+
+// WithQueue 在读循环与Handler之间加入容量为size的有界队列，Handler改为在独立goroutine中调用
+func WithQueue(size int, policy OverflowPolicy) Option {
+	return func(o *options) {
+		o.queueSize = size
+		o.overflow = policy
+	}
+}
+
+// WithConflateKey 设置OverflowConflate策略的合并键，例如按交易对合并行情快照
+func WithConflateKey(fn KeyFunc) Option {
+	return func(o *options) {
+		o.conflateKey = fn
+	}
+}
+
+// WithClock 设置心跳、定时重连、退避等待和状态事件时间使用的时钟，默认为系统时钟。
+// 读写超时仍使用系统时间
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		if c != nil {
+			o.clock = c
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package ws
+
+import (
+	"sync"
+)
+
+// OverflowPolicy 队列已满时的处理策略
+type OverflowPolicy int
+
+const (
+	// OverflowBlock 阻塞读取，直到Handler消费出空位（背压传导到连接，可能触发pong超时）
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest 丢弃队列中最旧的消息
+	OverflowDropOldest
+	// OverflowDropNewest 丢弃新到达的消息
+	OverflowDropNewest
+	// OverflowConflate 按键合并，同一键只保留最新一条消息（如行情快照），队列已满且为新键时丢弃最旧的消息
+	OverflowConflate
+)
+
+// String 返回策略名称
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowBlock:
+		return "BLOCK"
+	case OverflowDropOldest:
+		return "DROP_OLDEST"
+	case OverflowDropNewest:
+		return "DROP_NEWEST"
+	case OverflowConflate:
+		return "CONFLATE"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// KeyFunc 从消息中提取合并键，返回空字符串表示该消息不参与合并
+type KeyFunc func(msg []byte) string
+
+// QueueStats 队列统计
+type QueueStats struct {
+	// Depth 当前排队的消息数量
+	Depth int
+	// Capacity 队列容量，0表示未启用队列
+	Capacity int
+	// Dropped 因队列已满被丢弃的消息数量
+	Dropped int64
+	// Conflated 被同键新消息覆盖的消息数量
+	Conflated int64
+}
+
+// entry 队列中的消息
+type entry struct {
+	key string
+	msg []byte
+}
+
+// queue 读循环与Handler之间的有界队列
+type queue struct {
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+
+	items    []*entry
+	keys     map[string]*entry
+	capacity int
+	policy   OverflowPolicy
+	keyFunc  KeyFunc
+	closed   bool
+
+	dropped   int64
+	conflated int64
+}
+
+func newQueue(capacity int, policy OverflowPolicy, keyFunc KeyFunc) *queue {
+	q := &queue{
+		capacity: capacity,
+		policy:   policy,
+		keyFunc:  keyFunc,
+		keys:     make(map[string]*entry),
+	}
+	q.notEmpty = sync.NewCond(&q.mu)
+	q.notFull = sync.NewCond(&q.mu)
+	return q
+}
+
+// push 放入一条消息，按策略处理队列已满的情况，队列关闭后直接丢弃
+func (q *queue) push(msg []byte) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return
+	}
+
+	var key string
+	if q.policy == OverflowConflate && q.keyFunc != nil {
+		key = q.keyFunc(msg)
+		if e, ok := q.keys[key]; ok && key != "" {
+			// 覆盖排队中的同键消息，保持其在队列中的位置
+			e.msg = msg
+			q.conflated++
+			return
+		}
+	}
+
+	for len(q.items) >= q.capacity {
+		switch q.policy {
+		case OverflowBlock:
+			q.notFull.Wait()
+			if q.closed {
+				return
+			}
+			continue
+		case OverflowDropNewest:
+			q.dropped++
+			return
+		default:
+			q.removeFront()
+			q.dropped++
+		}
+	}
+
+	e := &entry{key: key, msg: msg}
+	q.items = append(q.items, e)
+	if key != "" {
+		q.keys[key] = e
+	}
+	q.notEmpty.Signal()
+}
+
+// pop 取出一条消息，队列为空时阻塞，队列关闭后返回false
+func (q *queue) pop() ([]byte, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.items) == 0 && !q.closed {
+		q.notEmpty.Wait()
+	}
+	if q.closed {
+		return nil, false
+	}
+	e := q.removeFront()
+	q.notFull.Signal()
+	return e.msg, true
+}
+
+// removeFront 移除队首消息，调用方需持有锁
+func (q *queue) removeFront() *entry {
+	e := q.items[0]
+	q.items[0] = nil
+	q.items = q.items[1:]
+	if e.key != "" && q.keys[e.key] == e {
+		delete(q.keys, e.key)
+	}
+	return e
+}
+
+// close 关闭队列，丢弃未消费的消息并唤醒所有等待者
+func (q *queue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.items = nil
+	q.keys = make(map[string]*entry)
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+}
+
+// stats 返回队列统计
+func (q *queue) stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return QueueStats{
+		Depth:     len(q.items),
+		Capacity:  q.capacity,
+		Dropped:   q.dropped,
+		Conflated: q.conflated,
+	}
+}
@@ -0,0 +1,100 @@
+package ws
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// drain 取出队列中当前所有消息
+func drain(q *queue) []string {
+	var out []string
+	for q.stats().Depth > 0 {
+		msg, _ := q.pop()
+		out = append(out, string(msg))
+	}
+	return out
+}
+
+func TestQueueOverflowPolicies(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    OverflowPolicy
+		keyFunc   KeyFunc
+		input     []string
+		expected  []string
+		dropped   int64
+		conflated int64
+	}{
+		{
+			name:     "drop oldest",
+			policy:   OverflowDropOldest,
+			input:    []string{"1", "2", "3", "4", "5"},
+			expected: []string{"3", "4", "5"},
+			dropped:  2,
+		},
+		{
+			name:     "drop newest",
+			policy:   OverflowDropNewest,
+			input:    []string{"1", "2", "3", "4", "5"},
+			expected: []string{"1", "2", "3"},
+			dropped:  2,
+		},
+		{
+			name:   "conflate latest per key",
+			policy: OverflowConflate,
+			keyFunc: func(msg []byte) string {
+				return strings.SplitN(string(msg), ":", 2)[0]
+			},
+			input:     []string{"btc:1", "eth:1", "btc:2", "btc:3", "eth:2", "bnb:1", "sol:1"},
+			expected:  []string{"eth:2", "bnb:1", "sol:1"},
+			dropped:   1,
+			conflated: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newQueue(3, tt.policy, tt.keyFunc)
+			for _, msg := range tt.input {
+				q.push([]byte(msg))
+			}
+			stats := q.stats()
+			require.Equal(t, tt.dropped, stats.Dropped)
+			require.Equal(t, tt.conflated, stats.Conflated)
+			require.Equal(t, tt.expected, drain(q))
+		})
+	}
+}
+
+func TestQueueBlock(t *testing.T) {
+	q := newQueue(1, OverflowBlock, nil)
+	q.push([]byte("1"))
+
+	pushed := make(chan struct{})
+	go func() {
+		q.push([]byte("2"))
+		close(pushed)
+	}()
+
+	select {
+	case <-pushed:
+		t.Fatal("push should block while queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	msg, ok := q.pop()
+	require.True(t, ok)
+	require.Equal(t, "1", string(msg))
+	<-pushed
+	require.Equal(t, []string{"2"}, drain(q))
+
+	// 关闭队列会唤醒阻塞的push和pop
+	q.push([]byte("3"))
+	go q.push([]byte("4"))
+	q.close()
+	_, ok = q.pop()
+	require.False(t, ok)
+}
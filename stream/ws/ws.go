@@ -0,0 +1,684 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/go-gotop/gotop/stream"
+)
+
+// maxPendingMessages 无缝重连或回补期间最多暂存的消息数量
+const maxPendingMessages = 100000
+
+// Config 连接参数
+type Config struct {
+	// URL WebSocket地址，Hooks.URL不为空时以其返回值为准
+	URL string
+	// Logger 可选的日志记录器，用于调试
+	Logger *slog.Logger
+	// Handler 数据处理函数
+	Handler func(data []byte)
+	// ErrorHandler 错误处理函数
+	ErrorHandler func(err error)
+	// SequenceFunc 可选，从消息中提取去重键和序列号（如成交ID），用于重连时去重
+	SequenceFunc stream.SequenceFunc
+	// BackfillFunc 可选，非无缝重连成功后回补断线期间缺失的数据。
+	// last 为各键已投递的最大序列号，返回的消息格式需与推送消息一致，
+	// 回补期间实时消息会被暂存，回补消息与暂存消息去重后按顺序投递。
+	BackfillFunc func(ctx context.Context, last map[string]int64) ([][]byte, error)
+}
+
+// Hooks 交易所适配器的扩展点，均为可选
+type Hooks struct {
+	// URL 返回本次建立连接使用的地址，例如Binance组合流把订阅拼接到地址中
+	URL func() string
+	// Auth 连接建立后首先调用，用于登录等鉴权操作
+	Auth func(conn *Conn) error
+	// OnConnect 鉴权之后调用，用于发送订阅请求，重连及无缝重连的新连接都会调用
+	OnConnect func(conn *Conn) error
+	// Ping 发送应用层心跳，例如OKX的文本"ping"，为空时发送WebSocket ping控制帧
+	Ping func(conn *Conn) error
+	// Filter 过滤消息，返回false的消息不投递给Handler（如订阅响应、pong），返回的错误会通知ErrorHandler
+	Filter func(conn *Conn, msg []byte) (bool, error)
+}
+
+// DialFunc 建立WebSocket连接的函数，可在测试中mock
+type DialFunc func(urlStr string, requestHeader http.Header) (*websocket.Conn, *http.Response, error)
+
+// DefaultDialer 默认的dial函数
+func DefaultDialer(urlStr string, requestHeader http.Header) (*websocket.Conn, *http.Response, error) {
+	var d websocket.Dialer
+	return d.Dial(urlStr, requestHeader)
+}
+
+// Client 通用的WebSocket客户端，负责建立连接、心跳、读取、定时轮换、断线重连、去重与回补，
+// 交易所相关的差异通过Hooks注入。启用队列后Handler在独立的goroutine中调用，慢消费者不会阻塞读取。
+type Client struct {
+	mu     sync.Mutex
+	id     string
+	hooks  Hooks
+	opts   *options
+	cfg    Config
+	conn   *Conn
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// parent 整个Client生命周期的上下文，重连时基于它创建新的ctx，Disconnect时取消
+	parent       context.Context
+	parentCancel context.CancelFunc
+
+	// 用于等待后台goroutine的结束
+	wg sync.WaitGroup
+
+	// reconnectWG 跟踪attemptReconnect，它自身会等待wg，不能计入wg
+	reconnectWG sync.WaitGroup
+
+	// closed 是否已调用Disconnect，之后不再启动重连
+	closed bool
+
+	// dedup 重连期间的数据去重
+	dedup *stream.Deduplicator
+
+	// candidate 无缝重连期间新建立的连接，切换前其消息暂存在pending中
+	candidate *Conn
+
+	// pending 暂存的消息（无缝重连新连接上的消息，或回补期间的实时消息）
+	pending [][]byte
+
+	// backfilling 是否正在回补数据
+	backfilling bool
+
+	// deliverMu 保证消息按顺序投递给Handler
+	deliverMu sync.Mutex
+
+	// 是否正在尝试重连
+	reconnecting bool
+
+	// state 当前连接状态
+	state atomic.Int32
+
+	// reconnects 累计成功重连次数
+	reconnects atomic.Int64
+
+	// queue 读循环与Handler之间的有界队列，未启用时为nil，Handler在读循环中同步调用
+	queue *queue
+
+	// consumerDone 队列消费goroutine退出的信号
+	consumerDone chan struct{}
+}
+
+// NewClient 创建一个新的Client，id用于状态事件
+func NewClient(id string, hooks Hooks, opts ...Option) *Client {
+	return &Client{
+		id:    id,
+		hooks: hooks,
+		opts:  applyOptions(opts...),
+	}
+}
+
+// Connect 建立连接并启动后台goroutine
+func (c *Client) Connect(ctx context.Context, cfg Config) error {
+	c.setState(stream.StateConnecting, 0, nil)
+	if err := c.start(ctx, cfg); err != nil {
+		c.setState(stream.StateFailed, 0, err)
+		return err
+	}
+	c.setState(stream.StateConnected, 0, nil)
+	c.log("Connected successfully")
+	return nil
+}
+
+// start 建立首次连接并启动后台goroutine
+func (c *Client) start(ctx context.Context, cfg Config) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cfg = cfg
+	c.closed = false
+	c.parent, c.parentCancel = context.WithCancel(ctx)
+	c.ctx, c.cancel = context.WithCancel(c.parent)
+	c.reconnecting = false
+	c.dedup = stream.NewDeduplicator(cfg.SequenceFunc)
+
+	conn, err := c.dial()
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+
+	// 队列消费者跨越重连，只在Disconnect时退出
+	if c.opts.queueSize > 0 {
+		c.queue = newQueue(c.opts.queueSize, c.opts.overflow, c.opts.conflateKey)
+		c.consumerDone = make(chan struct{})
+		go c.consume(c.queue, c.consumerDone)
+	}
+
+	// 启动后台goroutine
+	c.startGoroutines(conn)
+	return nil
+}
+
+// consume 从队列中取出消息并调用Handler，直到队列关闭
+func (c *Client) consume(q *queue, done chan struct{}) {
+	defer close(done)
+	for {
+		msg, ok := q.pop()
+		if !ok {
+			return
+		}
+		if c.cfg.Handler != nil {
+			c.cfg.Handler(msg)
+		}
+	}
+}
+
+// Disconnect 断开连接并等待后台goroutine退出
+func (c *Client) Disconnect() error {
+	c.mu.Lock()
+	c.closed = true
+	// 取消当前上下文，同时终止正在进行的重连
+	if c.cancel != nil {
+		c.cancel()
+	}
+	if c.parentCancel != nil {
+		c.parentCancel()
+	}
+	// 关闭当前连接
+	if c.conn != nil {
+		_ = c.conn.Close()
+		c.conn = nil
+	}
+	if c.candidate != nil {
+		_ = c.candidate.Close()
+		c.candidate = nil
+	}
+	c.pending = nil
+	q, done := c.queue, c.consumerDone
+	c.queue, c.consumerDone = nil, nil
+	c.mu.Unlock()
+
+	// 先关闭队列，唤醒阻塞在队列上的读循环
+	if q != nil {
+		q.close()
+	}
+
+	// 先等待重连结束，重连成功时启动的goroutine计入wg后再等待wg
+	c.reconnectWG.Wait()
+	c.wg.Wait()
+	if done != nil {
+		<-done
+	}
+	c.setState(stream.StateDisconnected, 0, nil)
+	c.log("Disconnected")
+	return nil
+}
+
+// Conn 返回当前的主连接，未连接或正在重连时返回nil
+func (c *Client) Conn() *Conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn
+}
+
+// State 返回当前连接状态
+func (c *Client) State() stream.State {
+	return stream.State(c.state.Load())
+}
+
+// Reconnects 返回累计成功重连次数
+func (c *Client) Reconnects() int64 {
+	return c.reconnects.Load()
+}
+
+// QueueStats 返回队列深度与丢弃计数，未启用队列时返回零值
+func (c *Client) QueueStats() QueueStats {
+	c.mu.Lock()
+	q := c.queue
+	c.mu.Unlock()
+
+	if q == nil {
+		return QueueStats{}
+	}
+	return q.stats()
+}
+
+// dial 建立一个新连接，依次执行Auth和OnConnect
+func (c *Client) dial() (*Conn, error) {
+	url := c.cfg.URL
+	if c.hooks.URL != nil {
+		url = c.hooks.URL()
+	}
+
+	raw, _, err := c.opts.dialer(url, nil)
+	if err != nil {
+		c.handleErr(err)
+		return nil, err
+	}
+	conn := newConn(raw, c.opts.writeWait, c.opts.pongWait)
+
+	for _, hook := range []func(*Conn) error{c.hooks.Auth, c.hooks.OnConnect} {
+		if hook == nil {
+			continue
+		}
+		if err := hook(conn); err != nil {
+			c.handleErr(err)
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// startGoroutines 启动后台goroutine
+func (c *Client) startGoroutines(conn *Conn) {
+	// 开始readLoop
+	c.wg.Add(1)
+	go c.readLoop(conn)
+
+	// 开始pingLoop
+	c.wg.Add(1)
+	go c.pingLoop()
+
+	// 开始autoReconnectLoop
+	c.wg.Add(1)
+	go c.autoReconnectLoop()
+}
+
+// readLoop 读取指定连接的数据，无缝重连期间新旧连接各有一个readLoop
+func (c *Client) readLoop(conn *Conn) {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		default:
+			_, msg, err := conn.conn.ReadMessage()
+			if err != nil {
+				c.mu.Lock()
+				primary := c.conn == conn
+				if c.candidate == conn {
+					// 无缝重连的新连接失败，放弃本次切换
+					c.candidate = nil
+					c.pending = nil
+				}
+				c.mu.Unlock()
+
+				// 已被替换的旧连接关闭属于正常流程
+				if !primary {
+					return
+				}
+
+				// 通知错误
+				c.handleErr(err)
+
+				// 异步尝试重连，readLoop立即返回，防止死锁
+				c.reconnect()
+
+				return // 结束当前readLoop
+			}
+
+			if c.hooks.Filter != nil {
+				ok, err := c.hooks.Filter(conn, msg)
+				if err != nil {
+					c.handleErr(err)
+				}
+				if !ok {
+					continue
+				}
+			}
+
+			c.deliver(conn, msg)
+		}
+	}
+}
+
+// deliver 投递消息：主连接的消息直接投递，新连接或回补期间的消息暂存
+func (c *Client) deliver(conn *Conn, msg []byte) {
+	c.deliverMu.Lock()
+	defer c.deliverMu.Unlock()
+
+	c.mu.Lock()
+	primary := c.conn == conn
+	hold := conn == c.candidate || (primary && c.backfilling)
+	if hold {
+		if len(c.pending) < maxPendingMessages {
+			c.pending = append(c.pending, msg)
+		} else {
+			c.log("Pending buffer full, dropping message")
+		}
+	}
+	c.mu.Unlock()
+
+	if primary && !hold {
+		c.emit(msg)
+	}
+}
+
+// emit 去重后放入队列，未启用队列时直接调用Handler，调用方需持有deliverMu
+func (c *Client) emit(msg []byte) {
+	if !c.dedup.Accept(msg) {
+		return
+	}
+	c.mu.Lock()
+	q := c.queue
+	c.mu.Unlock()
+
+	if q != nil {
+		q.push(msg)
+		return
+	}
+	if c.cfg.Handler != nil {
+		c.cfg.Handler(msg)
+	}
+}
+
+// flush 投递暂存的消息，跳过重叠窗口内已投递的消息，调用方需持有deliverMu
+func (c *Client) flush(pending [][]byte) {
+	for _, msg := range pending {
+		if c.dedup.Seen(msg) {
+			continue
+		}
+		c.emit(msg)
+	}
+}
+
+// ping 发送心跳
+func (c *Client) ping(conn *Conn) error {
+	if c.hooks.Ping != nil {
+		return c.hooks.Ping(conn)
+	}
+	return conn.WriteMessage(websocket.PingMessage, nil)
+}
+
+func (c *Client) pingLoop() {
+	defer c.wg.Done()
+
+	ticker := c.opts.clock.NewTicker(c.opts.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C():
+			c.mu.Lock()
+			conn := c.conn
+			c.mu.Unlock()
+
+			if conn == nil {
+				continue
+			}
+
+			if err := c.ping(conn); err != nil {
+				c.handleErr(err)
+				// 异步重连
+				c.reconnect()
+				return
+			}
+		}
+	}
+}
+
+// autoReconnectLoop 定时轮换连接，例如Binance的连接24小时后会被断开
+func (c *Client) autoReconnectLoop() {
+	defer c.wg.Done()
+
+	ticker := c.opts.clock.NewTicker(c.opts.reconnectInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C():
+			c.log("Time-based reconnect triggered")
+			if c.opts.seamlessOverlap > 0 {
+				// 先建立新连接再断开旧连接，ticker继续用于下一次轮换
+				c.rollover()
+				continue
+			}
+			c.reconnect()
+			return
+		}
+	}
+}
+
+// rollover 无缝重连：先建立新连接，新旧连接并行seamlessOverlap时长，去重后切换到新连接
+func (c *Client) rollover() {
+	c.mu.Lock()
+	if c.reconnecting || c.candidate != nil {
+		c.mu.Unlock()
+		return
+	}
+	ctx := c.ctx
+	c.mu.Unlock()
+
+	conn, err := c.dial()
+	if err != nil {
+		// 新连接建立失败，旧连接继续工作，等待下一次轮换
+		return
+	}
+
+	c.dedup.BeginOverlap()
+	c.mu.Lock()
+	c.candidate = conn
+	c.pending = nil
+	c.mu.Unlock()
+
+	c.wg.Add(1)
+	go c.readLoop(conn)
+
+	select {
+	case <-ctx.Done():
+		// 断开或重连时会关闭新连接
+		c.dedup.EndOverlap()
+		return
+	case <-c.opts.clock.After(c.opts.seamlessOverlap):
+	}
+
+	c.deliverMu.Lock()
+	c.mu.Lock()
+	if c.candidate != conn {
+		// 新连接在并行期间失败
+		c.mu.Unlock()
+		c.deliverMu.Unlock()
+		c.dedup.EndOverlap()
+		c.log("Seamless reconnect aborted")
+		return
+	}
+	old := c.conn
+	c.conn = conn
+	c.candidate = nil
+	pending := c.pending
+	c.pending = nil
+	c.mu.Unlock()
+
+	c.flush(pending)
+	c.dedup.EndOverlap()
+	c.deliverMu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+	c.log("Seamless reconnect completed")
+}
+
+// backfill 回补断线期间缺失的数据，完成后投递回补期间暂存的实时消息
+func (c *Client) backfill(ctx context.Context) {
+	defer c.wg.Done()
+
+	msgs, err := c.cfg.BackfillFunc(ctx, c.dedup.Last())
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		c.handleErr(fmt.Errorf("backfill error: %w", err))
+	}
+
+	c.deliverMu.Lock()
+	defer c.deliverMu.Unlock()
+
+	for _, msg := range msgs {
+		c.emit(msg)
+	}
+
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = nil
+	c.backfilling = false
+	c.mu.Unlock()
+
+	c.flush(pending)
+}
+
+// reconnect 在新的goroutine中重连，已调用Disconnect时不再重连
+func (c *Client) reconnect() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.reconnectWG.Add(1)
+	go func() {
+		defer c.reconnectWG.Done()
+		c.attemptReconnect()
+	}()
+}
+
+// attemptReconnect 断开当前连接，按退避策略重连，直到成功、放弃或被Disconnect终止
+func (c *Client) attemptReconnect() {
+	c.mu.Lock()
+	if c.reconnecting {
+		c.mu.Unlock()
+		return
+	}
+	c.reconnecting = true
+	c.mu.Unlock()
+
+	c.log("Attempting reconnect...")
+	c.setState(stream.StateReconnecting, 0, nil)
+
+	// 停止当前上下文，等待goroutine全部退出
+	c.mu.Lock()
+	if c.cancel != nil {
+		c.cancel()
+	}
+	conn := c.conn
+	candidate := c.candidate
+	c.conn = nil
+	c.candidate = nil
+	c.pending = nil
+	c.backfilling = false
+	c.mu.Unlock()
+
+	if conn != nil {
+		conn.Close()
+	}
+	if candidate != nil {
+		candidate.Close()
+	}
+	c.dedup.EndOverlap()
+
+	// 等待所有当前goroutine结束
+	c.wg.Wait()
+
+	c.mu.Lock()
+	parent := c.parent
+	c.mu.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		if parent.Err() != nil {
+			// 已主动断开，放弃重连
+			c.mu.Lock()
+			c.reconnecting = false
+			c.mu.Unlock()
+			return
+		}
+
+		// 每次尝试使用新的上下文
+		ctx, cancel := context.WithCancel(parent)
+		c.mu.Lock()
+		c.ctx = ctx
+		c.cancel = cancel
+		c.mu.Unlock()
+
+		conn, err := c.dial()
+		if err == nil {
+			c.log("Reconnected successfully")
+			// 回补期间暂存实时消息，需在readLoop启动前设置
+			backfill := c.cfg.BackfillFunc != nil
+			c.mu.Lock()
+			c.reconnecting = false
+			if c.closed {
+				// 建立连接期间调用了Disconnect
+				c.mu.Unlock()
+				cancel()
+				conn.Close()
+				return
+			}
+			c.conn = conn
+			c.backfilling = backfill
+			c.mu.Unlock()
+			c.startGoroutines(conn)
+			if backfill {
+				c.wg.Add(1)
+				go c.backfill(ctx)
+			}
+			c.reconnects.Add(1)
+			c.setState(stream.StateConnected, attempt, nil)
+			return
+		}
+		cancel()
+
+		wait, ok := c.opts.backoff.Next(attempt)
+		if !ok {
+			c.mu.Lock()
+			c.reconnecting = false
+			c.mu.Unlock()
+			c.log(fmt.Sprintf("Failed to reconnect after %d attempts", attempt))
+			c.setState(stream.StateFailed, attempt, err)
+			return
+		}
+		c.setState(stream.StateReconnecting, attempt, err)
+
+		select {
+		case <-parent.Done():
+		case <-c.opts.clock.After(wait):
+		}
+	}
+}
+
+// setState 更新连接状态并通知stateHandler
+func (c *Client) setState(state stream.State, attempt int, err error) {
+	c.state.Store(int32(state))
+	if c.opts.stateHandler != nil {
+		c.opts.stateHandler(stream.StateEvent{
+			ID:         c.id,
+			State:      state,
+			Attempt:    attempt,
+			Reconnects: c.reconnects.Load(),
+			Err:        err,
+			Time:       c.opts.clock.Now(),
+		})
+	}
+}
+
+func (c *Client) handleErr(err error) {
+	if c.cfg.ErrorHandler != nil {
+		c.cfg.ErrorHandler(err)
+	}
+	c.log("Error: " + err.Error())
+}
+
+func (c *Client) log(msg string) {
+	if c.cfg.Logger != nil {
+		c.cfg.Logger.Info(msg)
+	}
+}
@@ -0,0 +1,333 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-gotop/gotop/clock"
+	"github.com/go-gotop/gotop/stream"
+)
+
+func TestClientHooks(t *testing.T) {
+	// 服务端：首条消息必须是auth，随后回复文本ping，推送一条事件消息和一条数据消息，第1个连接随后断开
+	upgrader := websocket.Upgrader{}
+	var mu sync.Mutex
+	var connectCount int
+	var pings int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		connectCount++
+		n := connectCount
+		mu.Unlock()
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, msg, err := conn.ReadMessage()
+		require.NoError(t, err)
+		require.Equal(t, "auth", string(msg))
+		_, msg, err = conn.ReadMessage()
+		require.NoError(t, err)
+		require.Equal(t, "subscribe", string(msg))
+
+		conn.WriteMessage(websocket.TextMessage, []byte("event"))
+		conn.WriteMessage(websocket.TextMessage, []byte("data"))
+		if n == 1 {
+			return
+		}
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if string(msg) == "ping" {
+				mu.Lock()
+				pings++
+				mu.Unlock()
+			}
+		}
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	var onConnects int
+	received := make(chan string, 8)
+	errs := make(chan error, 8)
+	client := NewClient("hooks_test", Hooks{
+		URL: func() string { return wsURL },
+		Auth: func(conn *Conn) error {
+			return conn.WriteMessage(websocket.TextMessage, []byte("auth"))
+		},
+		OnConnect: func(conn *Conn) error {
+			mu.Lock()
+			onConnects++
+			mu.Unlock()
+			return conn.WriteMessage(websocket.TextMessage, []byte("subscribe"))
+		},
+		Ping: func(conn *Conn) error {
+			return conn.WriteMessage(websocket.TextMessage, []byte("ping"))
+		},
+		Filter: func(conn *Conn, msg []byte) (bool, error) {
+			if string(msg) == "event" {
+				return false, errors.New("event filtered")
+			}
+			return true, nil
+		},
+	},
+		WithPingInterval(20*time.Millisecond),
+		WithBackoff(&stream.ConstantBackoff{Interval: 10 * time.Millisecond}),
+	)
+
+	require.NoError(t, client.Connect(context.Background(), Config{
+		Handler: func(data []byte) {
+			received <- string(data)
+		},
+		ErrorHandler: func(err error) {
+			errs <- err
+		},
+	}))
+	defer client.Disconnect()
+
+	// 两个连接各投递一条数据消息，事件消息被过滤
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-received:
+			require.Equal(t, "data", msg)
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for data")
+		}
+	}
+	require.Eventually(t, func() bool {
+		return client.Reconnects() == 1 && client.State() == stream.StateConnected
+	}, time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return pings > 0
+	}, time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	require.Equal(t, 2, onConnects, "OnConnect should run on every new connection")
+	mu.Unlock()
+
+	select {
+	case err := <-errs:
+		require.EqualError(t, err, "event filtered")
+	default:
+		t.Fatal("filter error should be reported")
+	}
+}
+
+func TestClientQueueSlowHandler(t *testing.T) {
+	// 服务端连续推送100条消息，Handler很慢，读取不应被阻塞
+	upgrader := websocket.Upgrader{}
+	sent := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		for i := 0; i < 100; i++ {
+			conn.WriteMessage(websocket.TextMessage, []byte{byte(i)})
+		}
+		close(sent)
+		time.Sleep(time.Second)
+	}))
+	defer server.Close()
+
+	release := make(chan struct{})
+	var releaseOnce sync.Once
+	received := make(chan []byte, 100)
+	client := NewClient("queue_test", Hooks{}, WithQueue(10, OverflowDropOldest))
+	require.NoError(t, client.Connect(context.Background(), Config{
+		URL: "ws" + strings.TrimPrefix(server.URL, "http"),
+		Handler: func(data []byte) {
+			<-release
+			received <- data
+		},
+	}))
+	defer client.Disconnect()
+	defer releaseOnce.Do(func() { close(release) })
+
+	<-sent
+	var stats QueueStats
+	require.Eventually(t, func() bool {
+		stats = client.QueueStats()
+		// 1条在Handler中，其余在队列中或被丢弃
+		return stats.Depth+int(stats.Dropped)+1 == 100
+	}, time.Second, 10*time.Millisecond)
+	require.GreaterOrEqual(t, stats.Depth, 9)
+
+	releaseOnce.Do(func() { close(release) })
+	var last []byte
+	for i := 0; i < stats.Depth+1; i++ {
+		last = <-received
+	}
+	// 保留的是最新的消息
+	require.Equal(t, []byte{99}, last)
+	require.Zero(t, client.QueueStats().Depth)
+	require.Equal(t, 10, client.QueueStats().Capacity)
+}
+
+func TestClientClock(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sim := clock.NewSimulated(start)
+	pings := make(chan time.Time, 4)
+	states := make(chan stream.StateEvent, 4)
+	client := NewClient("clock_test", Hooks{
+		URL: func() string { return "ws" + strings.TrimPrefix(server.URL, "http") },
+		Ping: func(conn *Conn) error {
+			pings <- sim.Now()
+			return nil
+		},
+	},
+		WithClock(sim),
+		WithPingInterval(time.Minute),
+		WithStateHandler(func(e stream.StateEvent) { states <- e }),
+	)
+	require.NoError(t, client.Connect(context.Background(), Config{Handler: func([]byte) {}}))
+	defer client.Disconnect()
+
+	// 状态事件使用模拟时间
+	for e := range states {
+		require.Equal(t, start, e.Time)
+		if e.State == stream.StateConnected {
+			break
+		}
+	}
+
+	// 等待心跳与定时重连的ticker创建后再推进时间
+	sim.BlockUntil(2)
+	select {
+	case <-pings:
+		t.Fatal("ping sent before interval elapsed")
+	default:
+	}
+
+	sim.Advance(time.Minute)
+	select {
+	case at := <-pings:
+		require.Equal(t, start.Add(time.Minute), at)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for ping")
+	}
+}
+
+func TestClientDisconnectDuringReconnect(t *testing.T) {
+	// 服务端：第1个连接立即断开触发重连，之后的连接阻塞读取直到客户端关闭
+	upgrader := websocket.Upgrader{}
+	var mu sync.Mutex
+	var connectCount int
+	closed := make(chan struct{}, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		connectCount++
+		n := connectCount
+		mu.Unlock()
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		if n == 1 {
+			return
+		}
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				closed <- struct{}{}
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	// 第2次dial阻塞，模拟Disconnect时重连的dial尚未返回
+	var dials int
+	dialing := make(chan struct{})
+	release := make(chan struct{})
+	dialer := func(urlStr string, header http.Header) (*websocket.Conn, *http.Response, error) {
+		mu.Lock()
+		dials++
+		n := dials
+		mu.Unlock()
+		if n == 2 {
+			close(dialing)
+			<-release
+		}
+		return DefaultDialer(urlStr, header)
+	}
+
+	client := NewClient("disconnect_test", Hooks{
+		URL: func() string { return "ws" + strings.TrimPrefix(server.URL, "http") },
+	},
+		WithDialer(dialer),
+		WithBackoff(&stream.ConstantBackoff{Interval: 10 * time.Millisecond}),
+	)
+	require.NoError(t, client.Connect(context.Background(), Config{Handler: func([]byte) {}}))
+
+	select {
+	case <-dialing:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for reconnect dial")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		client.Disconnect()
+		close(done)
+	}()
+	require.Eventually(t, func() bool {
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		return client.closed
+	}, time.Second, time.Millisecond)
+
+	// Disconnect需等待进行中的重连结束
+	select {
+	case <-done:
+		t.Fatal("disconnect returned before reconnect finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for disconnect")
+	}
+
+	// 重连建立的连接被关闭，客户端保持断开
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("reconnected conn was not closed")
+	}
+	require.Equal(t, stream.StateDisconnected, client.State())
+	require.Nil(t, client.Conn())
+	mu.Lock()
+	require.Equal(t, 2, dials)
+	mu.Unlock()
+}
@@ -3,6 +3,8 @@ package types
 import (
 	"fmt"
 	"strings"
+
+	"github.com/shopspring/decimal"
 )
 
 // AccountType 账户类型：1-AccountTypeClassic, 2-AccountTypeUnified
@@ -52,5 +54,52 @@ const (
 	AccountTypeUnified
 )
 
+// Account 交易所账户快照
 type Account struct {
+	// ID 账户ID
+	ID string
+	// Exchange 交易所
+	Exchange string
+	// Type 账户类型
+	Type AccountType
+	// Balances 各资产余额
+	Balances []Asset
+	// Positions 合约持仓
+	Positions []AccountPosition
+	// QuoteAsset Equity和MarginUsed的计价资产，如 USDT、USD
+	QuoteAsset string
+	// Equity 账户权益，HasEquity为false时表示交易所未提供，由余额和持仓估算
+	Equity decimal.Decimal
+	// HasEquity 交易所是否提供了Equity，提供的权益可以为0
+	HasEquity bool
+	// MarginUsed 已用保证金，HasMarginUsed为false时表示交易所未提供，由持仓占用保证金估算
+	MarginUsed decimal.Decimal
+	// HasMarginUsed 交易所是否提供了MarginUsed
+	HasMarginUsed bool
+	// MarginRatio 保证金率，已用保证金 / 账户权益
+	MarginRatio decimal.Decimal
+	// UpdateTime 更新时间
+	UpdateTime int64
+}
+
+// AccountPosition 账户合约持仓
+type AccountPosition struct {
+	// Symbol 交易对
+	Symbol string
+	// MarketType 市场类型
+	MarketType MarketType
+	// PositionSide 持仓方向
+	PositionSide PositionSide
+	// Size 持仓数量，多为正空为负
+	Size decimal.Decimal
+	// EntryPrice 开仓均价
+	EntryPrice decimal.Decimal
+	// MarkPrice 标记价格
+	MarkPrice decimal.Decimal
+	// UnrealizedPnL 未实现盈亏，以SettleAsset计
+	UnrealizedPnL decimal.Decimal
+	// Margin 占用保证金，以SettleAsset计
+	Margin decimal.Decimal
+	// SettleAsset 结算资产，如U本位合约为USDT、币本位合约为BTC，为空时与账户的QuoteAsset相同
+	SettleAsset string
 }
@@ -242,6 +242,8 @@ type TradeEvent struct {
 	Symbol string
 	// Exchange 交易所
 	Exchange string
+	// TradeID 交易所成交ID
+	TradeID uint64
 	// Size 成交数量
 	Size decimal.Decimal
 	// Price 成交价格
@@ -266,6 +268,11 @@ type Asset struct {
 	Locked decimal.Decimal
 }
 
+// Total 总余额
+func (a Asset) Total() decimal.Decimal {
+	return a.Free.Add(a.Locked)
+}
+
 type Symbol struct {
 	// OriginalSymbol 原标的物名称
 	OriginalSymbol string
@@ -233,3 +233,27 @@ func (t *TradeAggregate) PriceRange() (PricePoint, PricePoint) {
 	}
 	return t.PeakPrice, t.ValleyPrice
 }
+
+// VolumeDelta 成交量差，主动买入量减主动卖出量
+func (t *TradeAggregate) VolumeDelta() decimal.Decimal {
+	return t.BuyVolume.Sub(t.SellVolume)
+}
+
+// ImbalanceRatio 笔数失衡，(买单数量-卖单数量)/总数量，取值[-1, 1]，没有成交时为0
+func (t *TradeAggregate) ImbalanceRatio() decimal.Decimal {
+	total := t.BuyCount + t.SellCount
+	if total == 0 {
+		return decimal.Zero
+	}
+	diff := decimal.NewFromInt(int64(t.BuyCount)).Sub(decimal.NewFromInt(int64(t.SellCount)))
+	return diff.Div(decimal.NewFromInt(int64(total)))
+}
+
+// AggressorRatio 主动买入量占总成交量的比例，取值[0, 1]，没有成交时为0.5
+func (t *TradeAggregate) AggressorRatio() decimal.Decimal {
+	total := t.BuyVolume.Add(t.SellVolume)
+	if !total.IsPositive() {
+		return decimal.NewFromFloat(0.5)
+	}
+	return t.BuyVolume.Div(total)
+}